@@ -0,0 +1,168 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FindingKind classifies the problems reported by [Template.Analyze].
+type FindingKind int
+
+const (
+	// FindingAlwaysEmpty reports a tag that always renders empty with
+	// Execute, as it references a variable missing from the schema.
+	FindingAlwaysEmpty FindingKind = iota
+	// FindingAlwaysError reports a tag that always fails to execute, e.g.
+	// calling an unknown function or passing a wrong number of arguments.
+	FindingAlwaysError
+)
+
+func (k FindingKind) String() string {
+	if k == FindingAlwaysError {
+		return "always error"
+	}
+	return "always empty"
+}
+
+// Finding is a problem of a tag reported by [Template.Analyze].
+type Finding struct {
+	Kind FindingKind
+
+	// Template is the name of the template, if any.
+	Template string
+
+	// Tag is the content of the tag.
+	Tag string
+
+	// Offset is the byte offset of the start tag in the template source.
+	Offset int
+
+	Message string
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("offset %d: tag %q: %s: %s", f.Offset, f.Tag, f.Kind, f.Message)
+	if f.Template != "" {
+		s = fmt.Sprintf("template %q: %s", f.Template, s)
+	}
+	return s
+}
+
+// Analyze checks the tags of the template against schema without executing
+// any function, e.g. to block deploys of broken templates in CI. The schema
+// is a Map holding the variables and functions available at execution; the
+// values of variables only need to have the right shape for dotted paths.
+//
+// It reports tags guaranteed to render empty with Execute because they
+// reference a variable missing from the schema, and tags guaranteed to fail
+// because they call a function that is neither in the schema, the registry
+// nor the builtins, or pass a wrong number of arguments to one. Variables
+// assigned by blocks, aliases and case-insensitive keys are taken into
+// account; tags routed to tag handlers, tags with defaults, flush hints and
+// tags inside with and range blocks, whose names may resolve to fields of
+// the block value, are skipped.
+func (t *Template) Analyze(schema Map) []Finding {
+	defined := t.blockVars()
+	ec := t.newEvalContext()
+	var findings []Finding
+	offsets := t.tagOffsets()
+	for i, tag := range t.tags {
+		tagOffset := offsets[i]
+		if t.isBlockTag(i) || t.isScopedTag(i) || defined[tag] || t.isHandlerTag(tag) || t.hasDefault(tag) || t.isFlushHint(tag, schema) {
+			continue
+		}
+
+		report := func(kind FindingKind, format string, args ...any) {
+			findings = append(findings, Finding{Kind: kind, Template: t.name, Tag: tag, Offset: tagOffset, Message: fmt.Sprintf(format, args...)})
+		}
+		switch t.classifyTag(tag) {
+		case tagFunction:
+			fc, err := parseFunctionCall(tag)
+			if err != nil {
+				report(FindingAlwaysError, "%s", err)
+				continue
+			}
+			if msg := ec.checkSchemaCall(fc, schema); msg != "" {
+				report(FindingAlwaysError, "%s", msg)
+			}
+		case tagExpression:
+			if msg := ec.checkSchemaExpression(tag, schema); msg != "" {
+				report(FindingAlwaysError, "%s", msg)
+			}
+		default:
+			if _, ok := ec.lookup(schema, tag); !ok {
+				report(FindingAlwaysEmpty, "unknown variable %q", tag)
+			}
+		}
+	}
+	return findings
+}
+
+// checkSchemaCall returns a description of the first function of the call,
+// or of its nested calls, that is unknown, gets a wrong number of arguments
+// or has unsupported results.
+func (ec *evalContext) checkSchemaCall(fc *functionCall, schema Map) string {
+	fn, ok := ec.lookupFunc(fc.Name, schema)
+	if !ok {
+		return fmt.Sprintf("unknown function %q", fc.Name)
+	}
+	if !fc.hasSpread() && !isValidArgCount(reflect.TypeOf(fn.Fn), len(fc.Args)) {
+		return fmt.Sprintf("function %q called with %d argument(s), expected %s", fc.Name, len(fc.Args), arity(reflect.TypeOf(fn.Fn)))
+	}
+	if err := checkResults(fc.Name, reflect.TypeOf(fn.Fn)); err != nil {
+		return err.Error()
+	}
+	for _, arg := range fc.Args {
+		if s, ok := arg.(*spreadArg); ok {
+			arg = s.arg
+		}
+		switch a := arg.(type) {
+		case *functionCall:
+			if msg := ec.checkSchemaCall(a, schema); msg != "" {
+				return msg
+			}
+		case *expressionPlaceholder:
+			if msg := ec.checkSchemaExpression(a.expression, schema); msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// checkSchemaExpression checks the function calls of an expression like
+// checkSchemaCall.
+func (ec *evalContext) checkSchemaExpression(expr string, schema Map) string {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return err.Error()
+	}
+	for _, tok := range tokens {
+		if tok.typ != tokenFunctionCall {
+			continue
+		}
+		fc, err := parseFunctionCall(tok.value)
+		if err != nil {
+			return err.Error()
+		}
+		if msg := ec.checkSchemaCall(fc, schema); msg != "" {
+			return msg
+		}
+	}
+	if _, err := toPostfix(tokens); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// arity describes the number of arguments fnType expects.
+func arity(fnType reflect.Type) string {
+	n := fnType.NumIn()
+	if takesCallInfo(fnType) {
+		n--
+	}
+	if fnType.IsVariadic() {
+		return fmt.Sprintf("at least %d", n-1)
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,66 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	schema := Map{
+		"name":  "",
+		"items": []string{},
+		"greet": func(name string) string { return name },
+		"join":  func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+	}
+	template := "{{capture x}}{{name}}{{end}}{{x}} {{name}} {{nick}} {{greet(name)}} {{greet()}} {{nope(1)}} " +
+		"{{upper(greet(name, 'x'))}} {{join(', ', items...)}} {{name + missing(1)}} {{name + nick}} {{(missing(1)) + 1}}"
+	tpl := New(template, "{{", "}}")
+
+	findings := tpl.Analyze(schema)
+	expected := []struct {
+		kind FindingKind
+		tag  string
+		msg  string
+	}{
+		{FindingAlwaysEmpty, "nick", `unknown variable "nick"`},
+		{FindingAlwaysError, "greet()", `function "greet" called with 0 argument(s), expected 1`},
+		{FindingAlwaysError, "nope(1)", `unknown function "nope"`},
+		{FindingAlwaysError, "upper(greet(name, 'x'))", `function "greet" called with 2 argument(s), expected 1`},
+		{FindingAlwaysError, "name + missing(1)", "invalid function name: name + missing"},
+		{FindingAlwaysError, "(missing(1)) + 1", `unknown function "missing"`},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	for i, want := range expected {
+		f := findings[i]
+		if f.Kind != want.kind || f.Tag != want.tag || f.Message != want.msg {
+			t.Fatalf("unexpected finding %d: %s", i, f)
+		}
+		if !strings.HasPrefix(template[f.Offset:], "{{"+f.Tag+"}}") {
+			t.Fatalf("offset %d of finding %d doesn't point to its tag", f.Offset, i)
+		}
+	}
+}
+
+func TestAnalyzeStrippedSuffix(t *testing.T) {
+	template := "{{name|max:5}} {{nick}}"
+	findings := New(template, "{{", "}}").Analyze(Map{"name": ""})
+	if len(findings) != 1 || findings[0].Offset != strings.Index(template, "{{nick}}") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+}
+
+func TestAnalyzeOptions(t *testing.T) {
+	tpl := New("{{Name}} {{who}} {{shout(Name)}}", "{{", "}}",
+		WithCaseInsensitiveKeys(), WithAliases(map[string]string{"who": "name"}), WithRegistry(registryWith("shout", func(s string) string { return s })))
+	if findings := tpl.Analyze(Map{"name": ""}); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+}
+
+func registryWith(name string, fn any) *Registry {
+	r := NewRegistry()
+	r.Register(name, fn)
+	return r
+}
@@ -0,0 +1,33 @@
+package fasttemplate
+
+import "strings"
+
+// keywordSet assigns a variable, e.g. {{set total = price * qty}}.
+const keywordSet = "set"
+
+// parseSet splits the argument of a set tag into the assigned variable name
+// and expression.
+func parseSet(arg string) (name, expr string, ok bool) {
+	name, expr, found := strings.Cut(arg, "=")
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	// "set a == b" is a comparison, not an assignment
+	return name, expr, found && isValidFunctionName(name) && expr != "" && expr[0] != '='
+}
+
+// executeSet evaluates the expression of a set node and assigns its value
+// to the variable for the rest of the execution. The expression may also be
+// a string, number or boolean literal. Like the values of with blocks,
+// expressions referencing missing variables are nil unless
+// WithStrictIdentifiers is set.
+func (t *Template) executeSet(nd *node, s *scope, std bool) error {
+	s.ec.setTag(nd.tag, t.tags[nd.tag])
+	v, ok := defaultLiteral(nd.value)
+	if !ok {
+		var err error
+		if v, err = t.evalBlockValue(nd.value, s, std, resolveTag); err != nil {
+			return err
+		}
+	}
+	s.set(nd.name, v)
+	return nil
+}
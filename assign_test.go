@@ -0,0 +1,51 @@
+package fasttemplate
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSetTag(t *testing.T) {
+	m := Map{"price": 100, "qty": 2, "nums": []int{1, 2}, "name": "ann", "user": Map{"first": "bo"}, "upper": strings.ToUpper}
+	tests := map[string]string{
+		`{{set discounted = price * 0.9}}{{discounted}}`:                   "90",
+		`{{set total=price*qty}}{{total}} {{total + 1}}`:                   "200 201",
+		`{{set who = upper(name)}}Hi {{who}}`:                              "Hi ANN",
+		`{{set a = 1}}{{set b = a + 1}}{{set a = b * 10}}{{a}},{{b}}`:      "20,2",
+		`{{set big = price > 50}}{{if big}}big{{else}}small{{end}}`:        "big",
+		`{{set nick = alias ?? 'guest'}}{{nick}}`:                          "guest",
+		`{{set price = price / 2}}{{price}}`:                               "50",
+		`{{with user}}{{set greeting = 'Hi ' + first}}{{greeting}}{{end}}`: "Hi bo",
+		`{{range i in nums}}{{set sq = i * i}}{{sq}} {{end}}`:              "1 4 ",
+		`{{set a == b}}`: "",
+	}
+	for src, want := range tests {
+		if s := New(src, "{{", "}}").ExecuteString(m); s != want {
+			t.Fatalf("unexpected output of %q: %q, want %q", src, s, want)
+		}
+	}
+
+	// the substitution map passed by the caller is never modified
+	tpl := New(`{{set price = 1}}{{price}}`, "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "1" || m["price"] != 100 {
+		t.Fatalf("unexpected output %q or map %v", s, m)
+	}
+
+	if _, err := New(`{{set x = fail()}}`, "{{", "}}").Execute(io.Discard, Map{"fail": func() (int, error) { return 0, io.EOF }}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSetTagAnalysis(t *testing.T) {
+	tpl := New(`{{set total = price * qty}}{{total}} {{name}}`, "{{", "}}")
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "price,qty,name" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+	if err := tpl.Validate(Map{"price": 1, "qty": 1, "name": ""}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if findings := tpl.Analyze(Map{"price": 1, "qty": 1, "name": ""}); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+}
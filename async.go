@@ -0,0 +1,143 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Future is a value computed asynchronously. Functions called from templates
+// may return a Future instead of a value to have [Template.ExecuteAsync]
+// compute it concurrently with the other tags.
+//
+// The function blocks until the value is available. Other values, including
+// channels, are never awaited; wrap channels with [FutureFromChan].
+type Future func() (any, error)
+
+// FutureFromChan returns a Future receiving the first value sent on ch. An
+// error value fails the tag, and a closed channel results in nil.
+func FutureFromChan[T any](ch <-chan T) Future {
+	return func() (any, error) {
+		v, ok := <-ch
+		if !ok {
+			return nil, nil
+		}
+		if err, ok := any(v).(error); ok {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// awaitFuture waits for the result of the future f.
+func awaitFuture(f Future) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("future: %v", r)
+		}
+	}()
+	return f()
+}
+
+// asyncTag is a tag resolved by ExecuteAsync.
+type asyncTag struct {
+	v    any
+	kind tagKind
+	err  error
+
+	// done is closed once v is available, nil if it was available
+	// immediately.
+	done chan struct{}
+}
+
+// ExecuteAsync works like Execute, but resolves futures concurrently: all
+// tags are evaluated up front, every [Future] they produce is started right
+// away, and the results are then written in template order as they
+// complete. Templates calling several independent remote lookups only wait
+// for the slowest one instead of all of them in turn.
+//
+// Templates with block tags or a token budget are executed sequentially,
+// like Execute.
+func (t *Template) ExecuteAsync(w io.Writer, m Map) (int64, error) {
+	if t.nodes != nil || t.tokenizer != nil || len(t.texts) == 0 {
+		return t.Execute(w, m)
+	}
+
+	ec := t.newEvalContext()
+	ec.async = true
+	ec.color = t.useColor(w)
+	var n int64
+	var err error
+	if t.renderHooks != nil {
+		defer t.observeRender(time.Now(), &n, &err, ec)
+	}
+	if len(t.outputStages) > 0 {
+		fw, finish := t.filterOutput(w)
+		_, err = t.executeAsync(fw, m, ec)
+		var ferr error
+		n, ferr = finish()
+		if err == nil {
+			err = ferr
+		}
+	} else {
+		n, err = t.executeAsync(w, m, ec)
+	}
+	return n, t.formatError(err)
+}
+
+// executeAsync implements ExecuteAsync for templates without blocks.
+func (t *Template) executeAsync(w io.Writer, m Map, ec *evalContext) (int64, error) {
+	tags := make([]asyncTag, len(t.tags))
+	for i := range tags {
+		at := &tags[i]
+		if t.isFlushHint(t.tags[i], m) {
+			continue
+		}
+		ec.setTag(i, t.tags[i])
+		at.v, at.kind, at.err = resolveTag(t.tags[i], m, ec)
+		if f, ok := at.v.(Future); ok && at.err == nil {
+			at.done = make(chan struct{})
+			go func() {
+				at.v, at.err = awaitFuture(f)
+				close(at.done)
+			}()
+		}
+	}
+	// wait for the pending futures if writing fails, so that none of them
+	// outlives the execution
+	defer func() {
+		for i := range tags {
+			if tags[i].done != nil {
+				<-tags[i].done
+			}
+		}
+	}()
+
+	var nn int64
+	for i := range tags {
+		ni, err := writeFull(w, t.texts[i])
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+
+		if t.isFlushHint(t.tags[i], m) {
+			if err := flushWriter(w); err != nil {
+				return nn, err
+			}
+			continue
+		}
+		at := &tags[i]
+		if at.done != nil {
+			<-at.done
+		}
+		ni, err = t.writeResolved(w, i, at.v, at.kind, at.err, false)
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+	}
+	ni, err := writeFull(w, t.texts[len(t.texts)-1])
+	nn += int64(ni)
+	return nn, err
+}
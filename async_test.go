@@ -0,0 +1,116 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteAsync(t *testing.T) {
+	var running, maxRunning int32
+	lookup := func(name string) Future {
+		return func() (any, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return strings.ToUpper(name), nil
+		}
+	}
+	ch := make(chan string, 1)
+	ch <- "from channel"
+	m := Map{
+		"lookup": lookup,
+		"ch":     func() Future { return FutureFromChan(ch) },
+		"plain":  "plain",
+	}
+
+	tpl := New("{{lookup('a')}} {{plain}} {{lookup('b')}} {{lookup('c')}} {{ch()}}", "{{", "}}")
+	var sb strings.Builder
+	n, err := tpl.ExecuteAsync(&sb, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "A plain B C from channel"; sb.String() != expected || n != int64(len(expected)) {
+		t.Fatalf("unexpected output %q (%d bytes)", sb.String(), n)
+	}
+	if maxRunning != 3 {
+		t.Fatalf("expected 3 futures running concurrently, got %d", maxRunning)
+	}
+
+	// futures are awaited in place by the other execution methods
+	maxRunning = 0
+	if s := New("{{lookup('a')}} {{lookup('b')}}", "{{", "}}").ExecuteString(m); s != "A B" || maxRunning != 1 {
+		t.Fatalf("unexpected output %q with %d futures running concurrently", s, maxRunning)
+	}
+}
+
+func TestExecuteAsyncErrors(t *testing.T) {
+	m := Map{
+		"fail": func() Future {
+			return func() (any, error) { return nil, errors.New("lookup failed") }
+		},
+		"boom": func() Future {
+			return func() (any, error) { panic("boom") }
+		},
+	}
+
+	for _, template := range []string{"a {{fail()}} b", "a {{boom()}} b"} {
+		tpl := New(template, "{{", "}}")
+		var sb strings.Builder
+		if _, err := tpl.ExecuteAsync(&sb, m); err == nil {
+			t.Fatalf("expected error for %q", template)
+		}
+		if s := tpl.ExecuteStringStd(m); s != template {
+			t.Fatalf("unexpected output %q", s)
+		}
+	}
+}
+
+func TestExecuteAsyncChannels(t *testing.T) {
+	// channels are values, not futures
+	buffered := make(chan int, 1)
+	buffered <- 1
+	unbuffered := make(chan int)
+	m := Map{"buffered": buffered, "unbuffered": unbuffered}
+	tpl := New("{{buffered}}{{unbuffered}}", "{{", "}}")
+	var sb strings.Builder
+	if _, err := tpl.ExecuteAsync(&sb, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(buffered) != 1 {
+		t.Fatal("channel values must not be received from")
+	}
+
+	errCh := make(chan error, 1)
+	errCh <- errors.New("lookup failed")
+	if _, err := New("{{f()}}", "{{", "}}").Execute(&sb, Map{"f": func() Future { return FutureFromChan(errCh) }}); err == nil {
+		t.Fatal("expected the error sent on the channel")
+	}
+}
+
+func TestExecuteAsyncWriteError(t *testing.T) {
+	// pending futures finish before ExecuteAsync returns
+	var finished int32
+	m := Map{"slow": func() Future {
+		return func() (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&finished, 1)
+			return "x", nil
+		}
+	}}
+	tpl := New("a{{slow()}}{{slow()}}", "{{", "}}")
+	if _, err := tpl.ExecuteAsync(&limitWriter{limit: 0}, m); err == nil {
+		t.Fatal("expected write error")
+	}
+	if n := atomic.LoadInt32(&finished); n != 2 {
+		t.Fatalf("expected 2 finished futures, got %d", n)
+	}
+}
@@ -0,0 +1,79 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// RenderBatch renders every template of tpls against m, e.g. the templates
+// of a notification fanned out to several channels.
+//
+// The executions share the results of memoized function calls, so an
+// idempotent function costing at least CostMedium is called once per
+// distinct arguments across the batch, and the string forms of numeric and
+// boolean variables are computed once per value. Results are only shared
+// between templates resolving the function from the same place: m, the same
+// registry or the builtins.
+//
+// It returns the outputs of all templates, leaving the outputs of failed
+// templates empty, together with the errors of the failed templates.
+func RenderBatch(tpls []*Template, m Map) ([]string, error) {
+	shared := &batchState{memo: make(map[string]any)}
+	out := make([]string, len(tpls))
+	var errs []error
+	var bb bytebufferpool.ByteBuffer
+	for i, t := range tpls {
+		bb.Reset()
+		var err error
+		if v, ok := t.singleValue(m); ok {
+			_, err = t.writeSingle(&bb, v)
+		} else {
+			ec := t.newEvalContext()
+			ec.memo = shared.memo
+			ec.batch = shared
+			_, err = t.executeContext(&bb, m, ec, false)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("template %d: %w", i, t.formatError(err)))
+			continue
+		}
+		out[i] = string(bb.B)
+	}
+	return out, errors.Join(errs...)
+}
+
+// batchState holds the state shared by the executions of RenderBatch.
+type batchState struct {
+	memo      map[string]any
+	formatted map[any]string
+}
+
+// format returns the string form of the variable value v if it is a
+// number or boolean formatted with fmt, computing it once per value.
+func (b *batchState) format(v any) any {
+	if !isScalarInput(v) {
+		return v
+	}
+	switch v.(type) {
+	case nil, string, time.Time:
+		return v
+	}
+	if s, ok := b.formatted[v]; ok {
+		return s
+	}
+	s := fmt.Sprintf("%v", v)
+	if b.formatted == nil {
+		b.formatted = make(map[any]string)
+	}
+	b.formatted[v] = s
+	return s
+}
+
+// formatsPlainly reports whether the values of variable tags are written
+// as formatted, so their string forms may be shared with other templates.
+func (t *Template) formatsPlainly() bool {
+	return t.transformers == nil && t.timeLayout == "" && t.contexts == nil
+}
@@ -0,0 +1,70 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderBatch(t *testing.T) {
+	calls := 0
+	lookup := Func{Fn: func(id string) string {
+		calls++
+		return "Ann#" + id
+	}, Idempotent: true, Cost: CostHigh}
+	m := Map{"user": lookup, "id": "7", "amount": 12.5}
+
+	tpls := []*Template{
+		New("Email: Hi {{user(id)}}, you paid {{amount}}", "{{", "}}"),
+		New("SMS: {{user(id)}} paid {{amount}}", "{{", "}}"),
+		New("{{id}}", "{{", "}}"),
+		New("Push: {{missing()}}", "{{", "}}"),
+		New("<b>{{amount}}</b>", "{{", "}}", WithEscaping(EscapeHTML)),
+	}
+	out, err := RenderBatch(tpls, m)
+	expected := []string{"Email: Hi Ann#7, you paid 12.5", "SMS: Ann#7 paid 12.5", "7", "", "<b>12.5</b>"}
+	if len(out) != len(expected) {
+		t.Fatalf("unexpected outputs %q", out)
+	}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Fatalf("unexpected output %d %q. Expected %q", i, out[i], expected[i])
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected the idempotent function to be called once, got %d", calls)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "template 3: ") || !errors.Is(err, errFunctionNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRenderBatchRegistries(t *testing.T) {
+	// templates binding a name to different functions don't share results
+	upper := NewRegistry()
+	upper.Register("f", Func{Fn: strings.ToUpper, Idempotent: true, Cost: CostHigh})
+	lower := NewRegistry()
+	lower.Register("f", Func{Fn: strings.ToLower, Idempotent: true, Cost: CostHigh})
+	out, err := RenderBatch([]*Template{
+		New("{{f('Ab')}}", "{{", "}}", WithRegistry(upper)),
+		New("{{f('Ab')}}", "{{", "}}", WithRegistry(lower)),
+	}, Map{})
+	if err != nil || out[0] != "AB" || out[1] != "ab" {
+		t.Fatalf("unexpected outputs %q, %v", out, err)
+	}
+
+	// closures made by the same factory share their code
+	greeter := func(greeting string) Func {
+		return Func{Fn: func(name string) string { return greeting + name }, Idempotent: true, Cost: CostHigh}
+	}
+	en, de := NewRegistry(), NewRegistry()
+	en.Register("greet", greeter("Hi "))
+	de.Register("greet", greeter("Hallo "))
+	out, err = RenderBatch([]*Template{
+		New("{{greet(name)}}", "{{", "}}", WithRegistry(en)),
+		New("{{greet(name)}}", "{{", "}}", WithRegistry(de)),
+	}, Map{"name": "Ann"})
+	if err != nil || out[0] != "Hi Ann" || out[1] != "Hallo Ann" {
+		t.Fatalf("unexpected outputs %q, %v", out, err)
+	}
+}
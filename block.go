@@ -0,0 +1,556 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Block tag keywords.
+const (
+	keywordCapture  = "capture"
+	keywordSection  = "section"
+	keywordIf       = "if"
+	keywordElse     = "else"
+	keywordWith     = "with"
+	keywordBreak    = "break"
+	keywordContinue = "continue"
+	keywordEnd      = "end"
+)
+
+// nodeKind is the kind of a parsed template node.
+type nodeKind int
+
+const (
+	nodeText nodeKind = iota
+	nodeTag
+	nodeCapture
+	nodeSection
+	nodeIf
+	nodeWith
+	nodeRange
+	nodeBreak
+	nodeContinue
+	nodeBlock
+	nodeExtends
+	nodeSet
+)
+
+// node is an element of the parsed template tree. The tree is only built
+// for templates containing block tags; plain templates are executed from
+// Template.texts and Template.tags directly.
+type node struct {
+	kind nodeKind
+	text []byte
+	// tag is the index into Template.tags for nodeTag and block nodes.
+	tag int
+	// name is the variable name of a capture block or set tag, the name of
+	// a section or block, the condition of an if block, the value of a with
+	// or range block or the layout of an extends tag.
+	name  string
+	nodes []node
+	// els holds the else branch of an if, with or range block. An else if
+	// branch is a single nested if node.
+	els []node
+	// key and elem are the names bound by a range block, if any.
+	key  string
+	elem string
+	// loop reports that the body of a range block may reference the loop
+	// variable.
+	loop bool
+	// value is the expression assigned by a set tag.
+	value string
+}
+
+// parseBlockTag splits a block tag into its keyword and argument.
+//
+// It returns false if the tag isn't a block tag.
+func parseBlockTag(tag string) (keyword, arg string, ok bool) {
+	tag = strings.TrimSpace(tag)
+	keyword, arg, _ = strings.Cut(tag, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch keyword {
+	case keywordCapture:
+		return keyword, arg, isValidFunctionName(arg)
+	case keywordSection, keywordBlock, keywordExtends:
+		name, ok := unquoteName(arg)
+		return keyword, name, ok
+	case keywordIf, keywordWith:
+		return keyword, arg, arg != ""
+	case keywordElse:
+		// arg is empty or holds the condition of an else if branch
+		if arg == "" {
+			return keyword, "", true
+		}
+		cond, ok := strings.CutPrefix(arg, keywordIf+" ")
+		cond = strings.TrimSpace(cond)
+		return keyword, cond, ok && cond != ""
+	case keywordRange:
+		_, _, _, ok := parseRange(arg)
+		return keyword, arg, ok
+	case keywordSet:
+		_, _, ok := parseSet(arg)
+		return keyword, arg, ok
+	case keywordBreak, keywordContinue, keywordEnd:
+		return keyword, arg, arg == ""
+	}
+	return "", "", false
+}
+
+// unquoteName returns the content of a non-empty single or double quoted
+// string without escapes.
+func unquoteName(s string) (string, bool) {
+	if len(s) < 3 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] {
+		return "", false
+	}
+	name := s[1 : len(s)-1]
+	return name, !strings.ContainsAny(name, "\"'\\")
+}
+
+// hasBlockTags reports whether any of the tags opens a block.
+func hasBlockTags(tags []string) bool {
+	for _, tag := range tags {
+		if kw, _, ok := parseBlockTag(tag); ok && kw != keywordEnd && kw != keywordElse && kw != keywordBreak && kw != keywordContinue {
+			return true
+		}
+	}
+	return false
+}
+
+// blockFrame is an open block while building the node tree.
+type blockFrame struct {
+	node  node
+	nodes []node
+	// inElse reports that nodes belong to the else branch of an if, with
+	// or range block.
+	inElse bool
+	// chained marks the if node of an else if branch, which is closed
+	// together with the enclosing if block.
+	chained bool
+}
+
+// parseBlocks builds t.nodes from t.texts and t.tags if the template
+// contains block tags.
+//
+// An end tag outside of any block is treated as a regular tag, so templates
+// using "end" as a plain variable keep working.
+func (t *Template) parseBlocks() error {
+	open := t.buildBlocks()
+	if len(open) > 0 {
+		t.nodes = nil
+		return fmt.Errorf("missing %s%s%s for block tag %s%s%s", t.startTag, keywordEnd, t.endTag, t.startTag, t.tags[open[0]], t.endTag)
+	}
+	var err error
+	t.checkRanges(func(i int, rerr error) {
+		if err == nil {
+			err = fmt.Errorf("block tag %s%s%s: %w", t.startTag, t.tags[i], t.endTag, rerr)
+		}
+	})
+	if err != nil {
+		t.nodes = nil
+	}
+	return err
+}
+
+// buildBlocks builds t.nodes like parseBlocks, closing blocks missing their
+// end tag at the end of the template. It returns the indexes of the tags
+// opening these blocks.
+func (t *Template) buildBlocks() []int {
+	t.nodes = nil
+	t.blockTags = nil
+	t.scopedTags = nil
+	if t.legacy || !hasBlockTags(t.tags) {
+		return nil
+	}
+
+	t.blockTags = make([]bool, len(t.tags))
+	stack := []blockFrame{{}}
+	// closeBlock closes the innermost block, or chain of else if blocks,
+	// and returns the index of the tag opening it.
+	closeBlock := func() int {
+		for {
+			done := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if done.inElse {
+				done.node.els = done.nodes
+			} else {
+				done.node.nodes = done.nodes
+			}
+			if done.node.kind == nodeRange {
+				done.node.loop = t.usesLoop(done.node.nodes)
+			}
+			parent := &stack[len(stack)-1]
+			parent.nodes = append(parent.nodes, done.node)
+			if !done.chained {
+				return done.node.tag
+			}
+		}
+	}
+	for i, text := range t.texts {
+		cur := &stack[len(stack)-1]
+		if len(text) > 0 {
+			cur.nodes = append(cur.nodes, node{kind: nodeText, text: text})
+		}
+		if i >= len(t.tags) {
+			break
+		}
+
+		for _, f := range stack {
+			if (f.node.kind == nodeWith || f.node.kind == nodeRange) && !f.inElse {
+				if t.scopedTags == nil {
+					t.scopedTags = make([]bool, len(t.tags))
+				}
+				t.scopedTags[i] = true
+				break
+			}
+		}
+
+		kw, arg, ok := parseBlockTag(t.tags[i])
+		if kw == keywordElse && ((cur.node.kind != nodeIf && cur.node.kind != nodeWith && cur.node.kind != nodeRange) || cur.inElse || len(stack) == 1) {
+			// like end, else outside of an if block is a regular tag
+			ok = false
+		}
+		if (kw == keywordBreak || kw == keywordContinue) && !inRange(stack) {
+			ok = false
+		}
+		if kw == keywordExtends && len(stack) > 1 {
+			// layouts can only be extended at the top level
+			ok = false
+		}
+		if !ok || (kw == keywordEnd && len(stack) == 1) {
+			cur.nodes = append(cur.nodes, node{kind: nodeTag, tag: i})
+			continue
+		}
+
+		t.blockTags[i] = true
+		switch kw {
+		case keywordCapture:
+			stack = append(stack, blockFrame{node: node{kind: nodeCapture, tag: i, name: arg}})
+		case keywordSection:
+			stack = append(stack, blockFrame{node: node{kind: nodeSection, tag: i, name: arg}})
+		case keywordBlock:
+			stack = append(stack, blockFrame{node: node{kind: nodeBlock, tag: i, name: arg}})
+		case keywordExtends:
+			cur.nodes = append(cur.nodes, node{kind: nodeExtends, tag: i, name: arg})
+		case keywordIf:
+			stack = append(stack, blockFrame{node: node{kind: nodeIf, tag: i, name: arg}})
+		case keywordWith:
+			stack = append(stack, blockFrame{node: node{kind: nodeWith, tag: i, name: arg}})
+		case keywordRange:
+			key, elem, expr, _ := parseRange(arg)
+			stack = append(stack, blockFrame{node: node{kind: nodeRange, tag: i, name: expr, key: key, elem: elem}})
+		case keywordElse:
+			cur.node.nodes = cur.nodes
+			cur.nodes = nil
+			cur.inElse = true
+			if arg != "" {
+				stack = append(stack, blockFrame{node: node{kind: nodeIf, tag: i, name: arg}, chained: true})
+			}
+		case keywordSet:
+			name, expr, _ := parseSet(arg)
+			cur.nodes = append(cur.nodes, node{kind: nodeSet, tag: i, name: name, value: expr})
+		case keywordBreak:
+			cur.nodes = append(cur.nodes, node{kind: nodeBreak, tag: i})
+		case keywordContinue:
+			cur.nodes = append(cur.nodes, node{kind: nodeContinue, tag: i})
+		case keywordEnd:
+			closeBlock()
+		}
+	}
+
+	var open []int
+	for len(stack) > 1 {
+		open = append(open, closeBlock())
+	}
+	t.nodes = extendLayout(stack[0].nodes)
+	return open
+}
+
+// scope holds the per-execution state of a template with blocks.
+type scope struct {
+	// data is the substitution map. It is copied on the first assignment, so
+	// the caller's map is never modified.
+	data  Map
+	owned bool
+
+	// routes maps section names to their writers when executing with
+	// ExecuteMulti. Sections are rendered in place if it is nil.
+	routes map[string]io.Writer
+
+	// ec is the evaluation context of the execution.
+	ec *evalContext
+}
+
+// set assigns a per-execution variable.
+func (s *scope) set(name string, v any) {
+	if !s.owned {
+		data := make(Map, len(s.data)+1)
+		for k, v := range s.data {
+			data[k] = v
+		}
+		s.data = data
+		s.owned = true
+	}
+	s.data[name] = v
+}
+
+// executeNodes renders the nodes to w.
+func (t *Template) executeNodes(w io.Writer, nodes []node, s *scope, std bool) (int64, error) {
+	var nn int64
+	for i := range nodes {
+		nd := &nodes[i]
+		switch nd.kind {
+		case nodeText:
+			ni, err := writeFull(w, nd.text)
+			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
+		case nodeTag:
+			ni, err := t.writeTag(w, nd.tag, s.data, s.ec, std)
+			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
+		case nodeCapture:
+			bb := t.getBuffer()
+			_, err := t.executeNodes(bb, nd.nodes, s, std)
+			captured := string(bb.B)
+			t.putBuffer(bb)
+			if err != nil {
+				return nn, err
+			}
+			s.set(nd.name, captured)
+		case nodeSection:
+			if s.routes == nil {
+				ni, err := t.executeNodes(w, nd.nodes, s, std)
+				nn += ni
+				if err != nil {
+					return nn, err
+				}
+				continue
+			}
+			sw, ok := s.routes[nd.name]
+			if !ok {
+				// unrouted sections are still evaluated, so errors surface
+				if _, err := t.executeNodes(io.Discard, nd.nodes, s, std); err != nil {
+					return nn, err
+				}
+				continue
+			}
+			ni, err := t.executeNodes(sw, nd.nodes, s, std)
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+		case nodeIf:
+			s.ec.setTag(nd.tag, t.tags[nd.tag])
+			ok, err := t.evalCondition(nd.name, s, std)
+			if err != nil {
+				return nn, err
+			}
+			branch := nd.els
+			if ok {
+				branch = nd.nodes
+			}
+			ni, err := t.executeNodes(w, branch, s, std)
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+		case nodeWith:
+			s.ec.setTag(nd.tag, t.tags[nd.tag])
+			v, err := t.evalWith(nd.name, s, std)
+			if err != nil {
+				return nn, err
+			}
+			if !truthy(v) {
+				ni, err := t.executeNodes(w, nd.els, s, std)
+				nn += ni
+				if err != nil {
+					return nn, err
+				}
+				continue
+			}
+			s.ec.with = append(s.ec.with, v)
+			ni, err := t.executeNodes(w, nd.nodes, s, std)
+			s.ec.with = s.ec.with[:len(s.ec.with)-1]
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+		case nodeBlock:
+			ni, err := t.executeBlock(w, nd, s, std)
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+		case nodeExtends:
+			ni, err := t.executeExtends(w, nd, s, std)
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+		case nodeSet:
+			if err := t.executeSet(nd, s, std); err != nil {
+				return nn, err
+			}
+		case nodeBreak:
+			return nn, errBreak
+		case nodeContinue:
+			return nn, errContinue
+		case nodeRange:
+			s.ec.setTag(nd.tag, t.tags[nd.tag])
+			v, err := t.evalBlockValue(nd.name, s, std, resolveRanged)
+			if err != nil {
+				return nn, err
+			}
+			ni, count, err := t.executeRange(w, nd, v, s, std)
+			nn += ni
+			if err != nil {
+				return nn, err
+			}
+			if count == 0 {
+				ni, err := t.executeNodes(w, nd.els, s, std)
+				nn += ni
+				if err != nil {
+					return nn, err
+				}
+			}
+		}
+	}
+	return nn, nil
+}
+
+// evalWith evaluates the value of a with block, which may also be a dotted
+// path selecting a field of a variable. Missing values are nil like the
+// conditions of if blocks are false.
+func (t *Template) evalWith(arg string, s *scope, std bool) (any, error) {
+	return t.evalBlockValue(arg, s, std, resolveTag)
+}
+
+// evalBlockValue implements evalWith, resolving arg with resolve.
+func (t *Template) evalBlockValue(arg string, s *scope, std bool, resolve func(string, Map, *evalContext) (any, tagKind, error)) (any, error) {
+	v, _, err := resolve(arg, s.data, s.ec)
+	if errors.Is(err, errVariableNotFound) {
+		if pv, ok := s.ec.lookupPath(s.data, arg); ok {
+			return pv, nil
+		}
+	}
+	if err != nil {
+		if std || isUnavailable(err) || (errors.Is(err, errVariableNotFound) && !t.strictIdentifiers) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot evaluate %q: %w", arg, err)
+	}
+	return v, nil
+}
+
+// lookupWith returns the field of the innermost with block value matching
+// name, which may be a dotted path, or the value itself if name is ".".
+func (ec *evalContext) lookupWith(name string) (any, bool) {
+	for i := len(ec.with) - 1; i >= 0; i-- {
+		if name == "." {
+			return ec.with[i], true
+		}
+		if v, ok := fieldValue(ec.with[i], name); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// evalCondition evaluates the condition of an if block. Like missing
+// variables rendering empty, conditions referencing them are false unless
+// WithStrictIdentifiers is set; with ExecuteStd, failing conditions are
+// false.
+func (t *Template) evalCondition(cond string, s *scope, std bool) (bool, error) {
+	v, _, err := resolveTag(cond, s.data, s.ec)
+	if err != nil {
+		if std || isUnavailable(err) || (errors.Is(err, errVariableNotFound) && !t.strictIdentifiers) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot evaluate condition %q: %w", cond, err)
+	}
+	return truthy(v), nil
+}
+
+// truthy reports whether v holds: true, a non-zero number, a string other
+// than "", "0" and "false", a non-empty slice or map, or any other non-nil
+// value.
+func truthy(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0
+	case reflect.String:
+		s := rv.String()
+		return s != "" && s != "0" && s != "false"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Pointer, reflect.Interface, reflect.Func, reflect.Chan:
+		return !rv.IsNil()
+	}
+	return true
+}
+
+// blockCond returns the condition of the i-th tag if it opens an if block
+// or an else if branch, its value if it opens a with or range block, or the
+// assigned expression if it is a set tag.
+func (t *Template) blockCond(i int) (string, bool) {
+	if !t.isBlockTag(i) {
+		return "", false
+	}
+	kw, arg, _ := parseBlockTag(t.tags[i])
+	switch kw {
+	case keywordRange:
+		_, _, arg, _ = parseRange(arg)
+	case keywordSet:
+		_, arg, _ = parseSet(arg)
+	}
+	return arg, (kw == keywordIf || kw == keywordElse || kw == keywordWith || kw == keywordRange || kw == keywordSet) && arg != ""
+}
+
+// isScopedTag reports whether the i-th tag is inside a with or range block,
+// where its names may resolve to fields of the block value.
+func (t *Template) isScopedTag(i int) bool {
+	return t.scopedTags != nil && t.scopedTags[i]
+}
+
+// isBlockTag reports whether the i-th tag is consumed by block syntax.
+func (t *Template) isBlockTag(i int) bool {
+	return t.blockTags != nil && t.blockTags[i]
+}
+
+// blockVars returns the names of the variables assigned by capture blocks
+// and set tags.
+func (t *Template) blockVars() map[string]bool {
+	var vars map[string]bool
+	for i, tag := range t.tags {
+		if !t.isBlockTag(i) {
+			continue
+		}
+		kw, arg, _ := parseBlockTag(tag)
+		if kw == keywordSet {
+			arg, _, _ = parseSet(arg)
+		} else if kw != keywordCapture {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]bool)
+		}
+		vars[arg] = true
+	}
+	return vars
+}
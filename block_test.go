@@ -0,0 +1,271 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCaptureBlock(t *testing.T) {
+	tpl := New("{{capture summary}}Order {{id}} for {{upper(name)}}{{end}}Subject: {{summary}}\nBody: {{summary}}!", "{{", "}}")
+
+	m := Map{
+		"id":    42,
+		"name":  "john",
+		"upper": strings.ToUpper,
+	}
+	s := tpl.ExecuteString(m)
+	expected := "Subject: Order 42 for JOHN\nBody: Order 42 for JOHN!"
+	if s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+	if _, ok := m["summary"]; ok {
+		t.Fatalf("capture must not modify the caller's map")
+	}
+
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+}
+
+func TestCaptureBlockInExpressions(t *testing.T) {
+	tpl := New("{{capture greeting}}Hello, {{name}}{{end}}{{greeting + '!'}} {{len(greeting)}}", "{{", "}}")
+
+	s := tpl.ExecuteString(Map{
+		"name": "Ann",
+		"len":  func(s string) int { return len(s) },
+	})
+	if expected := "Hello, Ann! 10"; s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+}
+
+func TestNestedCaptureBlocks(t *testing.T) {
+	tpl := New("{{capture outer}}[{{capture inner}}{{v}}{{end}}{{inner}}{{inner}}]{{end}}{{outer}}", "{{", "}}")
+
+	if s := tpl.ExecuteString(Map{"v": "x"}); s != "[xx]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestCaptureBlockStd(t *testing.T) {
+	tpl := New("{{capture c}}{{known}}-{{unknown}}{{end}}<{{c}}>", "{{", "}}")
+
+	if s := tpl.ExecuteStringStd(Map{"known": "k"}); s != "<k-{{unknown}}>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestCaptureBlockError(t *testing.T) {
+	tpl := New("before {{capture c}}{{fail()}}{{end}}{{c}}", "{{", "}}")
+
+	var bb bytes.Buffer
+	_, err := tpl.Execute(&bb, Map{})
+	if err == nil {
+		t.Fatalf("expecting error")
+	}
+	if bb.String() != "before " {
+		t.Fatalf("unexpected output %q", bb.String())
+	}
+}
+
+func TestUnclosedBlock(t *testing.T) {
+	_, err := NewTemplate("{{capture c}}foo", "{{", "}}")
+	if err == nil || !strings.Contains(err.Error(), "missing {{end}} for block tag {{capture c}}") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestEndTagWithoutBlock(t *testing.T) {
+	tpl := New("{{start}}-{{end}}", "{{", "}}")
+
+	if s := tpl.ExecuteString(Map{"start": "a", "end": "b"}); s != "a-b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestSections(t *testing.T) {
+	tpl := New(`{{section "headers"}}Content-Type: {{type}}{{end}}{{section 'body'}}Hello, {{name}}!{{end}} trailer`, "{{", "}}")
+	m := Map{"type": "text/plain", "name": "Ann"}
+
+	if s := tpl.ExecuteString(m); s != "Content-Type: text/plainHello, Ann! trailer" {
+		t.Fatalf("unexpected inline output %q", s)
+	}
+
+	var headers, body, copied, rest bytes.Buffer
+	n, err := tpl.ExecuteMulti(m, map[string]io.Writer{
+		"headers": &headers,
+		"body":    io.MultiWriter(&body, &copied),
+		"":        &rest,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if headers.String() != "Content-Type: text/plain" || body.String() != "Hello, Ann!" ||
+		copied.String() != body.String() || rest.String() != " trailer" {
+		t.Fatalf("unexpected outputs %q, %q, %q, %q", headers.String(), body.String(), copied.String(), rest.String())
+	}
+	if n != int64(headers.Len()+body.Len()+rest.Len()) {
+		t.Fatalf("unexpected byte count %d", n)
+	}
+
+	// unrouted content is discarded, but still evaluated
+	body.Reset()
+	n, err = tpl.ExecuteMulti(m, map[string]io.Writer{"body": &body})
+	if err != nil || n != int64(body.Len()) || body.String() != "Hello, Ann!" {
+		t.Fatalf("unexpected result %q, %d, %v", body.String(), n, err)
+	}
+	fail := func(w io.Writer, tag string) (int, error) { return 0, errors.New("failed") }
+	_, err = tpl.ExecuteMulti(Map{"type": fail, "name": "Ann"}, map[string]io.Writer{"body": &body})
+	if err == nil {
+		t.Fatalf("expected error from tag function in unrouted section")
+	}
+}
+
+func TestExecuteMultiWithoutSections(t *testing.T) {
+	tpl := New("Hello, {{name}}!", "{{", "}}")
+
+	var bb bytes.Buffer
+	n, err := tpl.ExecuteMulti(Map{"name": "Ann"}, map[string]io.Writer{"": &bb})
+	if err != nil || n != 11 || bb.String() != "Hello, Ann!" {
+		t.Fatalf("unexpected result %q, %d, %v", bb.String(), n, err)
+	}
+}
+
+func TestIfBlock(t *testing.T) {
+	tpl := New("Hi {{name}}{{if premium}}, thanks for being premium{{else}}, upgrade now{{end}}!", "{{", "}}")
+
+	if s := tpl.ExecuteString(Map{"name": "Ann", "premium": true}); s != "Hi Ann, thanks for being premium!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := tpl.ExecuteString(Map{"name": "Bob", "premium": false}); s != "Hi Bob, upgrade now!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	// missing conditions are false
+	if s := tpl.ExecuteString(Map{"name": "Eve"}); s != "Hi Eve, upgrade now!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestIfBlockConditions(t *testing.T) {
+	tpl := New("{{if score >= 90}}A{{else if score >= 80}}B{{else if isZero(score)}}none{{else}}C{{end}}", "{{", "}}")
+	for score, want := range map[int]string{95: "A", 85: "B", 70: "C", 0: "none"} {
+		s := tpl.ExecuteString(Map{"score": score, "isZero": func(n int) bool { return n == 0 }})
+		if s != want {
+			t.Fatalf("score %d: expected %q, got %q", score, want, s)
+		}
+	}
+
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{"", "no"},
+		{"0", "no"},
+		{"false", "no"},
+		{"x", "yes"},
+		{1.5, "yes"},
+		{nil, "no"},
+		{true, "yes"},
+		{[]int{}, "no"},
+		{[]int{1}, "yes"},
+		{Map{}, "no"},
+		{struct{}{}, "yes"},
+	}
+	for _, tt := range tests {
+		tpl := New("{{if v}}yes{{else}}no{{end}}", "{{", "}}")
+		if s := tpl.ExecuteString(Map{"v": tt.v}); s != tt.want {
+			t.Fatalf("%#v: expected %q, got %q", tt.v, tt.want, s)
+		}
+	}
+}
+
+func TestNestedIfBlocks(t *testing.T) {
+	tpl := New("{{if a}}a{{if b}}b{{else}}!b{{end}}{{capture c}}{{v}}{{end}}{{else}}!a{{end}}[{{c}}]", "{{", "}}")
+	tests := []struct {
+		m    Map
+		want string
+	}{
+		{Map{"a": true, "b": true, "v": "x"}, "ab[x]"},
+		{Map{"a": true, "b": false, "v": "x"}, "a!b[x]"},
+		{Map{"a": false, "v": "x"}, "!a[]"},
+	}
+	for _, tt := range tests {
+		if s := tpl.ExecuteString(tt.m); s != tt.want {
+			t.Fatalf("expected %q, got %q", tt.want, s)
+		}
+	}
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "a,b,v" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+}
+
+func TestIfBlockErrors(t *testing.T) {
+	_, err := NewTemplate("{{if a}}x{{else if b}}y", "{{", "}}")
+	if err == nil || !strings.Contains(err.Error(), "missing {{end}} for block tag {{if a}}") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tpl := New("{{if fail()}}x{{end}}", "{{", "}}")
+	var bb bytes.Buffer
+	_, err = tpl.Execute(&bb, Map{"fail": func() (bool, error) { return false, errors.New("boom") }})
+	if err == nil || !strings.Contains(err.Error(), `cannot evaluate condition "fail()": boom`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = New("{{if missing}}x{{end}}", "{{", "}}", WithStrictIdentifiers()).Execute(&bb, Map{})
+	if !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestElseTagWithoutIf(t *testing.T) {
+	tpl := New("{{else}}{{capture c}}{{else}}{{end}}{{c}}", "{{", "}}")
+
+	if s := tpl.ExecuteString(Map{"else": "e"}); s != "ee" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithBlock(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		Name    string
+		Address *address
+	}
+	tpl := New("{{with user}}{{Name}} from {{Address.city}}{{with Address}} ({{city}}, {{site}}){{end}}{{end}}{{with nobody}}x{{else}} -{{end}}", "{{", "}}")
+	m := Map{
+		"user": user{Name: "Ann", Address: &address{City: "Oslo"}},
+		"site": "example.com",
+	}
+	if s := tpl.ExecuteString(m); s != "Ann from Oslo (Oslo, example.com) -" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "user,nobody" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+}
+
+func TestWithBlockMaps(t *testing.T) {
+	tpl := New(`{{with user.name}}{{.}}/{{end}}{{with user}}{{name}}: {{upper(name)}} {{.}}{{end}} {{name}}`, "{{", "}}")
+	m := Map{
+		"user":  map[string]any{"name": "bob"},
+		"name":  "top",
+		"upper": strings.ToUpper,
+	}
+	if s := tpl.ExecuteString(m); s != "bob/bob: BOB map[name:bob] top" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	_, err := New("{{with missing}}x{{end}}", "{{", "}}", WithStrictIdentifiers()).Execute(&bytes.Buffer{}, m)
+	if !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
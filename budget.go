@@ -0,0 +1,198 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of a text, e.g. using the tokenizer of a
+// language model. It must count at least as many tokens for a text as for
+// any of its prefixes.
+type Tokenizer func(s string) int
+
+// CountApproxTokens estimates the tokens of s as one token per four
+// characters, a common approximation for English text.
+func CountApproxTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// truncateMarker marks tags whose values may be truncated to fit the token
+// budget, following a "|" at the end of the tag.
+const truncateMarker = "truncate"
+
+// WithTokenBudget limits the rendered output to budget tokens as counted by
+// count, or by CountApproxTokens if count is nil.
+//
+// Tags marked with a "|truncate" suffix, e.g. {{context | truncate}}, are
+// truncated to fit the budget: static text and the other tags are counted
+// first, then the remaining budget is given to the marked tags in template
+// order. Values are truncated before being escaped, so escape sequences
+// are never split. Execution fails without writing anything if the output
+// doesn't fit even with the marked tags left empty.
+//
+// The output is buffered to apply the budget. Templates with blocks don't
+// support token budgets.
+func WithTokenBudget(budget int, count Tokenizer) Option {
+	return func(t *Template) {
+		if count == nil {
+			count = CountApproxTokens
+		}
+		t.tokenBudget = budget
+		t.tokenizer = count
+	}
+}
+
+// markTruncated strips the truncation markers from the tags, recording the
+// marked tags in t.truncated.
+func (t *Template) markTruncated() {
+	t.truncated = make([]bool, len(t.tags))
+	for i, tag := range t.tags {
+		n := strings.LastIndexByte(tag, '|')
+		if n <= 0 || tag[n-1] == '|' || strings.TrimSpace(tag[n+1:]) != truncateMarker {
+			continue
+		}
+		t.stripTag(i, strings.TrimSpace(tag[:n]))
+		t.truncated[i] = true
+	}
+}
+
+// renderBudgeted renders the template like render, truncating the marked
+// tags to fit the token budget.
+func (t *Template) renderBudgeted(w io.Writer, m Map, ec *evalContext, std bool) (int64, error) {
+	texts := t.texts
+	if len(texts) == 0 {
+		texts = [][]byte{unsafeString2Bytes(t.template)}
+	}
+	values := make([][]byte, len(t.tags))
+	// escapes holds the escaping of the marked tags, whose values are
+	// truncated before being escaped
+	escapes := make([]func(s string) string, len(t.tags))
+	used := 0
+	for _, text := range texts {
+		used += t.tokenizer(unsafeBytes2String(text))
+	}
+	for i := range t.tags {
+		var bb bytes.Buffer
+		var err error
+		if t.truncated[i] && t.contexts != nil {
+			escapes[i], err = t.writeUnescaped(&bb, i, m, ec, std)
+		} else {
+			_, err = t.writeTag(&bb, i, m, ec, std)
+		}
+		if err != nil {
+			return 0, err
+		}
+		values[i] = bb.Bytes()
+		if !t.truncated[i] {
+			used += t.tokenizer(bb.String())
+		}
+	}
+	if used > t.tokenBudget {
+		return 0, fmt.Errorf("%w: %d tokens exceed the budget of %d", errTokenBudget, used, t.tokenBudget)
+	}
+
+	left := t.tokenBudget - used
+	for i, v := range values {
+		if !t.truncated[i] {
+			continue
+		}
+		values[i] = t.truncateTokens(v, left, escapes[i])
+		left -= t.tokenizer(unsafeBytes2String(values[i]))
+	}
+
+	var nn int64
+	for i, text := range texts {
+		ni, err := writeFull(w, text)
+		nn += int64(ni)
+		if err != nil || i == len(values) {
+			return nn, err
+		}
+		var start int64
+		traced := ec.trace != nil && ec.trace.w == w
+		if traced {
+			start = *ec.trace.n
+		}
+		ni, err = writeFull(w, values[i])
+		nn += int64(ni)
+		if traced {
+			ec.trace.add(t, i, start, err)
+		}
+		if err != nil {
+			return nn, err
+		}
+	}
+	return nn, nil
+}
+
+// writeUnescaped writes the value of the i-th tag like writeTag, but
+// without escaping it. It returns the escaping of the value and its
+// prefixes, nil if the value is written verbatim.
+func (t *Template) writeUnescaped(bb *bytes.Buffer, i int, m Map, ec *evalContext, std bool) (func(s string) string, error) {
+	tag := t.tags[i]
+	ec.setTag(i, tag)
+	v, kind, err := resolveTag(tag, m, ec)
+	if err != nil || t.isChecksumMarker(tag, v) {
+		_, err = t.writeResolved(bb, i, v, kind, err, std)
+		return nil, err
+	}
+	if v, err = t.finishValue(i, v, kind); err != nil {
+		_, err = t.writeResolved(bb, i, v, kind, err, std)
+		return nil, err
+	}
+	if t.taintReport != nil {
+		t.checkTaint(i, v)
+	}
+	if _, err := t.writeValue(bb, i, v, kind); err != nil {
+		return nil, err
+	}
+
+	ctx := t.contexts[i]
+	if _, ok := ctx.trusted(v); ok {
+		return nil, nil
+	}
+	n := bb.Len()
+	return func(s string) string {
+		if len(s) == n {
+			return ctx.escape(v, s)
+		}
+		// truncated values are text
+		return ctx.escape(s, s)
+	}, nil
+}
+
+// truncateTokens returns the longest prefix of v, cut at a rune boundary,
+// with at most limit tokens once escaped with escape, if not nil. It
+// returns the escaped prefix.
+func (t *Template) truncateTokens(v []byte, limit int, escape func(s string) string) []byte {
+	escaped := func(n int) string {
+		s := unsafeBytes2String(v[:n])
+		if escape != nil {
+			s = escape(s)
+		}
+		return s
+	}
+	if s := escaped(len(v)); t.tokenizer(s) <= limit {
+		return unsafeString2Bytes(s)
+	}
+	// binary search the rune boundaries for the longest prefix fitting
+	// the limit
+	bounds := []int{0}
+	for i := range unsafeBytes2String(v) {
+		if i > 0 {
+			bounds = append(bounds, i)
+		}
+	}
+	lo, hi := 0, len(bounds)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if t.tokenizer(escaped(bounds[mid])) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return unsafeString2Bytes(escaped(bounds[lo]))
+}
@@ -0,0 +1,92 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// countWords counts whitespace separated words.
+func countWords(s string) int {
+	return len(strings.Fields(s))
+}
+
+func TestTokenBudget(t *testing.T) {
+	tpl := New("System: {{role}}\nContext: {{context|truncate}}\nHistory: {{ history | truncate }}\nQuestion: {{question}}", "{{", "}}",
+		WithTokenBudget(13, countWords))
+
+	data := Map{
+		"role":     "helpful assistant",
+		"context":  "one two three four five six",
+		"history":  "seven eight nine",
+		"question": "why?",
+	}
+	// 7 words are fixed, leaving 6 for the marked tags in order
+	expected := "System: helpful assistant\nContext: one two three four five six\nHistory: \nQuestion: why?"
+	if s := tpl.ExecuteString(data); s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+
+	data["context"] = "one two three four"
+	expected = "System: helpful assistant\nContext: one two three four\nHistory: seven eight \nQuestion: why?"
+	if s := tpl.ExecuteString(data); s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+}
+
+func TestTokenBudgetRuneBoundaries(t *testing.T) {
+	tpl := New("<{{text|truncate}}>", "{{", "}}", WithTokenBudget(3, nil))
+
+	// "<" and ">" take a token each, leaving one token of four runes
+	if s := tpl.ExecuteString(Map{"text": "äöüßéè"}); s != "<äöüß>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestTokenBudgetEscaping(t *testing.T) {
+	countBytes := func(s string) int { return len(s) }
+
+	// values are truncated before being escaped, so entities aren't split
+	html := New("<p>{{text|truncate}}</p>", "{{", "}}", WithEscaping(EscapeHTML), WithTokenBudget(7+8, countBytes))
+	if s := html.ExecuteString(Map{"text": "a&b&c"}); s != "<p>a&amp;b</p>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// a trailing backslash must not escape the closing quote
+	json := New(`{"a": {{text|truncate}}}`, "{{", "}}", WithEscaping(EscapeJSON), WithTokenBudget(7+5, countBytes))
+	if s := json.ExecuteString(Map{"text": `x\y`}); s != `{"a": "x\\"}` {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// ExecuteAsync applies the budget as well
+	var sb strings.Builder
+	if _, err := html.ExecuteAsync(&sb, Map{"text": "a&b&c"}); err != nil || sb.String() != "<p>a&amp;b</p>" {
+		t.Fatalf("unexpected output %q, %v", sb.String(), err)
+	}
+}
+
+func TestTokenBudgetExceeded(t *testing.T) {
+	tpl := New("{{a}} {{b|truncate}}", "{{", "}}", WithTokenBudget(2, countWords))
+
+	var sb strings.Builder
+	_, err := tpl.Execute(&sb, Map{"a": "one two three", "b": "four"})
+	if !errors.Is(err, errTokenBudget) {
+		t.Fatalf("expected errTokenBudget; got %v", err)
+	}
+	if sb.Len() != 0 {
+		t.Fatalf("unexpected output %q", sb.String())
+	}
+
+	if s := tpl.ExecuteString(Map{"a": "one", "b": "two three"}); s != "one two " {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New("plain text here", "{{", "}}", WithTokenBudget(2, countWords)).ExecuteString(nil); s != "" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestTokenBudgetBlocks(t *testing.T) {
+	if _, err := NewTemplate("{{capture x}}a{{end}}{{x|truncate}}", "{{", "}}", WithTokenBudget(10, nil)); !errors.Is(err, errTokenBudgetBlocks) {
+		t.Fatalf("expected errTokenBudgetBlocks; got %v", err)
+	}
+}
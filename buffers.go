@@ -0,0 +1,51 @@
+package fasttemplate
+
+import "github.com/valyala/bytebufferpool"
+
+// BufferPool is a pool of the buffers a template uses for temporary output,
+// e.g. by ExecuteString, capture blocks, escaping and output filters.
+// *bytebufferpool.Pool implements it. Implementations must be safe for
+// concurrent use.
+type BufferPool interface {
+	Get() *bytebufferpool.ByteBuffer
+	Put(bb *bytebufferpool.ByteBuffer)
+}
+
+// WithBufferPool makes the template take its buffers from pool instead of a
+// pool of its own, e.g. to share a pool between many templates.
+func WithBufferPool(pool BufferPool) Option {
+	return func(t *Template) {
+		t.bufferPool = pool
+	}
+}
+
+// WithMaxBufferSize discards buffers that grew beyond n bytes instead of
+// returning them to the pool, so rendering an occasional large document
+// doesn't pin its buffer in memory for the lifetime of the template.
+func WithMaxBufferSize(n int) Option {
+	return func(t *Template) {
+		t.maxBufferSize = n
+	}
+}
+
+// getBuffer returns an empty buffer from the pool of the template.
+func (t *Template) getBuffer() *bytebufferpool.ByteBuffer {
+	if t.bufferPool != nil {
+		return t.bufferPool.Get()
+	}
+	return t.byteBufferPool.Get()
+}
+
+// putBuffer resets bb and returns it to the pool of the template, unless it
+// exceeds the maximum buffer size.
+func (t *Template) putBuffer(bb *bytebufferpool.ByteBuffer) {
+	if t.maxBufferSize > 0 && cap(bb.B) > t.maxBufferSize {
+		return
+	}
+	bb.Reset()
+	if t.bufferPool != nil {
+		t.bufferPool.Put(bb)
+		return
+	}
+	t.byteBufferPool.Put(bb)
+}
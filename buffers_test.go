@@ -0,0 +1,65 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// countingPool records the buffers taken from and returned to it.
+type countingPool struct {
+	gets, puts int
+	put        []*bytebufferpool.ByteBuffer
+}
+
+func (p *countingPool) Get() *bytebufferpool.ByteBuffer {
+	p.gets++
+	return &bytebufferpool.ByteBuffer{}
+}
+
+func (p *countingPool) Put(bb *bytebufferpool.ByteBuffer) {
+	p.puts++
+	p.put = append(p.put, bb)
+}
+
+func TestWithBufferPool(t *testing.T) {
+	pool := &countingPool{}
+	tpl := New("{{capture x}}Hi {{name}}{{end}}<{{x}}>", "{{", "}}",
+		WithBufferPool(pool),
+		WithOutputFilter(func(p []byte) []byte { return append(p, '!') }))
+
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "<Hi Ann>!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	// ExecuteString, the capture block and the output filter
+	if pool.gets != 3 || pool.puts != 3 {
+		t.Fatalf("expected 3 buffers taken and returned, got %d and %d", pool.gets, pool.puts)
+	}
+	for _, bb := range pool.put {
+		if bb.Len() != 0 {
+			t.Fatalf("expected returned buffers to be reset, got %q", bb.String())
+		}
+	}
+}
+
+func TestWithMaxBufferSize(t *testing.T) {
+	pool := &countingPool{}
+	tpl := New("{{body}}", "{{", "}}", WithBufferPool(pool), WithMaxBufferSize(1024), WithEscaping(EscapeHTML))
+
+	if s := tpl.ExecuteString(Map{"body": "small"}); s != "small" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if pool.puts != pool.gets {
+		t.Fatalf("expected small buffers to be returned, got %d of %d", pool.puts, pool.gets)
+	}
+
+	pool.gets, pool.puts = 0, 0
+	large := strings.Repeat("x", 4096)
+	if s := tpl.ExecuteString(Map{"body": large}); s != large {
+		t.Fatal("unexpected output for large document")
+	}
+	if pool.gets == 0 || pool.puts != 0 {
+		t.Fatalf("expected large buffers to be discarded, got %d of %d returned", pool.puts, pool.gets)
+	}
+}
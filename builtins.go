@@ -0,0 +1,70 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"sync"
+)
+
+// builtins holds the functions registered with RegisterBuiltin.
+var builtins = struct {
+	mu    sync.RWMutex
+	funcs Map
+
+	// replaced holds the names of scoped builtins replaced with
+	// RegisterBuiltin.
+	replaced map[string]bool
+}{funcs: Map{}, replaced: map[string]bool{}}
+
+// RegisterBuiltin makes fn callable from every template under the given name.
+//
+// Functions in the substitution [Map] take precedence over builtins with the
+// same name. Registering a name taken by a builtin, including those bound to
+// the execution such as join or counter, replaces it. RegisterBuiltin is typically called from init functions of
+// packages providing optional builtins. fn may be a [Func] annotating the
+// function with metadata. It panics if fn isn't a function.
+func RegisterBuiltin(name string, fn any) {
+	if !isFunc(fn) {
+		panic(fmt.Sprintf("builtin %q must be a function, got %T", name, fn))
+	}
+
+	builtins.mu.Lock()
+	builtins.funcs[name] = fn
+	if _, ok := scopedBuiltins[name]; ok {
+		builtins.replaced[name] = true
+	}
+	builtins.mu.Unlock()
+}
+
+// scopedBuiltin returns the binding of the named scoped builtin, unless it
+// was replaced with RegisterBuiltin.
+func scopedBuiltin(name string) (func(ec *evalContext, m Map) Func, bool) {
+	bind, ok := scopedBuiltins[name]
+	if !ok {
+		return nil, false
+	}
+	builtins.mu.RLock()
+	replaced := builtins.replaced[name]
+	builtins.mu.RUnlock()
+	return bind, !replaced
+}
+
+// lookupFunc returns the function called name from m, falling back to the
+// registry of the execution and the registered builtins.
+func (ec *evalContext) lookupFunc(name string, m Map) (Func, bool) {
+	if fn, ok := m[name]; ok && isFunc(fn) {
+		return asFunc(fn), true
+	}
+	if ec != nil {
+		if fn, ok := ec.registry.lookup(name); ok {
+			return asFunc(fn), true
+		}
+		if bind, ok := scopedBuiltin(name); ok {
+			return bind(ec, m), true
+		}
+	}
+
+	builtins.mu.RLock()
+	fn, ok := builtins.funcs[name]
+	builtins.mu.RUnlock()
+	return asFunc(fn), ok
+}
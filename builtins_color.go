@@ -0,0 +1,104 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func init() {
+	// the registered builtins never color their output; lookups bind them
+	// to the color mode of the execution
+	registerScoped("color", Func{Fn: colorFunc(false), Idempotent: true}, func(ec *evalContext, _ Map) Func {
+		return Func{Fn: colorFunc(ec.color), Idempotent: true}
+	})
+	registerScoped("bold", Func{Fn: boldFunc(false), Idempotent: true}, func(ec *evalContext, _ Map) Func {
+		return Func{Fn: boldFunc(ec.color), Idempotent: true}
+	})
+}
+
+// colorMode selects whether the color builtins emit ANSI escapes.
+type colorMode int
+
+const (
+	// colorAuto enables colors for terminals unless NO_COLOR is set.
+	colorAuto colorMode = iota
+	colorOn
+	colorOff
+)
+
+// ansiReset ends all ANSI attributes.
+const ansiReset = "\x1b[0m"
+
+// ansiColors maps the color names accepted by the color builtin to their
+// ANSI foreground codes.
+var ansiColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+}
+
+// WithColor enables or disables the ANSI escapes emitted by the color and
+// bold builtins. By default they are emitted if the destination writer is
+// a terminal and the NO_COLOR environment variable is empty.
+func WithColor(enabled bool) Option {
+	return func(t *Template) {
+		if enabled {
+			t.color = colorOn
+		} else {
+			t.color = colorOff
+		}
+	}
+}
+
+// useColor reports whether the color builtins emit ANSI escapes when
+// executing the template to w.
+func (t *Template) useColor(w io.Writer) bool {
+	switch t.color {
+	case colorOn:
+		return true
+	case colorOff:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorFunc returns the color builtin, wrapping s in the ANSI escapes of
+// the named color if enabled is set.
+func colorFunc(enabled bool) func(name, s string) (string, error) {
+	return func(name, s string) (string, error) {
+		code, ok := ansiColors[name]
+		if !ok {
+			return "", fmt.Errorf("color: unknown color %q", name)
+		}
+		if !enabled || s == "" {
+			return s, nil
+		}
+		return "\x1b[" + code + "m" + s + ansiReset, nil
+	}
+}
+
+// boldFunc returns the bold builtin, wrapping s in the ANSI escapes for
+// bold text if enabled is set.
+func boldFunc(enabled bool) func(s string) string {
+	return func(s string) string {
+		if !enabled || s == "" {
+			return s
+		}
+		return "\x1b[1m" + s + ansiReset
+	}
+}
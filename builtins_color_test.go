@@ -0,0 +1,52 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestColorBuiltins(t *testing.T) {
+	template := "{{color('red', level)}}: {{bold(msg)}}"
+	m := Map{"level": "ERROR", "msg": "disk full"}
+
+	if s := New(template, "{{", "}}", WithColor(true)).ExecuteString(m); s != "\x1b[31mERROR\x1b[0m: \x1b[1mdisk full\x1b[0m" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New(template, "{{", "}}", WithColor(false)).ExecuteString(m); s != "ERROR: disk full" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	// buffers aren't terminals
+	if s := New(template, "{{", "}}").ExecuteString(m); s != "ERROR: disk full" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// neither are pipes
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := New(template, "{{", "}}").Execute(w, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w.Close()
+	var bb bytes.Buffer
+	if _, err := bb.ReadFrom(r); err != nil || bb.String() != "ERROR: disk full" {
+		t.Fatalf("unexpected output %q, %v", bb.String(), err)
+	}
+
+	if _, err := New("{{color('mauve', msg)}}", "{{", "}}").Execute(&bb, m); err == nil {
+		t.Fatalf("expected error for unknown color")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if New("", "{{", "}}").useColor(os.Stdout) {
+		t.Fatalf("NO_COLOR must disable colors")
+	}
+	if !New("", "{{", "}}", WithColor(true)).useColor(os.Stdout) {
+		t.Fatalf("WithColor(true) must override NO_COLOR")
+	}
+}
@@ -0,0 +1,48 @@
+package fasttemplate
+
+import "errors"
+
+func init() {
+	// the registered counter only makes it known to completion and
+	// analysis; lookups bind it to the execution
+	registerScoped("counter", func(name string) (int, error) {
+		return 0, errors.New("counter: called outside of an execution")
+	}, func(ec *evalContext, _ Map) Func {
+		// the results only depend on the execution, so deterministic mode
+		// allows counter, but it isn't idempotent
+		return Func{Fn: ec.counter}
+	})
+}
+
+// scopedBuiltins holds the builtins depending on the state of an
+// execution. They are bound to its evalContext and the substitution map
+// when looked up and take precedence over their stock versions registered
+// for package-level functions, unless replaced with RegisterBuiltin.
+var scopedBuiltins = map[string]func(ec *evalContext, m Map) Func{}
+
+// registerScoped registers the builtin bound to the execution with bind,
+// and its stock version used without an execution.
+func registerScoped(name string, stock any, bind func(ec *evalContext, m Map) Func) {
+	builtins.mu.Lock()
+	builtins.funcs[name] = stock
+	builtins.mu.Unlock()
+	scopedBuiltins[name] = bind
+}
+
+// counter increments the named counter of the execution and returns its
+// value, starting at 1, e.g. for numbered lists or alternating row classes.
+func (ec *evalContext) counter(name string) int {
+	c := ec.sharedCounters()
+	c[name]++
+	return c[name]
+}
+
+// sharedCounters returns the counters of the execution, allocating them if
+// needed. Contexts derived from ec, such as those of lambdas and partials,
+// must share them.
+func (ec *evalContext) sharedCounters() map[string]int {
+	if ec.counters == nil {
+		ec.counters = make(map[string]int)
+	}
+	return ec.counters
+}
@@ -0,0 +1,49 @@
+package fasttemplate
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	tpl := New("{{counter('row')}}. {{a}}\n{{counter('row')}}. {{b}}\n[{{counter('other')}}]", "{{", "}}")
+	m := Map{"a": "apples", "b": "pears"}
+	expected := "1. apples\n2. pears\n[1]"
+	// counters start over with every execution
+	for i := 0; i < 2; i++ {
+		if s := tpl.ExecuteString(m); s != expected {
+			t.Fatalf("unexpected output %q. Expected %q", s, expected)
+		}
+	}
+
+	if s := New("row{{counter('row') % 2}} row{{counter('row') % 2}}", "{{", "}}").ExecuteString(Map{}); s != "row1 row0" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if s := New("{{counter('row')}}{{counter('row')}}", "{{", "}}", WithDeterministic()).ExecuteString(Map{}); s != "12" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// lambdas and partials share the counters of the execution
+	if s := New("{{map(items, (x) => counter('r'))}} {{counter('r')}}", "{{", "}}").ExecuteString(Map{"items": []int{7, 8, 9}}); s != "[1 2 3] 4" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	row := New("{{counter('r')}}", "{{", "}}")
+	if s := New(`{{counter('r')}}{{include("row")}}{{counter('r')}}`, "{{", "}}", WithPartial("row", row)).ExecuteString(Map{}); s != "123" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// counters are never memoized
+	if s := New("{{counter('r')}}{{counter('r')}}", "{{", "}}").ExecuteString(Map{}); s != "12" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// functions in the map take precedence
+	if s := New("{{counter('row')}}", "{{", "}}").ExecuteString(Map{"counter": func(string) int { return 7 }}); s != "7" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if _, err := New("{{counter('row')}}", "{{", "}}", WithDeterministic()).Execute(io.Discard, Map{"counter": func(string) int { return 7 }}); !errors.Is(err, errNondeterministicFunc) {
+		t.Fatalf("expected deterministic mode error, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+func init() {
+	RegisterBuiltin("variant", Func{Fn: variant, Idempotent: true})
+}
+
+// variant returns one of variants, chosen by a stable hash of id, so a user
+// is always assigned the same experiment variant. Include the experiment
+// name in id, e.g. "checkout:" + userID, to assign users independently in
+// different experiments. It returns "" without variants.
+func variant(id any, variants ...string) string {
+	if len(variants) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	fmt.Fprint(h, id)
+	return variants[h.Sum32()%uint32(len(variants))]
+}
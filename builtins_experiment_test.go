@@ -0,0 +1,39 @@
+package fasttemplate
+
+import "testing"
+
+func TestVariant(t *testing.T) {
+	// the assignment must never change, or users would switch variants
+	tests := []struct {
+		id       any
+		expected string
+	}{
+		{"user-1", "B"},
+		{"user-2", "B"},
+		{42, "C"},
+	}
+	for _, tt := range tests {
+		if got := variant(tt.id, "A", "B", "C"); got != tt.expected {
+			t.Errorf("variant(%v) = %q, expected %q", tt.id, got, tt.expected)
+		}
+	}
+
+	if got := variant("user-1"); got != "" {
+		t.Errorf("variant without variants = %q, expected empty string", got)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		counts[variant(i, "A", "B", "C")]++
+	}
+	for v, n := range counts {
+		if n < 900 || n > 1100 {
+			t.Errorf("variant %s assigned %d times out of 3000", v, n)
+		}
+	}
+
+	tpl := New("{{variant(userID, 'A', 'B', 'C')}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"userID": 42}); s != "C" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
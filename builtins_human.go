@@ -0,0 +1,149 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterBuiltin("humanDuration", Func{Fn: humanDuration, Idempotent: true})
+	RegisterBuiltin("humanBytes", Func{Fn: humanBytes, Idempotent: true})
+	// ago depends on the current time, so it isn't idempotent
+	RegisterBuiltin("ago", ago)
+}
+
+// durationUnits are the units used by humanDuration, largest first.
+var durationUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+}
+
+// humanDuration formats a duration using its two most significant units,
+// e.g. "3d 4h" or "1m 30s". Numbers are interpreted as seconds and strings
+// are parsed with time.ParseDuration.
+func humanDuration(v any) (string, error) {
+	d, err := toDuration(v)
+	if err != nil {
+		return "", fmt.Errorf("humanDuration: %w", err)
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d < time.Second {
+		return sign + d.Round(time.Millisecond).String(), nil
+	}
+
+	var parts []string
+	for _, u := range durationUnits {
+		if d < u.d && len(parts) == 0 {
+			continue
+		}
+		n := d / u.d
+		d -= n * u.d
+		if n > 0 {
+			parts = append(parts, strconv.FormatInt(int64(n), 10)+u.name)
+		}
+		if len(parts) == 2 || (len(parts) > 0 && n == 0) {
+			break
+		}
+	}
+	return sign + strings.Join(parts, " "), nil
+}
+
+// toDuration converts v to a time.Duration.
+func toDuration(v any) (time.Duration, error) {
+	switch dv := v.(type) {
+	case time.Duration:
+		return dv, nil
+	case string:
+		return time.ParseDuration(dv)
+	}
+	if isNumeric(v) {
+		return time.Duration(toFloat64(v) * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("expected duration, got %T", v)
+}
+
+// humanBytes formats a byte count using decimal units, e.g. "1.5 MB".
+func humanBytes(v any) (string, error) {
+	if !isNumeric(v) {
+		return "", fmt.Errorf("humanBytes: expected number, got %T", v)
+	}
+	n := toFloat64(v)
+	if math.Abs(n) < 1000 {
+		return strconv.FormatFloat(n, 'f', -1, 64) + " B", nil
+	}
+	units := []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	n /= 1000
+	i := 0
+	// pick the unit after rounding, so 999999 is "1 MB" rather than "1000 kB"
+	for math.Abs(math.Round(n*10)/10) >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	s := strconv.FormatFloat(n, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + " " + units[i], nil
+}
+
+// ago formats the time elapsed since t, e.g. "5 minutes ago" or, for times
+// in the future, "in 2 hours".
+func ago(v any) (string, error) {
+	var t time.Time
+	switch tv := v.(type) {
+	case time.Time:
+		t = tv
+	case *time.Time:
+		if tv == nil {
+			return "", nil
+		}
+		t = *tv
+	default:
+		return "", fmt.Errorf("ago: expected time.Time, got %T", v)
+	}
+	return relativeTime(time.Since(t)), nil
+}
+
+// relativeTime formats the elapsed duration d.
+func relativeTime(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var n int64
+	var unit string
+	switch {
+	case d < 10*time.Second:
+		return "just now"
+	case d < time.Minute:
+		n, unit = int64(d/time.Second), "second"
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
@@ -0,0 +1,73 @@
+package fasttemplate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanDuration(t *testing.T) {
+	for _, tc := range []struct {
+		in  any
+		out string
+	}{
+		{250 * time.Millisecond, "250ms"},
+		{90 * time.Second, "1m 30s"},
+		{3*time.Hour + 5*time.Second, "3h"},
+		{76*time.Hour + 30*time.Minute, "3d 4h"},
+		{-2 * time.Minute, "-2m"},
+		{45, "45s"},
+		{1.5, "1s"},
+		{"1h30m", "1h 30m"},
+	} {
+		s, err := humanDuration(tc.in)
+		if err != nil || s != tc.out {
+			t.Errorf("humanDuration(%v): got %q, %v, want %q", tc.in, s, err, tc.out)
+		}
+	}
+	if _, err := humanDuration(true); err == nil {
+		t.Errorf("expected error for invalid duration")
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for _, tc := range []struct {
+		in  any
+		out string
+	}{
+		{512, "512 B"},
+		{999, "999 B"},
+		{1000, "1 kB"},
+		{999949, "999.9 kB"},
+		{999950, "1 MB"},
+		{999999, "1 MB"},
+		{-999999, "-1 MB"},
+		{999999999, "1 GB"},
+		{1500000, "1.5 MB"},
+		{int64(3) << 40, "3.3 TB"},
+		{uint64(1e18), "1 EB"},
+		{1e21, "1000 EB"},
+	} {
+		s, err := humanBytes(tc.in)
+		if err != nil || s != tc.out {
+			t.Errorf("humanBytes(%v): got %q, %v, want %q", tc.in, s, err, tc.out)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	now := time.Now()
+	m := Map{
+		"a":    now.Add(-3 * time.Second),
+		"b":    now.Add(-5*time.Minute - time.Second),
+		"c":    now.Add(-49 * time.Hour),
+		"d":    now.Add(2*time.Hour + time.Minute),
+		"e":    now.Add(-400 * 24 * time.Hour),
+		"size": 2048,
+		"up":   42 * time.Hour,
+	}
+	tpl := New("{{ago(a)}}|{{ago(b)}}|{{ago(c)}}|{{ago(d)}}|{{ago(e)}}|{{humanBytes(size)}}|{{humanDuration(up)}}", "{{", "}}")
+	expected := "just now|5 minutes ago|2 days ago|in 2 hours|1 year ago|2 kB|1d 18h"
+	if s := tpl.ExecuteString(m); s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+}
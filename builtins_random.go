@@ -0,0 +1,112 @@
+package fasttemplate
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+)
+
+func init() {
+	// random builtins are never idempotent, so deterministic mode rejects
+	// them
+	RegisterBuiltin("random", random)
+	RegisterBuiltin("randString", randString)
+	RegisterBuiltin("uuidv4", uuidv4)
+}
+
+// randStringAlphabet holds the characters of strings returned by
+// randString.
+const randStringAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// randSource is the source of the random builtins: crypto/rand unless
+// seeded with SeedRandom.
+var randSource struct {
+	mu     sync.Mutex
+	seeded *rand.Rand
+}
+
+// SeedRandom makes the random, randString and uuidv4 builtins produce the
+// sequence determined by seed, so tests get reproducible output. The
+// sequence isn't suitable for nonces or other secrets. By default, and after
+// calling UnseedRandom, the builtins use crypto/rand.
+func SeedRandom(seed int64) {
+	randSource.mu.Lock()
+	randSource.seeded = rand.New(rand.NewSource(seed))
+	randSource.mu.Unlock()
+}
+
+// UnseedRandom makes the random builtins use crypto/rand again.
+func UnseedRandom() {
+	randSource.mu.Lock()
+	randSource.seeded = nil
+	randSource.mu.Unlock()
+}
+
+// randomInt returns a random number in [0, n).
+func randomInt(n int64) (int64, error) {
+	randSource.mu.Lock()
+	defer randSource.mu.Unlock()
+	if randSource.seeded != nil {
+		return randSource.seeded.Int63n(n), nil
+	}
+	v, err := crand.Int(crand.Reader, big.NewInt(n))
+	if err != nil {
+		return 0, err
+	}
+	return v.Int64(), nil
+}
+
+// randomBytes fills p with random bytes.
+func randomBytes(p []byte) error {
+	randSource.mu.Lock()
+	defer randSource.mu.Unlock()
+	if randSource.seeded != nil {
+		_, err := randSource.seeded.Read(p)
+		return err
+	}
+	_, err := crand.Read(p)
+	return err
+}
+
+// random returns a random integer between min and max, both inclusive.
+func random(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("random: max %d is less than min %d", max, min)
+	}
+	v, err := randomInt(int64(max) - int64(min) + 1)
+	if err != nil {
+		return 0, err
+	}
+	return min + int(v), nil
+}
+
+// randString returns a random string of n letters and digits, e.g. for
+// nonces.
+func randString(n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("randString: negative length %d", n)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		v, err := randomInt(int64(len(randStringAlphabet)))
+		if err != nil {
+			return "", err
+		}
+		b[i] = randStringAlphabet[v]
+	}
+	return string(b), nil
+}
+
+// uuidv4 returns a random RFC 4122 version 4 UUID, e.g. for correlation
+// IDs.
+func uuidv4() (string, error) {
+	var u [16]byte
+	if err := randomBytes(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
@@ -0,0 +1,64 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRandomBuiltins(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		v, err := random(-2, 2)
+		if err != nil || v < -2 || v > 2 {
+			t.Fatalf("random(-2, 2) = %d, %v", v, err)
+		}
+	}
+	if v, err := random(5, 5); err != nil || v != 5 {
+		t.Fatalf("random(5, 5) = %d, %v", v, err)
+	}
+	if _, err := random(2, 1); err == nil {
+		t.Fatalf("expected error for max < min")
+	}
+
+	s, err := randString(32)
+	if err != nil || !regexp.MustCompile(`^[A-Za-z0-9]{32}$`).MatchString(s) {
+		t.Fatalf("randString(32) = %q, %v", s, err)
+	}
+	if _, err := randString(-1); err == nil {
+		t.Fatalf("expected error for negative length")
+	}
+
+	u, err := uuidv4()
+	if err != nil || !uuidPattern.MatchString(u) {
+		t.Fatalf("uuidv4() = %q, %v", u, err)
+	}
+	if u2, _ := uuidv4(); u2 == u {
+		t.Fatalf("uuidv4 returned %q twice", u)
+	}
+}
+
+func TestSeedRandom(t *testing.T) {
+	defer UnseedRandom()
+
+	tpl := New("{{random(1, 100)}} {{randString(8)}} {{uuidv4()}}", "{{", "}}")
+	SeedRandom(1)
+	first := tpl.ExecuteString(Map{})
+	SeedRandom(1)
+	if s := tpl.ExecuteString(Map{}); s != first {
+		t.Fatalf("seeded output differs: %q and %q", s, first)
+	}
+	if s := tpl.ExecuteString(Map{}); s == first {
+		t.Fatalf("output repeated within a seeded sequence: %q", s)
+	}
+}
+
+func TestRandomDeterministic(t *testing.T) {
+	for _, template := range []string{"{{random(1, 2)}}", "{{randString(4)}}", "{{uuidv4()}}"} {
+		var bb bytes.Buffer
+		if _, err := New(template, "{{", "}}", WithDeterministic()).Execute(&bb, Map{}); err == nil {
+			t.Fatalf("expected %s to fail in deterministic mode", template)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package fasttemplate
+
+import (
+	"crypto/subtle"
+	"strings"
+)
+
+func init() {
+	RegisterBuiltin("constEq", Func{Fn: constEq, Idempotent: true})
+	RegisterBuiltin("redact", Func{Fn: redact, Idempotent: true})
+}
+
+// redactMask replaces the redacted part of a value.
+const redactMask = "****"
+
+// constEq reports whether a and b are equal in constant time, so comparing
+// tokens doesn't leak how many leading bytes match.
+func constEq(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// redact masks s, keeping its last keepLast runes, e.g. "****cdef". The
+// mask has a fixed length, so the length of s isn't revealed. Values not
+// longer than twice keepLast are masked completely, so short secrets don't
+// leak most of their content.
+func redact(s string, keepLast int) string {
+	if keepLast <= 0 || s == "" {
+		return redactMask
+	}
+	runes := []rune(s)
+	if len(runes) <= 2*keepLast {
+		return redactMask
+	}
+	var sb strings.Builder
+	sb.Grow(len(redactMask) + len(s))
+	sb.WriteString(redactMask)
+	sb.WriteString(string(runes[len(runes)-keepLast:]))
+	return sb.String()
+}
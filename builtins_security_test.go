@@ -0,0 +1,49 @@
+package fasttemplate
+
+import "testing"
+
+func TestConstEq(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"secret", "secret", true},
+		{"secret", "secreT", false},
+		{"secret", "secrets", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := constEq(tt.a, tt.b); got != tt.expected {
+			t.Errorf("constEq(%q, %q) = %t, expected %t", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		s        string
+		keepLast int
+		expected string
+	}{
+		{"sk_live_abcdef123456", 4, "****3456"},
+		{"sk_live_abcdef123456", 0, "****"},
+		{"sk_live_abcdef123456", -1, "****"},
+		{"12345678", 4, "****"},
+		{"123456789", 4, "****6789"},
+		{"", 4, "****"},
+		{"пароль-секрет", 3, "****рет"},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.s, tt.keepLast); got != tt.expected {
+			t.Errorf("redact(%q, %d) = %q, expected %q", tt.s, tt.keepLast, got, tt.expected)
+		}
+	}
+}
+
+func TestSecurityBuiltinsInTemplate(t *testing.T) {
+	tpl := New("token={{redact(token, 4)}} valid={{constEq(token, expected)}}", "{{", "}}")
+	s := tpl.ExecuteString(Map{"token": "tok_0123456789", "expected": "tok_0123456789"})
+	if s != "token=****6789 valid=true" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
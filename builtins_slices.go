@@ -0,0 +1,420 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterBuiltin("sum", Func{Fn: sum, Idempotent: true})
+	RegisterBuiltin("avg", Func{Fn: avg, Idempotent: true})
+	RegisterBuiltin("min", Func{Fn: minOf, Idempotent: true})
+	RegisterBuiltin("max", Func{Fn: maxOf, Idempotent: true})
+	RegisterBuiltin("sortBy", Func{Fn: sortBy, Idempotent: true})
+	RegisterBuiltin("filter", Func{Fn: filter, Idempotent: true})
+	RegisterBuiltin("mapField", Func{Fn: mapField, Idempotent: true})
+	RegisterBuiltin("map", Func{Fn: mapItems, Idempotent: true})
+	RegisterBuiltin("groupBy", Func{Fn: groupBy, Idempotent: true})
+	RegisterBuiltin("unique", Func{Fn: unique, Idempotent: true})
+}
+
+// numbers returns the elements of the numeric slice or array v as floats.
+func numbers(name string, v any) ([]float64, error) {
+	rv, err := sliceValue(name, v)
+	if err != nil {
+		return nil, err
+	}
+	nums := make([]float64, rv.Len())
+	for i := range nums {
+		ev := rv.Index(i)
+		switch ev.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			nums[i] = float64(ev.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			nums[i] = float64(ev.Uint())
+		case reflect.Float32, reflect.Float64:
+			nums[i] = ev.Float()
+		case reflect.Interface:
+			e := ev.Interface()
+			if !isNumeric(e) {
+				return nil, fmt.Errorf("%s: element %d is not a number: %T", name, i, e)
+			}
+			nums[i] = toFloat64(e)
+		default:
+			return nil, fmt.Errorf("%s: element %d is not a number: %s", name, i, ev.Type())
+		}
+	}
+	return nums, nil
+}
+
+// sum returns the sum of the numbers in the slice v.
+func sum(v any) (float64, error) {
+	nums, err := numbers("sum", v)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, n := range nums {
+		total += n
+	}
+	return total, nil
+}
+
+// avg returns the arithmetic mean of the numbers in the slice v, or 0 if it
+// is empty.
+func avg(v any) (float64, error) {
+	nums, err := numbers("avg", v)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	var total float64
+	for _, n := range nums {
+		total += n
+	}
+	return total / float64(len(nums)), nil
+}
+
+// minOf returns the smallest number in the slice v, or 0 if it is empty.
+func minOf(v any) (float64, error) {
+	nums, err := numbers("min", v)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	m := nums[0]
+	for _, n := range nums[1:] {
+		if n < m {
+			m = n
+		}
+	}
+	return m, nil
+}
+
+// maxOf returns the largest number in the slice v, or 0 if it is empty.
+func maxOf(v any) (float64, error) {
+	nums, err := numbers("max", v)
+	if err != nil || len(nums) == 0 {
+		return 0, err
+	}
+	m := nums[0]
+	for _, n := range nums[1:] {
+		if n > m {
+			m = n
+		}
+	}
+	return m, nil
+}
+
+// sliceValue returns v as a reflected slice or array.
+func sliceValue(name string, v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("%s: expected slice, got %T", name, v)
+	}
+	return rv, nil
+}
+
+// fieldValue returns the value of the named field of item. Items may be maps
+// with string keys or structs, optionally behind pointers. Struct fields are
+// matched by name or by the name in their json tag. A dotted name selects a
+// nested field.
+func fieldValue(item any, name string) (any, bool) {
+	for _, part := range strings.Split(name, ".") {
+		v, ok := field(reflect.ValueOf(item), part)
+		if !ok {
+			return nil, false
+		}
+		item = v
+	}
+	return item, true
+}
+
+func field(rv reflect.Value, name string) (any, bool) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Name == name || tag == name {
+				return rv.Field(i).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// itemMap returns the substitution map used to evaluate expressions against
+// item: its fields, plus the item itself as "it".
+func itemMap(item any) Map {
+	m := Map{"it": item}
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return m
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			iter := rv.MapRange()
+			for iter.Next() {
+				m[iter.Key().String()] = iter.Value().Interface()
+			}
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			m[f.Name] = rv.Field(i).Interface()
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+				m[tag] = rv.Field(i).Interface()
+			}
+		}
+	}
+	return m
+}
+
+// compareValues orders a and b: numbers numerically, times chronologically
+// and everything else by its string form.
+func compareValues(a, b any) int {
+	if isNumeric(a) && isNumeric(b) {
+		fa, fb := toFloat64(a), toFloat64(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(toString(a), toString(b))
+}
+
+// sortBy returns a copy of the slice items sorted by the named field or by
+// the keys computed by a lambda. The field name may be prefixed with "-" to
+// sort in descending order. Items without the field always sort first.
+func sortBy(items any, key any) (any, error) {
+	rv, err := sliceValue("sortBy", items)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), rv.Len(), rv.Len())
+	reflect.Copy(sorted, rv)
+	keys := make([]any, rv.Len())
+	var desc bool
+	switch key := key.(type) {
+	case string:
+		desc = strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+		for i := range keys {
+			keys[i], _ = fieldValue(sorted.Index(i).Interface(), name)
+		}
+	case Lambda:
+		for i := range keys {
+			if keys[i], err = key(sorted.Index(i).Interface()); err != nil {
+				return nil, fmt.Errorf("sortBy: item %d: %w", i, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("sortBy: expected a field name or lambda, got %T", key)
+	}
+
+	swap := reflect.Swapper(sorted.Interface())
+	sort.Stable(keyedSort{keys: keys, swap: swap, desc: desc})
+	return sorted.Interface(), nil
+}
+
+// keyedSort sorts a slice by precomputed keys.
+type keyedSort struct {
+	keys []any
+	swap func(i, j int)
+	desc bool
+}
+
+func (s keyedSort) Len() int { return len(s.keys) }
+
+func (s keyedSort) Less(i, j int) bool {
+	a, b := s.keys[i], s.keys[j]
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	if s.desc {
+		return compareValues(a, b) > 0
+	}
+	return compareValues(a, b) < 0
+}
+
+func (s keyedSort) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
+
+// filter returns the items of the slice for which the predicate holds. The
+// predicate is a lambda or an expression evaluated like [Eval] against the
+// fields of each item, which is also available as "it".
+func filter(items any, predicate any) (any, error) {
+	rv, err := sliceValue("filter", items)
+	if err != nil {
+		return nil, err
+	}
+	var holds func(item any) (bool, error)
+	switch p := predicate.(type) {
+	case string:
+		holds = func(item any) (bool, error) {
+			return Eval[bool](p, itemMap(item))
+		}
+	case Lambda:
+		holds = func(item any) (bool, error) {
+			v, err := p(item)
+			return toBool(v), err
+		}
+	default:
+		return nil, fmt.Errorf("filter: expected an expression or lambda, got %T", predicate)
+	}
+
+	filtered := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		ok, err := holds(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("filter: item %d: %w", i, err)
+		}
+		if ok {
+			filtered = reflect.Append(filtered, rv.Index(i))
+		}
+	}
+	return filtered.Interface(), nil
+}
+
+// mapField returns the values of the named field of every item in the slice.
+func mapField(items any, name string) ([]any, error) {
+	rv, err := sliceValue("mapField", items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		v, ok := fieldValue(rv.Index(i).Interface(), name)
+		if !ok {
+			return nil, fmt.Errorf("mapField: item %d has no field %q", i, name)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// mapItems returns the results of calling fn with every item of the slice.
+func mapItems(items any, fn Lambda) ([]any, error) {
+	rv, err := sliceValue("map", items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		if values[i], err = fn(rv.Index(i).Interface()); err != nil {
+			return nil, fmt.Errorf("map: item %d: %w", i, err)
+		}
+	}
+	return values, nil
+}
+
+// groupBy groups the items of the slice by the string form of the named
+// field. Every group is a slice of the same type as items, keeping the order
+// of the items. Items without the field are grouped under "".
+func groupBy(items any, name string) (map[string]any, error) {
+	rv, err := sliceValue("groupBy", items)
+	if err != nil {
+		return nil, err
+	}
+	sliceType := reflect.SliceOf(rv.Type().Elem())
+	groups := make(map[string]reflect.Value)
+	for i := 0; i < rv.Len(); i++ {
+		var key string
+		if v, ok := fieldValue(rv.Index(i).Interface(), name); ok {
+			key = toString(v)
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = reflect.MakeSlice(sliceType, 0, 1)
+		}
+		groups[key] = reflect.Append(g, rv.Index(i))
+	}
+
+	result := make(map[string]any, len(groups))
+	for key, g := range groups {
+		result[key] = g.Interface()
+	}
+	return result, nil
+}
+
+// unique returns a copy of the slice without duplicate values, keeping the
+// first occurrence of each. Values that aren't comparable, e.g. slices or
+// structs holding maps, are compared with reflect.DeepEqual.
+func unique(values any) (any, error) {
+	rv, err := sliceValue("unique", values)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[any]bool, rv.Len())
+	// kept holds the values that aren't comparable
+	var kept []any
+	result := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		v := rv.Index(i).Interface()
+		if v == nil || reflect.ValueOf(v).Comparable() {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+		} else {
+			if containsDeepEqual(kept, v) {
+				continue
+			}
+			kept = append(kept, v)
+		}
+		result = reflect.Append(result, rv.Index(i))
+	}
+	return result.Interface(), nil
+}
+
+// containsDeepEqual reports whether values holds a value deeply equal to v.
+func containsDeepEqual(values []any, v any) bool {
+	for _, x := range values {
+		if reflect.DeepEqual(x, v) {
+			return true
+		}
+	}
+	return false
+}
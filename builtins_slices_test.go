@@ -0,0 +1,122 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateBuiltins(t *testing.T) {
+	m := Map{
+		"latencies": []int{120, 80, 100},
+		"scores":    []float64{1.5, 2.5},
+		"mixed":     []any{1, 2.5, uint8(3)},
+		"empty":     []int{},
+		"durations": []time.Duration{time.Second, 2 * time.Second},
+	}
+	tpl := New("{{sum(latencies)}} {{avg(latencies)}} {{min(latencies)}} {{max(latencies)}} {{avg(scores)}} {{sum(mixed)}} {{max(empty)}} {{sum(latencies) / 2}}", "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "300 100 80 120 2 6.5 0 150" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if v, err := Eval[float64]("sum(durations)", m); err != nil || v != float64(3*time.Second) {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+	for _, expr := range []string{"sum(name)", "avg(words)"} {
+		if _, err := Eval[float64](expr, Map{"name": "x", "words": []string{"a"}}); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
+
+type product struct {
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Category string
+	hidden   int
+}
+
+func TestDataShapingBuiltins(t *testing.T) {
+	items := []product{
+		{Name: "desk", Price: 250, Category: "office"},
+		{Name: "pen", Price: 2, Category: "office"},
+		{Name: "lamp", Price: 120, Category: "home"},
+	}
+	rows := []map[string]any{
+		{"name": "b", "n": 2},
+		{"name": "a", "n": 1},
+		{"name": "c"},
+	}
+	m := Map{"items": items, "rows": rows, "values": []int{5, 1, 7}}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{mapField(sortBy(items, 'price'), 'name')}}", "[pen lamp desk]"},
+		{"{{mapField(sortBy(items, '-Price'), 'Name')}}", "[desk lamp pen]"},
+		{"{{mapField(sortBy(rows, 'n'), 'name')}}", "[c a b]"},
+		{"{{mapField(sortBy(rows, '-n'), 'name')}}", "[c b a]"},
+		{"{{mapField(filter(items, 'price > 100'), 'name')}}", "[desk lamp]"},
+		{"{{mapField(filter(items, \"Category == 'office' && price < 100\"), 'name')}}", "[pen]"},
+		{"{{sum(mapField(filter(items, 'price >= 120'), 'price'))}}", "370"},
+		{"{{filter(values, 'it > 4')}}", "[5 7]"},
+	}
+	for _, tt := range tests {
+		tpl := New(tt.template, "{{", "}}")
+		var sb strings.Builder
+		if _, err := tpl.Execute(&sb, m); err != nil {
+			t.Fatalf("unexpected error for %q: %s", tt.template, err)
+		}
+		if sb.String() != tt.expected {
+			t.Fatalf("unexpected output for %q: %q. Expected %q", tt.template, sb.String(), tt.expected)
+		}
+	}
+	if items[0].Name != "desk" {
+		t.Fatalf("sortBy must not modify its argument")
+	}
+
+	for _, expr := range []string{"mapField(items, 'hidden')", "mapField(items, 'missing')", "filter(items, 'missing > 1')", "sortBy(name, 'x')"} {
+		if _, err := Eval[string](expr, Map{"items": items, "name": "x"}); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestGroupByAndUnique(t *testing.T) {
+	items := []product{
+		{Name: "desk", Category: "office"},
+		{Name: "lamp", Category: "home"},
+		{Name: "pen", Category: "office"},
+	}
+
+	groups, err := groupBy(items, "Category")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	office, ok := groups["office"].([]product)
+	if len(groups) != 2 || !ok || len(office) != 2 || office[1].Name != "pen" {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+
+	m := Map{
+		"tags":  []string{"b", "a", "b", "c", "a"},
+		"mixed": []any{1, "1", 1, []int{1}, []int{1}},
+		"items": items,
+	}
+	tpl := New("{{unique(tags)}} {{unique(mixed)}} {{unique(mapField(items, 'Category'))}}", "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "[b a c] [1 1 [1]] [office home]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// comparable types holding values that aren't comparable don't panic,
+	// and values with the same string form aren't merged
+	type holder struct{ V any }
+	v, err := unique([]any{holder{[]int{1}}, holder{[]int{1}}, holder{1}, []string{"a b"}, []string{"a", "b"}, nil, nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := v.([]any); len(got) != 5 || got[2].([]string)[0] != "a b" || len(got[3].([]string)) != 2 || got[4] != nil {
+		t.Fatalf("unexpected result %#v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterBuiltin("table", Func{Fn: table, Idempotent: true, Cost: CostMedium})
+}
+
+// tableGap separates the columns of tables.
+const tableGap = "  "
+
+// table renders rows as a plain-text table for monospaced output: a header
+// naming the columns, a line of dashes and a line per row, with columns
+// padded to their widest cell. cols are the fields of the rows to show, as
+// accepted by sortBy; missing fields are left empty and columns of numbers
+// are aligned right. Lines are
+// separated by newlines and trailing spaces are trimmed.
+func table(rows any, cols ...string) (string, error) {
+	if len(cols) == 0 {
+		return "", errors.New("table: no columns")
+	}
+	rv, err := sliceValue("table", rows)
+	if err != nil {
+		return "", err
+	}
+
+	cells := make([][]string, rv.Len()+1)
+	cells[0] = cols
+	widths := make([]int, len(cols))
+	numeric := make([]bool, len(cols))
+	for i := range numeric {
+		numeric[i] = rv.Len() > 0
+	}
+	for r := 0; r < rv.Len(); r++ {
+		item := rv.Index(r).Interface()
+		row := make([]string, len(cols))
+		for c, col := range cols {
+			v, ok := fieldValue(item, col)
+			if ok && v != nil {
+				row[c] = fmt.Sprint(v)
+				numeric[c] = numeric[c] && isNumber(v)
+			}
+		}
+		cells[r+1] = row
+	}
+	for _, row := range cells {
+		for c, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		var line strings.Builder
+		for c, cell := range row {
+			if c > 0 {
+				line.WriteString(tableGap)
+			}
+			pad := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell))
+			if numeric[c] {
+				line.WriteString(pad)
+				line.WriteString(cell)
+			} else {
+				line.WriteString(cell)
+				line.WriteString(pad)
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+	}
+
+	writeRow(cells[0])
+	sb.WriteByte('\n')
+	for c, w := range widths {
+		if c > 0 {
+			sb.WriteString(tableGap)
+		}
+		sb.WriteString(strings.Repeat("-", w))
+	}
+	for _, row := range cells[1:] {
+		sb.WriteByte('\n')
+		writeRow(row)
+	}
+	return sb.String(), nil
+}
+
+// isNumber reports whether v is an integer or floating point number.
+func isNumber(v any) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package fasttemplate
+
+import "testing"
+
+func TestTable(t *testing.T) {
+	type host struct {
+		Name   string
+		Uptime float64
+		Region string `json:"region"`
+	}
+	rows := []host{
+		{"web-1", 99.95, "eu"},
+		{"db-primary", 100, "us-east"},
+		{"cache", 7.5, ""},
+	}
+	s, err := table(rows, "Name", "Uptime", "region")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "" +
+		"Name        Uptime  region\n" +
+		"----------  ------  -------\n" +
+		"web-1        99.95  eu\n" +
+		"db-primary     100  us-east\n" +
+		"cache          7.5"
+	if s != expected {
+		t.Fatalf("unexpected table\n%s\nExpected\n%s", s, expected)
+	}
+
+	maps := []map[string]any{{"k": "ä", "v": 1}, {"k": "long key"}}
+	if s, _ := table(maps, "k", "v"); s != "k         v\n--------  -\nä         1\nlong key" {
+		t.Fatalf("unexpected table %q", s)
+	}
+
+	if _, err := table(rows); err == nil {
+		t.Fatalf("expected error without columns")
+	}
+	if _, err := table("rows", "a"); err == nil {
+		t.Fatalf("expected error for non-slice rows")
+	}
+
+	tpl := New("{{table(hosts, 'Name', 'region')}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"hosts": rows[:1]}); s != "Name   region\n-----  ------\nweb-1  eu" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -0,0 +1,33 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterBuiltin(t *testing.T) {
+	RegisterBuiltin("testShout", func(s string) string { return strings.ToUpper(s) + "!" })
+
+	tpl := New("{{testShout(name)}} {{testShout('x') + '?'}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"name": "hi"}); s != "HI! X!?" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if err := tpl.Validate(Map{"name": "hi"}); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	// functions in the map take precedence over builtins
+	s := tpl.ExecuteString(Map{"name": "hi", "testShout": strings.ToLower})
+	if s != "hi x?" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	v, err := Eval[string]("testShout(name)", Map{"name": "eval"})
+	if err != nil || v != "EVAL!" {
+		t.Fatalf("unexpected result %q, %v", v, err)
+	}
+}
+
+func TestRegisterBuiltinPanics(t *testing.T) {
+	expectPanic(t, func() { RegisterBuiltin("notAFunc", "value") })
+}
@@ -0,0 +1,182 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterBuiltin("upper", Func{Fn: strings.ToUpper, Idempotent: true})
+	RegisterBuiltin("lower", Func{Fn: strings.ToLower, Idempotent: true})
+	RegisterBuiltin("trim", Func{Fn: strings.TrimSpace, Idempotent: true})
+	RegisterBuiltin("normalizeNFC", Func{Fn: normalizeNFC, Idempotent: true})
+	RegisterBuiltin("stripControl", Func{Fn: stripControl, Idempotent: true})
+	RegisterBuiltin("htmlEncode", Func{Fn: htmlEncode, Idempotent: true})
+	RegisterBuiltin("htmlDecode", Func{Fn: html.UnescapeString, Idempotent: true})
+	RegisterBuiltin("cdata", Func{Fn: cdata, Idempotent: true})
+}
+
+// stripControl removes control characters from s, keeping tabs, newlines
+// and carriage returns.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// htmlEncode escapes HTML special characters and encodes every non-ASCII
+// rune as a numeric character reference, so the result is safe for HTML
+// and XML documents in any encoding.
+func htmlEncode(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '<':
+			sb.WriteString("&lt;")
+		case r == '>':
+			sb.WriteString("&gt;")
+		case r == '&':
+			sb.WriteString("&amp;")
+		case r == '"':
+			sb.WriteString("&#34;")
+		case r == '\'':
+			sb.WriteString("&#39;")
+		case r >= utf8.RuneSelf:
+			fmt.Fprintf(&sb, "&#%d;", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+//go:generate go run normtables_gen.go
+
+// combiningClassRange is a range of runes sharing a canonical combining
+// class.
+type combiningClassRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+// Hangul syllable constants used by the algorithmic (de)composition.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// combiningClass returns the canonical combining class of r.
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool {
+		return combiningClasses[i].hi >= r
+	})
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// normalizeNFC returns the Unicode Normalization Form C of s.
+func normalizeNFC(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	// canonical decomposition
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		runes = decomposeRune(runes, r)
+	}
+
+	// canonical ordering of combining marks
+	for i := 0; i < len(runes); {
+		if combiningClass(runes[i]) == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool {
+			return combiningClass(marks[a]) < combiningClass(marks[b])
+		})
+		i = j
+	}
+
+	// canonical composition
+	starter := -1
+	out := runes[:0]
+	var lastClass uint8
+	for _, r := range runes {
+		class := combiningClass(r)
+		if starter >= 0 && (lastClass < class || (lastClass == 0 && len(out)-1 == starter)) {
+			if c, ok := composeRunes(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// decomposeRune appends the full canonical decomposition of r to dst.
+func decomposeRune(dst []rune, r rune) []rune {
+	if r >= hangulSBase && r < hangulSBase+hangulSCount {
+		si := r - hangulSBase
+		dst = append(dst, hangulLBase+si/hangulNCount, hangulVBase+(si%hangulNCount)/hangulTCount)
+		if t := si % hangulTCount; t != 0 {
+			dst = append(dst, hangulTBase+t)
+		}
+		return dst
+	}
+	d, ok := nfcDecompositions[r]
+	if !ok {
+		return append(dst, r)
+	}
+	for _, c := range d {
+		dst = decomposeRune(dst, c)
+	}
+	return dst
+}
+
+// composeRunes returns the primary composite of the pair, if any.
+func composeRunes(a, b rune) (rune, bool) {
+	// Hangul LV and LVT syllables
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+(b-hangulVBase))*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 &&
+		b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + (b - hangulTBase), true
+	}
+	c, ok := nfcCompositions[[2]rune{a, b}]
+	return c, ok
+}
@@ -0,0 +1,37 @@
+package fasttemplate
+
+import (
+	"testing"
+)
+
+func TestNormalizeNFC(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{"plain ascii", "plain ascii"},
+		{"e\u0301", "\u00e9"},
+		{"\u00e9", "\u00e9"},
+		{"A\u030a", "\u00c5"},
+		{"\u212b", "\u00c5"},               // singleton decomposition
+		{"q\u0307\u0323", "q\u0323\u0307"}, // canonical reordering
+		{"a\u0302\u0323", "\u1ead"},        // reordered before composing
+		{"\u1100\u1161\u11a8", "\uac01"},   // Hangul LVT
+		{"\u0915\u093c", "\u0915\u093c"},   // composition exclusion
+		{"\U0001F44D\U0001F3FD", "\U0001F44D\U0001F3FD"},
+	} {
+		if s := normalizeNFC(tc.in); s != tc.out {
+			t.Errorf("normalizeNFC(%+q): got %+q, want %+q", tc.in, s, tc.out)
+		}
+	}
+}
+
+func TestTextBuiltins(t *testing.T) {
+	tpl := New("<p title='{{htmlEncode(v)}}'>{{stripControl(v)}}|{{htmlDecode(e)}}|{{normalizeNFC(n)}}</p>", "{{", "}}")
+	s := tpl.ExecuteString(Map{
+		"v": "a<b>\x00'\u00fc\u200b\n",
+		"e": "&lt;&#252;&amp;&eacute;",
+		"n": "cafe\u0301",
+	})
+	want := "<p title='a&lt;b&gt;\x00&#39;&#252;&#8203;\n'>a<b>'\u00fc\u200b\n|<\u00fc&\u00e9|caf\u00e9</p>"
+	if s != want {
+		t.Fatalf("unexpected output\ngot:  %q\nwant: %q", s, want)
+	}
+}
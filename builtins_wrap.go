@@ -0,0 +1,135 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	RegisterBuiltin("truncate", Func{Fn: truncateText, Idempotent: true})
+	RegisterBuiltin("wrap", Func{Fn: wrapText, Idempotent: true})
+}
+
+// defaultEllipsis is appended by truncate unless another one is given.
+const defaultEllipsis = "…"
+
+// truncateText shortens s to at most n user-perceived characters, ending
+// with ellipsis, "…" unless given, if anything was cut. The ellipsis counts
+// toward n, so the result always fits the limit. Characters are grapheme
+// clusters, so combining marks, emoji sequences and flags are never split.
+func truncateText(s string, n int, ellipsis ...string) (string, error) {
+	if len(ellipsis) > 1 {
+		return "", errors.New("truncate: expected at most one ellipsis")
+	}
+	if n < 0 {
+		return "", errors.New("truncate: negative length")
+	}
+	e := defaultEllipsis
+	if len(ellipsis) == 1 {
+		e = ellipsis[0]
+	}
+
+	clusters := graphemes(s)
+	if len(clusters) <= n {
+		return s, nil
+	}
+	ec := graphemes(e)
+	if len(ec) >= n {
+		return strings.Join(ec[:n], ""), nil
+	}
+	return strings.Join(clusters[:n-len(ec)], "") + e, nil
+}
+
+// wrapText wraps s into lines of at most width user-perceived characters,
+// breaking at spaces. Words longer than width start a new line and are
+// broken every width characters. Existing line breaks are kept.
+func wrapText(s string, width int) (string, error) {
+	if width <= 0 {
+		return "", errors.New("wrap: width must be positive")
+	}
+	var sb strings.Builder
+	for i, para := range strings.Split(s, "\n") {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		lineLen := 0
+		for _, word := range strings.Fields(para) {
+			clusters := graphemes(word)
+			if lineLen > 0 && lineLen+1+len(clusters) <= width {
+				sb.WriteByte(' ')
+				lineLen++
+			} else if lineLen > 0 {
+				sb.WriteByte('\n')
+				lineLen = 0
+			}
+			for len(clusters) > width-lineLen {
+				sb.WriteString(strings.Join(clusters[:width-lineLen], ""))
+				sb.WriteByte('\n')
+				clusters = clusters[width-lineLen:]
+				lineLen = 0
+			}
+			sb.WriteString(strings.Join(clusters, ""))
+			lineLen += len(clusters)
+		}
+	}
+	return sb.String(), nil
+}
+
+// graphemes splits s into approximate grapheme clusters: runes followed by
+// combining marks, variation selectors, emoji modifiers and tags, emoji
+// joined by zero width joiners, flags made of regional indicator pairs and
+// CRLF sequences.
+func graphemes(s string) []string {
+	var clusters []string
+	start := 0
+	var prev rune
+	regional := 0 // regional indicators in the current cluster
+	for i, r := range s {
+		if i > 0 && !joinsCluster(prev, r, regional) {
+			clusters = append(clusters, s[start:i])
+			start = i
+			regional = 0
+		}
+		if isRegionalIndicator(r) {
+			regional++
+		}
+		prev = r
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// joinsCluster reports whether r continues the grapheme cluster ending with
+// prev, which holds regional regional indicators.
+func joinsCluster(prev, r rune, regional int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case prev == '\u200d':
+		// zero width joiner
+		return true
+	case r == '\u200d', unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f:
+		// variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff:
+		// emoji skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f:
+		// tags of emoji tag sequences
+		return true
+	case isRegionalIndicator(r):
+		return regional%2 == 1 && isRegionalIndicator(prev)
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is a regional indicator symbol, two
+// of which make up a flag.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
@@ -0,0 +1,86 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected []string
+	}{
+		{"abc", []string{"a", "b", "c"}},
+		{"éx", []string{"é", "x"}},
+		{"👍🏽!", []string{"👍🏽", "!"}},
+		{"👩‍💻x", []string{"👩‍💻", "x"}},
+		{"🇩🇪🇫🇷", []string{"🇩🇪", "🇫🇷"}},
+		{"a\r\nb", []string{"a", "\r\n", "b"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := graphemes(tt.s)
+		if strings.Join(got, "|") != strings.Join(tt.expected, "|") || len(got) != len(tt.expected) {
+			t.Errorf("graphemes(%q) = %q, expected %q", tt.s, got, tt.expected)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		s        string
+		n        int
+		ellipsis []string
+		expected string
+	}{
+		{"Hello, world", 20, nil, "Hello, world"},
+		{"Hello, world", 12, nil, "Hello, world"},
+		{"Hello, world", 8, nil, "Hello, …"},
+		{"Hello, world", 8, []string{"..."}, "Hello..."},
+		{"Hello, world", 5, []string{""}, "Hello"},
+		{"Hello, world", 2, []string{"..."}, ".."},
+		{"Café 🇩🇪 party", 7, nil, "Café 🇩🇪…"},
+		{"abc", 0, nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := truncateText(tt.s, tt.n, tt.ellipsis...)
+		if err != nil || got != tt.expected {
+			t.Errorf("truncate(%q, %d, %q) = %q, %v, expected %q", tt.s, tt.n, tt.ellipsis, got, err, tt.expected)
+		}
+	}
+
+	if _, err := truncateText("abc", -1); err == nil {
+		t.Errorf("expected error for negative length")
+	}
+	if _, err := truncateText("abc", 1, "a", "b"); err == nil {
+		t.Errorf("expected error for several ellipses")
+	}
+
+	tpl := New("{{truncate(msg, 10)}}|{{truncate(msg, 10, '...')}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"msg": "Your order has shipped"}); s != "Your orde…|Your or..." {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"the quick brown fox jumps", 10, "the quick\nbrown fox\njumps"},
+		{"the quick brown fox", 100, "the quick brown fox"},
+		{"a supercalifragilistic word", 8, "a\nsupercal\nifragili\nstic\nword"},
+		{"first line\nsecond  line here", 11, "first line\nsecond line\nhere"},
+		{"über öde äpfel", 4, "über\nöde\näpfe\nl"},
+	}
+	for _, tt := range tests {
+		got, err := wrapText(tt.s, tt.width)
+		if err != nil || got != tt.expected {
+			t.Errorf("wrap(%q, %d) = %q, %v, expected %q", tt.s, tt.width, got, err, tt.expected)
+		}
+	}
+	if _, err := wrapText("abc", 0); err == nil {
+		t.Errorf("expected error for zero width")
+	}
+}
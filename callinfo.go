@@ -0,0 +1,60 @@
+package fasttemplate
+
+import "reflect"
+
+// CallInfo describes the call of a function by a template. Functions whose
+// first parameter is a CallInfo receive it in addition to the arguments of
+// the call, e.g. to log which template invoked a shared helper:
+//
+//	"price": func(ci fasttemplate.CallInfo, cents int) string {
+//		log.Printf("%s: tag %d %q", ci.Template, ci.Index, ci.Tag)
+//		return fmt.Sprintf("%.2f", float64(cents)/100)
+//	},
+//
+// The CallInfo parameter isn't counted when checking the number of
+// arguments of calls, and calls of such functions aren't memoized.
+type CallInfo struct {
+	// Template is the name of the template set with NewNamed or WithName,
+	// if any.
+	Template string
+
+	// Tag is the content of the tag being evaluated.
+	Tag string
+
+	// Index is the index of the tag among the tags of the template, counting
+	// block tags.
+	Index int
+
+	// Func is the name the function was called by.
+	Func string
+}
+
+// WithName names the template like NewNamed.
+func WithName(name string) Option {
+	return func(t *Template) {
+		t.name = name
+	}
+}
+
+var callInfoType = reflect.TypeOf(CallInfo{})
+
+// takesCallInfo reports whether the first parameter of fnType is a
+// CallInfo.
+func takesCallInfo(fnType reflect.Type) bool {
+	return fnType.NumIn() > 0 && fnType.In(0) == callInfoType
+}
+
+// setTag records the i-th tag of the template as the tag being evaluated.
+func (ec *evalContext) setTag(i int, tag string) {
+	if ec != nil {
+		ec.tagIndex, ec.tag = i, tag
+	}
+}
+
+// callInfo returns the CallInfo of a call of the named function.
+func (ec *evalContext) callInfo(name string) CallInfo {
+	if ec == nil {
+		return CallInfo{Func: name}
+	}
+	return CallInfo{Template: ec.name, Tag: ec.tag, Index: ec.tagIndex, Func: name}
+}
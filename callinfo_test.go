@@ -0,0 +1,53 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCallInfo(t *testing.T) {
+	var calls []CallInfo
+	m := Map{
+		"price": func(ci CallInfo, cents int) string {
+			calls = append(calls, ci)
+			return fmt.Sprintf("%.2f", float64(cents)/100)
+		},
+		"join": func(ci CallInfo, parts ...string) string {
+			calls = append(calls, ci)
+			return strings.Join(parts, "+")
+		},
+		"n": 250,
+	}
+	tpl := New("{{if n > 0}}{{price(n)}}{{end}} {{join('a', 'b')}} {{upper(join())}}", "{{", "}}", WithName("invoice"))
+	if s := tpl.ExecuteString(m); s != "2.50 a+b " {
+		t.Fatalf("unexpected output %q", s)
+	}
+	want := []CallInfo{
+		{Template: "invoice", Tag: "price(n)", Index: 1, Func: "price"},
+		{Template: "invoice", Tag: "join('a', 'b')", Index: 3, Func: "join"},
+		{Template: "invoice", Tag: "upper(join())", Index: 4, Func: "join"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+
+	// the CallInfo parameter isn't an argument
+	if findings := tpl.Analyze(m); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	findings := New("{{price()}}", "{{", "}}").Analyze(m)
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "called with 0 argument(s), expected 1") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	// unnamed templates and Eval
+	calls = nil
+	if v, err := Eval[string]("price(n)", m); err != nil || v != "2.50" {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+	if !reflect.DeepEqual(calls, []CallInfo{{Func: "price"}}) {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+}
@@ -0,0 +1,809 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// WithCELCompatibility switches the tags of the template to a subset of the
+// Common Expression Language, so the same expressions can be checked by CEL
+// based tools and rendered by fasttemplate:
+//
+//	{{user.age >= 18 ? "adult" : "minor"}}
+//	{{user.country in ["DE", "FR"] && user.name.startsWith("A")}}
+//
+// Every tag other than a plain variable name is parsed as a CEL expression.
+// The subset covers literals, lists, member access, indexing, the ternary
+// operator, the in operator, the logical, relational and arithmetic
+// operators, the string methods contains, startsWith, endsWith, matches and
+// size, the size function and calls of the functions available to the
+// template.
+//
+// Unlike the built-in evaluator, unknown identifiers are missing variables
+// instead of literal strings, and operands aren't converted implicitly: + concatenates
+// strings and lists only, arithmetic mixing integers and doubles fails, and
+// logical operators and conditions require booleans. Integers evaluate to
+// int64. Lambdas, spread arguments and method chains on functions aren't
+// supported. WithCELCompatibility takes precedence over WithExprEngine;
+// Analyze, Explain and Variables keep using the built-in parser.
+func WithCELCompatibility() Option {
+	return func(t *Template) {
+		t.cel = true
+	}
+}
+
+// celCache caches the parsed CEL expressions by source.
+var celCache sync.Map // map[string]celNode
+
+// isPlainTag reports whether the tag is a plain variable name, which is
+// looked up as is in CEL mode.
+func isPlainTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		if !isIdentByte(tag[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileCEL returns the parsed CEL expression.
+func compileCEL(expr string) (celNode, error) {
+	if n, ok := celCache.Load(expr); ok {
+		return n.(celNode), nil
+	}
+	tokens, err := lexCEL(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &celParser{tokens: tokens}
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	celCache.Store(expr, n)
+	return n, nil
+}
+
+// evalCEL evaluates the CEL expression against data.
+func evalCEL(expr string, data Map, ec *evalContext) (any, error) {
+	n, err := compileCEL(expr)
+	if err != nil {
+		return nil, err
+	}
+	return n.eval(data, ec)
+}
+
+type celTokenKind int
+
+const (
+	celInt celTokenKind = iota
+	celDouble
+	celString
+	celIdent
+	celOp
+)
+
+type celToken struct {
+	kind celTokenKind
+	text string
+	pos  int
+}
+
+// lexCEL splits the expression into tokens. String tokens hold the unquoted
+// value.
+func lexCEL(s string) ([]celToken, error) {
+	var tokens []celToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9':
+			start, kind := i, celInt
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9') {
+				if s[i] == '.' {
+					kind = celDouble
+				}
+				i++
+			}
+			tokens = append(tokens, celToken{kind, s[start:i], start})
+		case isIdentByte(c, true):
+			start := i
+			for i < len(s) && isIdentByte(s[i], false) {
+				i++
+			}
+			tokens = append(tokens, celToken{celIdent, s[start:i], start})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] != '\\' || i+1 == len(s) {
+					sb.WriteByte(s[i])
+					continue
+				}
+				i++
+				switch s[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				default:
+					sb.WriteByte(s[i])
+				}
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, celToken{celString, sb.String(), start})
+		default:
+			if i+1 < len(s) {
+				switch op := s[i : i+2]; op {
+				case "||", "&&", "==", "!=", "<=", ">=":
+					tokens = append(tokens, celToken{celOp, op, i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("?:<>+-*/%!()[],.", rune(c)) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, celToken{celOp, string(c), i})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// celParser is a recursive descent parser of CEL expressions.
+type celParser struct {
+	tokens []celToken
+	pos    int
+}
+
+// accept consumes the next token if it is the operator or keyword op.
+func (p *celParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].text == op && p.tokens[p.pos].kind >= celIdent {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *celParser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("expected %q at position %d, got %q", op, p.tokens[p.pos].pos, p.tokens[p.pos].text)
+	}
+	return fmt.Errorf("expected %q at end of expression", op)
+}
+
+func (p *celParser) parseExpr() (celNode, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &celCond{cond, then, els}, nil
+}
+
+// celPrecedence lists the binary operators from the lowest precedence.
+var celPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *celParser) parseBinary(level int) (celNode, error) {
+	if level == len(celPrecedence) {
+		return p.parseUnary()
+	}
+	l, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := "", false
+		for _, candidate := range celPrecedence[level] {
+			if p.accept(candidate) {
+				op, ok = candidate, true
+				break
+			}
+		}
+		if !ok {
+			return l, nil
+		}
+		r, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &celBinary{op, l, r}
+	}
+}
+
+func (p *celParser) parseUnary() (celNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			x, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &celUnary{op, x}, nil
+		}
+	}
+	return p.parseMember()
+}
+
+func (p *celParser) parseMember() (celNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			if p.pos == len(p.tokens) || p.tokens[p.pos].kind != celIdent {
+				return nil, fmt.Errorf("expected field name after '.'")
+			}
+			name := p.tokens[p.pos].text
+			p.pos++
+			if p.accept("(") {
+				args, err := p.parseList(")")
+				if err != nil {
+					return nil, err
+				}
+				x = &celCall{recv: x, name: name, args: args}
+			} else if id, ok := x.(*celIdentNode); ok {
+				// keep dotted paths together, so flat keys like "user.name"
+				// resolve as well
+				x = &celIdentNode{id.name + "." + name}
+			} else {
+				x = &celSelect{x, name}
+			}
+		case p.accept("["):
+			i, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &celIndex{x, i}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *celParser) parsePrimary() (celNode, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case celInt:
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		return &celLiteral{v}, err
+	case celDouble:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		return &celLiteral{v}, err
+	case celString:
+		return &celLiteral{tok.text}, nil
+	case celIdent:
+		switch tok.text {
+		case "true", "false":
+			return &celLiteral{tok.text == "true"}, nil
+		case "null":
+			return &celLiteral{nil}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+		}
+		if p.accept("(") {
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return &celCall{name: tok.text, args: args}, nil
+		}
+		return &celIdentNode{tok.text}, nil
+	}
+	switch tok.text {
+	case "(":
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case "[":
+		elems, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &celList{elems}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// parseList parses comma separated expressions up to the closing token.
+func (p *celParser) parseList(closing string) ([]celNode, error) {
+	var list []celNode
+	if p.accept(closing) {
+		return list, nil
+	}
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if p.accept(closing) {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// celNode is a node of a parsed CEL expression.
+type celNode interface {
+	eval(data Map, ec *evalContext) (any, error)
+}
+
+type celLiteral struct{ v any }
+
+func (n *celLiteral) eval(Map, *evalContext) (any, error) { return n.v, nil }
+
+type celIdentNode struct{ name string }
+
+func (n *celIdentNode) eval(data Map, ec *evalContext) (any, error) {
+	if v, ok := ec.lookup(data, n.name); ok {
+		return v, nil
+	}
+	if root, rest, nested := strings.Cut(n.name, "."); nested {
+		if v, ok := ec.lookup(data, root); ok {
+			if v, ok = fieldValue(v, rest); ok {
+				return v, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errVariableNotFound, n.name)
+}
+
+type celSelect struct {
+	x     celNode
+	field string
+}
+
+func (n *celSelect) eval(data Map, ec *evalContext) (any, error) {
+	x, err := n.x.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := fieldValue(x, n.field)
+	if !ok {
+		return nil, fmt.Errorf("no such field %q", n.field)
+	}
+	return v, nil
+}
+
+type celIndex struct{ x, i celNode }
+
+func (n *celIndex) eval(data Map, ec *evalContext) (any, error) {
+	x, err := n.x.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.i.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(x)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		idx, isInt := celInteger(i)
+		if !isInt {
+			return nil, fmt.Errorf("invalid index type %T", i)
+		}
+		if idx < 0 || idx >= int64(rv.Len()) {
+			return nil, fmt.Errorf("index %d out of range", idx)
+		}
+		return rv.Index(int(idx)).Interface(), nil
+	case reflect.Map:
+		v, ok := mapIndex(rv, i)
+		if !ok {
+			return nil, fmt.Errorf("no such key: %v", i)
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("cannot index %T", x)
+}
+
+type celList struct{ elems []celNode }
+
+func (n *celList) eval(data Map, ec *evalContext) (any, error) {
+	list := make([]any, len(n.elems))
+	for i, e := range n.elems {
+		v, err := e.eval(data, ec)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+type celCond struct{ cond, then, els celNode }
+
+func (n *celCond) eval(data Map, ec *evalContext) (any, error) {
+	c, err := n.cond.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition must be a bool, got %T", c)
+	}
+	if b {
+		return n.then.eval(data, ec)
+	}
+	return n.els.eval(data, ec)
+}
+
+type celUnary struct {
+	op string
+	x  celNode
+}
+
+func (n *celUnary) eval(data Map, ec *evalContext) (any, error) {
+	x, err := n.x.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("no matching overload for !%T", x)
+		}
+		return !b, nil
+	}
+	if i, ok := celInteger(x); ok {
+		return -i, nil
+	}
+	if f, ok := celDoubleValue(x); ok {
+		return -f, nil
+	}
+	return nil, fmt.Errorf("no matching overload for -%T", x)
+}
+
+type celBinary struct {
+	op   string
+	l, r celNode
+}
+
+func (n *celBinary) eval(data Map, ec *evalContext) (any, error) {
+	l, err := n.l.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("no matching overload for %T %s", l, n.op)
+		}
+		// short-circuit like CEL
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+		r, err := n.r.eval(data, ec)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("no matching overload for %s %T", n.op, r)
+		}
+		return rb, nil
+	}
+	r, err := n.r.eval(data, ec)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return celEqual(l, r), nil
+	case "!=":
+		return !celEqual(l, r), nil
+	case "in":
+		return celIn(l, r)
+	case "<", "<=", ">", ">=":
+		return celCompare(n.op, l, r)
+	}
+	return celArith(n.op, l, r)
+}
+
+type celCall struct {
+	// recv is the receiver of member calls, nil for global calls.
+	recv celNode
+	name string
+	args []celNode
+}
+
+func (n *celCall) eval(data Map, ec *evalContext) (any, error) {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(data, ec)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if n.recv != nil {
+		recv, err := n.recv.eval(data, ec)
+		if err != nil {
+			return nil, err
+		}
+		return celMethod(recv, n.name, args)
+	}
+	if n.name == "size" && len(args) == 1 {
+		return celSize(args[0])
+	}
+
+	// call the function like the built-in evaluator, passing the evaluated
+	// arguments as literals
+	fc := &functionCall{Name: n.name, Args: make([]any, len(args))}
+	for i, a := range args {
+		if s, ok := a.(string); ok {
+			a = literalString(s)
+		}
+		fc.Args[i] = a
+	}
+	return fc.execute(data, data, ec)
+}
+
+// celMethod calls the string method name on recv.
+func celMethod(recv any, name string, args []any) (any, error) {
+	if name == "size" && len(args) == 0 {
+		return celSize(recv)
+	}
+	s, ok := recv.(string)
+	if !ok || len(args) != 1 {
+		return nil, fmt.Errorf("no matching overload for %T.%s", recv, name)
+	}
+	arg, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("no matching overload for string.%s(%T)", name, args[0])
+	}
+	switch name {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	case "matches":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	}
+	return nil, fmt.Errorf("no matching overload for string.%s", name)
+}
+
+// celSize returns the number of code points of strings and the number of
+// elements of lists and maps.
+func celSize(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		return int64(utf8.RuneCountInString(s)), nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return int64(rv.Len()), nil
+	}
+	return nil, fmt.Errorf("no matching overload for size(%T)", v)
+}
+
+// celInteger returns v as an int64 if it is an integer.
+func celInteger(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// celDoubleValue returns v as a float64 if it is a floating-point number.
+func celDoubleValue(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64 {
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// celNumbers returns a and b as float64 if both are numbers.
+func celNumbers(a, b any) (float64, float64, bool) {
+	fa, ok := celFloat(a)
+	if !ok {
+		return 0, 0, false
+	}
+	fb, ok := celFloat(b)
+	return fa, fb, ok
+}
+
+func celFloat(v any) (float64, bool) {
+	if i, ok := celInteger(v); ok {
+		return float64(i), true
+	}
+	return celDoubleValue(v)
+}
+
+// celEqual compares numbers by value regardless of their type, and other
+// values of different types as unequal.
+func celEqual(a, b any) bool {
+	if ia, ok := celInteger(a); ok {
+		if ib, ok := celInteger(b); ok {
+			return ia == ib
+		}
+	}
+	if fa, fb, ok := celNumbers(a, b); ok {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func celCompare(op string, a, b any) (any, error) {
+	var c int
+	ia, aInt := celInteger(a)
+	ib, bInt := celInteger(b)
+	fa, fb, numbers := celNumbers(a, b)
+	sa, aStr := a.(string)
+	sb, bStr := b.(string)
+	switch {
+	case aInt && bInt:
+		c = compareOrdered(ia, ib)
+	case numbers:
+		c = compareOrdered(fa, fb)
+	case aStr && bStr:
+		c = strings.Compare(sa, sb)
+	default:
+		return nil, fmt.Errorf("no matching overload for %T %s %T", a, op, b)
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func celIn(v, container any) (any, error) {
+	rv := reflect.ValueOf(container)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if celEqual(v, rv.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		_, ok := mapIndex(rv, v)
+		return ok, nil
+	}
+	return nil, fmt.Errorf("no matching overload for %T in %T", v, container)
+}
+
+// mapIndex returns the element of the map rv stored under key.
+func mapIndex(rv reflect.Value, key any) (reflect.Value, bool) {
+	k := reflect.ValueOf(key)
+	if !k.IsValid() || !k.Type().ConvertibleTo(rv.Type().Key()) {
+		return reflect.Value{}, false
+	}
+	if k.Kind() != rv.Type().Key().Kind() {
+		// don't turn integers into strings of runes and alike
+		return reflect.Value{}, false
+	}
+	v := rv.MapIndex(k.Convert(rv.Type().Key()))
+	return v, v.IsValid()
+}
+
+func celArith(op string, a, b any) (any, error) {
+	ia, aInt := celInteger(a)
+	ib, bInt := celInteger(b)
+	if aInt && bInt {
+		switch op {
+		case "+":
+			return ia + ib, nil
+		case "-":
+			return ia - ib, nil
+		case "*":
+			return ia * ib, nil
+		case "/", "%":
+			if ib == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return ia / ib, nil
+			}
+			return ia % ib, nil
+		}
+	}
+	fa, aDouble := celDoubleValue(a)
+	fb, bDouble := celDoubleValue(b)
+	if aDouble && bDouble && op != "%" {
+		switch op {
+		case "+":
+			return fa + fb, nil
+		case "-":
+			return fa - fb, nil
+		case "*":
+			return fa * fb, nil
+		case "/":
+			return fa / fb, nil
+		}
+	}
+	if op == "+" {
+		if sa, ok := a.(string); ok {
+			if sb, ok := b.(string); ok {
+				return sa + sb, nil
+			}
+		}
+		ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+		if (ra.Kind() == reflect.Slice || ra.Kind() == reflect.Array) && (rb.Kind() == reflect.Slice || rb.Kind() == reflect.Array) {
+			list := make([]any, 0, ra.Len()+rb.Len())
+			for _, rv := range []reflect.Value{ra, rb} {
+				for i := 0; i < rv.Len(); i++ {
+					list = append(list, rv.Index(i).Interface())
+				}
+			}
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("no matching overload for %T %s %T", a, op, b)
+}
@@ -0,0 +1,112 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithCELCompatibility(t *testing.T) {
+	type user struct {
+		Name    string
+		Age     int
+		Country string
+	}
+	m := Map{
+		"user":    user{Name: "Alice", Age: 20, Country: "DE"},
+		"allowed": []string{"DE", "FR"},
+		"limits":  map[string]int{"daily": 5},
+		"price":   2.5,
+		"qty":     3,
+		"name":    "bob",
+		"upper":   strings.ToUpper,
+	}
+	tests := []struct {
+		tag, want string
+	}{
+		{`user.Age >= 18 ? "adult" : "minor"`, "adult"},
+		{`user.Age < 18 ? "minor" : user.Age < 65 ? "adult" : "senior"`, "adult"},
+		{`user.Country in allowed`, "true"},
+		{`"US" in ["DE", "FR"]`, "false"},
+		{`"daily" in limits`, "true"},
+		{`limits["daily"] * qty`, "15"},
+		{`allowed[1]`, "FR"},
+		{`user.Name.startsWith("A") && user.Name.endsWith("e")`, "true"},
+		{`name.contains("o") || missing`, "true"},
+		{`name.matches("^b.b$")`, "true"},
+		{`name.size() + size(allowed)`, "5"},
+		{`7 / 2`, "3"},
+		{`price * 2.0`, "5"},
+		{`-qty`, "-3"},
+		{`!(qty == 3)`, "false"},
+		{`qty == 3.0`, "true"},
+		{`upper(name + "!")`, "BOB!"},
+		{`'it\'s'`, "it's"},
+	}
+	for _, tt := range tests {
+		tpl := New("{{"+tt.tag+"}}", "{{", "}}", WithCELCompatibility())
+		var bb bytes.Buffer
+		if _, err := tpl.Execute(&bb, m); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.tag, err)
+		}
+		if bb.String() != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.tag, tt.want, bb.String())
+		}
+	}
+}
+
+func TestWithCELCompatibilityErrors(t *testing.T) {
+	m := Map{"qty": 3, "price": 2.5, "name": "bob", "upper": strings.ToUpper}
+	tests := []struct {
+		tag, want string
+	}{
+		{`qty * price`, "no matching overload for int * float64"},
+		{`name + qty`, "no matching overload for string + int"},
+		{`qty && true`, "no matching overload for int &&"},
+		{`qty ? 1 : 2`, "condition must be a bool, got int"},
+		{`qty / 0`, "division by zero"},
+		{`name.trim()`, "no matching overload for string.trim"},
+	}
+	for _, tt := range tests {
+		tpl := New("{{"+tt.tag+"}}", "{{", "}}", WithCELCompatibility())
+		_, err := tpl.Execute(&bytes.Buffer{}, m)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error %q, got %v", tt.tag, tt.want, err)
+		}
+	}
+
+	// unknown identifiers are missing variables instead of strings
+	ec := New("", "{{", "}}", WithCELCompatibility()).newEvalContext()
+	for _, tag := range []string{"upper(unknown)", "name == bob"} {
+		if _, _, err := evalTag(tag, m, ec); !errors.Is(err, errVariableNotFound) {
+			t.Fatalf("%s: expected errVariableNotFound, got %v", tag, err)
+		}
+	}
+	if s := New("[{{upper(unknown)}}]", "{{", "}}", WithCELCompatibility()).ExecuteString(m); s != "[]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// with the literal fallback, unquoted arguments that aren't names are
+	// literals
+	if s := New("{{upper(hello world)}}", "{{", "}}", WithLiteralFallback()).ExecuteString(m); s != "HELLO WORLD" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	_, err := New("{{upper(hello world)}}", "{{", "}}", WithCELCompatibility()).Execute(&bytes.Buffer{}, m)
+	if err == nil || !strings.Contains(err.Error(), `expected "," at position 12, got "world"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestWithCELCompatibilityValidate(t *testing.T) {
+	m := Map{"a": 1}
+	if err := New("{{a}} {{a > 1 ? 'x' : 'y'}}", "{{", "}}", WithCELCompatibility()).Validate(m); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, tag := range []string{"a ? 1", "a +", "(a", "a $ 1", "'a"} {
+		err := New("{{"+tag+"}}", "{{", "}}", WithCELCompatibility()).Validate(m)
+		if err == nil || !strings.Contains(err.Error(), "invalid expression") {
+			t.Fatalf("%s: expected a syntax error, got %v", tag, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package fasttemplate
+
+import "strings"
+
+// isMethodChain reports whether s may be a method chain: a call following a
+// receiver or another call.
+func isMethodChain(s string) bool {
+	if strings.Contains(s, ").") {
+		return true
+	}
+	paren := strings.IndexByte(s, '(')
+	return paren > 0 && strings.ContainsAny(s[:paren], ".'\"")
+}
+
+// desugarChain rewrites the method chain sugar receiver.f(args).g() to the
+// nested call g(f(receiver, args)), passing the receiver as the first
+// argument. The receiver is a variable, possibly a dotted path, a quoted
+// literal, a number or a function call. It reports false if s isn't a
+// method chain.
+func desugarChain(s string) (string, bool) {
+	i := scanReceiver(s)
+	if i == 0 {
+		return "", false
+	}
+	receiver := s[:i]
+
+	var chained bool
+	for i < len(s) {
+		if s[i] != '.' {
+			return "", false
+		}
+		i++
+		start := i
+		for i < len(s) && isIdentByte(s[i], i == start) {
+			i++
+		}
+		name := s[start:i]
+		if name == "" {
+			return "", false
+		}
+		if i == len(s) || s[i] != '(' {
+			// a field of a variable receiver, e.g. user.name.upper()
+			if chained || !isIdentStart(receiver[0]) || strings.HasSuffix(receiver, ")") {
+				return "", false
+			}
+			receiver = s[:i]
+			continue
+		}
+
+		end := scanParens(s, i)
+		if end < 0 {
+			return "", false
+		}
+		args := strings.TrimSpace(s[i+1 : end-1])
+		if args != "" {
+			receiver = name + "(" + receiver + ", " + args + ")"
+		} else {
+			receiver = name + "(" + receiver + ")"
+		}
+		chained = true
+		i = end
+	}
+	if !chained {
+		return "", false
+	}
+	return receiver, true
+}
+
+// scanReceiver returns the end of the receiver of a method chain at the
+// start of s, or 0 if there is none.
+func scanReceiver(s string) int {
+	if s == "" {
+		return 0
+	}
+	switch c := s[0]; {
+	case c == '\'' || c == '"':
+		return scanQuoted(s, 0)
+	case c >= '0' && c <= '9':
+		i := 0
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9') {
+			i++
+		}
+		return i
+	case isIdentStart(c):
+		i := 1
+		for i < len(s) && isIdentByte(s[i], false) {
+			i++
+		}
+		if i < len(s) && s[i] == '(' {
+			if end := scanParens(s, i); end > 0 {
+				return end
+			}
+			return 0
+		}
+		return i
+	}
+	return 0
+}
+
+// scanQuoted returns the end of the quoted string starting at s[i], or 0 if
+// it is unterminated.
+func scanQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// scanParens returns the end of the parenthesized text starting at s[i],
+// skipping quoted strings, or -1 if it is unbalanced.
+func scanParens(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"':
+			end := scanQuoted(s, i)
+			if end == 0 {
+				return -1
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// isIdentByte reports whether c may appear in an identifier, at its start
+// if first is set.
+func isIdentByte(c byte, first bool) bool {
+	return isIdentStart(c) || !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,74 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDesugarChain(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+		ok       bool
+	}{
+		{"name.upper()", "upper(name)", true},
+		{"name.upper().trim()", "trim(upper(name))", true},
+		{"name.repeat(3).upper()", "upper(repeat(name, 3))", true},
+		{"user.name.upper()", "upper(user.name)", true},
+		{"'a.b'.upper()", "upper('a.b')", true},
+		{`"x(y)".upper()`, `upper("x(y)")`, true},
+		{"3.5.round(1)", "round(3.5, 1)", true},
+		{"concat(a, ').').upper()", "upper(concat(a, ').'))", true},
+		{"name.replace('(', ')').trim()", "trim(replace(name, '(', ')'))", true},
+		{"upper(name)", "", false},
+		{"concat('a).b', c)", "", false},
+		{"name.upper().field", "", false},
+		{"name.upper(", "", false},
+		{"name.", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := desugarChain(tt.s)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("desugarChain(%q) = %q, %t; expected %q, %t", tt.s, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestMethodChains(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("shout", func(s string) string { return s + "!" })
+	m := Map{
+		"name":   "  Ann ",
+		"title":  "go",
+		"repeat": strings.Repeat,
+		"user":   "bob",
+		"items":  []string{"a", "b"},
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{name.trim().upper()}}", "ANN"},
+		{"{{title.repeat(2).upper().shout()}}", "GOGO!"},
+		{"{{'x'.upper()}}", "X"},
+		{"{{upper(title).shout()}}", "GO!"},
+		{"{{title.upper() + '?'}}", "GO?"},
+		{"{{concat(title.upper(), user.shout())}}", "GObob!"},
+		{"{{map(items, (x) => x.upper())}}", "[A B]"},
+	}
+	m["concat"] = func(a, b string) string { return a + b }
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var bb bytes.Buffer
+			tpl := New(tt.template, "{{", "}}", WithRegistry(reg))
+			if _, err := tpl.Execute(&bb, m); err != nil || bb.String() != tt.expected {
+				t.Fatalf("unexpected result %q, %v", bb.String(), err)
+			}
+			if err := tpl.Validate(m); err != nil {
+				t.Fatalf("unexpected validation error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// checksumKey is the name of the checksum tag.
+const checksumKey = "_checksum"
+
+// checksumMarker stands in for checksum tags until the output is complete.
+// The NUL bytes keep it from clashing with text output.
+const checksumMarker = "\x00fasttemplate:checksum\x00"
+
+// WithChecksumTag replaces {{_checksum}} tags with the lowercase hex digest
+// of the rest of the rendered output, for formats embedding their own
+// integrity checksum. The digest covers the output with all checksum tags
+// removed. newHash returns the hash to use; SHA-256 is used if it is nil.
+//
+// The digest is inserted verbatim: value transformers and escaping don't
+// apply to checksum tags. It is computed over the rendered output before any
+// output filter or writer, whatever the order of the options, so a stage
+// such as compression doesn't hide the checksum tags.
+//
+// The output is buffered until rendering finishes. Like output filters, the
+// checksum is computed by Execute, ExecuteStd, ExecuteAsync and the methods
+// built on them, but not by ExecuteMulti. Keys present in the substitution
+// map take precedence over _checksum.
+func WithChecksumTag(newHash func() hash.Hash) Option {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	return func(t *Template) {
+		t.checksumTag = true
+		stage := func(w io.Writer) io.WriteCloser {
+			return &filterWriter{w: w, t: t, f: func(p []byte) []byte {
+				return fillChecksums(p, newHash())
+			}}
+		}
+		// the first stage gets the rendered output
+		t.outputStages = append([]func(w io.Writer) io.WriteCloser{stage}, t.outputStages...)
+	}
+}
+
+// isChecksumMarker reports whether v is the value lookupChecksum resolved
+// the tag to.
+func (t *Template) isChecksumMarker(tag string, v any) bool {
+	if !t.checksumTag || tag != checksumKey {
+		return false
+	}
+	s, ok := v.(string)
+	return ok && s == checksumMarker
+}
+
+// fillChecksums replaces the checksum markers in p with the digest of the
+// rest of p.
+func fillChecksums(p []byte, h hash.Hash) []byte {
+	parts := bytes.Split(p, []byte(checksumMarker))
+	if len(parts) == 1 {
+		return p
+	}
+	for _, part := range parts {
+		h.Write(part)
+	}
+	sum := []byte(hex.EncodeToString(h.Sum(nil)))
+	return bytes.Join(parts, sum)
+}
+
+// lookupChecksum returns the marker of checksum tags.
+func (ec *evalContext) lookupChecksum(name string) (any, bool) {
+	if !ec.checksumTag || name != checksumKey {
+		return nil, false
+	}
+	return checksumMarker, true
+}
@@ -0,0 +1,59 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithChecksumTag(t *testing.T) {
+	tpl := New("! checksum {{_checksum}}\nhostname {{host}}\n", "{{", "}}", WithChecksumTag(nil))
+	m := Map{"host": "r1"}
+	sum := sha256.Sum256([]byte("! checksum \nhostname r1\n"))
+	expected := "! checksum " + hex.EncodeToString(sum[:]) + "\nhostname r1\n"
+	if s := tpl.ExecuteString(m); s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	if vars := tpl.Variables(); len(vars) != 1 || vars[0] != "host" {
+		t.Fatalf("unexpected variables %q", vars)
+	}
+
+	md := New("{{_checksum}}:{{x}}:{{_checksum}}", "{{", "}}", WithChecksumTag(md5.New))
+	mdSum := md5.Sum([]byte("::"))
+	mdHex := hex.EncodeToString(mdSum[:])
+	if s := md.ExecuteString(Map{"x": ""}); s != mdHex+"::"+mdHex {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if s := New("[{{_checksum}}]", "{{", "}}").ExecuteString(Map{}); s != "[]" {
+		t.Fatalf("_checksum must not be resolved by default, got %q", s)
+	}
+}
+
+func TestChecksumTagEscaping(t *testing.T) {
+	for _, mode := range []EscapeMode{EscapeXML, EscapeJSON, EscapeHTML} {
+		tpl := New(`<sum>{{_checksum}}</sum>"{{name}}"`, "{{", "}}", WithChecksumTag(nil), WithEscaping(mode),
+			WithValueTransformer(func(tag string, v any) any { return fmt.Sprint("t:", v) }))
+		s := tpl.ExecuteString(Map{"name": "a&b"})
+		rest := strings.Replace(s, "<sum>", "", 1)
+		digest, rest, _ := strings.Cut(rest, "</sum>")
+		sum := sha256.Sum256([]byte("<sum></sum>" + rest))
+		if digest != hex.EncodeToString(sum[:]) {
+			t.Fatalf("unexpected output with escaping mode %v: %q", mode, s)
+		}
+	}
+
+	// stages registered before the checksum tag get the filled in output
+	tpl := New("{{_checksum}}", "{{", "}}", WithOutputFilter(bytes.ToUpper), WithChecksumTag(nil))
+	sum := sha256.Sum256(nil)
+	if s := tpl.ExecuteString(Map{}); s != strings.ToUpper(hex.EncodeToString(sum[:])) {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
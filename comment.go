@@ -0,0 +1,134 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"strings"
+)
+
+// isCommentTag reports whether tag is a comment such as {{# note #}} or
+// {{- # note # -}}.
+func isCommentTag(tag string) bool {
+	tag, _, _ = cutTrimMarkers(tag)
+	return len(tag) >= 2 && tag[0] == '#' && tag[len(tag)-1] == '#'
+}
+
+// commentEnd returns the length of the comment tag at the start of s, the
+// template following a start tag, up to the end tag closing it. Comments
+// only end at an end tag preceded by '#', so end tags inside them don't leak
+// the rest of the comment into the output. It returns -1 if s doesn't start
+// a comment, and errUnterminatedComment if the comment isn't closed.
+func commentEnd(s, endTag string) (int, error) {
+	inner := s
+	if hasTrimPrefix(inner) {
+		inner = strings.TrimLeft(inner[1:], trimCutset)
+	}
+	if !strings.HasPrefix(inner, "#") || strings.HasPrefix(inner[1:], endTag) {
+		// a single # isn't a comment
+		return -1, nil
+	}
+	from := len(s) - len(inner) + 1
+	for {
+		n := strings.Index(s[from:], endTag)
+		if n < 0 {
+			return -1, errUnterminatedComment
+		}
+		end := from + n
+		if isCommentTag(s[:end]) {
+			return end, nil
+		}
+		from = end + 1
+	}
+}
+
+// dropTags drops the comment tags of the template and turns raw blocks into
+// static text, joining the texts around them, and returns offsets without
+// the entries of the dropped tags. The dropped bytes, including whitespace
+// removed by trim markers of the dropped tags, are recorded for tagOffsets.
+//
+// Like end outside of any block, a raw tag without a following endraw tag is
+// a regular tag, so templates using "raw" as a plain variable keep working.
+func (t *Template) dropTags(offsets []int) []int {
+	dropping := false
+	for _, tag := range t.tags {
+		if isCommentTag(tag) || isRawTag(tag) {
+			dropping = true
+			break
+		}
+	}
+	if !dropping {
+		return offsets
+	}
+
+	texts, tags := t.texts[:1], t.tags[:0]
+	var kept []int
+	droppedBytes := make([]int, 0, len(t.tags))
+	dropped := 0
+	// join appends text to the last static text, copying them as they
+	// aren't adjacent in the source
+	join := func(text ...[]byte) {
+		last := texts[len(texts)-1]
+		for _, s := range text {
+			last = append(last[:len(last):len(last)], s...)
+		}
+		texts[len(texts)-1] = last
+	}
+	// trim removes the leading or trailing whitespace of text if set
+	trim := func(text []byte, leading, trailing bool) []byte {
+		n := len(text)
+		if leading {
+			text = bytes.TrimLeft(text, trimCutset)
+		}
+		if trailing {
+			text = bytes.TrimRight(text, trimCutset)
+		}
+		dropped += n - len(text)
+		return text
+	}
+	for i := 0; i < len(t.tags); i++ {
+		tag := t.tags[i]
+		switch {
+		case isCommentTag(tag):
+			_, left, right := cutTrimMarkers(tag)
+			texts[len(texts)-1] = trim(texts[len(texts)-1], false, left)
+			join(trim(t.texts[i+1], right, false))
+			dropped += len(t.startTag) + len(tag) + len(t.endTag)
+			continue
+		case isRawTag(tag):
+			end := i + 1
+			for end < len(t.tags) && !isEndRawTag(t.tags[end]) {
+				end++
+			}
+			if end == len(t.tags) {
+				break
+			}
+			// the tags of the block are copied literally
+			content := append([]byte(nil), t.texts[i+1]...)
+			for j := i + 1; j < end; j++ {
+				content = append(content, t.startTag...)
+				content = append(content, t.tags[j]...)
+				content = append(content, t.endTag...)
+				content = append(content, t.texts[j+1]...)
+			}
+			_, left, right := cutTrimMarkers(tag)
+			_, endLeft, endRight := cutTrimMarkers(t.tags[end])
+			texts[len(texts)-1] = trim(texts[len(texts)-1], false, left)
+			join(trim(content, right, endLeft), trim(t.texts[end+1], endRight, false))
+			dropped += len(t.startTag) + len(tag) + len(t.endTag) + len(t.startTag) + len(t.tags[end]) + len(t.endTag)
+			i = end
+			continue
+		}
+		texts = append(texts, t.texts[i+1])
+		tags = append(tags, tag)
+		droppedBytes = append(droppedBytes, dropped)
+		dropped = 0
+		if offsets != nil {
+			kept = append(kept, offsets[i])
+		}
+	}
+	if len(tags) == len(t.tags) {
+		// only raw tags without an endraw tag
+		return offsets
+	}
+	t.texts, t.tags, t.dropped = texts, tags, droppedBytes
+	return kept
+}
@@ -0,0 +1,78 @@
+package fasttemplate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommentTags(t *testing.T) {
+	src := "{{# greeting #}}Hello{{# the name\nof the user #}}, {{name}}!{{# end #}}"
+	tpl := New(src, "{{", "}}")
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "Hello, Ann!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := tpl.ExecuteStringStd(Map{}); s != "Hello, {{name}}!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if vars := tpl.Variables(); !reflect.DeepEqual(vars, []string{"name"}) {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+
+	// offsets account for the dropped comments
+	findings := tpl.Analyze(Map{})
+	if len(findings) != 1 || findings[0].Offset != strings.Index(src, "{{name}}") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	// comments inside blocks and templates made of comments only
+	tpl = New("{{if ok}}{{# shown when ok #}}yes{{else}}no{{end}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"ok": true}); s != "yes" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New("a{{##}}b", "{{", "}}").ExecuteString(nil); s != "ab" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// a single # isn't a comment
+	if s := New("{{#}}", "{{", "}}").ExecuteString(Map{"#": "hash"}); s != "hash" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// comments end at # followed by the end tag, even if they contain end
+	// tags, and must be closed
+	tpl = New("a{{# {{x}} b }} c #}}d {{- # e }} # -}} {{name}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "adAnn" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	for _, src := range []string{"a{{# b }} c", "{{# a }}{{name}}# }}"} {
+		if _, err := NewTemplate(src, "{{", "}}"); err == nil || !errors.Is(err, errUnterminatedComment) {
+			t.Fatalf("unexpected error %v for %q", err, src)
+		}
+	}
+}
+
+func TestCommentTagsTolerant(t *testing.T) {
+	src := "{{# note #}}{{a +}} {{# unclosed"
+	_, errs := ParseTolerant(src, "{{", "}}")
+	if len(errs) != 2 || errs[0].Offset != strings.Index(src, "{{a +") || errs[1].Offset != strings.LastIndex(src, "{{") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	src = "{{a +}} {{# unclosed }} b"
+	_, errs = ParseTolerant(src, "{{", "}}")
+	if len(errs) != 2 || !errors.Is(errs[1].Err, errUnterminatedComment) || errs[1].Offset != strings.LastIndex(src, "{{") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestCommentTagsPatch(t *testing.T) {
+	tpl := New("{{# note #}}Hi {{name}}", "{{", "}}")
+	if err := tpl.Patch(TextEdit{Offset: len("{{# note #}}"), Length: 2, Text: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "Hello Ann" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
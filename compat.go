@@ -0,0 +1,165 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// TagFunc can be used as a substitution value in the map passed to Execute*
+// functions, and is called for every tag by the ExecuteFunc* functions.
+// It must write the contents of the tag to w and return the number of bytes
+// written, like in valyala/fasttemplate.
+type TagFunc func(w io.Writer, tag string) (int, error)
+
+// WithLegacyBehavior restores the semantics of valyala/fasttemplate, so the
+// import can be swapped without changing the output of existing templates:
+// every tag is resolved as a plain variable by its exact content, spaces
+// included. Function calls, expressions, defaults, blocks, comments, raw
+// blocks, whitespace control and flush tags aren't recognized.
+//
+// Unknown tags render empty with Execute and are kept by ExecuteStd, TagFunc
+// values are called with the tag, and templates with unclosed tags are
+// rejected, like without the option. Unlike valyala/fasttemplate, values of
+// other types than []byte, string and TagFunc are formatted instead of
+// causing a panic.
+func WithLegacyBehavior() Option {
+	return func(t *Template) {
+		t.legacy = true
+	}
+}
+
+// ExecuteFunc calls f on each template tag (placeholder) occurrence and
+// writes the template with the tags substituted by f to w.
+//
+// Returns the number of bytes written to w.
+//
+// This function is optimized for constantly changing templates.
+// Use Template.ExecuteFunc for frozen templates.
+func ExecuteFunc(template, startTag, endTag string, w io.Writer, f TagFunc) (int64, error) {
+	if err := checkDelimiters(startTag, endTag); err != nil {
+		return 0, err
+	}
+	s := unsafeString2Bytes(template)
+	a := unsafeString2Bytes(startTag)
+	b := unsafeString2Bytes(endTag)
+
+	var nn int64
+	var ni int
+	var err error
+	for {
+		n := bytes.Index(s, a)
+		if n < 0 {
+			break
+		}
+		ni, err = writeFull(w, s[:n])
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+
+		s = s[n+len(a):]
+		n = bytes.Index(s, b)
+		if n < 0 {
+			// cannot find end tag - just write it to the output.
+			ni, err = writeFull(w, a)
+			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
+			break
+		}
+
+		ni, err = f(w, unsafeBytes2String(s[:n]))
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+		s = s[n+len(b):]
+	}
+	ni, err = writeFull(w, s)
+	nn += int64(ni)
+
+	return nn, err
+}
+
+// ExecuteFuncString calls f on each template tag (placeholder) occurrence
+// and substitutes it with the data written to TagFunc's w.
+//
+// Returns the resulting string. It panics if f returns an error; use
+// ExecuteFuncStringWithErr to handle errors.
+func ExecuteFuncString(template, startTag, endTag string, f TagFunc) string {
+	s, err := ExecuteFuncStringWithErr(template, startTag, endTag, f)
+	if err != nil {
+		panic(fmt.Sprintf("unexpected error: %s", err))
+	}
+	return s
+}
+
+// ExecuteFuncStringWithErr works like ExecuteFuncString, but returns the
+// error of f instead of panicking.
+func ExecuteFuncStringWithErr(template, startTag, endTag string, f TagFunc) (string, error) {
+	var bb bytes.Buffer
+	if _, err := ExecuteFunc(template, startTag, endTag, &bb, f); err != nil {
+		return "", err
+	}
+	return bb.String(), nil
+}
+
+// ExecuteFunc calls f on each template tag (placeholder) occurrence and
+// writes the template with the tags substituted by f to w.
+//
+// Tags are passed to f as parsed: comments and raw blocks are dropped and
+// whitespace control is applied unless WithLegacyBehavior is set, and block
+// tags are passed to f like the other tags.
+//
+// Returns the number of bytes written to w.
+func (t *Template) ExecuteFunc(w io.Writer, f TagFunc) (int64, error) {
+	n := len(t.texts) - 1
+	if n == -1 {
+		ni, err := writeFull(w, unsafeString2Bytes(t.template))
+		return int64(ni), err
+	}
+
+	var nn int64
+	for i := 0; i < n; i++ {
+		ni, err := writeFull(w, t.texts[i])
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+
+		ni, err = f(w, t.tags[i])
+		nn += int64(ni)
+		if err != nil {
+			return nn, t.formatError(err)
+		}
+	}
+	ni, err := writeFull(w, t.texts[n])
+	nn += int64(ni)
+	return nn, err
+}
+
+// ExecuteFuncString calls f on each template tag (placeholder) occurrence
+// and substitutes it with the data written to TagFunc's w.
+//
+// Returns the resulting string. It panics if f returns an error; use
+// ExecuteFuncStringWithErr to handle errors.
+func (t *Template) ExecuteFuncString(f TagFunc) string {
+	s, err := t.ExecuteFuncStringWithErr(f)
+	if err != nil {
+		panic(fmt.Sprintf("unexpected error: %s", err))
+	}
+	return s
+}
+
+// ExecuteFuncStringWithErr works like ExecuteFuncString, but returns the
+// error of f instead of panicking.
+func (t *Template) ExecuteFuncStringWithErr(f TagFunc) (string, error) {
+	bb := t.getBuffer()
+	defer t.putBuffer(bb)
+	if _, err := t.ExecuteFunc(bb, f); err != nil {
+		return "", err
+	}
+	return bb.String(), nil
+}
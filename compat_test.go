@@ -0,0 +1,110 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// TestCompatibility locks down the semantics of valyala/fasttemplate. Cases
+// marked shared hold without WithLegacyBehavior as well.
+func TestCompatibility(t *testing.T) {
+	tagFunc := TagFunc(func(w io.Writer, tag string) (int, error) {
+		return w.Write([]byte("[" + tag + "]"))
+	})
+	tests := []struct {
+		name     string
+		template string
+		m        Map
+		expected string
+		std      string
+		shared   bool
+	}{
+		{"Unknown", "a{{x}}b", Map{}, "ab", "a{{x}}b", true},
+		{"Known", "a{{x}}b{{y}}", Map{"x": "1", "y": []byte("2")}, "a1b2", "a1b2", true},
+		{"Empty", "a{{}}b", Map{}, "ab", "a{{}}b", true},
+		{"TagFunc", "a{{x}}b", Map{"x": tagFunc}, "a[x]b", "a[x]b", true},
+		{"PlainTagFunc", "a{{x}}b", Map{"x": func(w io.Writer, tag string) (int, error) { return w.Write([]byte(tag + tag)) }}, "axxb", "axxb", true},
+		{"Spaces", "{{ x }}", Map{"x": "1"}, "", "{{ x }}", false},
+		{"SpacedKey", "{{ x }}", Map{" x ": "1"}, "1", "1", false},
+		{"ExpressionKey", "{{a+b}}", Map{"a+b": "k", "a": 1, "b": 2}, "k", "k", false},
+		{"Expression", "{{a+b}}", Map{"a": 1, "b": 2}, "", "{{a+b}}", false},
+		{"Call", "{{upper(x)}}", Map{"x": "a"}, "", "{{upper(x)}}", false},
+		{"Blocks", "{{if x}}y{{end}}", Map{"if x": "1", "end": "2"}, "1y2", "1y2", false},
+		{"Comment", "a{{# c #}}b", Map{}, "ab", "a{{# c #}}b", false},
+		{"Trim", "a {{- x -}} b", Map{"- x -": "1"}, "a 1 b", "a 1 b", false},
+		{"Default", "{{x ?? 'y'}}", Map{}, "", "{{x ?? 'y'}}", false},
+		{"Flush", "a{{flush}}b", Map{}, "ab", "a{{flush}}b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := [][]Option{{WithLegacyBehavior()}}
+			if tt.shared {
+				opts = append(opts, nil)
+			}
+			for _, o := range opts {
+				tpl, err := NewTemplate(tt.template, "{{", "}}", o...)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				var bb bytes.Buffer
+				n, err := tpl.Execute(&bb, tt.m)
+				if err != nil || bb.String() != tt.expected || n != int64(len(tt.expected)) {
+					t.Fatalf("unexpected result %q (%d bytes), %v", bb.String(), n, err)
+				}
+				if s := tpl.ExecuteString(tt.m); s != tt.expected {
+					t.Fatalf("unexpected result %q", s)
+				}
+				if s := tpl.ExecuteStringStd(tt.m); s != tt.std {
+					t.Fatalf("unexpected std result %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestCompatibilityUnclosedTags(t *testing.T) {
+	for _, opts := range [][]Option{{WithLegacyBehavior()}, nil} {
+		if _, err := NewTemplate("a{{b", "{{", "}}", opts...); err == nil {
+			t.Fatal("expected error for unclosed tag")
+		}
+	}
+	// the functions parsing on the fly write unclosed tags as is
+	if s := ExecuteString("a{{x}}{{b", "{{", "}}", Map{"x": "1"}); s != "a1{{b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := ExecuteStringStd("a{{y}}{{b", "{{", "}}", Map{}); s != "a{{y}}{{b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	tagFunc := func(w io.Writer, tag string) (int, error) { return w.Write([]byte("<" + tag + ">")) }
+	if s := ExecuteFuncString("a{{x}}{{b", "{{", "}}", tagFunc); s != "a<x>{{b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestExecuteFunc(t *testing.T) {
+	tagFunc := func(w io.Writer, tag string) (int, error) { return w.Write([]byte("<" + tag + ">")) }
+	tpl := New("a{{ x }}b{{if y}}c{{end}}", "{{", "}}")
+	if s := tpl.ExecuteFuncString(tagFunc); s != "a< x >b<if y>c<end>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New("no tags", "{{", "}}").ExecuteFuncString(tagFunc); s != "no tags" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	errTag := errors.New("tag failed")
+	failing := func(w io.Writer, tag string) (int, error) { return 0, errTag }
+	if _, err := tpl.ExecuteFuncStringWithErr(failing); !errors.Is(err, errTag) {
+		t.Fatalf("expected tag error, got %v", err)
+	}
+	if _, err := ExecuteFuncStringWithErr("a{{x}}", "{{", "}}", failing); !errors.Is(err, errTag) {
+		t.Fatalf("expected tag error, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	tpl.ExecuteFuncString(failing)
+}
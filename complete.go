@@ -0,0 +1,230 @@
+package fasttemplate
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SuggestionKind is the kind of a completion suggestion.
+type SuggestionKind int
+
+const (
+	// SuggestVariable suggests a variable or a field of a map or struct.
+	SuggestVariable SuggestionKind = iota
+	// SuggestFunction suggests a function of the Map or a builtin.
+	SuggestFunction
+)
+
+func (k SuggestionKind) String() string {
+	if k == SuggestFunction {
+		return "function"
+	}
+	return "variable"
+}
+
+// Suggestion is a completion suggestion returned by [Complete].
+type Suggestion struct {
+	Kind SuggestionKind
+
+	// Label is the name to insert.
+	Label string
+
+	// Detail is the type of a variable or the signature of a function,
+	// e.g. "join(string, ...any) string".
+	Detail string
+
+	// Start and End are the byte range of the template replaced by Label:
+	// the partially typed name before the cursor.
+	Start int
+	End   int
+}
+
+// Complete suggests completions for the cursor at byte offset of a template
+// using "{{" and "}}" as tag delimiters. See [CompleteWithTags].
+func Complete(template string, offset int, m Map) []Suggestion {
+	return CompleteWithTags(template, "{{", "}}", offset, m)
+}
+
+// CompleteWithTags suggests completions for the cursor at byte offset of the
+// template, e.g. for autocompletion in template editors.
+//
+// Inside a tag, it suggests the variables and functions of m and the
+// builtins starting with the name typed before the cursor. After a dot, it
+// suggests the keys of the map or the exported fields of the struct the path
+// before the dot refers to in m. Functions are suggested with their
+// signature, variables with their type.
+//
+// It returns nil if the cursor is outside of a tag or inside a string.
+func CompleteWithTags(template, startTag, endTag string, offset int, m Map) []Suggestion {
+	if offset < 0 || offset > len(template) {
+		return nil
+	}
+
+	start, inTag := offset, false
+	var prev TokenInfo
+	for _, tok := range Scan(template, startTag, endTag) {
+		if tok.Start >= offset {
+			break
+		}
+		switch tok.Kind {
+		case TokenTagOpen:
+			inTag = tok.End <= offset
+		case TokenTagClose:
+			inTag = false
+		case TokenString:
+			if offset < tok.End || (offset == tok.End && !isClosedString(tok.Text)) {
+				return nil
+			}
+		case TokenNumber:
+			if offset <= tok.End {
+				return nil
+			}
+		case TokenIdentifier:
+			if offset <= tok.End {
+				start = tok.Start
+			}
+		case TokenOperator:
+			// a path ending with a dot, e.g. "user."
+			if tok.Text == "." && tok.End == offset && prev.Kind == TokenIdentifier && prev.End == tok.Start {
+				start = prev.Start
+			}
+		}
+		prev = tok
+	}
+	if !inTag {
+		return nil
+	}
+
+	prefix := template[start:offset]
+	if dot := strings.LastIndexByte(prefix, '.'); dot >= 0 {
+		return completeFields(prefix[:dot], prefix[dot+1:], m, start+dot+1, offset)
+	}
+
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+	for _, name := range m.Keys() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		v := m[name]
+		seen[name] = true
+		if isFunc(v) {
+			suggestions = append(suggestions, Suggestion{Kind: SuggestFunction, Label: name, Detail: funcSignature(name, v), Start: start, End: offset})
+		} else {
+			suggestions = append(suggestions, Suggestion{Kind: SuggestVariable, Label: name, Detail: typeName(v), Start: start, End: offset})
+		}
+	}
+	builtins.mu.RLock()
+	for name, fn := range builtins.funcs {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			suggestions = append(suggestions, Suggestion{Kind: SuggestFunction, Label: name, Detail: funcSignature(name, fn), Start: start, End: offset})
+		}
+	}
+	builtins.mu.RUnlock()
+
+	sortSuggestions(suggestions)
+	return suggestions
+}
+
+// isClosedString reports whether the string literal s has a closing quote.
+func isClosedString(s string) bool {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return false
+	}
+	escaped := 0
+	for i := len(s) - 2; i > 0 && s[i] == '\\'; i-- {
+		escaped++
+	}
+	return escaped%2 == 0
+}
+
+// completeFields suggests the keys or fields starting with prefix of the
+// value at path in m.
+func completeFields(path, prefix string, m Map, start, end int) []Suggestion {
+	v, ok := fieldValue(m, path)
+	if !ok {
+		return nil
+	}
+
+	var suggestions []Suggestion
+	add := func(name string, v any) {
+		if strings.HasPrefix(name, prefix) {
+			suggestions = append(suggestions, Suggestion{Kind: SuggestVariable, Label: name, Detail: typeName(v), Start: start, End: end})
+		}
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			add(iter.Key().String(), iter.Value().Interface())
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			if f := rv.Type().Field(i); f.IsExported() {
+				add(f.Name, rv.Field(i).Interface())
+			}
+		}
+	}
+	sortSuggestions(suggestions)
+	return suggestions
+}
+
+// funcSignature formats the signature of the function fn called name.
+func funcSignature(name string, fn any) string {
+	ft := reflect.TypeOf(asFunc(fn).Fn)
+	params := make([]string, ft.NumIn())
+	for i := range params {
+		if ft.IsVariadic() && i == len(params)-1 {
+			params[i] = "..." + displayType(ft.In(i).Elem())
+			continue
+		}
+		params[i] = displayType(ft.In(i))
+	}
+	sig := name + "(" + strings.Join(params, ", ") + ")"
+
+	results := make([]string, ft.NumOut())
+	for i := range results {
+		results[i] = displayType(ft.Out(i))
+	}
+	switch len(results) {
+	case 0:
+		return sig
+	case 1:
+		return sig + " " + results[0]
+	}
+	return sig + " (" + strings.Join(results, ", ") + ")"
+}
+
+// typeName returns the type of v, using "any" for nil.
+func typeName(v any) string {
+	if v == nil {
+		return "any"
+	}
+	return displayType(reflect.TypeOf(v))
+}
+
+// displayType formats t, spelling the empty interface as any.
+func displayType(t reflect.Type) string {
+	return strings.ReplaceAll(t.String(), "interface {}", "any")
+}
+
+// sortSuggestions sorts variables before functions, both by label.
+func sortSuggestions(suggestions []Suggestion) {
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Kind != suggestions[j].Kind {
+			return suggestions[i].Kind < suggestions[j].Kind
+		}
+		return suggestions[i].Label < suggestions[j].Label
+	})
+}
@@ -0,0 +1,116 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatSuggestions formats suggestions as "label:detail" for comparison.
+func formatSuggestions(suggestions []Suggestion) string {
+	parts := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		parts[i] = fmt.Sprintf("%s:%s", s.Label, s.Detail)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestComplete(t *testing.T) {
+	type address struct {
+		City   string
+		Street string
+		zip    string
+	}
+	m := Map{
+		"user":     Map{"name": "Ann", "nick": "ann", "address": &address{City: "Oslo"}},
+		"users":    []string{"a"},
+		"upload":   func(name string, sizes ...int) (string, error) { return "", nil },
+		"upper":    func(s string) string { return s },
+		"greeting": "hi",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Prefix",
+			template: "Hi {{us|}}",
+			expected: "user:fasttemplate.Map users:[]string",
+		},
+		{
+			name:     "Functions",
+			template: "{{up|",
+			expected: "upload:upload(string, ...int) (string, error) upper:upper(string) string",
+		},
+		{
+			name:     "Builtins",
+			template: "{{x + tri|}}",
+			expected: "trim:" + funcSignature("trim", builtins.funcs["trim"]),
+		},
+		{
+			name:     "Fields",
+			template: "{{user.n|}}",
+			expected: "name:string nick:string",
+		},
+		{
+			name:     "StructFields",
+			template: "{{upper(user.address.|)}}",
+			expected: "City:string Street:string",
+		},
+		{
+			name:     "MiddleOfName",
+			template: "{{gre|eting}}",
+			expected: "greeting:string",
+		},
+		{
+			name:     "OutsideTag",
+			template: "us| {{user}}",
+		},
+		{
+			name:     "InString",
+			template: "{{upper('us|')}}",
+		},
+		{
+			name:     "UnterminatedString",
+			template: "{{upper('us|",
+		},
+		{
+			name:     "UnknownPath",
+			template: "{{nobody.|}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.IndexByte(tt.template, '|')
+			template := tt.template[:offset] + tt.template[offset+1:]
+			suggestions := Complete(template, offset, m)
+			if s := formatSuggestions(suggestions); s != tt.expected {
+				t.Fatalf("unexpected suggestions\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompleteRange(t *testing.T) {
+	suggestions := CompleteWithTags("<% user.na %>", "<%", "%>", 10, Map{"user": Map{"name": "Ann"}})
+	if len(suggestions) != 1 {
+		t.Fatalf("unexpected suggestions %+v", suggestions)
+	}
+	if s := suggestions[0]; s.Kind != SuggestVariable || s.Start != 8 || s.End != 10 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+
+	suggestions = Complete("{{}}", 2, Map{"upload": func() {}})
+	found := false
+	for _, s := range suggestions {
+		if s.Label == "upload" && s.Kind == SuggestFunction && s.Start == 2 && s.End == 2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected upload in %s", formatSuggestions(suggestions))
+	}
+}
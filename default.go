@@ -0,0 +1,113 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+)
+
+// splitDefault splits a tag with a default value, such as
+// name ?? "anonymous" or name|default("anonymous"), into the value and the
+// default. Defaults may be chained: a ?? b ?? "c".
+func splitDefault(tag string) (value, def string, ok bool) {
+	if !strings.Contains(tag, "??") && !strings.Contains(tag, "default(") {
+		return "", "", false
+	}
+	tag = strings.TrimSpace(tag)
+	pipe := -1
+	depth := 0
+	var quote byte
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && c == '?' && i+1 < len(tag) && tag[i+1] == '?':
+			return strings.TrimSpace(tag[:i]), strings.TrimSpace(tag[i+2:]), i > 0 && i+2 < len(tag)
+		case depth == 0 && c == '|' && (i == 0 || tag[i-1] != '|') && (i+1 == len(tag) || tag[i+1] != '|'):
+			pipe = i
+		}
+	}
+	if pipe <= 0 {
+		return "", "", false
+	}
+	arg, ok := strings.CutPrefix(strings.TrimSpace(tag[pipe+1:]), "default(")
+	if !ok || !strings.HasSuffix(arg, ")") {
+		return "", "", false
+	}
+	def = strings.TrimSpace(arg[:len(arg)-1])
+	return strings.TrimSpace(tag[:pipe]), def, def != ""
+}
+
+// defaultLiteral returns the value of a default that is a string, number or
+// boolean literal.
+func defaultLiteral(def string) (any, bool) {
+	arg, err := parseArg(def)
+	if err != nil {
+		return nil, false
+	}
+	switch v := arg.(type) {
+	case literalString:
+		return string(v), true
+	case int, float64, bool:
+		return v, true
+	}
+	return nil, false
+}
+
+// evalDefault evaluates value, falling back to def if value references a
+// missing variable, calls a function that timed out or whose circuit is
+// open, or is nil. Other errors aren't masked by the default.
+func evalDefault(value, def string, m Map, ec *evalContext) (any, tagKind, error) {
+	v, kind, err := evalTag(value, m, ec)
+	if err == nil && v != nil {
+		return v, kind, nil
+	}
+	if err != nil && !errors.Is(err, errVariableNotFound) && !isUnavailable(err) {
+		return nil, kind, err
+	}
+	if lit, ok := defaultLiteral(def); ok {
+		return lit, tagVariable, nil
+	}
+	return evalTag(def, m, ec)
+}
+
+// analyzeDefault analyzes a tag with a default value like analyzeTag,
+// merging the references of the value and the default.
+func analyzeDefault(value, def string, literal func(string) bool) *tagAnalysis {
+	a := analyzeTag(value, literal)
+	if _, ok := defaultLiteral(def); ok {
+		return a
+	}
+	d := analyzeTag(def, literal)
+	for _, f := range d.funcs {
+		a.addFunc(f)
+	}
+	for _, id := range d.idents {
+		a.addIdent(id, d.types[id])
+	}
+	if a.err == nil {
+		a.err = d.err
+	}
+	return a
+}
+
+// hasDefault reports whether the tag has a default value, so it always
+// renders something.
+func hasDefault(tag string) bool {
+	_, _, ok := splitDefault(tag)
+	return ok
+}
+
+// hasDefault works like the package-level hasDefault, but honors
+// WithLegacyBehavior.
+func (t *Template) hasDefault(tag string) bool {
+	return !t.legacy && hasDefault(tag)
+}
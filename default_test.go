@@ -0,0 +1,64 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultValues(t *testing.T) {
+	m := Map{
+		"name":  "Ann",
+		"empty": nil,
+		"upper": strings.ToUpper,
+		"fail":  func() (string, error) { return "", errors.New("boom") },
+	}
+	tests := map[string]string{
+		`{{name ?? "anonymous"}}`:            "Ann",
+		`{{nick ?? "anonymous"}}`:            "anonymous",
+		`{{empty ?? 'none'}}`:                "none",
+		`{{nick|default("anonymous")}}`:      "anonymous",
+		`{{name | default('x')}}`:            "Ann",
+		`{{nick ?? alias ?? 'guest'}}`:       "guest",
+		`{{nick ?? name ?? 'guest'}}`:        "Ann",
+		`{{nick ?? upper(name)}}`:            "ANN",
+		`{{upper(nick) ?? 'x'}}`:             "x",
+		`{{count ?? 0}}`:                     "0",
+		`{{ratio ?? 1.5}} {{flag ?? true}}`:  "1.5 true",
+		`{{nick ?? 'a ?? b'}}`:               "a ?? b",
+		`{{'a??b' + 'c'}}`:                   "a??bc",
+		`{{name == 'Ann' || name == 'Bob'}}`: "true",
+	}
+	for src, want := range tests {
+		if s := New(src, "{{", "}}").ExecuteString(m); s != want {
+			t.Fatalf("unexpected output of %q: %q, want %q", src, s, want)
+		}
+	}
+
+	// errors other than missing variables aren't masked by the default
+	var sb strings.Builder
+	if _, err := New(`{{fail() ?? 'x'}}`, "{{", "}}").Execute(&sb, m); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// the default is rendered instead of preserving the tag
+	if s := New(`{{nick ?? 'guest'}} {{nick}}`, "{{", "}}").ExecuteStringStd(m); s != "guest {{nick}}" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := ExecuteString(`{{nick ?? 'guest'}}`, "{{", "}}", m); s != "guest" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestDefaultValuesAnalysis(t *testing.T) {
+	tpl := New(`{{nick ?? name ?? 'guest'}} {{title|default(upper(name))}}`, "{{", "}}")
+	if err := tpl.Validate(Map{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if findings := tpl.Analyze(Map{}); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "nick,name,title" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+}
@@ -0,0 +1,473 @@
+// Package email renders complete RFC 5322 email messages from fasttemplate
+// templates.
+//
+// A Template combines a subject template with plain text and HTML body
+// templates. Rendering it produces a MIME message with properly encoded
+// headers, quoted-printable bodies and base64 encoded attachments:
+//
+//	t, err := email.New("Order {{id}} shipped", textBody, htmlBody, "{{", "}}")
+//	...
+//	err = t.Render(w, email.Message{
+//		From: "Shop <shop@example.com>",
+//		To:   []string{"ann@example.com"},
+//	}, fasttemplate.Map{"id": "1234"})
+//
+// The HTML body is parsed with contextual HTML escaping enabled.
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwisiswant0/fasttemplate"
+	"github.com/dwisiswant0/fasttemplate/internal/sanitize"
+)
+
+// Template renders email messages.
+type Template struct {
+	subject *fasttemplate.Template
+	text    *fasttemplate.Template
+	html    *fasttemplate.Template
+}
+
+// Message holds the envelope of a rendered message: its addresses, extra
+// headers and attachments.
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	ReplyTo string
+
+	// Date is the date of the message; the current time if zero.
+	Date time.Time
+
+	// Headers holds additional headers, e.g. List-Unsubscribe. Headers
+	// written from the other fields, such as From, Subject, Date and the
+	// MIME headers, are rejected. A Message-ID is generated unless given.
+	Headers map[string]string
+
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename string
+
+	// ContentType is the media type of the attachment, optionally with
+	// parameters. If empty, it is derived from the file name extension.
+	// Invalid media types fail the rendering.
+	ContentType string
+
+	Data []byte
+}
+
+// New parses the subject, plain text and HTML templates of a message. The
+// text or html template may be empty to omit that body, but not both. The
+// given options apply to all templates.
+func New(subject, text, html, startTag, endTag string, opts ...fasttemplate.Option) (*Template, error) {
+	if text == "" && html == "" {
+		return nil, errors.New("email: text or HTML body required")
+	}
+
+	var t Template
+	var err error
+	if t.subject, err = fasttemplate.NewTemplate(subject, startTag, endTag, opts...); err != nil {
+		return nil, fmt.Errorf("email: subject: %w", err)
+	}
+	if text != "" {
+		if t.text, err = fasttemplate.NewTemplate(text, startTag, endTag, opts...); err != nil {
+			return nil, fmt.Errorf("email: text body: %w", err)
+		}
+	}
+	if html != "" {
+		htmlOpts := append([]fasttemplate.Option{fasttemplate.WithEscaping(fasttemplate.EscapeHTML)}, opts...)
+		if t.html, err = fasttemplate.NewTemplate(html, startTag, endTag, htmlOpts...); err != nil {
+			return nil, fmt.Errorf("email: HTML body: %w", err)
+		}
+	}
+	return &t, nil
+}
+
+// Render renders the message for data and writes it to w. Nothing is
+// written if rendering a template fails.
+func (t *Template) Render(w io.Writer, msg Message, data fasttemplate.Map) error {
+	subject, err := execute(t.subject, data)
+	if err != nil {
+		return fmt.Errorf("email: subject: %w", err)
+	}
+	var text, html []byte
+	if t.text != nil {
+		if text, err = execute(t.text, data); err != nil {
+			return fmt.Errorf("email: text body: %w", err)
+		}
+	}
+	if t.html != nil {
+		if html, err = execute(t.html, data); err != nil {
+			return fmt.Errorf("email: HTML body: %w", err)
+		}
+	}
+
+	var bb bytes.Buffer
+	if err := writeHeaders(&bb, msg, string(subject)); err != nil {
+		return err
+	}
+	if err := writeBody(&bb, text, html, msg.Attachments); err != nil {
+		return err
+	}
+	_, err = w.Write(bb.Bytes())
+	return err
+}
+
+// execute renders t to a new buffer.
+func execute(t *fasttemplate.Template, data fasttemplate.Map) ([]byte, error) {
+	var bb bytes.Buffer
+	if _, err := t.Execute(&bb, data); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
+
+// writeHeaders writes the message headers.
+func writeHeaders(w *bytes.Buffer, msg Message, subject string) error {
+	from, err := formatAddresses("From", []string{msg.From})
+	if err != nil {
+		return err
+	}
+	if len(msg.To) == 0 && len(msg.Cc) == 0 {
+		return errors.New("email: no recipients")
+	}
+
+	date := msg.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	writeHeader(w, "From", from)
+	for _, h := range []struct {
+		name  string
+		addrs []string
+	}{{"To", msg.To}, {"Cc", msg.Cc}} {
+		if len(h.addrs) == 0 {
+			continue
+		}
+		v, err := formatAddresses(h.name, h.addrs)
+		if err != nil {
+			return err
+		}
+		writeHeader(w, h.name, v)
+	}
+	if msg.ReplyTo != "" {
+		v, err := formatAddresses("Reply-To", []string{msg.ReplyTo})
+		if err != nil {
+			return err
+		}
+		writeHeader(w, "Reply-To", v)
+	}
+	writeHeader(w, "Subject", encodeHeader(subject))
+	writeHeader(w, "Date", date.Format(time.RFC1123Z))
+
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("email: invalid header name %q", name)
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("email: header %q is set from the message", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hasMessageID := false
+	for _, name := range names {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		hasMessageID = hasMessageID || key == "Message-Id"
+		writeHeader(w, key, encodeHeader(msg.Headers[name]))
+	}
+	if !hasMessageID {
+		id, err := messageID(from)
+		if err != nil {
+			return err
+		}
+		writeHeader(w, "Message-ID", id)
+	}
+	writeHeader(w, "MIME-Version", "1.0")
+	return nil
+}
+
+// reservedHeaders are the headers written from the fields of Message and
+// the body, which can't be set in Message.Headers.
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// messageID generates a unique Message-ID in the domain of the from
+// address.
+func messageID(from string) (string, error) {
+	domain := "localhost"
+	if a, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndexByte(a.Address, '@'); i >= 0 {
+			domain = a.Address[i+1:]
+		}
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("email: Message-ID: %w", err)
+	}
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">", nil
+}
+
+// maxHeaderLength is the length header lines are folded at.
+const maxHeaderLength = 78
+
+// writeHeader writes a header, folding it at spaces into lines of at most
+// maxHeaderLength bytes where possible.
+func writeHeader(w *bytes.Buffer, name, value string) {
+	line := name + ": " + value
+	// continuation lines are folded after their first word, so no line is
+	// blank
+	start := len(name) + 1
+	for len(line) > maxHeaderLength {
+		i := -1
+		if start < maxHeaderLength {
+			i = strings.LastIndexByte(line[start:maxHeaderLength+1], ' ')
+		}
+		if i < 0 {
+			// fold at the first space after the limit instead
+			from := maxHeaderLength + 1
+			if start > from {
+				from = start
+			}
+			if i = strings.IndexByte(line[from:], ' '); i < 0 {
+				break
+			}
+			i += from - start
+		}
+		i += start
+		w.WriteString(line[:i])
+		w.WriteString("\r\n")
+		line = line[i:]
+		start = 2
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// formatAddresses parses and formats the addresses of a header.
+func formatAddresses(header string, addrs []string) (string, error) {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return "", fmt.Errorf("email: %s address %q: %w", header, addr, err)
+		}
+		formatted[i] = a.String()
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+// encodeHeader encodes a header value, replacing line breaks so rendered
+// values can't inject headers.
+func encodeHeader(v string) string {
+	return mime.QEncoding.Encode("utf-8", sanitize.StripLineBreaks(v))
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c <= ' ' || c >= 0x7f || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeBody writes the MIME body of the message.
+func writeBody(w *bytes.Buffer, text, html []byte, attachments []Attachment) error {
+	if len(attachments) == 0 {
+		return writeContent(w, text, html)
+	}
+
+	mw := multipart.NewWriter(w)
+	writeHeader(w, "Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	w.WriteString("\r\n")
+
+	var content bytes.Buffer
+	if err := writeContent(&content, text, html); err != nil {
+		return err
+	}
+	header, body, _ := strings.Cut(content.String(), "\r\n\r\n")
+	h, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(pw, body); err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		if err := writeAttachment(mw, a); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeContent writes the text and HTML bodies, as alternatives if both are
+// given, including their headers.
+func writeContent(w *bytes.Buffer, text, html []byte) error {
+	switch {
+	case html == nil:
+		return writeTextPart(w, "text/plain", text)
+	case text == nil:
+		return writeTextPart(w, "text/html", html)
+	}
+
+	mw := multipart.NewWriter(w)
+	writeHeader(w, "Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+	w.WriteString("\r\n")
+	for _, part := range []struct {
+		mediaType string
+		body      []byte
+	}{{"text/plain", text}, {"text/html", html}} {
+		pw, err := mw.CreatePart(textPartHeader(part.mediaType))
+		if err != nil {
+			return err
+		}
+		if err := writeQuotedPrintable(pw, part.body); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func textPartHeader(mediaType string) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"}))
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return h
+}
+
+func writeTextPart(w *bytes.Buffer, mediaType string, body []byte) error {
+	h := textPartHeader(mediaType)
+	writeHeader(w, "Content-Type", h.Get("Content-Type"))
+	writeHeader(w, "Content-Transfer-Encoding", h.Get("Content-Transfer-Encoding"))
+	w.WriteString("\r\n")
+	return writeQuotedPrintable(w, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body []byte) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write(body); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// parseHeader parses the CRLF separated header lines written by
+// writeHeader, unfolding folded lines.
+func parseHeader(s string) (textproto.MIMEHeader, error) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(s + "\r\n\r\n")))
+	return r.ReadMIMEHeader()
+}
+
+// writeAttachment writes an attachment part encoded as base64.
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// the content type is written to the part header as is, so it must not
+	// inject other headers
+	if strings.ContainsAny(contentType, "\r\n") {
+		return fmt.Errorf("email: attachment %q: line break in content type %q", a.Filename, contentType)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("email: attachment %q: content type %q: %w", a.Filename, contentType, err)
+	}
+	contentType = mime.FormatMediaType(mediaType, params)
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	lw := &lineWriter{w: pw}
+	enc := base64.NewEncoder(base64.StdEncoding, lw)
+	if _, err := enc.Write(a.Data); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(pw, "\r\n")
+	return err
+}
+
+// maxLineLength is the length of base64 encoded lines.
+const maxLineLength = 76
+
+// lineWriter breaks its output into lines of maxLineLength bytes.
+type lineWriter struct {
+	w   io.Writer
+	col int
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if lw.col == maxLineLength {
+			if _, err := io.WriteString(lw.w, "\r\n"); err != nil {
+				return n, err
+			}
+			lw.col = 0
+		}
+		chunk := p
+		if room := maxLineLength - lw.col; len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		written, err := lw.w.Write(chunk)
+		n += written
+		lw.col += written
+		if err != nil {
+			return n, err
+		}
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
@@ -0,0 +1,267 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func render(t *testing.T, tpl *Template, msg Message, data fasttemplate.Map) *mail.Message {
+	t.Helper()
+	var bb bytes.Buffer
+	if err := tpl.Render(&bb, msg, data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m, err := mail.ReadMessage(&bb)
+	if err != nil {
+		t.Fatalf("cannot parse message: %s\n%s", err, bb.String())
+	}
+	return m
+}
+
+func readQuotedPrintable(t *testing.T, r io.Reader) string {
+	t.Helper()
+	b, err := io.ReadAll(quotedprintable.NewReader(r))
+	if err != nil {
+		t.Fatalf("cannot decode body: %s", err)
+	}
+	return string(b)
+}
+
+func TestRenderText(t *testing.T) {
+	tpl, err := New("Order {{id}} – shipped", "Hi {{name}},\nyour order {{id}} is on its way.", "", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := render(t, tpl, Message{
+		From: "Shop <shop@example.com>",
+		To:   []string{"Ann Müller <ann@example.com>", "bob@example.com"},
+		Date: date,
+	}, fasttemplate.Map{"id": "42", "name": "Ann"})
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("cannot decode subject: %s", err)
+	}
+	if subject != "Order 42 – shipped" {
+		t.Fatalf("unexpected subject %q", subject)
+	}
+	to, err := m.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "Ann Müller" || to[1].Address != "bob@example.com" {
+		t.Fatalf("unexpected To %v (%v)", to, err)
+	}
+	if got, _ := m.Header.Date(); !got.Equal(date) {
+		t.Fatalf("unexpected date %s", got)
+	}
+	if got := m.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	if body := readQuotedPrintable(t, m.Body); body != "Hi Ann,\r\nyour order 42 is on its way." {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestRenderAlternativeWithAttachments(t *testing.T) {
+	tpl, err := New("Hello", "Hi {{name}}", "<p>Hi {{name}}</p>", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data := bytes.Repeat([]byte{0, 1, 2, 250}, 100)
+	m := render(t, tpl, Message{
+		From: "shop@example.com",
+		To:   []string{"ann@example.com"},
+		Attachments: []Attachment{
+			{Filename: "invoice.pdf", Data: data},
+			{Filename: "notes", ContentType: "text/plain", Data: []byte("notes")},
+		},
+	}, fasttemplate.Map{"name": "<Ann>"})
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("unexpected content type %q (%v)", mediaType, err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	part, err := mr.NextRawPart()
+	if err != nil {
+		t.Fatalf("cannot read body part: %s", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected body content type %q", mediaType)
+	}
+	alt := multipart.NewReader(part, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hi <Ann>"},
+		{"text/html; charset=utf-8", "<p>Hi &lt;Ann&gt;</p>"},
+	} {
+		p, err := alt.NextRawPart()
+		if err != nil {
+			t.Fatalf("cannot read alternative: %s", err)
+		}
+		if got := p.Header.Get("Content-Type"); got != want.contentType {
+			t.Fatalf("unexpected content type %q; want %q", got, want.contentType)
+		}
+		if body := readQuotedPrintable(t, p); body != want.body {
+			t.Fatalf("unexpected body %q; want %q", body, want.body)
+		}
+	}
+
+	for _, want := range []struct {
+		filename, contentType string
+		data                  []byte
+	}{
+		{"invoice.pdf", "application/pdf", data},
+		{"notes", "text/plain", []byte("notes")},
+	} {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			t.Fatalf("cannot read attachment: %s", err)
+		}
+		if p.FileName() != want.filename || p.Header.Get("Content-Type") != want.contentType {
+			t.Fatalf("unexpected attachment %q of type %q", p.FileName(), p.Header.Get("Content-Type"))
+		}
+		raw, _ := io.ReadAll(p)
+		for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\r\n") {
+			if len(line) > maxLineLength {
+				t.Fatalf("line too long: %d", len(line))
+			}
+		}
+		got, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(raw)))
+		if err != nil || !bytes.Equal(got, want.data) {
+			t.Fatalf("unexpected attachment data for %q (%v)", want.filename, err)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf("expected end of message; got %v", err)
+	}
+}
+
+func TestRenderHeaderInjection(t *testing.T) {
+	tpl, err := New("Hi {{name}}", "body", "", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m := render(t, tpl, Message{
+		From:    "shop@example.com",
+		To:      []string{"ann@example.com"},
+		Headers: map[string]string{"x-campaign": "a\r\nBcc: eve@example.com"},
+	}, fasttemplate.Map{"name": "Ann\r\nBcc: eve@example.com"})
+
+	if got := m.Header.Get("Bcc"); got != "" {
+		t.Fatalf("unexpected Bcc header %q", got)
+	}
+	if got := m.Header.Get("Subject"); got != "Hi Ann Bcc: eve@example.com" {
+		t.Fatalf("unexpected subject %q", got)
+	}
+	if got := m.Header.Get("X-Campaign"); got != "a Bcc: eve@example.com" {
+		t.Fatalf("unexpected X-Campaign %q", got)
+	}
+}
+
+func TestRenderMessageID(t *testing.T) {
+	tpl, err := New("subject", "body", "", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	msg := Message{From: "Shop <shop@example.com>", To: []string{"ann@example.com"}}
+	first := render(t, tpl, msg, nil).Header.Get("Message-Id")
+	second := render(t, tpl, msg, nil).Header.Get("Message-Id")
+	if !strings.HasPrefix(first, "<") || !strings.HasSuffix(first, "@example.com>") || first == second {
+		t.Fatalf("unexpected Message-IDs %q and %q", first, second)
+	}
+
+	msg.Headers = map[string]string{"Message-ID": "<1@example.com>"}
+	var bb bytes.Buffer
+	if err := tpl.Render(&bb, msg, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := strings.Count(strings.ToLower(bb.String()), "message-id:"); n != 1 {
+		t.Fatalf("expected a single Message-ID, got %d\n%s", n, bb.String())
+	}
+	m, _ := mail.ReadMessage(&bb)
+	if got := m.Header.Get("Message-Id"); got != "<1@example.com>" {
+		t.Fatalf("unexpected Message-ID %q", got)
+	}
+}
+
+func TestRenderFoldsHeaders(t *testing.T) {
+	tpl, err := New("{{subject}}", "body", "", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	subject := strings.Repeat("Grüße aus Köln ", 10)
+	to := make([]string, 8)
+	for i := range to {
+		to[i] = fmt.Sprintf("Recipient Number %d <recipient%d@example.com>", i, i)
+	}
+	var bb bytes.Buffer
+	if err := tpl.Render(&bb, Message{From: "shop@example.com", To: to}, fasttemplate.Map{"subject": subject}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	header, _, _ := strings.Cut(bb.String(), "\r\n\r\n")
+	for _, line := range strings.Split(header, "\r\n") {
+		if len(line) > 78 {
+			t.Fatalf("header line longer than 78 bytes: %q", line)
+		}
+	}
+
+	m, err := mail.ReadMessage(&bb)
+	if err != nil {
+		t.Fatalf("cannot parse message: %s", err)
+	}
+	got, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || got != subject {
+		t.Fatalf("unexpected subject %q (%v)", got, err)
+	}
+	addrs, err := m.Header.AddressList("To")
+	if err != nil || len(addrs) != len(to) || addrs[7].Address != "recipient7@example.com" {
+		t.Fatalf("unexpected To %v (%v)", addrs, err)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := New("subject", "", "", "{{", "}}"); err == nil {
+		t.Fatalf("expected error for missing bodies")
+	}
+
+	tpl, err := New("{{fail()}}", "body", "", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fail := func() (string, error) { return "", io.ErrUnexpectedEOF }
+	var bb bytes.Buffer
+	err = tpl.Render(&bb, Message{From: "a@example.com", To: []string{"b@example.com"}}, fasttemplate.Map{"fail": fail})
+	if err == nil || bb.Len() != 0 {
+		t.Fatalf("expected error and no output; got %v and %q", err, bb.String())
+	}
+
+	tpl, _ = New("subject", "body", "", "{{", "}}")
+	for _, msg := range []Message{
+		{From: "not an address", To: []string{"b@example.com"}},
+		{From: "a@example.com"},
+		{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"Bad Name": "x"}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"from": "eve@example.com"}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"Content-Type": "text/html"}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"MIME-Version": "2.0"}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"Date": "yesterday"}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Attachments: []Attachment{{Filename: "a.txt", ContentType: "text/plain\r\nBcc: eve@example.com"}}},
+		{From: "a@example.com", To: []string{"b@example.com"}, Attachments: []Attachment{{Filename: "a.txt", ContentType: "text/plain; charset"}}},
+	} {
+		if err := tpl.Render(io.Discard, msg, nil); err == nil {
+			t.Fatalf("expected error for %+v", msg)
+		}
+	}
+}
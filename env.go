@@ -0,0 +1,91 @@
+package fasttemplate
+
+import (
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envKey is the name of the environment descriptor object.
+const envKey = "_env"
+
+// WithEnvironmentInfo exposes an _env object describing the rendering
+// environment, so generated configs and reports can stamp their provenance:
+//
+//   - _env.hostname is the host name reported by the kernel.
+//   - _env.pid is the process ID.
+//   - _env.version is the version of the main module of the binary, e.g.
+//     "v1.2.3", or "(devel)" for builds outside of a module version.
+//   - _env.timestamp is the time.Time the execution started at.
+//
+// Keys present in the substitution map take precedence over _env, and
+// variable policies apply to its fields like to any other variable. The
+// timestamp makes the output differ between executions, even in
+// deterministic mode.
+func WithEnvironmentInfo() Option {
+	return func(t *Template) {
+		t.envInfo = true
+	}
+}
+
+// processEnv holds the parts of the environment descriptor fixed for the
+// life of the process, initialized on first use.
+var processEnv struct {
+	once   sync.Once
+	fields map[string]any
+}
+
+// processEnvFields returns the fields of processEnv.
+func processEnvFields() map[string]any {
+	processEnv.once.Do(func() {
+		hostname, _ := os.Hostname()
+		var version string
+		if info, ok := debug.ReadBuildInfo(); ok {
+			version = info.Main.Version
+		}
+		processEnv.fields = map[string]any{
+			"hostname": hostname,
+			"pid":      os.Getpid(),
+			"version":  version,
+		}
+	})
+	return processEnv.fields
+}
+
+// newEnv returns the environment descriptor of an execution starting now.
+func newEnv() map[string]any {
+	env := make(map[string]any, 4)
+	for k, v := range processEnvFields() {
+		env[k] = v
+	}
+	env["timestamp"] = time.Now()
+	return env
+}
+
+// lookupEnv returns the environment descriptor or one of its fields named
+// by a tag like _env.hostname.
+func (ec *evalContext) lookupEnv(name string) (any, bool) {
+	if ec.env == nil || !strings.HasPrefix(name, envKey) {
+		return nil, false
+	}
+	if ec.allowVariable != nil && !ec.allowVariable(name) {
+		return nil, false
+	}
+	if name == envKey {
+		return ec.env, true
+	}
+	field, ok := strings.CutPrefix(name, envKey+".")
+	if !ok {
+		return nil, false
+	}
+	v, ok := ec.env[field]
+	return v, ok
+}
+
+// isEnvTag reports whether the variable name refers to the environment
+// descriptor of a template using WithEnvironmentInfo.
+func (t *Template) isEnvTag(name string) bool {
+	return t.envInfo && (name == envKey || strings.HasPrefix(name, envKey+"."))
+}
@@ -0,0 +1,44 @@
+package fasttemplate
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithEnvironmentInfo(t *testing.T) {
+	hostname, _ := os.Hostname()
+	tpl := New("# generated on {{_env.hostname}} by pid {{_env.pid}} ({{_env.version}}) for {{name}}", "{{", "}}", WithEnvironmentInfo())
+	m := Map{"name": "ann"}
+	expected := "# generated on " + hostname + " by pid " + strconv.Itoa(os.Getpid()) + " ((devel)) for ann"
+	if s := tpl.ExecuteString(m); s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	if vars := tpl.Variables(); len(vars) != 1 || vars[0] != "name" {
+		t.Fatalf("unexpected variables %q", vars)
+	}
+
+	before := time.Now()
+	ts := New("{{format(_env.timestamp, 'RFC3339Nano')}}", "{{", "}}", WithEnvironmentInfo()).ExecuteString(Map{})
+	stamp, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil || stamp.Before(before) || stamp.After(time.Now()) {
+		t.Fatalf("unexpected timestamp %q: %v", ts, err)
+	}
+
+	// map keys take precedence
+	if s := tpl.ExecuteString(Map{"_env.hostname": "override", "name": "ann"}); !strings.HasPrefix(s, "# generated on override ") {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if s := New("[{{_env.hostname}}]", "{{", "}}").ExecuteString(Map{}); s != "[]" {
+		t.Fatalf("_env must not be exposed by default, got %q", s)
+	}
+	if s := New("[{{_env.hostname}}]", "{{", "}}", WithEnvironmentInfo(), WithAllowedVariables("name")).ExecuteString(Map{}); s != "[]" {
+		t.Fatalf("_env must honor variable policies, got %q", s)
+	}
+}
@@ -0,0 +1,152 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CompareOptions configures the rendering and normalization of
+// [EqualRendered].
+type CompareOptions struct {
+	// StartTag and EndTag are the tag delimiters of both templates;
+	// "{{" and "}}" if empty.
+	StartTag string
+	EndTag   string
+
+	// Options are applied to both templates, e.g. WithEscaping.
+	Options []Option
+
+	// CollapseWhitespace replaces runs of whitespace with a single space
+	// and trims leading and trailing whitespace.
+	CollapseWhitespace bool
+
+	// NormalizeNumbers formats standalone numbers canonically, so "1.50",
+	// "1.5" and "15e-1" are equal. Numbers within words or dotted
+	// sequences, such as the version "1.10.2", are left as is.
+	NormalizeNumbers bool
+
+	// NormalizeEntities spells HTML character references canonically, so
+	// differently escaped outputs such as "&#39;", "&#x27;" and "&apos;" are
+	// equal. References are never decoded, so "&lt;" and "<" still differ.
+	NormalizeEntities bool
+}
+
+// numberPattern matches decimal numbers.
+var numberPattern = regexp.MustCompile(`-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`)
+
+// entityPattern matches HTML character references.
+var entityPattern = regexp.MustCompile(`&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+// EqualRendered renders both templates with m and reports whether their
+// outputs are equal after the normalizations enabled in opts, e.g. to back
+// golden file tests across library upgrades. The error reports templates
+// failing to parse or execute.
+func EqualRendered(tplA, tplB string, m Map, opts CompareOptions) (bool, error) {
+	a, err := opts.render(tplA, m)
+	if err != nil {
+		return false, fmt.Errorf("first template: %w", err)
+	}
+	b, err := opts.render(tplB, m)
+	if err != nil {
+		return false, fmt.Errorf("second template: %w", err)
+	}
+	return opts.normalize(a) == opts.normalize(b), nil
+}
+
+// render parses and executes template.
+func (o *CompareOptions) render(template string, m Map) (string, error) {
+	startTag, endTag := o.StartTag, o.EndTag
+	if startTag == "" {
+		startTag = "{{"
+	}
+	if endTag == "" {
+		endTag = "}}"
+	}
+	t, err := NewTemplate(template, startTag, endTag, o.Options...)
+	if err != nil {
+		return "", err
+	}
+	var bb bytes.Buffer
+	if _, err := t.Execute(&bb, m); err != nil {
+		return "", err
+	}
+	return bb.String(), nil
+}
+
+// normalize applies the enabled normalizations to s.
+func (o *CompareOptions) normalize(s string) string {
+	if o.NormalizeEntities {
+		s = entityPattern.ReplaceAllStringFunc(s, canonicalEntity)
+	}
+	if o.NormalizeNumbers {
+		s = normalizeNumbers(s)
+	}
+	if o.CollapseWhitespace {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	return s
+}
+
+// canonicalEntity returns the decimal character reference of the characters
+// referenced by entity, or entity itself if it is unknown.
+func canonicalEntity(entity string) string {
+	decoded := html.UnescapeString(entity)
+	if decoded == entity {
+		return entity
+	}
+	var sb strings.Builder
+	for _, r := range decoded {
+		fmt.Fprintf(&sb, "&#%d;", r)
+	}
+	return sb.String()
+}
+
+// normalizeNumbers formats the standalone numbers of s canonically.
+func normalizeNumbers(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range numberPattern.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if !isStandaloneNumber(s, start, end) {
+			continue
+		}
+		f, err := strconv.ParseFloat(s[start:end], 64)
+		if err != nil {
+			continue
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// isStandaloneNumber reports whether s[start:end] isn't part of a word or
+// a dotted sequence such as a version number. A dot ending a sentence may
+// follow the number.
+func isStandaloneNumber(s string, start, end int) bool {
+	if start > 0 && (isWordByte(s[start-1]) || s[start-1] == '.') {
+		return false
+	}
+	if end < len(s) {
+		if isWordByte(s[end]) {
+			return false
+		}
+		if s[end] == '.' && end+1 < len(s) && isWordByte(s[end+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
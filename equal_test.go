@@ -0,0 +1,111 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEqualRendered(t *testing.T) {
+	m := Map{"name": "O'Brien", "price": 1.5, "qty": 2}
+	tests := []struct {
+		name     string
+		a, b     string
+		opts     CompareOptions
+		expected bool
+	}{
+		{
+			name:     "Identical",
+			a:        "Hi {{name}}",
+			b:        "Hi O'Brien",
+			expected: true,
+		},
+		{
+			name: "Different",
+			a:    "Hi {{name}}",
+			b:    "Hello {{name}}",
+		},
+		{
+			name: "WhitespaceDiffers",
+			a:    "<p>\n  {{name}}\n</p>",
+			b:    "<p> {{name}} </p>",
+		},
+		{
+			name:     "CollapseWhitespace",
+			a:        "<p>\n  {{name}}\n</p>",
+			b:        "<p> {{name}} </p>",
+			opts:     CompareOptions{CollapseWhitespace: true},
+			expected: true,
+		},
+		{
+			name:     "NormalizeNumbers",
+			a:        "Total: {{price * qty}}.00 ({{price}})",
+			b:        "Total: 3 (1.50)",
+			opts:     CompareOptions{NormalizeNumbers: true},
+			expected: true,
+		},
+		{
+			name:     "NormalizeEntities",
+			a:        "<b>{{name}}</b>",
+			b:        "<b>O&#x27;Brien</b>",
+			opts:     CompareOptions{NormalizeEntities: true, Options: []Option{WithEscaping(EscapeHTML)}},
+			expected: true,
+		},
+		{
+			name:     "NamedEntities",
+			a:        "&lt;a&gt; &apos;",
+			b:        "&#60;a&#x3E; &#39;",
+			opts:     CompareOptions{NormalizeEntities: true},
+			expected: true,
+		},
+		{
+			// an escaping regression isn't hidden by the normalization
+			name: "EntitiesAreNotDecoded",
+			a:    "&lt;script&gt;",
+			b:    "<script>",
+			opts: CompareOptions{NormalizeEntities: true},
+		},
+		{
+			name: "VersionNumbers",
+			a:    "v1.10 and 1.10.2",
+			b:    "v1.1 and 1.1.2",
+			opts: CompareOptions{NormalizeNumbers: true},
+		},
+		{
+			name:     "NumbersEndingSentences",
+			a:        "Costs 1.50. Or (2.0)",
+			b:        "Costs 1.5. Or (2)",
+			opts:     CompareOptions{NormalizeNumbers: true},
+			expected: true,
+		},
+		{
+			name:     "Delimiters",
+			a:        "Hi <%name%>",
+			b:        "Hi O'Brien",
+			opts:     CompareOptions{StartTag: "<%", EndTag: "%>"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal, err := EqualRendered(tt.a, tt.b, m, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if equal != tt.expected {
+				t.Fatalf("unexpected result %v", equal)
+			}
+		})
+	}
+}
+
+func TestEqualRenderedErrors(t *testing.T) {
+	if _, err := EqualRendered("{{a", "", nil, CompareOptions{}); err == nil || !strings.HasPrefix(err.Error(), "first template") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fail := func() (string, error) { return "", errors.New("unavailable") }
+	if _, err := EqualRendered("", "{{fail()}}", Map{"fail": fail}, CompareOptions{}); err == nil || !strings.HasPrefix(err.Error(), "second template") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
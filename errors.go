@@ -1,8 +1,77 @@
 package fasttemplate
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSkipped is returned by [Template.ExecuteIf] when the guard condition
+// evaluates to false and nothing has been rendered.
+var ErrSkipped = errors.New("template skipped: condition is false")
+
+// ErrTemplateCycle is returned when templates include each other in a cycle
+// or the include depth limit set with [WithMaxIncludeDepth] is exceeded. The
+// error message names the chain of included templates.
+var ErrTemplateCycle = errors.New("template cycle")
+
+// DelimiterError is returned when a template is parsed or executed with an
+// empty start or end tag.
+type DelimiterError struct {
+	StartTag string
+	EndTag   string
+}
+
+func (e *DelimiterError) Error() string {
+	if e.StartTag == "" {
+		return "startTag cannot be empty"
+	}
+	return "endTag cannot be empty"
+}
+
+// checkDelimiters returns a *DelimiterError if a delimiter is empty.
+func checkDelimiters(startTag, endTag string) error {
+	if startTag == "" || endTag == "" {
+		return &DelimiterError{StartTag: startTag, EndTag: endTag}
+	}
+	return nil
+}
+
+// LimitError is returned when a template exceeds a parse-time limit set
+// with [WithMaxTemplateSize] or [WithMaxTags].
+type LimitError struct {
+	// Limit names the exceeded limit: "template size" or "tag count".
+	Limit string
+	Max   int
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %d exceeds the limit of %d", e.Limit, e.Value, e.Max)
+}
 
 var (
 	errVariableNotFound = errors.New("variable not found")
 	errFunctionNotFound = errors.New("function not found")
+	errPartialNotFound  = errors.New("partial not found")
+	errTemplateNotFound = errors.New("template not found")
+	errNoTemplateFiles  = errors.New("no template files")
+
+	errNondeterministicFunc = errors.New("function is not idempotent in deterministic mode")
+	errFuncTimeout          = errors.New("function call timed out")
+	errCircuitOpen          = errors.New("circuit breaker is open for function")
+	errReadOnlyMap          = errors.New("map is read-only")
+	errInvalidSpread        = errors.New("invalid spread argument")
+	errInvalidFrameSize     = errors.New("frame size must be positive")
+	errInvalidLambda        = errors.New("invalid lambda")
+	errTokenBudget          = errors.New("token budget exceeded")
+	errInvalidEdit          = errors.New("invalid text edit")
+	errTokenBudgetBlocks    = errors.New("token budgets are not supported with blocks")
+	errNotSingleTag         = errors.New("template is not a single tag")
+	errUnsupportedResults   = errors.New("unsupported function results")
+	errUnterminatedComment  = errors.New("unterminated comment")
+
+	// errBreak and errContinue unwind the nodes of a range block to the
+	// loop rendering it.
+	errBreak    = errors.New("break outside of a range block")
+	errContinue = errors.New("continue outside of a range block")
 )
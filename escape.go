@@ -0,0 +1,894 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeMode selects how substituted values are escaped.
+type EscapeMode int
+
+const (
+	// EscapeNone writes values as is. This is the default.
+	EscapeNone EscapeMode = iota
+
+	// EscapeHTML escapes values according to the HTML context they appear
+	// in. The context of every tag is detected from the surrounding static
+	// text when the template is parsed, similar to html/template:
+	//   - element content and attribute values are HTML-escaped,
+	//   - URL attributes (href, src, xlink:href, data-*url, ...) reject
+	//     unsafe schemes and are percent-encoded,
+	//   - script elements and event handler attributes get JS string
+	//     escaping, values inside JS comments are dropped and values inside
+	//     regular expression literals only match themselves,
+	//   - style elements and attributes get CSS escaping,
+	//   - values inside comments are dropped,
+	//   - tags can't be element names, so parsing fails for <{{tag}}.
+	EscapeHTML
+
+	// EscapeXML escapes values according to the XML context they appear in:
+	//   - element content and attribute values are XML-escaped, and
+	//     characters not allowed in XML documents are replaced with U+FFFD,
+	//   - values inside CDATA sections are split around "]]>",
+	//   - values inside comments are dropped.
+	EscapeXML
+
+	// EscapeJSON escapes values according to their position in a JSON
+	// document:
+	//   - values inside JSON strings are escaped as string content,
+	//   - other values are written as JSON values: numbers and booleans as
+	//     is, maps, slices and structs encoded with encoding/json and
+	//     everything else as a quoted string.
+	EscapeJSON
+
+	// EscapeURL escapes values according to their position in a URL:
+	//   - a value at the start of the URL keeps its structure, but URLs with
+	//     schemes other than http, https and mailto are rejected,
+	//   - values anywhere else, e.g. path segments and query parameters, are
+	//     percent-encoded as URL components.
+	EscapeURL
+)
+
+// WithEscaping enables escaping of substituted values using the given mode.
+func WithEscaping(mode EscapeMode) Option {
+	return func(t *Template) {
+		t.escapeMode = mode
+	}
+}
+
+// HTML is a trusted HTML fragment. Values of this type are written without
+// escaping in HTML element content when [EscapeHTML] is enabled and escaped
+// as regular strings in every other context.
+//
+// Use of this type presents a security risk: the content must come from a
+// trusted source, as it is included verbatim in the output.
+type HTML string
+
+// XML is a trusted XML fragment. Values of this type are written without
+// escaping in XML element content when [EscapeXML] is enabled and escaped
+// as regular strings in every other context.
+//
+// Use of this type presents a security risk: the content must come from a
+// trusted source, as it is included verbatim in the output.
+type XML string
+
+// ctxKind is the kind of content a tag is substituted into.
+type ctxKind uint8
+
+const (
+	ctxRaw ctxKind = iota
+	ctxText
+	ctxName
+	ctxElementName
+	ctxComment
+	ctxURLStart
+	ctxURLPath
+	ctxURLQuery
+	ctxJS
+	ctxJSString
+	ctxJSRegexp
+	ctxCSS
+	ctxCDATA
+	ctxJSONValue
+	ctxJSONString
+)
+
+// Attribute value quoting of an escaping context.
+const (
+	attrNone uint8 = iota
+	attrQuoted
+	attrUnquoted
+)
+
+// escapeContext describes where a tag is located in the output document.
+type escapeContext struct {
+	kind ctxKind
+	attr uint8
+	xml  bool
+}
+
+// contextLexer tracks the escaping context across the static texts of a
+// template.
+type contextLexer interface {
+	// feed advances the lexer over the static text.
+	feed(text []byte)
+
+	// context returns the escaping context at the current lexer position.
+	context() escapeContext
+
+	// afterTag updates the lexer state after a substituted value.
+	afterTag()
+
+	// clone returns a copy of the lexer, e.g. to lex the branches of a
+	// block separately.
+	clone() contextLexer
+
+	// same reports whether the lexer is in the same context as other, so
+	// branches ending in them can be joined.
+	same(other contextLexer) bool
+}
+
+// newContextLexer returns the lexer of the escaping mode.
+func newContextLexer(mode EscapeMode) contextLexer {
+	switch mode {
+	case EscapeJSON:
+		return &jsonLexer{}
+	case EscapeURL:
+		return &urlLexer{}
+	}
+	return &htmlLexer{xml: mode == EscapeXML}
+}
+
+// computeContexts sets the escaping context of every tag separating the
+// static texts. Templates with blocks are lexed along their node tree, so
+// every branch of a block starts in the context of the block tag; it fails
+// if the branches end in different contexts, as the context after the block
+// would depend on the data.
+func (t *Template) computeContexts() error {
+	t.contexts = nil
+	if t.escapeMode == EscapeNone || len(t.texts) < 2 {
+		return nil
+	}
+	l := newContextLexer(t.escapeMode)
+	contexts := make([]escapeContext, len(t.texts)-1)
+	for i := range contexts {
+		l.feed(t.texts[i])
+		contexts[i] = l.context()
+		l.afterTag()
+	}
+	if t.nodes != nil {
+		cw := contextWalker{t: t, contexts: contexts}
+		if _, err := cw.walk(t.nodes, newContextLexer(t.escapeMode)); err != nil {
+			return err
+		}
+	}
+	for i, c := range contexts {
+		if c.kind == ctxElementName && !t.isBlockTag(i) {
+			// the element could be a script or style element the lexer
+			// doesn't know about
+			return fmt.Errorf("tag %s%s%s can't be an HTML element name", t.startTag, t.tags[i], t.endTag)
+		}
+	}
+	t.contexts = contexts
+	return nil
+}
+
+// contextWalker computes the escaping contexts of the tags along the node
+// tree of a template.
+type contextWalker struct {
+	t        *Template
+	contexts []escapeContext
+	// loops holds the lexers at the start of the enclosing range blocks.
+	loops []contextLexer
+}
+
+// walk lexes the nodes starting with l and returns the lexer at their end.
+func (cw *contextWalker) walk(nodes []node, l contextLexer) (contextLexer, error) {
+	for i := range nodes {
+		nd := &nodes[i]
+		switch nd.kind {
+		case nodeText:
+			l.feed(nd.text)
+		case nodeTag:
+			cw.contexts[nd.tag] = l.context()
+			l.afterTag()
+		case nodeIf, nodeWith, nodeRange:
+			if nd.kind == nodeRange {
+				cw.loops = append(cw.loops, l)
+			}
+			body, err := cw.walk(nd.nodes, l.clone())
+			if nd.kind == nodeRange {
+				cw.loops = cw.loops[:len(cw.loops)-1]
+			}
+			if err != nil {
+				return nil, err
+			}
+			if nd.kind == nodeRange && !body.same(l) {
+				return nil, cw.errorf(nd, "body of block tag %s doesn't end in the escaping context it starts in")
+			}
+			els, err := cw.walk(nd.els, l.clone())
+			if err != nil {
+				return nil, err
+			}
+			if !body.same(els) {
+				return nil, cw.errorf(nd, "branches of block tag %s end in different escaping contexts")
+			}
+			l = body
+		case nodeBreak, nodeContinue:
+			if loop := cw.loops[len(cw.loops)-1]; !l.same(loop) {
+				return nil, cw.errorf(nd, "block tag %s isn't in the escaping context its loop starts in")
+			}
+		case nodeCapture:
+			// captured content is written where the variable is used
+			if _, err := cw.walk(nd.nodes, l.clone()); err != nil {
+				return nil, err
+			}
+		case nodeSet:
+		default:
+			var err error
+			if l, err = cw.walk(nd.nodes, l); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return l, nil
+}
+
+// errorf returns an error about the tag of nd, formatted into msg.
+func (cw *contextWalker) errorf(nd *node, msg string) error {
+	t := cw.t
+	return fmt.Errorf(msg, t.startTag+t.tags[nd.tag]+t.endTag)
+}
+
+// trusted returns v if it is trusted markup written verbatim in the
+// context.
+func (ctx escapeContext) trusted(v any) (string, bool) {
+	if ctx.kind != ctxText || ctx.attr != attrNone {
+		return "", false
+	}
+	switch s := v.(type) {
+	case HTML:
+		return string(s), !ctx.xml
+	case escapedHTML:
+		return string(s), !ctx.xml
+	case XML:
+		return string(s), ctx.xml
+	case escapedXML:
+		return string(s), ctx.xml
+	}
+	return "", false
+}
+
+// writeEscaped writes the value of the i-th tag escaped for its context.
+func (t *Template) writeEscaped(w io.Writer, i int, v any, kind tagKind) (int, error) {
+	ctx := t.contexts[i]
+	if s, ok := ctx.trusted(v); ok {
+		return writeFull(w, unsafeString2Bytes(s))
+	}
+
+	bb := t.getBuffer()
+	defer t.putBuffer(bb)
+	if _, err := t.writeValue(bb, i, v, kind); err != nil {
+		return 0, err
+	}
+	return writeFull(w, unsafeString2Bytes(ctx.escape(v, bb.String())))
+}
+
+// escape escapes the formatted value s of v for the context.
+func (c escapeContext) escape(v any, s string) string {
+	switch c.kind {
+	case ctxRaw:
+		return s
+	case ctxComment:
+		return ""
+	case ctxName, ctxElementName:
+		return filterName(s)
+	case ctxCDATA:
+		return escapeCDATA(s)
+	case ctxURLStart:
+		s = normalizeURL(filterURL(s))
+	case ctxURLPath:
+		s = normalizeURL(s)
+	case ctxURLQuery:
+		s = escapeURLComponent(s)
+	case ctxJS:
+		switch v.(type) {
+		case bool, int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64, float32, float64:
+			// numbers and booleans are valid JS literals
+		default:
+			s = `"` + escapeJSString(s) + `"`
+		}
+	case ctxJSString:
+		s = escapeJSString(s)
+	case ctxJSRegexp:
+		s = escapeJSRegexp(s)
+	case ctxCSS:
+		s = escapeCSS(s)
+	case ctxJSONValue:
+		return jsonValue(v, s)
+	case ctxJSONString:
+		return escapeJSONString(s)
+	}
+
+	switch {
+	case c.attr == attrUnquoted:
+		return escapeHTMLUnquoted(s)
+	case c.xml && (c.attr == attrQuoted || c.kind == ctxText):
+		return escapeXML(s, c.attr == attrQuoted)
+	case c.attr == attrQuoted || c.kind == ctxText:
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+// filterName keeps values that are safe as tag or attribute names.
+func filterName(s string) string {
+	if s == "" {
+		return "ZgotmplZ"
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isASCIIAlnum(c) && c != '-' && c != '_' {
+			return "ZgotmplZ"
+		}
+	}
+	return s
+}
+
+// filterURL replaces URLs with schemes other than http, https and mailto.
+func filterURL(s string) string {
+	if i := strings.IndexByte(s, ':'); i >= 0 && !strings.ContainsRune(s[:i], '/') {
+		switch strings.ToLower(s[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "#ZgotmplZ"
+		}
+	}
+	return s
+}
+
+// normalizeURL percent-encodes bytes that are not allowed in URLs, keeping
+// the URL structure intact.
+func normalizeURL(s string) string {
+	return percentEncode(s, func(c byte) bool {
+		return isURLUnreserved(c) || strings.IndexByte("!#$%&'()*+,/:;=?@[]", c) >= 0
+	})
+}
+
+// escapeURLComponent percent-encodes everything but unreserved bytes.
+func escapeURLComponent(s string) string {
+	return percentEncode(s, isURLUnreserved)
+}
+
+func percentEncode(s string, keep func(c byte) bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if keep(c) {
+			if sb.Len() > 0 {
+				sb.WriteByte(c)
+			}
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString(s[:i])
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	if sb.Len() == 0 {
+		return s
+	}
+	return sb.String()
+}
+
+// escapeJSString escapes s for use inside a JS string or template literal.
+// Quotes, "${" and HTML special chars are escaped as well, so the result is
+// also safe in template literals and HTML attributes.
+func escapeJSString(s string) string {
+	return escapeJS(s, "")
+}
+
+// escapeJSRegexp escapes s for use inside a JS regular expression literal,
+// so that it only matches itself.
+func escapeJSRegexp(s string) string {
+	return escapeJS(s, `.*+?^|()[]-`)
+}
+
+// escapeJS escapes s for use inside JS literals. Runes in special are
+// escaped in addition to the ones escapeJSString escapes.
+func escapeJS(s, special string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r < utf8.RuneSelf && strings.IndexByte(special, byte(r)) >= 0 {
+			fmt.Fprintf(&sb, `\u%04X`, r)
+			continue
+		}
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '"', '\'', '`', '$', '{', '}', '<', '>', '&', '=', '/', '\u2028', '\u2029':
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// escapeCSS escapes everything but alphanumerics for use in CSS.
+func escapeCSS(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r < utf8.RuneSelf && !isASCIIAlnum(byte(r)) && r != ' ' && r != '-' && r != '_' && r != '.' && r != '#' && r != '%' {
+			fmt.Fprintf(&sb, `\%X `, r)
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapeXML escapes s for use in XML element content or, if attr is set,
+// in a quoted attribute value. Whitespace in attribute values is escaped as
+// well, so it survives attribute value normalization.
+func escapeXML(s string, attr bool) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '<':
+			sb.WriteString("&lt;")
+		case r == '>':
+			sb.WriteString("&gt;")
+		case r == '&':
+			sb.WriteString("&amp;")
+		case r == '"':
+			sb.WriteString("&#34;")
+		case r == '\'':
+			sb.WriteString("&#39;")
+		case attr && (r == '\t' || r == '\n' || r == '\r'):
+			fmt.Fprintf(&sb, "&#x%X;", r)
+		case !isXMLChar(r):
+			sb.WriteRune(utf8.RuneError)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// escapeCDATA escapes s for use inside a CDATA section by closing the
+// section before every ">" of a "]]>" sequence and reopening it right after.
+func escapeCDATA(s string) string {
+	s = strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>")
+	return strings.Map(func(r rune) rune {
+		if !isXMLChar(r) {
+			return utf8.RuneError
+		}
+		return r
+	}, s)
+}
+
+// cdata wraps s in a CDATA section.
+func cdata(s string) XML {
+	return XML("<![CDATA[" + escapeCDATA(s) + "]]>")
+}
+
+// isXMLChar reports whether r is allowed in XML 1.0 documents.
+func isXMLChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r >= 0x20 && r <= 0xD7FF, r >= 0xE000 && r <= 0xFFFD, r >= 0x10000 && r <= utf8.MaxRune:
+		return true
+	}
+	return false
+}
+
+// escapeHTMLUnquoted escapes s for use in an unquoted attribute value.
+func escapeHTMLUnquoted(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case 0, '\t', '\n', '\f', '\r', ' ', '"', '&', '\'', '<', '=', '>', '`':
+			fmt.Fprintf(&sb, "&#%d;", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func isASCIIAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isURLUnreserved(c byte) bool {
+	return isASCIIAlnum(c) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// htmlState is the state of the HTML lexer.
+type htmlState uint8
+
+const (
+	stateText htmlState = iota
+	stateTag
+	stateAfterName
+	stateBeforeValue
+	stateAttrValue
+	stateComment
+	stateRawText
+	stateCDATA
+	stateProcInst
+	stateElemName
+)
+
+// Element content types.
+const (
+	elemNormal uint8 = iota
+	elemScript
+	elemStyle
+	elemRCDATA
+)
+
+// Attribute value types.
+const (
+	attrValueNormal uint8 = iota
+	attrValueURL
+	attrValueJS
+	attrValueCSS
+)
+
+// URL parts within a URL attribute value.
+const (
+	urlPartNone uint8 = iota
+	urlPartPath
+	urlPartQuery
+)
+
+// htmlLexer tracks the HTML context across the static texts of a template.
+// It only understands as much HTML as needed to choose an escaper. With xml
+// set, it lexes XML instead: elements have no special content, attributes
+// no special value types, and CDATA sections and processing instructions
+// are recognized.
+type htmlLexer struct {
+	xml     bool
+	state   htmlState
+	elem    uint8
+	rawName string
+	attr    uint8
+	delim   byte
+	urlPart uint8
+	js      jsLexer
+}
+
+func (l *htmlLexer) clone() contextLexer {
+	c := *l
+	c.js = l.js.clone()
+	return &c
+}
+
+// same compares the parts of the lexer state that matter in its state, as
+// leftovers of earlier elements and attributes are kept.
+func (l *htmlLexer) same(other contextLexer) bool {
+	o := other.(*htmlLexer)
+	if l.xml != o.xml || l.state != o.state {
+		return false
+	}
+	switch l.state {
+	case stateText, stateComment, stateCDATA, stateProcInst:
+		return true
+	case stateRawText:
+		return l.elem == o.elem && l.rawName == o.rawName && (l.elem != elemScript || l.js.same(&o.js))
+	case stateTag:
+		return l.elem == o.elem && l.rawName == o.rawName
+	case stateAttrValue:
+		if l.delim != o.delim || l.urlPart != o.urlPart || (l.attr == attrValueJS && !l.js.same(&o.js)) {
+			return false
+		}
+	}
+	return l.elem == o.elem && l.rawName == o.rawName && l.attr == o.attr
+}
+
+func (l *htmlLexer) context() escapeContext {
+	c := l.lexContext()
+	c.xml = l.xml
+	return c
+}
+
+// lexContext returns the escaping context at the current lexer position.
+func (l *htmlLexer) lexContext() escapeContext {
+	switch l.state {
+	case stateText:
+		return escapeContext{kind: ctxText}
+	case stateComment:
+		return escapeContext{kind: ctxComment}
+	case stateCDATA:
+		return escapeContext{kind: ctxCDATA}
+	case stateProcInst:
+		return escapeContext{kind: ctxText, attr: attrQuoted}
+	case stateElemName:
+		if l.xml {
+			return escapeContext{kind: ctxName}
+		}
+		return escapeContext{kind: ctxElementName}
+	case stateRawText:
+		switch l.elem {
+		case elemScript:
+			return escapeContext{kind: l.js.context()}
+		case elemStyle:
+			return escapeContext{kind: ctxCSS}
+		}
+		return escapeContext{kind: ctxText}
+	case stateBeforeValue, stateAttrValue:
+		c := escapeContext{kind: ctxText, attr: attrQuoted}
+		if l.state == stateBeforeValue || l.delim == 0 {
+			c.attr = attrUnquoted
+		}
+		switch l.attr {
+		case attrValueURL:
+			switch l.urlPart {
+			case urlPartNone:
+				c.kind = ctxURLStart
+			case urlPartPath:
+				c.kind = ctxURLPath
+			default:
+				c.kind = ctxURLQuery
+			}
+		case attrValueJS:
+			c.kind = l.js.context()
+		case attrValueCSS:
+			c.kind = ctxCSS
+		}
+		return c
+	}
+	return escapeContext{kind: ctxName}
+}
+
+func (l *htmlLexer) afterTag() {
+	if l.state == stateBeforeValue {
+		l.startValue(0)
+	}
+	if l.state == stateAttrValue && l.attr == attrValueURL && l.urlPart == urlPartNone {
+		l.urlPart = urlPartPath
+	}
+	if (l.state == stateRawText && l.elem == elemScript) || (l.state == stateAttrValue && l.attr == attrValueJS) {
+		l.js.afterValue()
+	}
+}
+
+func (l *htmlLexer) startValue(delim byte) {
+	l.state = stateAttrValue
+	l.delim = delim
+	l.urlPart = urlPartNone
+	l.js.reset()
+}
+
+func (l *htmlLexer) feed(text []byte) {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch l.state {
+		case stateText:
+			j := bytes.IndexByte(text[i:], '<')
+			if j < 0 {
+				return
+			}
+			i += j
+			rest := text[i:]
+			switch {
+			case bytes.HasPrefix(rest, []byte("<!--")):
+				l.state = stateComment
+				i += 4
+			case l.xml && bytes.HasPrefix(rest, []byte("<![CDATA[")):
+				l.state = stateCDATA
+				i += 9
+			case l.xml && bytes.HasPrefix(rest, []byte("<?")):
+				l.state = stateProcInst
+				i += 2
+			case len(rest) > 1 && rest[1] == '/':
+				// end tags don't change the context
+				k := bytes.IndexByte(rest, '>')
+				if k < 0 {
+					return
+				}
+				i += k + 1
+			case len(rest) == 1:
+				// a tag follows "<"
+				l.state = stateElemName
+				i++
+			case len(rest) > 1 && isASCIIAlnum(rest[1]):
+				k := 1
+				for k < len(rest) && (isASCIIAlnum(rest[k]) || rest[k] == '-' || (l.xml && isXMLNameByte(rest[k]))) {
+					k++
+				}
+				if l.xml {
+					l.state = stateTag
+					l.elem = elemNormal
+				} else {
+					l.openElement(strings.ToLower(string(rest[1:k])))
+				}
+				i += k
+			default:
+				i++
+			}
+		case stateElemName:
+			// the rest of an element name started by a tag
+			for i < len(text) && (isASCIIAlnum(text[i]) || text[i] == '-' || (l.xml && isXMLNameByte(text[i]))) {
+				i++
+			}
+			l.state = stateTag
+			l.elem = elemNormal
+			l.rawName = ""
+		case stateTag:
+			switch {
+			case isHTMLSpace(c) || c == '/':
+				i++
+			case c == '>':
+				i++
+				l.state = stateText
+				if l.elem != elemNormal {
+					l.state = stateRawText
+					l.js.reset()
+				}
+			default:
+				k := i
+				for k < len(text) && !isHTMLSpace(text[k]) && text[k] != '=' && text[k] != '>' && text[k] != '/' {
+					k++
+				}
+				l.attr = attrValueNormal
+				if !l.xml {
+					l.attr = attrValueType(strings.ToLower(string(text[i:k])))
+				}
+				l.state = stateAfterName
+				i = k
+			}
+		case stateAfterName:
+			switch {
+			case isHTMLSpace(c):
+				i++
+			case c == '=':
+				l.state = stateBeforeValue
+				i++
+			default:
+				l.state = stateTag
+			}
+		case stateBeforeValue:
+			switch {
+			case isHTMLSpace(c):
+				i++
+			case c == '"' || c == '\'':
+				l.startValue(c)
+				i++
+			default:
+				l.startValue(0)
+			}
+		case stateAttrValue:
+			if (l.delim != 0 && c == l.delim) || (l.delim == 0 && (isHTMLSpace(c) || c == '>')) {
+				l.state = stateTag
+				if l.delim != 0 {
+					i++
+				}
+				continue
+			}
+			switch l.attr {
+			case attrValueURL:
+				if c == '?' || c == '#' {
+					l.urlPart = urlPartQuery
+				} else if l.urlPart == urlPartNone {
+					l.urlPart = urlPartPath
+				}
+			case attrValueJS:
+				i += l.js.next(text, i)
+				continue
+			}
+			i++
+		case stateComment:
+			j := bytes.Index(text[i:], []byte("-->"))
+			if j < 0 {
+				return
+			}
+			i += j + 3
+			l.state = stateText
+		case stateCDATA:
+			j := bytes.Index(text[i:], []byte("]]>"))
+			if j < 0 {
+				return
+			}
+			i += j + 3
+			l.state = stateText
+		case stateProcInst:
+			j := bytes.Index(text[i:], []byte("?>"))
+			if j < 0 {
+				return
+			}
+			i += j + 2
+			l.state = stateText
+		case stateRawText:
+			// the end tag closes the element even inside JS strings and
+			// comments
+			if c == '<' && hasPrefixFold(text[i:], "</"+l.rawName) {
+				l.state = stateText
+				l.elem = elemNormal
+				continue
+			}
+			if l.elem == elemScript {
+				i += l.js.next(text, i)
+				continue
+			}
+			i++
+		}
+	}
+}
+
+// openElement switches to the start tag of the named element.
+func (l *htmlLexer) openElement(name string) {
+	l.state = stateTag
+	l.rawName = name
+	switch name {
+	case "script":
+		l.elem = elemScript
+	case "style":
+		l.elem = elemStyle
+	case "textarea", "title":
+		l.elem = elemRCDATA
+	default:
+		l.elem = elemNormal
+	}
+}
+
+// attrValueType returns the value type of the named attribute. Like
+// html/template, it ignores "data-" prefixes and namespaces such as
+// "xlink:", and treats attributes whose names contain "src", "uri" or
+// "url" as URLs.
+func attrValueType(name string) uint8 {
+	if rest, ok := strings.CutPrefix(name, "data-"); ok {
+		name = rest
+	} else if prefix, rest, ok := strings.Cut(name, ":"); ok {
+		if prefix == "xmlns" {
+			return attrValueURL
+		}
+		name = rest
+	}
+	switch {
+	case strings.HasPrefix(name, "on"):
+		return attrValueJS
+	case name == "style":
+		return attrValueCSS
+	}
+	switch name {
+	case "href", "src", "action", "formaction", "cite", "poster", "background",
+		"longdesc", "usemap", "manifest", "codebase", "data", "xmlns":
+		return attrValueURL
+	}
+	if strings.Contains(name, "src") || strings.Contains(name, "uri") || strings.Contains(name, "url") {
+		return attrValueURL
+	}
+	return attrValueNormal
+}
+
+func isXMLNameByte(c byte) bool {
+	return c == '_' || c == ':' || c == '.' || c >= utf8.RuneSelf
+}
+
+func hasPrefixFold(s []byte, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(string(s[:len(prefix)]), prefix)
+}
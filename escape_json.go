@@ -0,0 +1,102 @@
+package fasttemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// jsonLexer tracks whether tags are located inside JSON strings.
+type jsonLexer struct {
+	inString bool
+	escaped  bool
+}
+
+func (l *jsonLexer) feed(text []byte) {
+	for _, c := range text {
+		switch {
+		case l.escaped:
+			l.escaped = false
+		case !l.inString:
+			l.inString = c == '"'
+		case c == '\\':
+			l.escaped = true
+		case c == '"':
+			l.inString = false
+		}
+	}
+}
+
+func (l *jsonLexer) context() escapeContext {
+	if l.inString {
+		return escapeContext{kind: ctxJSONString}
+	}
+	return escapeContext{kind: ctxJSONValue}
+}
+
+func (l *jsonLexer) afterTag() {}
+
+func (l *jsonLexer) clone() contextLexer {
+	c := *l
+	return &c
+}
+
+func (l *jsonLexer) same(other contextLexer) bool {
+	return *l == *other.(*jsonLexer)
+}
+
+// jsonValue returns the formatted value s of v as a JSON value.
+func jsonValue(v any, s string) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64:
+		return s
+	case string, []byte, fmt.Stringer, io.Reader, io.WriterTo, func(io.Writer, string) (int, error):
+		return `"` + escapeJSONString(s) + `"`
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return `"` + escapeJSONString(s) + `"`
+}
+
+// escapeJSONString escapes s for use inside a JSON string. HTML special
+// chars are escaped as well, like encoding/json does.
+func escapeJSONString(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '<', '>', '&', '\u2028', '\u2029':
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package fasttemplate
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEscapeJSONContexts(t *testing.T) {
+	data := Map{
+		"text":  "say \"hi\"\n<b>\\",
+		"num":   42,
+		"ratio": 0.5,
+		"ok":    true,
+		"none":  nil,
+		"tags":  []string{"a", "b"},
+		"user":  struct{ Name string }{"Ann"},
+		"raw":   []byte("bytes"),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "String",
+			template: `{"msg": "{{text}}"}`,
+			expected: `{"msg": "say \"hi\"\n\u003cb\u003e\\"}`,
+		},
+		{
+			name:     "StringAfterEscapedQuote",
+			template: `{"msg": "a\" {{text}}"}`,
+			expected: `{"msg": "a\" say \"hi\"\n\u003cb\u003e\\"}`,
+		},
+		{
+			name:     "Values",
+			template: `{"n": {{num}}, "r": {{ratio}}, "ok": {{ok}}, "none": {{none}}}`,
+			expected: `{"n": 42, "r": 0.5, "ok": true, "none": null}`,
+		},
+		{
+			name:     "QuotedValues",
+			template: `{"msg": {{text}}, "raw": {{raw}}}`,
+			expected: `{"msg": "say \"hi\"\n\u003cb\u003e\\", "raw": "bytes"}`,
+		},
+		{
+			name:     "Encoded",
+			template: `{"tags": {{tags}}, "user": {{user}}}`,
+			expected: `{"tags": ["a","b"], "user": {"Name":"Ann"}}`,
+		},
+		{
+			name:     "Expression",
+			template: `{"sum": {{num + 1}}, "greeting": "{{'hi ' + text}}"}`,
+			expected: `{"sum": 43, "greeting": "hi say \"hi\"\n\u003cb\u003e\\"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", WithEscaping(EscapeJSON))
+			s := tpl.ExecuteString(data)
+			if s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+			if !json.Valid([]byte(s)) {
+				t.Fatalf("invalid JSON %s", s)
+			}
+		})
+	}
+}
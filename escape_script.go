@@ -0,0 +1,239 @@
+package fasttemplate
+
+import "unicode/utf8"
+
+// JS lexer modes.
+const (
+	jsModeCode uint8 = iota
+	jsModeString
+	jsModeTemplate
+	jsModeRegexp
+	jsModeRegexpClass
+	jsModeLineComment
+	jsModeBlockComment
+)
+
+// jsRegexpKeywords are the keywords after which a slash starts a regular
+// expression literal rather than a division.
+var jsRegexpKeywords = map[string]bool{
+	"break":      true,
+	"case":       true,
+	"continue":   true,
+	"delete":     true,
+	"do":         true,
+	"else":       true,
+	"finally":    true,
+	"in":         true,
+	"instanceof": true,
+	"return":     true,
+	"throw":      true,
+	"try":        true,
+	"typeof":     true,
+	"void":       true,
+}
+
+// jsLexer tracks the JS context of script elements and event handler
+// attributes. It recognizes string, template and regular expression
+// literals as well as comments, and tells regular expressions from
+// divisions with the same heuristic as html/template: a slash starts a
+// regular expression unless it follows a value, i.e. an identifier other
+// than a keyword, a literal or a closing bracket.
+type jsLexer struct {
+	mode  uint8
+	quote byte
+	esc   bool
+
+	// prev is the last significant byte in code and before the byte
+	// preceding it if adjacent. run counts adjacent '+' or '-' bytes.
+	prev   byte
+	before byte
+	run    int
+	space  bool
+	ident  []byte
+	braces []int
+}
+
+func (l *jsLexer) reset() {
+	*l = jsLexer{ident: l.ident[:0], braces: l.braces[:0]}
+}
+
+func (l *jsLexer) clone() jsLexer {
+	c := *l
+	c.ident = append([]byte(nil), l.ident...)
+	c.braces = append([]int(nil), l.braces...)
+	return c
+}
+
+// same reports whether l and o are in the same JS context. The tokens they
+// last saw may differ as long as they agree on what a slash starts.
+func (l *jsLexer) same(o *jsLexer) bool {
+	if l.mode != o.mode || l.quote != o.quote || l.esc != o.esc || len(l.braces) != len(o.braces) {
+		return false
+	}
+	for i := range l.braces {
+		if l.braces[i] != o.braces[i] {
+			return false
+		}
+	}
+	return l.mode != jsModeCode || l.regexpAllowed() == o.regexpAllowed()
+}
+
+// context returns the kind of the current JS context. Tags inside comments
+// are dropped.
+func (l *jsLexer) context() ctxKind {
+	switch l.mode {
+	case jsModeCode:
+		return ctxJS
+	case jsModeString, jsModeTemplate:
+		return ctxJSString
+	case jsModeRegexp, jsModeRegexpClass:
+		return ctxJSRegexp
+	}
+	return ctxComment
+}
+
+// afterValue updates the state after a substituted value. In code, the
+// value is an expression, so a following slash is a division.
+func (l *jsLexer) afterValue() {
+	if l.mode == jsModeCode {
+		l.token(')')
+	}
+}
+
+// next advances the lexer over the byte at text[i] and returns the number
+// of bytes consumed.
+func (l *jsLexer) next(text []byte, i int) int {
+	c := text[i]
+	switch l.mode {
+	case jsModeCode:
+		return l.code(text, i)
+	case jsModeLineComment:
+		if c == '\n' || c == '\r' {
+			l.mode = jsModeCode
+			l.space = true
+		}
+		return 1
+	case jsModeBlockComment:
+		if c == '*' && i+1 < len(text) && text[i+1] == '/' {
+			l.mode = jsModeCode
+			l.space = true
+			return 2
+		}
+		return 1
+	}
+
+	switch {
+	case l.esc:
+		l.esc = false
+	case c == '\\':
+		l.esc = true
+	case l.mode == jsModeString && c == l.quote,
+		l.mode == jsModeTemplate && c == '`',
+		l.mode == jsModeRegexp && c == '/':
+		l.mode = jsModeCode
+		l.token(c)
+	case l.mode == jsModeTemplate && c == '$' && i+1 < len(text) && text[i+1] == '{':
+		l.braces = append(l.braces, 0)
+		l.mode = jsModeCode
+		l.token('{')
+		return 2
+	case l.mode == jsModeRegexp && c == '[':
+		l.mode = jsModeRegexpClass
+	case l.mode == jsModeRegexpClass && c == ']':
+		l.mode = jsModeRegexp
+	}
+	return 1
+}
+
+// code advances the lexer over the code byte at text[i].
+func (l *jsLexer) code(text []byte, i int) int {
+	c := text[i]
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		l.space = true
+	case '"', '\'':
+		l.mode = jsModeString
+		l.quote = c
+	case '`':
+		l.mode = jsModeTemplate
+	case '/':
+		if i+1 < len(text) {
+			switch text[i+1] {
+			case '/':
+				l.mode = jsModeLineComment
+				return 2
+			case '*':
+				l.mode = jsModeBlockComment
+				return 2
+			}
+		}
+		if l.regexpAllowed() {
+			l.mode = jsModeRegexp
+			return 1
+		}
+		l.token(c)
+	case '{':
+		if n := len(l.braces); n > 0 {
+			l.braces[n-1]++
+		}
+		l.token(c)
+	case '}':
+		if n := len(l.braces); n > 0 {
+			if l.braces[n-1] == 0 {
+				// end of a template literal substitution
+				l.braces = l.braces[:n-1]
+				l.mode = jsModeTemplate
+				return 1
+			}
+			l.braces[n-1]--
+		}
+		l.token(c)
+	default:
+		l.token(c)
+	}
+	return 1
+}
+
+// token records the significant code byte c.
+func (l *jsLexer) token(c byte) {
+	if isJSIdentByte(c) {
+		if !isJSIdentByte(l.prev) || l.space {
+			l.ident = l.ident[:0]
+		}
+		l.ident = append(l.ident, c)
+	}
+	if (c == '+' || c == '-') && c == l.prev && !l.space {
+		l.run++
+	} else {
+		l.run = 1
+	}
+	l.before = 0
+	if !l.space {
+		l.before = l.prev
+	}
+	l.prev = c
+	l.space = false
+}
+
+// regexpAllowed reports whether a slash at the current position starts a
+// regular expression literal.
+func (l *jsLexer) regexpAllowed() bool {
+	switch l.prev {
+	case 0:
+		return true
+	case '+', '-':
+		// "++" and "--" follow values, a single "+" or "-" doesn't
+		return l.run&1 == 1
+	case '.':
+		// "42." is a number
+		return l.before < '0' || l.before > '9'
+	case ',', '<', '>', '=', '*', '%', '&', '|', '^', '?', '!', '~',
+		'(', '[', ':', ';', '{', '}':
+		return true
+	}
+	return isJSIdentByte(l.prev) && jsRegexpKeywords[string(l.ident)]
+}
+
+func isJSIdentByte(c byte) bool {
+	return isASCIIAlnum(c) || c == '_' || c == '$' || c >= utf8.RuneSelf
+}
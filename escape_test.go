@@ -0,0 +1,374 @@
+package fasttemplate
+
+import "testing"
+
+func TestEscapeHTMLContexts(t *testing.T) {
+	data := Map{
+		"text":  `<b>"Tom" & 'Jerry'</b>`,
+		"url":   "javascript:alert(1)",
+		"link":  "https://example.com/a b",
+		"query": "a&b=c d",
+		"name":  `O'Reilly </script>`,
+		"count": 42,
+		"color": "red;}body{display:none",
+		"trust": HTML("<em>ok</em>"),
+		"attr":  "onclick",
+		"plain": "x y",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "ElementContent",
+			template: "<p>{{text}}</p>",
+			expected: "<p>&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;</p>",
+		},
+		{
+			name:     "QuotedAttribute",
+			template: `<input value="{{text}}">`,
+			expected: `<input value="&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;">`,
+		},
+		{
+			name:     "UnquotedAttribute",
+			template: `<input value={{plain}}>`,
+			expected: `<input value=x&#32;y>`,
+		},
+		{
+			name:     "UnsafeURLScheme",
+			template: `<a href="{{url}}">x</a>`,
+			expected: `<a href="#ZgotmplZ">x</a>`,
+		},
+		{
+			name:     "SafeURL",
+			template: `<a href='{{link}}'>x</a>`,
+			expected: `<a href='https://example.com/a%20b'>x</a>`,
+		},
+		{
+			name:     "URLQuery",
+			template: `<a href="/search?q={{query}}&amp;lang=en">x</a>`,
+			expected: `<a href="/search?q=a%26b%3Dc%20d&amp;lang=en">x</a>`,
+		},
+		{
+			name:     "ScriptValue",
+			template: `<script>var name = {{name}}, count = {{count}};</script>`,
+			expected: `<script>var name = "O\u0027Reilly \u003C\u002Fscript\u003E", count = 42;</script>`,
+		},
+		{
+			name:     "ScriptString",
+			template: `<script>var s = 'hi {{name}}';</script><p>{{plain}}</p>`,
+			expected: `<script>var s = 'hi O\u0027Reilly \u003C\u002Fscript\u003E';</script><p>x y</p>`,
+		},
+		{
+			name:     "EventHandler",
+			template: `<button onclick="greet({{name}})">`,
+			expected: `<button onclick="greet(&#34;O\u0027Reilly \u003C\u002Fscript\u003E&#34;)">`,
+		},
+		{
+			name:     "Style",
+			template: `<style>p { color: {{color}} }</style>`,
+			expected: `<style>p { color: red\3B \7D body\7B display\3A none }</style>`,
+		},
+		{
+			name:     "Comment",
+			template: `<!-- {{text}} -->{{plain}}`,
+			expected: `<!--  -->x y`,
+		},
+		{
+			name:     "AttributeName",
+			template: `<div {{attr}}="x" {{text}}="y">`,
+			expected: `<div onclick="x" ZgotmplZ="y">`,
+		},
+		{
+			name:     "TrustedHTML",
+			template: `<div title="{{trust}}">{{trust}}</div>`,
+			expected: `<div title="&lt;em&gt;ok&lt;/em&gt;"><em>ok</em></div>`,
+		},
+		{
+			name:     "XLinkHref",
+			template: `<svg><a xlink:href="{{url}}">x</a></svg>`,
+			expected: `<svg><a xlink:href="#ZgotmplZ">x</a></svg>`,
+		},
+		{
+			name:     "DataURLAttribute",
+			template: `<div data-src="{{url}}" data-image-url="{{url}}" data-name="{{url}}">`,
+			expected: `<div data-src="#ZgotmplZ" data-image-url="#ZgotmplZ" data-name="javascript:alert(1)">`,
+		},
+		{
+			name:     "Textarea",
+			template: `<textarea>{{text}}</textarea>`,
+			expected: `<textarea>&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;</textarea>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", WithEscaping(EscapeHTML))
+			if s := tpl.ExecuteString(data); s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeHTMLScript(t *testing.T) {
+	data := Map{
+		"v": "alert(1)",
+		"t": "${alert(1)}",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "LineComment",
+			template: "<script>// don't\nvar x = {{v}};</script>",
+			expected: "<script>// don't\nvar x = \"alert(1)\";</script>",
+		},
+		{
+			name:     "BlockComment",
+			template: "<script>/* it's */ var x = {{v}}; /* {{v}} */</script>",
+			expected: "<script>/* it's */ var x = \"alert(1)\"; /*  */</script>",
+		},
+		{
+			name:     "RegexpLiteral",
+			template: "<script>var re = /'/; var x = {{v}};</script>",
+			expected: "<script>var re = /'/; var x = \"alert(1)\";</script>",
+		},
+		{
+			name:     "RegexpClass",
+			template: "<script>var re = /[/']/g; var x = {{v}};</script>",
+			expected: "<script>var re = /[/']/g; var x = \"alert(1)\";</script>",
+		},
+		{
+			name:     "RegexpValue",
+			template: "<script>var re = /^{{v}}$/;</script>",
+			expected: "<script>var re = /^alert\\u00281\\u0029$/;</script>",
+		},
+		{
+			name:     "Division",
+			template: "<script>var x = a / 2, y = '{{v}}';</script>",
+			expected: "<script>var x = a / 2, y = 'alert(1)';</script>",
+		},
+		{
+			name:     "KeywordRegexp",
+			template: "<script>return /'/.test({{v}});</script>",
+			expected: "<script>return /'/.test(\"alert(1)\");</script>",
+		},
+		{
+			name:     "EscapedQuote",
+			template: `<script>var s = '\\'; var x = {{v}};</script>`,
+			expected: `<script>var s = '\\'; var x = "alert(1)";</script>`,
+		},
+		{
+			name:     "EventHandlerComment",
+			template: "<a onclick=\"// it's\nfoo({{v}})\">",
+			expected: "<a onclick=\"// it's\nfoo(&#34;alert(1)&#34;)\">",
+		},
+		{
+			name:     "TemplateLiteral",
+			template: "<script>var s = `{{t}}`;</script>",
+			expected: "<script>var s = `\\u0024\\u007Balert(1)\\u007D`;</script>",
+		},
+		{
+			name:     "TemplateSubstitution",
+			template: "<script>var s = `a ${ {a: 1}.a } {{v}} ${ {{v}} }`;</script>",
+			expected: "<script>var s = `a ${ {a: 1}.a } alert(1) ${ \"alert(1)\" }`;</script>",
+		},
+		{
+			name:     "EndTagInString",
+			template: "<script>var s = '</script><p>{{v}}</p>",
+			expected: "<script>var s = '</script><p>alert(1)</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", WithEscaping(EscapeHTML))
+			if s := tpl.ExecuteString(data); s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeHTMLElementName(t *testing.T) {
+	for _, src := range []string{`<{{x}} a=1>`, `<{{x}}-y>`, `<p></p><{{x}}>`} {
+		if _, err := NewTemplate(src, "{{", "}}", WithEscaping(EscapeHTML)); err == nil {
+			t.Fatalf("expected an error for %s", src)
+		}
+	}
+
+	// XML element names are filtered like attribute names
+	tpl := New(`<{{x}} a="1"/>`, "{{", "}}", WithEscaping(EscapeXML))
+	if s := tpl.ExecuteString(Map{"x": "item onload=alert(1)"}); s != `<ZgotmplZ a="1"/>` {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := tpl.ExecuteString(Map{"x": "item"}); s != `<item a="1"/>` {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestEscapeHTMLFunctionResults(t *testing.T) {
+	tpl := New("<p>{{wrap(text)}}</p><p>{{text + '!'}}</p>", "{{", "}}", WithEscaping(EscapeHTML))
+
+	s := tpl.ExecuteString(Map{
+		"text": "<i>",
+		"wrap": func(s string) string { return "[" + s + "]" },
+	})
+	if expected := "<p>[&lt;i&gt;]</p><p>&lt;i&gt;!</p>"; s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+}
+
+func TestEscapeHTMLStd(t *testing.T) {
+	tpl := New("<p>{{text}} {{unknown}}</p>", "{{", "}}", WithEscaping(EscapeHTML))
+
+	if s := tpl.ExecuteStringStd(Map{"text": "a<b"}); s != "<p>a&lt;b {{unknown}}</p>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestEscapeXMLContexts(t *testing.T) {
+	data := Map{
+		"text":  "<a> & \"b\"\x00",
+		"multi": "a\nb",
+		"code":  "if (a[b[0]]> 1) {}",
+		"trust": XML("<b/>"),
+		"html":  HTML("<b/>"),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "ElementContent",
+			template: "<soap:Body>{{text}}</soap:Body>",
+			expected: "<soap:Body>&lt;a&gt; &amp; &#34;b&#34;\uFFFD</soap:Body>",
+		},
+		{
+			name:     "Attribute",
+			template: `<m:item m:name='{{multi}}'/>`,
+			expected: `<m:item m:name='a&#xA;b'/>`,
+		},
+		{
+			name:     "NoURLAttributes",
+			template: `<link href="{{text}}"/>`,
+			expected: "<link href=\"&lt;a&gt; &amp; &#34;b&#34;\uFFFD\"/>",
+		},
+		{
+			name:     "CDATASection",
+			template: "<script><![CDATA[{{code}}]]></script><p>{{code}}</p>",
+			expected: "<script><![CDATA[if (a[b[0]]]]><![CDATA[> 1) {}]]></script><p>if (a[b[0]]&gt; 1) {}</p>",
+		},
+		{
+			name:     "CDATABuiltin",
+			template: "<code>{{cdata(code)}}</code>",
+			expected: "<code><![CDATA[if (a[b[0]]]]><![CDATA[> 1) {}]]></code>",
+		},
+		{
+			name:     "ProcessingInstruction",
+			template: `<?xml version="1.0"?><?style href="{{multi}}"?><p>{{multi}}</p>`,
+			expected: `<?xml version="1.0"?><?style href="a&#xA;b"?><p>a` + "\n" + `b</p>`,
+		},
+		{
+			name:     "Comment",
+			template: "<!-- {{text}} --><p/>",
+			expected: "<!--  --><p/>",
+		},
+		{
+			name:     "TrustedXML",
+			template: "<p>{{trust}}</p><p>{{html}}</p><p a='{{trust}}'/>",
+			expected: "<p><b/></p><p>&lt;b/&gt;</p><p a='&lt;b/&gt;'/>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", WithEscaping(EscapeXML))
+			s := tpl.ExecuteString(data)
+			if s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeNone(t *testing.T) {
+	tpl := New("<p>{{text}}</p>", "{{", "}}")
+
+	if s := tpl.ExecuteString(Map{"text": "<b>"}); s != "<p><b></p>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestEscapeHTMLBlocks(t *testing.T) {
+	data := Map{
+		"a":     false,
+		"x":     "javascript:1",
+		"link":  "javascript:alert(1)",
+		"items": []string{"<b>", "javascript:2"},
+		"user":  Map{"name": "<Ann>"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "IfElse",
+			template: `{{if a}}<a href="{{x}}">{{else}}<p>{{x}}</p>{{end}}`,
+			expected: `<p>javascript:1</p>`,
+		},
+		{
+			name:     "ElseBranchStartsAtBlock",
+			template: `<a href="{{if a}}x{{else}}{{x}}{{end}}">`,
+			expected: `<a href="#ZgotmplZ">`,
+		},
+		{
+			name:     "Range",
+			template: `{{range item in items}}<a href="{{item}}">{{item}}</a>{{end}}{{x}}`,
+			expected: `<a href="%3Cb%3E">&lt;b&gt;</a><a href="#ZgotmplZ">javascript:2</a>javascript:1`,
+		},
+		{
+			name:     "With",
+			template: `{{with user}}<p title="{{name}}">{{name}}</p>{{else}}<p>none</p>{{end}}`,
+			expected: `<p title="&lt;Ann&gt;">&lt;Ann&gt;</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := NewTemplate(tt.template, "{{", "}}", WithEscaping(EscapeHTML))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if s := tpl.ExecuteString(data); s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+
+	for _, src := range []string{
+		`{{if a}}<a href="{{else}}<p>{{end}}{{x}}`,
+		`{{if a}}<script>var s = '{{end}}{{x}}`,
+		`{{with user}}<a href="{{end}}{{x}}`,
+		`{{range item in items}}<a href="{{item}}{{end}}`,
+		`{{range item in items}}<a href="{{break}}">{{end}}`,
+	} {
+		if _, err := NewTemplate(src, "{{", "}}", WithEscaping(EscapeHTML)); err == nil {
+			t.Fatalf("expected an error for %s", src)
+		}
+	}
+	// without escaping, the contexts don't matter
+	if _, err := NewTemplate(`{{if a}}<a href="{{else}}<p>{{end}}{{x}}`, "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,34 @@
+package fasttemplate
+
+// urlLexer tracks whether tags are located at the start of a URL.
+type urlLexer struct {
+	started bool
+}
+
+func (l *urlLexer) feed(text []byte) {
+	if len(text) > 0 {
+		l.started = true
+	}
+}
+
+// context returns ctxURLStart at the start of the URL. Path segments and
+// query parameters are both escaped as URL components.
+func (l *urlLexer) context() escapeContext {
+	if !l.started {
+		return escapeContext{kind: ctxURLStart}
+	}
+	return escapeContext{kind: ctxURLQuery}
+}
+
+func (l *urlLexer) afterTag() {
+	l.started = true
+}
+
+func (l *urlLexer) clone() contextLexer {
+	c := *l
+	return &c
+}
+
+func (l *urlLexer) same(other contextLexer) bool {
+	return *l == *other.(*urlLexer)
+}
@@ -0,0 +1,44 @@
+package fasttemplate
+
+import "testing"
+
+func TestEscapeURLContexts(t *testing.T) {
+	data := Map{
+		"base": "https://api.example.com/v1",
+		"evil": "javascript:alert(1)",
+		"id":   "../admin",
+		"q":    "a b&c=d",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Start",
+			template: "{{base}}/users/{{id}}",
+			expected: "https://api.example.com/v1/users/..%2Fadmin",
+		},
+		{
+			name:     "UnsafeScheme",
+			template: "{{evil}}",
+			expected: "#ZgotmplZ",
+		},
+		{
+			name:     "Query",
+			template: "https://example.com/search?q={{q}}&page={{id}}",
+			expected: "https://example.com/search?q=a%20b%26c%3Dd&page=..%2Fadmin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", WithEscaping(EscapeURL))
+			s := tpl.ExecuteString(data)
+			if s != tt.expected {
+				t.Fatalf("unexpected output\n got: %s\nwant: %s", s, tt.expected)
+			}
+		})
+	}
+}
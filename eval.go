@@ -29,7 +29,7 @@ func Eval[T EvalType](expression string, m Map) (T, error) {
 			return zero, err
 		}
 
-		result, err := fnCall.execute(m, m)
+		result, err := fnCall.execute(m, m, nil)
 		if err != nil {
 			// Forward all errors from function execution
 			return zero, err
@@ -40,7 +40,7 @@ func Eval[T EvalType](expression string, m Map) (T, error) {
 
 	// Handle expressions
 	if isExpression(expression) {
-		result, err := evalExpression(expression, m)
+		result, err := evalExpression(expression, m, nil)
 		if err != nil {
 			return zero, err
 		}
@@ -56,6 +56,38 @@ func Eval[T EvalType](expression string, m Map) (T, error) {
 	return zero, fmt.Errorf("%w: %s", errVariableNotFound, expression)
 }
 
+// ExecuteTyped evaluates a template consisting of a single tag, e.g.
+// "{{total > 100 && premium}}", and returns the result converted to T
+// instead of its formatted text, so one parsed template serves both
+// rendering and rule evaluation.
+//
+// The tag is evaluated like Execute evaluates it, honoring the options of
+// the template such as the registry, aliases and value transformers, while
+// escaping, length limits and time layouts don't apply. Templates with text
+// around the tag, several tags or blocks are rejected.
+func ExecuteTyped[T EvalType](t *Template, m Map) (T, error) {
+	var zero T
+	if len(t.tags) != 1 || len(t.texts[0]) > 0 || len(t.texts[1]) > 0 || t.nodes != nil {
+		return zero, t.formatError(fmt.Errorf("%w: %q", errNotSingleTag, t.template))
+	}
+
+	tag := t.tags[0]
+	ec := t.newEvalContext()
+	ec.setTag(0, tag)
+	v, _, err := resolveTag(tag, m, ec)
+	if err != nil {
+		return zero, t.formatError(err)
+	}
+	for _, transform := range t.transformers {
+		v = transform(tag, v)
+	}
+	if s, ok := v.(string); ok && t.pii != nil {
+		v, _ = t.pii.redact(tag, s)
+	}
+	result, err := convertToType[T](v)
+	return result, t.formatError(err)
+}
+
 // convertToType handles converting a value to the desired type T
 func convertToType[T any](val any) (T, error) {
 	var zero T
@@ -87,7 +119,7 @@ func convertToType[T any](val any) (T, error) {
 
 	// Try with reflection as a last resort
 	valValue := reflect.ValueOf(val)
-	if valValue.Type().ConvertibleTo(targetType) {
+	if valValue.IsValid() && valValue.Type().ConvertibleTo(targetType) {
 		convertedValue := valValue.Convert(targetType)
 		return convertedValue.Interface().(T), nil
 	}
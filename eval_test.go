@@ -1,6 +1,7 @@
 package fasttemplate
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -271,3 +272,51 @@ func TestEval_TypeConversion(t *testing.T) {
 		t.Errorf("Expected true, got %v", boolFromStr)
 	}
 }
+
+func TestExecuteTyped(t *testing.T) {
+	m := Map{
+		"total":   150,
+		"premium": true,
+		"price":   2.5,
+		"qty":     4,
+		"name":    "ann",
+		"greet":   func(s string) string { return "hi " + s },
+		"nothing": func() any { return nil },
+	}
+
+	rule := New("{{total > 100 && premium}}", "{{", "}}")
+	if ok, err := ExecuteTyped[bool](rule, m); err != nil || !ok {
+		t.Fatalf("unexpected result %v, %v", ok, err)
+	}
+	if s := rule.ExecuteString(m); s != "true" {
+		t.Fatalf("the same template must render, got %q", s)
+	}
+
+	if v, err := ExecuteTyped[float64](New("{{price * qty}}", "{{", "}}"), m); err != nil || v != 10 {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+	if v, err := ExecuteTyped[int](New("{{qty}}", "{{", "}}"), m); err != nil || v != 4 {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+	if v, err := ExecuteTyped[string](New("[[greet(name)]]", "[[", "]]"), m); err != nil || v != "hi ann" {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+
+	// template options apply
+	tpl := New("{{customer}}", "{{", "}}", WithAliases(map[string]string{"customer": "name"}))
+	if v, err := ExecuteTyped[string](tpl, m); err != nil || v != "ann" {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+
+	for _, src := range []string{"total: {{total}}", "{{total}}{{qty}}", "{{if premium}}x{{end}}", "no tags"} {
+		if _, err := ExecuteTyped[int](New(src, "{{", "}}"), m); !errors.Is(err, errNotSingleTag) {
+			t.Fatalf("%q: unexpected error %v", src, err)
+		}
+	}
+	if _, err := ExecuteTyped[int](New("{{missing}}", "{{", "}}"), m); !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ExecuteTyped[uint8](New("{{nothing()}}", "{{", "}}"), m); err == nil || !strings.Contains(err.Error(), "cannot convert value of type <nil>") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tagKind describes how a tag is interpreted during execution.
+type tagKind int
+
+const (
+	tagVariable tagKind = iota
+	tagFunction
+	tagExpression
+)
+
+func (k tagKind) String() string {
+	switch k {
+	case tagFunction:
+		return "function"
+	case tagExpression:
+		return "expression"
+	default:
+		return "variable"
+	}
+}
+
+// classifyTag reports how evalTag is going to interpret the tag. literal
+// reports the tags resolved as plain variables by the template, if any.
+// Hyphenated names such as content-type are variables, as a matching key
+// wins over the subtraction.
+func classifyTag(tag string, literal func(string) bool) tagKind {
+	if isVariableTag(tag, literal) {
+		return tagVariable
+	}
+	if value, _, ok := splitDefault(tag); ok {
+		return classifyTag(value, literal)
+	}
+	if isFunctionCall(tag) {
+		return tagFunction
+	}
+	if isExpression(tag) {
+		return tagExpression
+	}
+	return tagVariable
+}
+
+// Inferred identifier types used by Explain.
+const (
+	typeAny    = "any"
+	typeNumber = "number"
+	typeString = "string"
+	typeBool   = "bool"
+)
+
+// tagAnalysis holds the identifiers and functions referenced by a tag.
+type tagAnalysis struct {
+	kind  tagKind
+	funcs []string
+	// idents keeps identifiers in order of first appearance.
+	idents []string
+	types  map[string]string
+	err    error
+}
+
+func (a *tagAnalysis) addFunc(name string) {
+	for _, f := range a.funcs {
+		if f == name {
+			return
+		}
+	}
+	a.funcs = append(a.funcs, name)
+}
+
+func (a *tagAnalysis) addIdent(name, typ string) {
+	if a.types == nil {
+		a.types = make(map[string]string)
+	}
+	cur, ok := a.types[name]
+	if !ok {
+		a.idents = append(a.idents, name)
+		a.types[name] = typ
+		return
+	}
+	if cur == typeAny {
+		a.types[name] = typ
+	}
+}
+
+// analyzeTag collects the identifiers and functions referenced by the tag
+// together with the types inferred from their usage. literal is passed to
+// classifyTag.
+func analyzeTag(tag string, literal func(string) bool) *tagAnalysis {
+	if isVariableTag(tag, literal) {
+		a := &tagAnalysis{kind: tagVariable}
+		a.addIdent(tag, typeAny)
+		return a
+	}
+	if value, def, ok := splitDefault(tag); ok {
+		return analyzeDefault(value, def, literal)
+	}
+	a := &tagAnalysis{kind: classifyTag(tag, literal)}
+	switch a.kind {
+	case tagFunction:
+		fc, err := parseFunctionCall(tag)
+		if err != nil {
+			a.err = err
+			return a
+		}
+		a.analyzeCall(fc)
+	case tagExpression:
+		a.analyzeExpression(tag)
+	default:
+		a.addIdent(strings.TrimSpace(tag), typeAny)
+	}
+	return a
+}
+
+func (a *tagAnalysis) analyzeCall(fc *functionCall) {
+	a.addFunc(fc.Name)
+	for _, arg := range fc.Args {
+		switch v := arg.(type) {
+		case string:
+			if isLikelyVariable(v) {
+				a.addIdent(v, typeAny)
+			}
+		case *functionCall:
+			a.analyzeCall(v)
+		case *expressionPlaceholder:
+			a.analyzeExpression(v.expression)
+		}
+	}
+}
+
+// operand describes a value on the postfix stack during type inference.
+type operand struct {
+	ident string
+	typ   string
+}
+
+func (a *tagAnalysis) analyzeExpression(expr string) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		a.err = err
+		return
+	}
+	postfix, err := toPostfix(tokens)
+	if err != nil {
+		a.err = err
+		return
+	}
+
+	var stack []operand
+	for _, t := range postfix {
+		switch t.typ {
+		case tokenNumber:
+			stack = append(stack, operand{typ: typeNumber})
+		case tokenString:
+			stack = append(stack, operand{typ: typeString})
+		case tokenFunctionCall:
+			if fc, err := parseFunctionCall(t.value); err == nil {
+				a.analyzeCall(fc)
+			}
+			stack = append(stack, operand{typ: typeAny})
+		case tokenIdentifier:
+			if t.value == "true" || t.value == "false" {
+				stack = append(stack, operand{typ: typeBool})
+				continue
+			}
+			if t.value == "." {
+				// the value of the enclosing block isn't a variable
+				stack = append(stack, operand{typ: typeAny})
+				continue
+			}
+			a.addIdent(t.value, typeAny)
+			stack = append(stack, operand{ident: t.value, typ: typeAny})
+		case tokenOperator:
+			if t.value == "!" {
+				if len(stack) == 0 {
+					a.err = fmt.Errorf("not enough operands for operator !")
+					return
+				}
+				if o := stack[len(stack)-1]; o.ident != "" {
+					a.addIdent(o.ident, typeBool)
+				}
+				stack[len(stack)-1] = operand{typ: typeBool}
+				continue
+			}
+			if len(stack) < 2 {
+				a.err = fmt.Errorf("not enough operands for operator %s", t.value)
+				return
+			}
+			l, r := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			stack = append(stack, operand{typ: a.inferOperator(t.value, l, r)})
+		}
+	}
+}
+
+// inferOperator records the operand types implied by op and returns the
+// type of its result.
+func (a *tagAnalysis) inferOperator(op string, l, r operand) string {
+	hint := func(o operand, typ string) {
+		if o.ident != "" && typ != typeAny {
+			a.addIdent(o.ident, typ)
+		}
+	}
+	switch op {
+	case "-", "*", "/", "%", "**":
+		hint(l, typeNumber)
+		hint(r, typeNumber)
+		return typeNumber
+	case "&&", "||":
+		hint(l, typeBool)
+		hint(r, typeBool)
+		return typeBool
+	case "+":
+		typ := l.typ
+		if typ == typeAny {
+			typ = r.typ
+		}
+		hint(l, typ)
+		hint(r, typ)
+		return typ
+	default:
+		// comparisons take the type of the other side
+		hint(l, r.typ)
+		hint(r, l.typ)
+		return typeBool
+	}
+}
+
+// Explain returns a human-readable breakdown of the parsed template.
+//
+// The output lists literal segments and tags in template order. Each tag is
+// classified as a block, variable, function call or expression, followed by
+// the functions it calls and the identifiers it references together with
+// the types inferred from how they are used.
+//
+// The format is meant for humans and may change between releases.
+func (t *Template) Explain() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "template with %d tag(s), delimiters %q and %q\n", len(t.tags), t.startTag, t.endTag)
+
+	if len(t.texts) == 0 {
+		writeLiteral(&sb, unsafeString2Bytes(t.template))
+		return sb.String()
+	}
+
+	for i, text := range t.texts {
+		writeLiteral(&sb, text)
+		if i >= len(t.tags) {
+			break
+		}
+		if t.isBlockTag(i) {
+			fmt.Fprintf(&sb, "block %s%s%s\n", t.startTag, t.tags[i], t.endTag)
+			continue
+		}
+		t.explainTag(&sb, t.tags[i])
+	}
+	return sb.String()
+}
+
+func writeLiteral(sb *strings.Builder, text []byte) {
+	if len(text) > 0 {
+		fmt.Fprintf(sb, "literal %q\n", text)
+	}
+}
+
+func (t *Template) explainTag(sb *strings.Builder, tag string) {
+	a := t.analyzeTag(tag)
+	fmt.Fprintf(sb, "%s %s%s%s\n", a.kind, t.startTag, tag, t.endTag)
+	if a.err != nil {
+		fmt.Fprintf(sb, "    error: %s\n", a.err)
+	}
+	if len(a.funcs) > 0 {
+		fmt.Fprintf(sb, "    calls: %s\n", strings.Join(a.funcs, ", "))
+	}
+	for _, id := range a.idents {
+		fmt.Fprintf(sb, "    %s: %s\n", id, a.types[id])
+	}
+}
+
+// Variables returns the names of the variables referenced by the template's
+// tags, including function arguments, expression operands, conditions of if
+// blocks, values of with and range blocks and expressions assigned by set
+// tags, in order of first appearance. Variables assigned by blocks and set
+// tags, tags inside with and range blocks, tags routed to tag handlers,
+// flush tags, the _env object and the _checksum tag are left out.
+func (t *Template) Variables() []string {
+	defined := t.blockVars()
+	seen := make(map[string]bool)
+	var names []string
+	for i, tag := range t.tags {
+		if t.isScopedTag(i) {
+			continue
+		}
+		if cond, ok := t.blockCond(i); ok {
+			// conditions of if blocks reference variables like tags
+			tag = cond
+		} else if t.isBlockTag(i) || t.isHandlerTag(tag) || (!t.legacy && isFlushTag(tag)) {
+			continue
+		}
+		for _, id := range t.analyzeTag(tag).idents {
+			if !seen[id] && !defined[id] && !t.isEnvTag(id) && !(t.checksumTag && id == checksumKey) {
+				seen[id] = true
+				names = append(names, id)
+			}
+		}
+	}
+	return names
+}
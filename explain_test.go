@@ -0,0 +1,100 @@
+package fasttemplate
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tpl := New("Hi {{name}}! Total: {{price * qty}} {{upper(first + ' ' + last)}}{{age >= 18 && active}}", "{{", "}}")
+
+	expected := `template with 4 tag(s), delimiters "{{" and "}}"
+literal "Hi "
+variable {{name}}
+    name: any
+literal "! Total: "
+expression {{price * qty}}
+    price: number
+    qty: number
+literal " "
+function {{upper(first + ' ' + last)}}
+    calls: upper
+    first: string
+    last: string
+expression {{age >= 18 && active}}
+    age: number
+    active: bool
+`
+	if s := tpl.Explain(); s != expected {
+		t.Fatalf("unexpected explanation:\n%s\nExpected:\n%s", s, expected)
+	}
+}
+
+func TestExplainClassifiesLikeExecute(t *testing.T) {
+	// hyphenated names are variables like for Execute, also as the values
+	// and defaults of tags with defaults
+	tpl := New("{{content-type}} {{x-id ?? a-b}} {{a - b}}", "{{", "}}")
+
+	expected := `template with 3 tag(s), delimiters "{{" and "}}"
+variable {{content-type}}
+    content-type: any
+literal " "
+variable {{x-id ?? a-b}}
+    x-id: any
+    a-b: any
+literal " "
+expression {{a - b}}
+    a: number
+    b: number
+`
+	if s := tpl.Explain(); s != expected {
+		t.Fatalf("unexpected explanation:\n%s\nExpected:\n%s", s, expected)
+	}
+
+	// literal tags are honored inside defaults too
+	tpl = New("{{a*b ?? c}}", "{{", "}}", WithLiteralTags(regexp.MustCompile(`^a\*b$`)))
+	expected = `template with 1 tag(s), delimiters "{{" and "}}"
+variable {{a*b ?? c}}
+    a*b: any
+    c: any
+`
+	if s := tpl.Explain(); s != expected {
+		t.Fatalf("unexpected explanation:\n%s\nExpected:\n%s", s, expected)
+	}
+	if s := tpl.ExecuteString(Map{"a*b": "x", "a": 2, "b": 3}); s != "x" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestExplainNoTags(t *testing.T) {
+	tpl := New("plain text", "{{", "}}")
+
+	expected := "template with 0 tag(s), delimiters \"{{\" and \"}}\"\nliteral \"plain text\"\n"
+	if s := tpl.Explain(); s != expected {
+		t.Fatalf("unexpected explanation %q. Expected %q", s, expected)
+	}
+}
+
+func TestExplainInvalidTag(t *testing.T) {
+	tpl := New("{{(1 + 2}}", "{{", "}}")
+
+	expected := "template with 1 tag(s), delimiters \"{{\" and \"}}\"\nexpression {{(1 + 2}}\n    error: mismatched parentheses\n"
+	if s := tpl.Explain(); s != expected {
+		t.Fatalf("unexpected explanation %q. Expected %q", s, expected)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tpl := New("{{capture total}}{{price * qty}}{{end}}{{name}} {{upper(first + ' ' + name)}} {{total}} {{ price }}", "{{", "}}")
+
+	expected := []string{"price", "qty", "name", "first"}
+	got := tpl.Variables()
+	if len(got) != len(expected) {
+		t.Fatalf("unexpected variables %q. Expected %q", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("unexpected variables %q. Expected %q", got, expected)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package fasttemplate
+
+import "sync"
+
+// ExprEngine compiles expressions, letting another expression language such
+// as expr-lang/expr or cel-go replace the built-in evaluator:
+//
+//	type exprEngine struct{}
+//
+//	func (exprEngine) Compile(s string) (fasttemplate.CompiledExpr, error) {
+//		program, err := expr.Compile(s)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return compiled{program}, nil
+//	}
+//
+// Engines must be safe for concurrent use.
+type ExprEngine interface {
+	Compile(expr string) (CompiledExpr, error)
+}
+
+// CompiledExpr is an expression compiled by an ExprEngine. It must be safe
+// for concurrent use.
+type CompiledExpr interface {
+	Eval(m Map) (any, error)
+}
+
+// WithExprEngine delegates the evaluation of expressions to engine: tags
+// classified as expressions, expressions passed as function arguments and
+// lambda bodies. Tags are still split into variables, function calls and
+// expressions by fasttemplate, and function calls keep being executed by
+// it. Every expression is compiled once and the compiled form is cached by
+// the template.
+//
+// Engines receive the substitution map as is, with the parameters of the
+// lambda being evaluated added; aliases and case-insensitive keys don't
+// apply. Analyze, Explain and Variables keep using the built-in parser.
+func WithExprEngine(engine ExprEngine) Option {
+	return func(t *Template) {
+		t.exprEngine = &exprEngine{engine: engine}
+	}
+}
+
+// exprEngine caches the expressions compiled by an ExprEngine.
+type exprEngine struct {
+	engine   ExprEngine
+	compiled sync.Map // map[string]CompiledExpr
+}
+
+// eval evaluates the expression with the engine.
+func (e *exprEngine) eval(expr string, data Map, ec *evalContext) (any, error) {
+	c, ok := e.compiled.Load(expr)
+	if !ok {
+		compiled, err := e.engine.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		c, _ = e.compiled.LoadOrStore(expr, compiled)
+	}
+	if len(ec.params) > 0 {
+		scoped := make(Map, len(data)+len(ec.params))
+		for k, v := range data {
+			scoped[k] = v
+		}
+		for k, v := range ec.params {
+			scoped[k] = v
+		}
+		data = scoped
+	}
+	return c.(CompiledExpr).Eval(data)
+}
@@ -0,0 +1,62 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// concatEngine evaluates "a + b" by concatenating the operands.
+type concatEngine struct {
+	compiles atomic.Int32
+}
+
+type concatExpr []string
+
+func (e *concatEngine) Compile(expr string) (CompiledExpr, error) {
+	e.compiles.Add(1)
+	if !strings.Contains(expr, " + ") {
+		return nil, errors.New("unsupported expression " + expr)
+	}
+	return concatExpr(strings.Split(expr, " + ")), nil
+}
+
+func (c concatExpr) Eval(m Map) (any, error) {
+	var sb strings.Builder
+	for _, operand := range c {
+		v, ok := m[operand]
+		if !ok {
+			return nil, fmt.Errorf("unknown operand %s", operand)
+		}
+		fmt.Fprint(&sb, v)
+	}
+	return sb.String(), nil
+}
+
+func TestWithExprEngine(t *testing.T) {
+	engine := &concatEngine{}
+	tpl := New("{{a + b}} {{upper(a + c)}} {{map(items, x => x + a)}}", "{{", "}}", WithExprEngine(engine))
+	m := Map{"a": 1, "b": 2, "c": "x", "items": []string{"p", "q"}}
+	for i := 0; i < 2; i++ {
+		if s := tpl.ExecuteString(m); s != "12 1X [p1 q1]" {
+			t.Fatalf("unexpected output %q", s)
+		}
+	}
+	if n := engine.compiles.Load(); n != 3 {
+		t.Fatalf("expected every expression to be compiled once, got %d compiles", n)
+	}
+
+	var bb bytes.Buffer
+	_, err := New("{{a * b}}", "{{", "}}", WithExprEngine(engine)).Execute(&bb, m)
+	if err == nil || !strings.Contains(err.Error(), "unsupported expression a * b") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// without an engine, the built-in evaluator adds numbers
+	if s := New("{{a + b}}", "{{", "}}").ExecuteString(m); s != "3" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -2,6 +2,8 @@ package fasttemplate
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,6 +29,7 @@ var operators = map[string]int{
 	"+": 5, "-": 5,
 	"*": 6, "/": 6, "%": 6,
 	"**": 7, // Power operator
+	"!":  8, // Unary negation
 }
 
 // Common operators for quick detection (single char)
@@ -101,43 +104,63 @@ func isExpression(tag string) bool {
 }
 
 // evalExpression evaluates an expression and returns the result
-func evalExpression(expression string, data Map) (interface{}, error) {
+func evalExpression(expression string, data Map, ec *evalContext) (interface{}, error) {
 	// check if it's a simple function call that doesn't need tokenization
 	if isFunctionCall(expression) {
-		funcCall, err := parseFunctionCall(expression)
+		funcCall, err := parseFunctionCallCached(expression)
 		if err != nil {
 			return nil, err
 		}
-		result, err := funcCall.execute(data, data)
+		result, err := funcCall.execute(data, data, ec)
 		if err != nil {
 			return nil, err
 		}
 		return result, nil
 	}
+	if ec != nil && ec.exprEngine != nil {
+		return ec.exprEngine.eval(expression, data, ec)
+	}
+
+	postfixTokens, err := compileExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evaluate the postfix expression
+	return evaluatePostfix(postfixTokens, data, ec)
+}
 
+// compileExpression returns the postfix tokens of the expression, parsing it
+// unless it is cached.
+func compileExpression(expression string) ([]token, error) {
 	exprCache.mu.RLock()
 	postfixTokens, found := exprCache.postfix[expression]
 	exprCache.mu.RUnlock()
+	if found {
+		return postfixTokens, nil
+	}
 
-	if !found {
-		tokens, err := tokenize(expression)
-		if err != nil {
-			return nil, err
-		}
-
-		postfixTokens, err = toPostfix(tokens)
-		if err != nil {
-			return nil, err
-		}
+	// the expression may be a substring of a template source, which the
+	// cache must not keep alive
+	expression = strings.Clone(expression)
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
 
-		// Store in cache
-		exprCache.mu.Lock()
-		exprCache.postfix[expression] = postfixTokens
-		exprCache.mu.Unlock()
+	postfixTokens, err = toPostfix(tokens)
+	if err != nil {
+		return nil, err
 	}
 
-	// Evaluate the postfix expression
-	return evaluatePostfix(postfixTokens, data)
+	// Store in cache
+	exprCache.mu.Lock()
+	if len(exprCache.postfix) >= maxParseCacheSize {
+		exprCache.postfix = make(map[string][]token)
+	}
+	exprCache.postfix[expression] = postfixTokens
+	exprCache.mu.Unlock()
+	return postfixTokens, nil
 }
 
 // Token types
@@ -192,6 +215,11 @@ var tokenPool = sync.Pool{
 	},
 }
 
+// isIdentStart reports whether c may start an identifier.
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
 // tokenize converts a string expression into tokens
 func tokenize(expr string) ([]token, error) {
 	// Get token slice from pool
@@ -218,7 +246,7 @@ func tokenize(expr string) ([]token, error) {
 		}
 
 		// Handle identifiers and function calls (variable names) - fast path
-		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' {
+		if isIdentStart(c) {
 			start := i
 			i++
 			// Fast scan for identifier chars
@@ -227,6 +255,9 @@ func tokenize(expr string) ([]token, error) {
 				if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
 					(ch >= '0' && ch <= '9') || ch == '_' {
 					i++
+				} else if ch == '.' && i+1 < len(expr) && isIdentStart(expr[i+1]) {
+					// dotted path, e.g. user.name
+					i++
 				} else {
 					break
 				}
@@ -279,6 +310,13 @@ func tokenize(expr string) ([]token, error) {
 			continue
 		}
 
+		// A lone dot is the value of the enclosing with or range block
+		if c == '.' && (i+1 == len(expr) || (!isIdentByte(expr[i+1], false) && expr[i+1] != '.')) {
+			tokens = append(tokens, token{tokenIdentifier, "."})
+			i++
+			continue
+		}
+
 		// Handle strings - optimized path
 		if c == '"' || c == '\'' {
 			quote := c
@@ -350,10 +388,26 @@ func toPostfix(infix []token) ([]token, error) {
 	output := make([]token, 0, len(infix))
 	stack := make([]token, 0, len(infix)/2)
 
-	for _, t := range infix {
+	for i, t := range infix {
 		switch t.typ {
 		case tokenNumber, tokenString, tokenIdentifier, tokenFunctionCall:
 			output = append(output, t)
+		case tokenOperator:
+			if t.value == "!" {
+				// negation is a prefix operator, so it can only follow
+				// the start, an operator or an opening parenthesis
+				if i > 0 && infix[i-1].typ != tokenOperator && infix[i-1].typ != tokenLeftParen {
+					return nil, fmt.Errorf("unexpected operator !")
+				}
+				stack = append(stack, t)
+				continue
+			}
+			for len(stack) > 0 && stack[len(stack)-1].typ == tokenOperator &&
+				operators[stack[len(stack)-1].value] >= operators[t.value] {
+				output = append(output, stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, t)
 		case tokenLeftParen:
 			stack = append(stack, t)
 		case tokenRightParen:
@@ -366,13 +420,6 @@ func toPostfix(infix []token) ([]token, error) {
 			}
 			// Pop the left parenthesis
 			stack = stack[:len(stack)-1]
-		case tokenOperator:
-			for len(stack) > 0 && stack[len(stack)-1].typ == tokenOperator &&
-				operators[stack[len(stack)-1].value] >= operators[t.value] {
-				output = append(output, stack[len(stack)-1])
-				stack = stack[:len(stack)-1]
-			}
-			stack = append(stack, t)
 		}
 	}
 
@@ -388,13 +435,15 @@ func toPostfix(infix []token) ([]token, error) {
 }
 
 // evaluatePostfix evaluates a postfix expression with variable substitution
-func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
+func evaluatePostfix(postfix []token, data Map, ec *evalContext) (interface{}, error) {
 	// pre-alloc stack with reasonable capacity based on postfix length
 	stackCapacity := len(postfix) / 2
 	if stackCapacity < 4 {
 		stackCapacity = 4
 	}
 	stack := make([]interface{}, 0, stackCapacity)
+	// literals has the bits of the stack positions holding string literals
+	var literals uint64
 
 	for _, t := range postfix {
 		switch t.typ {
@@ -439,17 +488,20 @@ func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
 				}
 			}
 
+			if len(stack) < 64 {
+				literals |= 1 << len(stack)
+			}
 			stack = append(stack, s)
 
 		case tokenFunctionCall:
 			// Parse and execute the function call
-			funcCall, err := parseFunctionCall(t.value)
+			funcCall, err := parseFunctionCallCached(t.value)
 			if err != nil {
 				return nil, err
 			}
 
 			// Execute the function with access to all data
-			result, err := funcCall.execute(data, data)
+			result, err := funcCall.execute(data, data, ec)
 			if err != nil {
 				return nil, err
 			}
@@ -458,13 +510,18 @@ func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
 
 		case tokenIdentifier:
 			// Variable lookup optimization
-			val, ok := data[t.value]
+			val, ok := ec.lookup(data, t.value)
+			if !ok && (t.value == "true" || t.value == "false") {
+				stack = append(stack, t.value == "true")
+				continue
+			}
 			if !ok {
-				// it looks like a variable
-				if isLikelyVariable(t.value) {
+				// unknown identifiers are only literals with
+				// WithLiteralFallback, as they are usually typos of
+				// variable names
+				if ec == nil || !ec.literalFallback || isLikelyVariable(t.value) {
 					return nil, fmt.Errorf("%w: %s", errVariableNotFound, t.value)
 				}
-				// otherwise, use as string literal
 				stack = append(stack, t.value)
 				continue
 			}
@@ -474,6 +531,7 @@ func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
 				// Apply function to top of stack if it's a string
 				if argVal, isStr := stack[len(stack)-1].(string); isStr {
 					stack[len(stack)-1] = funcVal(argVal)
+					literals &^= 1 << (len(stack) - 1)
 					continue
 				}
 			}
@@ -482,6 +540,14 @@ func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
 			stack = append(stack, val)
 
 		case tokenOperator:
+			if t.value == "!" {
+				if len(stack) == 0 {
+					return nil, fmt.Errorf("not enough operands for operator !")
+				}
+				stack[len(stack)-1] = !toBool(stack[len(stack)-1])
+				literals &^= 1 << (len(stack) - 1)
+				continue
+			}
 			// Error check for stack underflow
 			if len(stack) < 2 {
 				return nil, fmt.Errorf("not enough operands for operator %s", t.value)
@@ -491,9 +557,17 @@ func evaluatePostfix(postfix []token, data Map) (interface{}, error) {
 			b := stack[len(stack)-1]
 			a := stack[len(stack)-2]
 			stack = stack[:len(stack)-2] // Reduce stack
+			aLit, bLit := literals&(1<<len(stack)) != 0, literals&(1<<(len(stack)+1)) != 0
+			literals &^= 3 << len(stack)
 
-			// Apply operator (which already handles type conversion)
-			result, err := applyOperator(t.value, a, b)
+			var result any
+			var err error
+			if ec.hasStringOperators() && (aLit || bLit) && (t.value == "*" || t.value == "%") {
+				result, err = applyStringOperator(t.value, a, b, aLit, bLit)
+			} else {
+				// Apply operator (which already handles type conversion)
+				result, err = applyOperator(t.value, a, b)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -607,8 +681,131 @@ func applyOperator(op string, a, b interface{}) (interface{}, error) {
 	}
 }
 
+// applyStringOperator applies the Python-style string operators enabled by
+// WithStringOperators: "-" * 20 repeats a string literal and "%05d" % id
+// formats operands with a string literal holding verbs. aLit and bLit report
+// whether a and b are string literals. Other operands are handled by
+// applyOperator.
+func applyStringOperator(op string, a, b any, aLit, bLit bool) (any, error) {
+	if op == "*" {
+		if s, ok := a.(string); ok && aLit && isNumeric(b) {
+			return repeatString(s, b)
+		}
+		if s, ok := b.(string); ok && bLit && isNumeric(a) {
+			return repeatString(s, a)
+		}
+		return applyOperator(op, a, b)
+	}
+
+	format, ok := a.(string)
+	if !ok || !aLit || !strings.Contains(format, "%") {
+		return applyOperator(op, a, b)
+	}
+	args, ok := b.([]any)
+	if !ok {
+		args = []any{b}
+	}
+	if err := checkFormatArgs(format, args); err != nil {
+		return nil, fmt.Errorf("cannot format %v with %q: %w", b, format, err)
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+// checkFormatArgs reports whether args match the verbs of format one to
+// one. Explicit argument indexes and * widths aren't supported.
+func checkFormatArgs(format string, args []any) error {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return fmt.Errorf("missing verb at end of format")
+		}
+		verb := format[i]
+		switch {
+		case verb == '%':
+			continue
+		case verb == '*' || verb == '[':
+			return fmt.Errorf("argument indexes and * widths are not supported")
+		case n == len(args):
+			return fmt.Errorf("missing operand for %%%c", verb)
+		case !verbAccepts(verb, args[n]):
+			return fmt.Errorf("%%%c does not format %T", verb, args[n])
+		}
+		n++
+	}
+	if n < len(args) {
+		return fmt.Errorf("%d operands for %d verbs", len(args), n)
+	}
+	return nil
+}
+
+// verbAccepts reports whether the fmt verb formats v without a
+// "%!verb(...)" error.
+func verbAccepts(verb byte, v any) bool {
+	switch v.(type) {
+	case fmt.Formatter:
+		return true
+	case fmt.Stringer, error:
+		if verb == 's' || verb == 'q' || verb == 'x' || verb == 'X' {
+			return true
+		}
+	}
+	var kind reflect.Kind
+	if v != nil {
+		kind = reflect.TypeOf(v).Kind()
+	}
+	isInt := kind >= reflect.Int && kind <= reflect.Uintptr
+	isFloat := kind >= reflect.Float32 && kind <= reflect.Complex128
+	isString := kind == reflect.String || (kind == reflect.Slice && reflect.TypeOf(v).Elem().Kind() == reflect.Uint8)
+	switch verb {
+	case 'v', 'T':
+		return true
+	case 't':
+		return kind == reflect.Bool
+	case 'd', 'o', 'O', 'c', 'U':
+		return isInt
+	case 'b':
+		return isInt || isFloat
+	case 'x', 'X':
+		return isInt || isFloat || isString
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return isFloat
+	case 's':
+		return isString
+	case 'q':
+		return isString || isInt
+	case 'p':
+		return kind == reflect.Pointer || kind == reflect.Map || kind == reflect.Slice ||
+			kind == reflect.Func || kind == reflect.Chan || kind == reflect.UnsafePointer
+	}
+	return false
+}
+
+// maxRepeatLen is the maximum length of a string repeated with the string
+// "*" operator. Longer results fail instead of allocating without bound.
+const maxRepeatLen = 1 << 20
+
 // Helper functions for type conversion
 
+// repeatString returns s repeated n times. n must be a non-negative
+// integer and the result at most maxRepeatLen bytes long.
+func repeatString(s string, n any) (string, error) {
+	f := toFloat64(n)
+	if f < 0 || f != math.Trunc(f) {
+		return "", fmt.Errorf("cannot repeat a string %v times", n)
+	}
+	if len(s) > 0 && f > float64(maxRepeatLen/len(s)) {
+		return "", fmt.Errorf("repeated string longer than %d bytes", maxRepeatLen)
+	}
+	return strings.Repeat(s, int(f)), nil
+}
+
 func isNumeric(v interface{}) bool {
 	switch v.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
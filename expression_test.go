@@ -89,6 +89,11 @@ func TestSimpleExpressions(t *testing.T) {
 			{"and false", "{{true && false}}", "false"},
 			{"or true", "{{true || false}}", "true"},
 			{"or false", "{{false || false}}", "false"},
+			{"not", "{{!true}}", "false"},
+			{"not not", "{{!!true}}", "true"},
+			{"not binds tighter", "{{!false && false}}", "false"},
+			{"not group", "{{!(1 > 2) && true}}", "true"},
+			{"not string", "{{!''}}", "true"},
 		}
 
 		for _, tc := range testCases {
@@ -400,6 +405,8 @@ func TestErrorHandling(t *testing.T) {
 		}{
 			{"division by zero", "{{1 / 0}}"},
 			{"mismatched parentheses", "{{(1 + 2}}"},
+			{"not after operand", "{{true ! false}}"},
+			{"not without operand", "{{!}}"},
 		}
 
 		for _, tc := range testCases {
@@ -443,3 +450,60 @@ func TestExampleFromReadme(t *testing.T) {
 		t.Errorf("Expected %q, got %q", expected, result)
 	}
 }
+
+func TestStringOperators(t *testing.T) {
+	m := Map{"id": 42, "pair": []any{"a", 1}, "price": 1.5, "n": 3, "name": "hi", "num": "4"}
+	tests := map[string]string{
+		`{{"-" * 5}}`:        "-----",
+		`{{3 * 'ab'}}`:       "ababab",
+		`{{'=' * n}}`:        "===",
+		`{{'x' * 0}}`:        "",
+		`{{name + '!' * 2}}`: "hi!!",
+		`{{"%05d" % id}}`:    "00042",
+		`{{'%s=%d' % pair}}`: "a=1",
+		`{{'%.2f' % price}}`: "1.50",
+		`{{id % 5}}`:         "2",
+	}
+	render := func(src string, opts ...Option) (string, error) {
+		var sb strings.Builder
+		_, err := New(src, "{{", "}}", opts...).Execute(&sb, m)
+		return sb.String(), err
+	}
+	for src, want := range tests {
+		s, err := render(src, WithStringOperators())
+		if err != nil || s != want {
+			t.Fatalf("unexpected result of %s: %q, %v, want %q", src, s, err, want)
+		}
+		if _, err := render(src); err == nil && src != `{{id % 5}}` {
+			t.Fatalf("expected an error for %s without WithStringOperators", src)
+		}
+	}
+
+	// string values of variables are operands, not formats
+	m["n"], m["price"] = "10", "2.5"
+	for _, src := range []string{
+		`{{'x' * -1}}`, `{{'x' * 1.5}}`, `{{'x' * 'y'}}`,
+		`{{n % 3}}`, `{{price * 2}}`, `{{num * 2}}`, `{{'%d' % name}}`, `{{'%d %d' % id}}`,
+	} {
+		if s, err := render(src, WithStringOperators()); err == nil {
+			t.Fatalf("expected an error for %s, got %q", src, s)
+		}
+	}
+	if s, err := render(`{{'100%' + '!' * 1}}`, WithStringOperators()); err != nil || s != "100%!" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+
+	// values looking like fmt errors are formatted as is
+	m["bang"] = "100%!"
+	if s, err := render(`{{'%s' % bang}}`, WithStringOperators()); err != nil || s != "100%!" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+
+	// repeating is bounded instead of exhausting memory
+	m["huge"] = 1e15
+	for _, src := range []string{`{{'x' * 1000000000000000}}`, `{{'ab' * huge}}`} {
+		if s, err := render(src, WithStringOperators()); err == nil {
+			t.Fatalf("expected an error for %s, got %d bytes", src, len(s))
+		}
+	}
+}
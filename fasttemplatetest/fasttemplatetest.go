@@ -0,0 +1,179 @@
+// Package fasttemplatetest provides helpers for testing fasttemplate
+// templates: golden file assertions, fuzzing templates across generated
+// data and checking that two template versions render identically.
+//
+// Golden files are rewritten instead of compared when tests run with the
+// FASTTEMPLATE_UPDATE environment variable set to a true value:
+//
+//	FASTTEMPLATE_UPDATE=1 go test ./...
+package fasttemplatetest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// UpdateEnv is the environment variable enabling the update of golden
+// files. The package doesn't register a flag, as test binaries commonly
+// define their own -update flag.
+const UpdateEnv = "FASTTEMPLATE_UPDATE"
+
+// updating reports whether golden files are updated.
+func updating() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return update
+}
+
+// TB is the subset of testing.TB used by the helpers.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// AssertGolden renders t with m and compares the output with the content of
+// the golden file at path. With FASTTEMPLATE_UPDATE set, the golden file is
+// written instead.
+func AssertGolden(tb TB, t *fasttemplate.Template, m fasttemplate.Map, path string) {
+	tb.Helper()
+	got, err := render(t, m)
+	if err != nil {
+		tb.Fatalf("cannot render template for %s: %s", path, err)
+		return
+	}
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("cannot create directory for golden file: %s", err)
+			return
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("cannot update golden file: %s", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("cannot read golden file (run with %s=1 to create it): %s", UpdateEnv, err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		tb.Errorf("output differs from golden file %s\n%s", path, diff(string(want), string(got)))
+	}
+}
+
+// AssertSameOutput renders both templates with every Map of corpus and
+// reports the Maps they render differently or fail for, e.g. to check that
+// a rewritten template is equivalent to the old one.
+func AssertSameOutput(tb TB, before, after *fasttemplate.Template, corpus []fasttemplate.Map) {
+	tb.Helper()
+	for i, m := range corpus {
+		want, err := render(before, m)
+		if err != nil {
+			tb.Errorf("corpus[%d]: old template: %s", i, err)
+			continue
+		}
+		got, err := render(after, m)
+		if err != nil {
+			tb.Errorf("corpus[%d]: new template: %s", i, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			tb.Errorf("corpus[%d]: outputs differ\n%s", i, diff(string(want), string(got)))
+		}
+	}
+}
+
+// Fuzz renders t with n Maps generated by gen from a pseudo-random source
+// seeded with seed and reports the Maps failing to render or failing check,
+// if not nil. Failures report the seed and iteration, so they can be
+// reproduced.
+func Fuzz(tb TB, t *fasttemplate.Template, seed int64, n int, gen func(r *rand.Rand) fasttemplate.Map, check func(m fasttemplate.Map, out string) error) {
+	tb.Helper()
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		m := gen(r)
+		out, err := render(t, m)
+		if err == nil && check != nil {
+			err = check(m, string(out))
+		}
+		if err != nil {
+			tb.Errorf("seed %d, iteration %d: %s\nmap: %v", seed, i, err, m)
+		}
+	}
+}
+
+// RandomMap returns a generator for Fuzz producing Maps with the given keys
+// set to random strings, numbers, booleans or nil.
+func RandomMap(keys ...string) func(r *rand.Rand) fasttemplate.Map {
+	return func(r *rand.Rand) fasttemplate.Map {
+		m := make(fasttemplate.Map, len(keys))
+		for _, key := range keys {
+			m[key] = randomValue(r)
+		}
+		return m
+	}
+}
+
+// fuzzRunes are the characters of random strings, including characters
+// with special meaning in common output formats.
+const fuzzRunes = "abcXYZ019 \t\n<>&'\"{}\\/%éü€😀"
+
+func randomValue(r *rand.Rand) any {
+	switch r.Intn(5) {
+	case 0:
+		return r.Intn(2000) - 1000
+	case 1:
+		return r.NormFloat64() * 1000
+	case 2:
+		return r.Intn(2) == 0
+	case 3:
+		return nil
+	}
+	runes := []rune(fuzzRunes)
+	s := make([]rune, r.Intn(16))
+	for i := range s {
+		s[i] = runes[r.Intn(len(runes))]
+	}
+	return string(s)
+}
+
+// render executes t with m.
+func render(t *fasttemplate.Template, m fasttemplate.Map) (out []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	var bb bytes.Buffer
+	if _, err := t.Execute(&bb, m); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
+
+// diff describes the first line differing between want and got.
+func diff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g || i >= len(wl) || i >= len(gl) {
+			return fmt.Sprintf("line %d:\n-%q\n+%q", i+1, w, g)
+		}
+	}
+	return ""
+}
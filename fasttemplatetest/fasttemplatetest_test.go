@@ -0,0 +1,117 @@
+package fasttemplatetest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// test binaries commonly define their own -update flag, which must not
+// clash with the package
+var _ = flag.Bool("update", false, "update golden files")
+
+// recorder is a TB recording failures.
+type recorder struct {
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "testdata", "greeting.golden")
+	tpl := fasttemplate.New("Hi {{name}}\nBye {{name}}", "{{", "}}")
+
+	var r recorder
+	AssertGolden(&r, tpl, fasttemplate.Map{"name": "Ann"}, path)
+	if !r.fatal || !strings.Contains(r.errors[0], UpdateEnv+"=1") {
+		t.Fatalf("expected missing golden file error; got %q", r.errors)
+	}
+
+	t.Setenv(UpdateEnv, "true")
+	r = recorder{}
+	AssertGolden(&r, tpl, fasttemplate.Map{"name": "Ann"}, path)
+	os.Unsetenv(UpdateEnv)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "Hi Ann\nBye Ann" {
+		t.Fatalf("unexpected golden file %q", b)
+	}
+
+	r = recorder{}
+	AssertGolden(&r, tpl, fasttemplate.Map{"name": "Ann"}, path)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+
+	r = recorder{}
+	AssertGolden(&r, tpl, fasttemplate.Map{"name": "Bob"}, path)
+	if len(r.errors) != 1 || r.fatal || !strings.Contains(r.errors[0], "line 1:\n-\"Hi Ann\"\n+\"Hi Bob\"") {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+}
+
+func TestAssertSameOutput(t *testing.T) {
+	old := fasttemplate.New("{{a}}-{{b}}", "{{", "}}")
+	rewritten := fasttemplate.New("{{a + '-' + b}}", "{{", "}}")
+	corpus := []fasttemplate.Map{
+		{"a": "x", "b": "y"},
+		{"a": 1, "b": 2},
+		{"a": "x", "b": nil},
+	}
+
+	var r recorder
+	AssertSameOutput(&r, old, rewritten, corpus)
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "corpus[2]") {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+}
+
+func TestFuzz(t *testing.T) {
+	tpl := fasttemplate.New("<p>{{a}}{{b}}</p>", "{{", "}}", fasttemplate.WithEscaping(fasttemplate.EscapeHTML))
+	noMarkup := func(m fasttemplate.Map, out string) error {
+		if strings.Count(out, "<") != 2 {
+			return errors.New("unescaped markup")
+		}
+		return nil
+	}
+
+	var r recorder
+	Fuzz(&r, tpl, 1, 200, RandomMap("a", "b"), noMarkup)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+
+	raw := fasttemplate.New("<p>{{a}}{{b}}</p>", "{{", "}}")
+	Fuzz(&r, raw, 1, 200, RandomMap("a", "b"), noMarkup)
+	if len(r.errors) == 0 || !strings.HasPrefix(r.errors[0], "seed 1, iteration ") {
+		t.Fatalf("expected failures; got %q", r.errors)
+	}
+
+	panicky := fasttemplate.New("{{f()}}", "{{", "}}")
+	r = recorder{}
+	Fuzz(&r, panicky, 1, 1, func(*rand.Rand) fasttemplate.Map {
+		return fasttemplate.Map{"f": func() string { panic("boom") }}
+	}, nil)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "boom") {
+		t.Fatalf("unexpected errors %q", r.errors)
+	}
+}
@@ -0,0 +1,97 @@
+package fasttemplate
+
+import (
+	"io"
+	"strings"
+)
+
+// keywordFlush is the tag flushing the output written so far.
+const keywordFlush = "flush"
+
+// isFlushTag reports whether the tag is a flush tag.
+func isFlushTag(tag string) bool {
+	return len(tag) >= len(keywordFlush) && strings.TrimSpace(tag) == keywordFlush
+}
+
+// isFlushHint reports whether the tag flushes the output when executed with
+// m. Like end outside of blocks, a flush tag is a regular variable if m has
+// a flush key, so templates using it as a variable keep working.
+func isFlushHint(tag string, m Map) bool {
+	if !isFlushTag(tag) {
+		return false
+	}
+	_, isVar := m[keywordFlush]
+	return !isVar
+}
+
+// isFlushHint works like the package-level isFlushHint, but honors
+// WithLegacyBehavior.
+func (t *Template) isFlushHint(tag string, m Map) bool {
+	return !t.legacy && isFlushHint(tag, m)
+}
+
+// flushWriter flushes w if it implements Flush, like http.Flusher and
+// bufio.Writer do, and does nothing otherwise.
+func flushWriter(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// canFlush reports whether w implements Flush.
+func canFlush(w io.Writer) bool {
+	switch w.(type) {
+	case interface{ Flush() error }, interface{ Flush() }:
+		return true
+	}
+	return false
+}
+
+// WithFlushEvery flushes the writer passed to Execute every time at least n
+// bytes have been written to it since the last flush, if it implements Flush
+// like http.Flusher and bufio.Writer do. Server-sent events and chunked
+// responses then show the output of large renders progressively.
+//
+// Flushes can also be requested at specific points of the template with
+// {{flush}} tags, which work without the option.
+func WithFlushEvery(n int) Option {
+	return func(t *Template) {
+		t.flushEvery = n
+	}
+}
+
+// flushingWriter flushes the underlying writer every time at least every
+// bytes have been written since the last flush.
+type flushingWriter struct {
+	w       io.Writer
+	every   int
+	pending int
+}
+
+func (fw *flushingWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.pending += n
+	if err == nil && fw.pending >= fw.every {
+		err = fw.Flush()
+	}
+	return n, err
+}
+
+// Flush flushes the underlying writer.
+func (fw *flushingWriter) Flush() error {
+	fw.pending = 0
+	return flushWriter(fw.w)
+}
+
+// flushing returns w flushing automatically as set with WithFlushEvery, or
+// w itself if it can't be flushed.
+func (t *Template) flushing(w io.Writer) io.Writer {
+	if t.flushEvery <= 0 || !canFlush(w) {
+		return w
+	}
+	return &flushingWriter{w: w, every: t.flushEvery}
+}
@@ -0,0 +1,95 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// flushRecorder records the length of the output at every flush.
+type flushRecorder struct {
+	bytes.Buffer
+	flushes []int
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.flushes = append(fr.flushes, fr.Len())
+}
+
+// failingFlusher fails every flush, like bufio.Writer on write errors.
+type failingFlusher struct {
+	bytes.Buffer
+}
+
+var errFlush = errors.New("flush failed")
+
+func (ff *failingFlusher) Flush() error {
+	return errFlush
+}
+
+func TestFlushTags(t *testing.T) {
+	tpl := New("a{{flush}}b{{ flush }}{{range x in xs}}{{x}}{{flush}}{{end}}", "{{", "}}")
+	m := Map{"xs": []string{"c", "d"}}
+	var fr flushRecorder
+	if _, err := tpl.Execute(&fr, m); err != nil || fr.String() != "abcd" || !equalInts(fr.flushes, []int{1, 2, 3, 4}) {
+		t.Fatalf("unexpected result %q, flushes %v, %v", fr.String(), fr.flushes, err)
+	}
+
+	flat := New("a{{flush}}b{{x}}", "{{", "}}")
+	fr = flushRecorder{}
+	if _, err := flat.ExecuteAsync(&fr, Map{"x": "c"}); err != nil || fr.String() != "abc" || !equalInts(fr.flushes, []int{1}) {
+		t.Fatalf("unexpected result %q, flushes %v, %v", fr.String(), fr.flushes, err)
+	}
+
+	// flush is a regular variable if the map has it
+	fr = flushRecorder{}
+	if _, err := flat.Execute(&fr, Map{"flush": "!", "x": "c"}); err != nil || fr.String() != "a!bc" || len(fr.flushes) != 0 {
+		t.Fatalf("unexpected result %q, flushes %v, %v", fr.String(), fr.flushes, err)
+	}
+
+	// writers without Flush are left alone, and flush errors fail the execution
+	if s := flat.ExecuteString(Map{}); s != "ab" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if _, err := flat.Execute(&failingFlusher{}, Map{}); !errors.Is(err, errFlush) {
+		t.Fatalf("expected flush error, got %v", err)
+	}
+
+	if err := flat.Validate(Map{"x": 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if findings := flat.Analyze(Map{"x": ""}); len(findings) != 0 {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "xs" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+}
+
+func TestFlushEvery(t *testing.T) {
+	tpl := New("{{a}}{{b}}{{c}}", "{{", "}}", WithFlushEvery(4))
+	var fr flushRecorder
+	if _, err := tpl.Execute(&fr, Map{"a": "xx", "b": "yyy", "c": "zzzzz"}); err != nil || !equalInts(fr.flushes, []int{5, 10}) {
+		t.Fatalf("unexpected flushes %v, %v", fr.flushes, err)
+	}
+
+	// flush tags restart the count
+	tpl = New("{{a}}{{flush}}{{b}}{{c}}", "{{", "}}", WithFlushEvery(4))
+	fr = flushRecorder{}
+	if _, err := tpl.Execute(&fr, Map{"a": "xxx", "b": "yyy", "c": "z"}); err != nil || !equalInts(fr.flushes, []int{3, 7}) {
+		t.Fatalf("unexpected flushes %v, %v", fr.flushes, err)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package fasttemplate
+
+import "fmt"
+
+// frameWriter slices the written output into frames of a fixed size passed
+// to emit. The frame buffer is reused, so frames are only valid during the
+// emit call.
+type frameWriter struct {
+	buf  []byte
+	emit func(frame []byte) error
+	// n is the number of bytes emitted.
+	n int64
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(fw.buf[len(fw.buf):cap(fw.buf)], p)
+		fw.buf = fw.buf[:len(fw.buf)+n]
+		p = p[n:]
+		written += n
+		if len(fw.buf) == cap(fw.buf) {
+			if err := fw.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush emits the buffered output as a frame, if any.
+func (fw *frameWriter) flush() error {
+	if len(fw.buf) == 0 {
+		return nil
+	}
+	if err := fw.emit(fw.buf); err != nil {
+		return err
+	}
+	fw.n += int64(len(fw.buf))
+	fw.buf = fw.buf[:0]
+	return nil
+}
+
+// ExecuteFramed executes the template like Execute, slicing the output into
+// frames of frameSize bytes passed to emit as it renders, e.g. for protocols
+// limiting the size of messages such as MQTT or UDP syslog. All frames but
+// the last one are exactly frameSize bytes long; frames are cut at byte
+// boundaries, so multi-byte characters may span two frames.
+//
+// The frame passed to emit is only valid until emit returns. An error
+// returned by emit stops the execution and is returned.
+//
+// Returns the number of bytes emitted.
+func (t *Template) ExecuteFramed(m Map, frameSize int, emit func(frame []byte) error) (int64, error) {
+	if frameSize <= 0 {
+		return 0, t.formatError(fmt.Errorf("%w: %d", errInvalidFrameSize, frameSize))
+	}
+	fw := &frameWriter{buf: make([]byte, 0, frameSize), emit: emit}
+	if _, err := t.Execute(fw, m); err != nil {
+		return fw.n, err
+	}
+	return fw.n, t.formatError(fw.flush())
+}
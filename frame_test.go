@@ -0,0 +1,58 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecuteFramed(t *testing.T) {
+	tpl := New("<13>app: {{msg}} id={{id}}", "{{", "}}")
+	m := Map{"msg": strings.Repeat("x", 20), "id": 42}
+	want := tpl.ExecuteString(m)
+
+	for _, size := range []int{1, 7, len(want), len(want) + 5} {
+		var frames []string
+		n, err := tpl.ExecuteFramed(m, size, func(frame []byte) error {
+			frames = append(frames, string(frame))
+			return nil
+		})
+		if err != nil || n != int64(len(want)) || strings.Join(frames, "") != want {
+			t.Fatalf("unexpected result for size %d: %q (%d bytes), %v", size, frames, n, err)
+		}
+		for i, f := range frames {
+			if len(f) > size || (i < len(frames)-1 && len(f) != size) {
+				t.Fatalf("unexpected frame %d of size %d: %q", i, size, f)
+			}
+		}
+	}
+
+	// errors of emit stop the execution
+	errFull := errors.New("queue full")
+	calls := 0
+	n, err := tpl.ExecuteFramed(m, 4, func(frame []byte) error {
+		calls++
+		if calls == 2 {
+			return errFull
+		}
+		return nil
+	})
+	if !errors.Is(err, errFull) || calls != 2 || n != 4 {
+		t.Fatalf("unexpected result: %d calls, %d bytes, %v", calls, n, err)
+	}
+
+	// frames rendered before a failing tag are emitted
+	failing := New("abcdef{{fail()}}", "{{", "}}")
+	var emitted string
+	_, err = failing.ExecuteFramed(Map{"fail": func() (string, error) { return "", errFull }}, 4, func(frame []byte) error {
+		emitted += string(frame)
+		return nil
+	})
+	if !errors.Is(err, errFull) || emitted != "abcd" {
+		t.Fatalf("unexpected result %q, %v", emitted, err)
+	}
+
+	if _, err := tpl.ExecuteFramed(m, 0, func([]byte) error { return nil }); !errors.Is(err, errInvalidFrameSize) {
+		t.Fatalf("expected frame size error, got %v", err)
+	}
+}
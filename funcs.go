@@ -0,0 +1,426 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Cost is the relative cost of calling a function.
+type Cost int
+
+const (
+	// CostLow is the cost of cheap functions such as string helpers. This is
+	// the default.
+	CostLow Cost = iota
+
+	// CostMedium is the cost of functions doing noticeable work, e.g.
+	// formatting or converting larger values.
+	CostMedium
+
+	// CostHigh is the cost of expensive functions, e.g. functions doing I/O
+	// or heavy computations.
+	CostHigh
+)
+
+// Func annotates a function with metadata describing its behavior, so the
+// engine doesn't have to guess about side effects.
+//
+// A Func may be used anywhere a plain function is accepted: as a value in
+// the substitution [Map] or as a builtin passed to [RegisterBuiltin].
+//
+//	m := fasttemplate.Map{
+//		"lookup": fasttemplate.Func{Fn: lookup, Idempotent: true, Cost: fasttemplate.CostHigh},
+//	}
+type Func struct {
+	// Fn is the function to call.
+	Fn any
+
+	// Idempotent reports that Fn has no side effects and always returns the
+	// same result for the same arguments. Calls of idempotent functions may
+	// be memoized and are allowed in deterministic mode.
+	Idempotent bool
+
+	// Cost is the relative cost of calling Fn. Idempotent calls costing at
+	// least CostMedium are memoized within a single execution.
+	Cost Cost
+}
+
+// asFunc returns v as a Func.
+func asFunc(v any) Func {
+	if f, ok := v.(Func); ok {
+		return f
+	}
+	return Func{Fn: v}
+}
+
+// isFunc reports whether v is a function or a Func.
+func isFunc(v any) bool {
+	if f, ok := v.(Func); ok {
+		v = f.Fn
+	}
+	return v != nil && reflect.TypeOf(v).Kind() == reflect.Func
+}
+
+// WithDeterministic enables the deterministic mode: only functions annotated
+// as idempotent with [Func] may be called, so rendering the same template
+// with the same data always produces the same output.
+//
+// Calling other functions fails with an error.
+func WithDeterministic() Option {
+	return func(t *Template) {
+		t.deterministic = true
+	}
+}
+
+// evalContext holds the state of a single template execution shared by all
+// function calls and expressions it evaluates.
+type evalContext struct {
+	deterministic bool
+
+	// async defers awaiting futures to the caller.
+	async bool
+
+	// memo caches results of idempotent calls by name and arguments.
+	memo       map[string]any
+	memoHits   int
+	memoMisses int
+
+	// policies holds the call policies of the template by function name.
+	policies map[string]*callPolicy
+
+	// allowVariable restricts the variables that may be referenced.
+	allowVariable func(name string) bool
+
+	// caseInsensitive enables case-insensitive variable lookups using the
+	// lowercase index of the data keys.
+	caseInsensitive bool
+	fold            *foldIndex
+
+	// aliases maps tag names to data keys or paths.
+	aliases map[string]string
+
+	// literalTag reports whether a tag is resolved as a plain variable.
+	literalTag func(tag string) bool
+
+	// literalFallback passes unknown arguments that don't look like
+	// variable names as literal strings.
+	literalFallback bool
+
+	// stringOperators enables string repetition and formatting with the *
+	// and % operators.
+	stringOperators bool
+
+	// registry provides functions in addition to the builtins.
+	registry *Registry
+
+	// params holds the arguments of the lambda being evaluated.
+	params map[string]any
+
+	// with holds the values of the enclosing with blocks, innermost last.
+	with []any
+
+	// tagHandlers maps tag prefixes to their handlers.
+	tagHandlers map[string]TagHandler
+
+	// counters holds the values of the counter builtin by name.
+	counters map[string]int
+
+	// env is the environment descriptor exposed as _env, if enabled.
+	env map[string]any
+
+	// checksumTag resolves _checksum to the checksum marker.
+	checksumTag bool
+
+	// color enables the ANSI escapes of the color builtins.
+	color bool
+
+	// separator is the default separator of the join builtin.
+	separator string
+
+	// partials holds the templates rendered by include, and includes the
+	// chain of partials being rendered by the execution.
+	partials        map[string]*Template
+	includes        *includeChain
+	maxIncludeDepth int
+
+	// blocks holds the blocks overriding those of the layouts by name.
+	blocks map[string]blockOverride
+
+	// trace records the spans of the tags for ExecuteTraced.
+	trace *tracer
+
+	// batch holds the state shared by the executions of RenderBatch.
+	batch *batchState
+
+	// exprEngine evaluates expressions instead of the built-in evaluator.
+	exprEngine *exprEngine
+
+	// cel evaluates tags as CEL expressions.
+	cel bool
+
+	// name is the name of the template, and tag and tagIndex describe the
+	// tag being evaluated, for CallInfo.
+	name     string
+	tag      string
+	tagIndex int
+}
+
+// newEvalContext returns the evaluation context for a single execution.
+func (t *Template) newEvalContext() *evalContext {
+	allow := t.allowVariable
+	if vars := t.blockVars(); allow != nil && vars != nil {
+		// variables assigned by the template itself are always allowed
+		allow = func(name string) bool {
+			return vars[name] || t.allowVariable(name)
+		}
+	}
+	var env map[string]any
+	if t.envInfo {
+		env = newEnv()
+	}
+	return &evalContext{
+		deterministic:   t.deterministic,
+		policies:        t.callPolicies,
+		allowVariable:   allow,
+		caseInsensitive: t.caseInsensitive,
+		aliases:         t.aliases,
+		literalTag:      t.literalTagFunc(),
+		literalFallback: t.literalFallback,
+		stringOperators: t.stringOperators,
+		registry:        t.registry,
+		tagHandlers:     t.tagHandlers,
+		env:             env,
+		checksumTag:     t.checksumTag,
+		color:           t.color == colorOn,
+		exprEngine:      t.exprEngine,
+		cel:             t.cel,
+		separator:       t.sliceSeparator(),
+		partials:        t.partials,
+		maxIncludeDepth: t.maxIncludeDepth,
+		name:            t.name,
+	}
+}
+
+// lookup returns the value of the named variable from data, treating
+// variables that may not be referenced as missing.
+func (ec *evalContext) lookup(data Map, name string) (any, bool) {
+	if ec != nil && ec.params != nil {
+		if v, ok := ec.param(name); ok {
+			return v, true
+		}
+	}
+	if ec != nil && ec.with != nil {
+		if v, ok := ec.lookupWith(name); ok {
+			return v, true
+		}
+	}
+	v, ok := ec.lookupKey(data, name)
+	if !ok && ec != nil && ec.aliases != nil {
+		if target, isAlias := ec.aliases[name]; isAlias {
+			return ec.lookupPath(data, target)
+		}
+	}
+	if !ok && ec != nil && ec.env != nil {
+		v, ok = ec.lookupEnv(name)
+	}
+	if !ok && ec != nil && ec.checksumTag {
+		v, ok = ec.lookupChecksum(name)
+	}
+	return v, ok
+}
+
+// lookupKey works like lookup, but ignores aliases.
+func (ec *evalContext) lookupKey(data Map, name string) (any, bool) {
+	v, ok := data[name]
+	if !ok && ec != nil && ec.caseInsensitive {
+		name, ok = ec.foldKey(data, name)
+		v = data[name]
+	}
+	if ok && ec != nil && ec.allowVariable != nil && !ec.allowVariable(name) {
+		return nil, false
+	}
+	return v, ok
+}
+
+// lookupPath returns the value of an alias target, which is either a key of
+// data or a dotted path selecting a nested field, e.g. "user.name".
+func (ec *evalContext) lookupPath(data Map, path string) (any, bool) {
+	if v, ok := ec.lookupKey(data, path); ok {
+		return v, true
+	}
+	root, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return nil, false
+	}
+	v, ok := ec.lookupKey(data, root)
+	if !ok {
+		return nil, false
+	}
+	return fieldValue(v, rest)
+}
+
+// foldIndex maps the lowercase keys of a Map to its keys.
+type foldIndex struct {
+	// data, ptr and size identify the indexed map and its number of keys,
+	// as variables are only ever added during an execution. Holding data
+	// keeps its address from being reused by another map.
+	data Map
+	ptr  uintptr
+	size int
+
+	keys   map[string]string
+	misses map[string]struct{}
+}
+
+// indexes reports whether the index was built for data.
+func (fi *foldIndex) indexes(data Map) bool {
+	return fi != nil && fi.ptr == reflect.ValueOf(data).Pointer() && fi.size == len(data)
+}
+
+// newFoldIndex builds the fold index of data.
+func newFoldIndex(data Map) *foldIndex {
+	fi := &foldIndex{
+		data:   data,
+		ptr:    reflect.ValueOf(data).Pointer(),
+		size:   len(data),
+		keys:   make(map[string]string, len(data)),
+		misses: make(map[string]struct{}),
+	}
+	for k := range data {
+		lk := strings.ToLower(k)
+		// prefer the smallest key among keys differing in case only, so
+		// the choice doesn't depend on map iteration order
+		if prev, dup := fi.keys[lk]; !dup || k < prev {
+			fi.keys[lk] = k
+		}
+	}
+	return fi
+}
+
+// foldKey returns the key of data matching name case-insensitively. The
+// index of the keys is built once per map and rebuilt when the keys change,
+// e.g. after a capture block assigns a variable. Misses are cached, so
+// repeated lookups of unknown variables don't lowercase the keys again.
+func (ec *evalContext) foldKey(data Map, name string) (string, bool) {
+	if !ec.fold.indexes(data) {
+		ec.fold = newFoldIndex(data)
+	}
+	if _, miss := ec.fold.misses[name]; miss {
+		return "", false
+	}
+	key, ok := ec.fold.keys[strings.ToLower(name)]
+	if !ok {
+		ec.fold.misses[name] = struct{}{}
+	}
+	return key, ok
+}
+
+// hasStringOperators reports whether WithStringOperators applies.
+func (ec *evalContext) hasStringOperators() bool {
+	return ec != nil && ec.stringOperators
+}
+
+// policy returns the call policy of the named function, if any.
+func (ec *evalContext) policy(name string) *callPolicy {
+	if ec == nil {
+		return nil
+	}
+	return ec.policies[name]
+}
+
+// checkCall returns an error if the function f, resolved from funcs, data
+// or the context, may not be called in the context.
+func (ec *evalContext) checkCall(name string, f Func, funcs, data Map) error {
+	if ec != nil && ec.deterministic && !f.Idempotent && !ec.isScopedFunc(name, funcs, data) {
+		return fmt.Errorf("%w: %s", errNondeterministicFunc, name)
+	}
+	return nil
+}
+
+// isScopedFunc reports whether the named function resolves to a builtin
+// bound to the execution, such as counter. Their results only depend on the
+// execution, so deterministic mode allows them even if they aren't
+// idempotent.
+func (ec *evalContext) isScopedFunc(name string, funcs, data Map) bool {
+	if _, ok := scopedBuiltin(name); !ok {
+		return false
+	}
+	if _, ok := funcs[name]; ok {
+		return false
+	}
+	if fn, ok := data[name]; ok && isFunc(fn) {
+		return false
+	}
+	_, ok := ec.registry.lookup(name)
+	return !ok
+}
+
+// memoKey returns the memoization key of a call of f with args, where f is
+// resolved from funcs, data or the context like functionCall.execute does.
+// It returns false if the call must not be memoized.
+func (ec *evalContext) memoKey(name string, f Func, funcs, data Map, args []reflect.Value) (string, bool) {
+	if ec == nil || !f.Idempotent || f.Cost < CostMedium {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString(name)
+	// the memo may be shared by templates binding name to other functions
+	sb.WriteByte(0)
+	sb.WriteString(ec.funcSource(name, funcs, data))
+	for _, arg := range args {
+		if !arg.IsValid() {
+			sb.WriteString("\x00nil")
+			continue
+		}
+		switch arg.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16,
+			reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+			reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			fmt.Fprintf(&sb, "\x00%s:%v", arg.Type(), arg.Interface())
+		default:
+			// only scalar arguments can be compared reliably
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// funcSource identifies where the named function is resolved from. The
+// memo shared by RenderBatch is keyed by it, as functions bound to the same
+// name by different templates, such as closures made by the same factory,
+// can't be told apart by their code.
+func (ec *evalContext) funcSource(name string, funcs, data Map) string {
+	if _, ok := funcs[name]; ok {
+		return fmt.Sprintf("map %p", funcs)
+	}
+	if fn, ok := data[name]; ok && isFunc(fn) {
+		return fmt.Sprintf("map %p", data)
+	}
+	if _, ok := ec.registry.lookup(name); ok {
+		return fmt.Sprintf("registry %p", ec.registry)
+	}
+	if _, ok := scopedBuiltin(name); ok {
+		return fmt.Sprintf("context %p", ec)
+	}
+	return "builtin"
+}
+
+// memoized returns the cached result for key.
+func (ec *evalContext) memoized(key string) (any, bool) {
+	v, ok := ec.memo[key]
+	if ok {
+		ec.memoHits++
+	} else {
+		ec.memoMisses++
+	}
+	return v, ok
+}
+
+// memoize caches the result for key.
+func (ec *evalContext) memoize(key string, v any) {
+	if ec.memo == nil {
+		ec.memo = make(map[string]any)
+	}
+	ec.memo[key] = v
+}
@@ -0,0 +1,61 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFuncMemoization(t *testing.T) {
+	calls := 0
+	lookup := func(id int) string {
+		calls++
+		return strings.Repeat("x", id)
+	}
+	tpl := New("{{lookup(2)}} {{lookup(2)}}{{lookup(3)}} {{lookup(n)}}", "{{", "}}")
+
+	m := Map{"n": 2, "lookup": Func{Fn: lookup, Idempotent: true, Cost: CostHigh}}
+	if s := tpl.ExecuteString(m); s != "xx xxxxx xx" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+
+	// results are only memoized within a single execution
+	tpl.ExecuteString(m)
+	if calls != 4 {
+		t.Fatalf("expected 4 calls, got %d", calls)
+	}
+
+	// cheap and non-idempotent functions are always called
+	for _, f := range []Func{{Fn: lookup, Idempotent: true}, {Fn: lookup, Cost: CostHigh}} {
+		calls = 0
+		tpl.ExecuteString(Map{"n": 2, "lookup": f})
+		if calls != 4 {
+			t.Fatalf("expected 4 calls for %+v, got %d", f, calls)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	tpl := New("{{upper(name)}} {{htmlEncode(name)}}", "{{", "}}", WithDeterministic())
+
+	s := tpl.ExecuteString(Map{"name": "a&b", "upper": Func{Fn: strings.ToUpper, Idempotent: true}})
+	if s != "A&B a&amp;b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	m := Map{"name": "a&b", "upper": strings.ToUpper}
+	if _, err := tpl.Execute(&strings.Builder{}, m); !errors.Is(err, errNondeterministicFunc) {
+		t.Fatalf("expected nondeterministic function error, got %v", err)
+	}
+	if err := tpl.Validate(m); !errors.Is(err, errNondeterministicFunc) {
+		t.Fatalf("expected nondeterministic function validation error, got %v", err)
+	}
+
+	// the mode doesn't restrict other templates
+	if s := New("{{upper(name)}}", "{{", "}}").ExecuteString(m); s != "A&B" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
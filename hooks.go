@@ -0,0 +1,48 @@
+package fasttemplate
+
+import "time"
+
+// RenderStats describes a single execution of a template.
+type RenderStats struct {
+	// Template is the name of the template, if any.
+	Template string
+
+	// Duration is the time spent rendering.
+	Duration time.Duration
+
+	// Bytes is the number of bytes written.
+	Bytes int64
+
+	// Err is the error the execution failed with, if any.
+	Err error
+
+	// MemoHits and MemoMisses count lookups of memoized function results,
+	// see [Func].
+	MemoHits   int
+	MemoMisses int
+}
+
+// WithRenderHook registers a function called after every execution of the
+// template with statistics about it, e.g. to export metrics without
+// wrapping every call site. Hooks must be safe for concurrent use if the
+// template is executed concurrently.
+func WithRenderHook(f func(RenderStats)) Option {
+	return func(t *Template) {
+		t.renderHooks = append(t.renderHooks, f)
+	}
+}
+
+// observeRender calls the render hooks for an execution started at start.
+func (t *Template) observeRender(start time.Time, n *int64, err *error, ec *evalContext) {
+	stats := RenderStats{
+		Template:   t.name,
+		Duration:   time.Since(start),
+		Bytes:      *n,
+		Err:        *err,
+		MemoHits:   ec.memoHits,
+		MemoMisses: ec.memoMisses,
+	}
+	for _, hook := range t.renderHooks {
+		hook(stats)
+	}
+}
@@ -0,0 +1,55 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWithRenderHook(t *testing.T) {
+	var stats []RenderStats
+	hook := func(s RenderStats) { stats = append(stats, s) }
+	fail := func() (string, error) { return "", errors.New("boom") }
+
+	tpl := New("Hello, {{name}}!{{fail()}}", "{{", "}}", WithRenderHook(hook))
+	m := Map{"name": "Ann", "fail": fail}
+
+	if _, err := tpl.Execute(io.Discard, m); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := tpl.ExecuteStringStd(m); s != "Hello, Ann!{{fail()}}" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	var bb bytes.Buffer
+	if _, err := tpl.ExecuteAsync(&bb, Map{"name": "Ann", "fail": func() string { return "" }}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(stats))
+	}
+	if stats[0].Err == nil || stats[0].Bytes != 11 {
+		t.Fatalf("unexpected stats %+v", stats[0])
+	}
+	if stats[1].Err != nil || stats[1].Bytes != 21 {
+		t.Fatalf("unexpected stats %+v", stats[1])
+	}
+	if stats[2].Err != nil || stats[2].Bytes != 11 {
+		t.Fatalf("unexpected stats %+v", stats[2])
+	}
+}
+
+func TestWithRenderHookOutputFilter(t *testing.T) {
+	var stats RenderStats
+	tpl := New("{{name}}", "{{", "}}",
+		WithOutputFilter(func(p []byte) []byte { return append(p, "!!"...) }),
+		WithRenderHook(func(s RenderStats) { stats = s }))
+
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "Ann!!" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if stats.Bytes != 5 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
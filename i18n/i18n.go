@@ -0,0 +1,179 @@
+// Package i18n translates fasttemplate templates through message catalogs,
+// so one template serves all languages.
+//
+// Messages are looked up with {{i18n:key}} tags, using the locale stored
+// under LocaleKey in the substitution map, or by calling a function bound
+// to a locale, as in {{t("welcome.title")}}:
+//
+//	c := i18n.Catalog{}
+//	err := c.LoadJSON("de", file)
+//	...
+//	tr := i18n.New(c, "en")
+//	t := fasttemplate.New("<h1>{{i18n:welcome.title}}</h1>", "{{", "}}", tr.Option())
+//	s := t.ExecuteString(fasttemplate.Map{i18n.LocaleKey: "de-AT"})
+//
+// Lookups fall back from a locale to its parents, e.g. from "de-AT" to
+// "de", and then to the fallback locales of the Translator.
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// Prefix is the tag prefix routed to a Translator, as in {{i18n:key}}.
+const Prefix = "i18n"
+
+// LocaleKey is the key of the substitution map holding the locale of
+// {{i18n:key}} tags.
+const LocaleKey = "locale"
+
+// errMissingMessage is returned for keys missing from all locales of the
+// fallback chain.
+var errMissingMessage = errors.New("missing message")
+
+// Catalog maps locales to their messages, keyed by message ID. Locale names
+// are lower case with "-" separating subtags, as normalized by Add and
+// LoadJSON.
+type Catalog map[string]map[string]string
+
+// Add adds messages to the locale, replacing existing messages with the
+// same IDs.
+func (c Catalog) Add(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+	if c[locale] == nil {
+		c[locale] = make(map[string]string, len(messages))
+	}
+	for id, msg := range messages {
+		c[locale][id] = msg
+	}
+}
+
+// LoadJSON adds the messages of a JSON catalog to the locale. Nested
+// objects are flattened to dotted IDs, so {"welcome": {"title": "Hi"}}
+// defines "welcome.title". Objects with an "other" string, as written by
+// go-i18n, define a message with their own ID.
+func (c Catalog) LoadJSON(locale string, r io.Reader) error {
+	var v map[string]any
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return fmt.Errorf("i18n: %s: %w", locale, err)
+	}
+	messages := make(map[string]string)
+	if err := flatten(messages, "", v); err != nil {
+		return fmt.Errorf("i18n: %s: %w", locale, err)
+	}
+	c.Add(locale, messages)
+	return nil
+}
+
+// flatten adds the messages of the JSON object v to messages, prefixing
+// their IDs with prefix.
+func flatten(messages map[string]string, prefix string, v map[string]any) error {
+	for k, item := range v {
+		id := k
+		if prefix != "" {
+			id = prefix + "." + k
+		}
+		switch item := item.(type) {
+		case string:
+			messages[id] = item
+		case map[string]any:
+			if other, ok := item["other"].(string); ok {
+				messages[id] = other
+				continue
+			}
+			if err := flatten(messages, id, item); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q: unsupported value %v", id, item)
+		}
+	}
+	return nil
+}
+
+// Translator looks up messages in a Catalog.
+type Translator struct {
+	catalog  Catalog
+	fallback []string
+}
+
+// New returns a Translator for the catalog, falling back to the given
+// locales, in order, for messages missing from the requested locale.
+func New(catalog Catalog, fallback ...string) *Translator {
+	return &Translator{catalog: catalog, fallback: fallback}
+}
+
+// Translate returns the message with the ID for the locale. It fails if no
+// locale of the fallback chain defines the message.
+func (tr *Translator) Translate(locale, id string) (string, error) {
+	for _, l := range tr.chain(locale) {
+		if msg, ok := tr.catalog[l][id]; ok {
+			return msg, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q for locale %q", errMissingMessage, id, locale)
+}
+
+// chain returns the fallback chain of the locale: the locale, its parents
+// and the fallback locales with their parents, without duplicates.
+func (tr *Translator) chain(locale string) []string {
+	var chain []string
+	add := func(l string) {
+		for l = normalizeLocale(l); l != ""; l = parentLocale(l) {
+			found := false
+			for _, c := range chain {
+				if c == l {
+					found = true
+					break
+				}
+			}
+			if !found {
+				chain = append(chain, l)
+			}
+		}
+	}
+	add(locale)
+	for _, l := range tr.fallback {
+		add(l)
+	}
+	return chain
+}
+
+// Option returns the option routing {{i18n:key}} tags to tr. The locale is
+// read from the LocaleKey entry of the substitution map; only the fallback
+// locales are used if it is missing.
+func (tr *Translator) Option() fasttemplate.Option {
+	return fasttemplate.WithTagPrefix(Prefix, func(id string, m fasttemplate.Map) (any, error) {
+		locale, _ := m[LocaleKey].(string)
+		return tr.Translate(locale, id)
+	})
+}
+
+// Func returns a function translating message IDs for the locale, to be
+// added to the substitution map, e.g. as "t" for {{t("welcome.title")}}.
+func (tr *Translator) Func(locale string) func(id string) (string, error) {
+	return func(id string) (string, error) {
+		return tr.Translate(locale, id)
+	}
+}
+
+// normalizeLocale returns the locale in lower case with "-" separating its
+// subtags, so "de_AT" and "de-at" name the same locale.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// parentLocale returns the locale without its last subtag, or "" for a
+// language without subtags.
+func parentLocale(locale string) string {
+	if i := strings.LastIndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+	return ""
+}
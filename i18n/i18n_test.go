@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func testCatalog(t *testing.T) Catalog {
+	c := Catalog{}
+	if err := c.LoadJSON("en", strings.NewReader(`{
+		"welcome": {"title": "Welcome", "body": "Hello"},
+		"bye": {"description": "farewell", "other": "Goodbye"}
+	}`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.LoadJSON("de", strings.NewReader(`{"welcome.title": "Willkommen", "welcome.body": "Hallo"}`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c.Add("de_AT", map[string]string{"welcome.body": "Servus"})
+	return c
+}
+
+func TestTranslate(t *testing.T) {
+	tr := New(testCatalog(t), "en")
+	tests := []struct {
+		locale, id, expected string
+	}{
+		{"en", "welcome.title", "Welcome"},
+		{"de", "welcome.title", "Willkommen"},
+		{"de-AT", "welcome.body", "Servus"},
+		{"de-at", "welcome.title", "Willkommen"},
+		{"de-AT", "bye", "Goodbye"},
+		{"fr", "welcome.body", "Hello"},
+		{"", "welcome.body", "Hello"},
+	}
+	for _, tt := range tests {
+		msg, err := tr.Translate(tt.locale, tt.id)
+		if err != nil || msg != tt.expected {
+			t.Fatalf("unexpected message for %s/%s: %q, %v. Expected %q", tt.locale, tt.id, msg, err, tt.expected)
+		}
+	}
+
+	if _, err := tr.Translate("de", "missing"); !errors.Is(err, errMissingMessage) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTranslatorTemplate(t *testing.T) {
+	tr := New(testCatalog(t), "en")
+	tpl := fasttemplate.New("<h1>{{i18n:welcome.title}}</h1><p>{{t('welcome.body')}}, {{name}}</p>", "{{", "}}", tr.Option())
+
+	for locale, expected := range map[string]string{
+		"en":    "<h1>Welcome</h1><p>Hello, Ann</p>",
+		"de-AT": "<h1>Willkommen</h1><p>Servus, Ann</p>",
+	} {
+		s := tpl.ExecuteString(fasttemplate.Map{
+			LocaleKey: locale,
+			"t":       tr.Func(locale),
+			"name":    "Ann",
+		})
+		if s != expected {
+			t.Fatalf("unexpected output for %s: %q. Expected %q", locale, s, expected)
+		}
+	}
+
+	var bb bytes.Buffer
+	_, err := fasttemplate.New("{{i18n:missing}}", "{{", "}}", tr.Option()).Execute(&bb, fasttemplate.Map{LocaleKey: "de"})
+	if !errors.Is(err, errMissingMessage) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestLoadJSONErrors(t *testing.T) {
+	c := Catalog{}
+	if err := c.LoadJSON("en", strings.NewReader(`{"a": 1}`)); err == nil || !strings.Contains(err.Error(), `message "a"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := c.LoadJSON("en", strings.NewReader(`[`)); err == nil {
+		t.Fatalf("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,144 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	// the registered include only makes it known to completion and
+	// analysis; lookups bind it to the execution
+	registerScoped("include", func(name string) (string, error) {
+		return "", errors.New("include: called outside of an execution")
+	}, func(ec *evalContext, m Map) Func {
+		// partials check the calls of their own functions
+		return Func{Fn: func(name string) (any, error) {
+			return ec.include(name, m)
+		}, Idempotent: true}
+	})
+}
+
+// WithPartial registers the partial template under name, so the template can
+// render it with {{include("name")}} against the same [Map], e.g. for shared
+// page headers and footers:
+//
+//	header := fasttemplate.New("<h1>{{title}}</h1>", "{{", "}}")
+//	page := fasttemplate.New(`{{include("header")}}{{body}}`, "{{", "}}",
+//		fasttemplate.WithPartial("header", header))
+//
+// Partials are executed with their own options and may include other
+// partials, either their own or those of the including template. Including
+// an unknown partial fails the execution.
+func WithPartial(name string, partial *Template) Option {
+	return func(t *Template) {
+		if t.partials == nil {
+			t.partials = make(map[string]*Template)
+		}
+		t.partials[name] = partial
+	}
+}
+
+// include renders the named partial against m. The output of partials
+// escaping HTML or XML is returned as a trusted fragment, so it isn't
+// escaped again.
+func (ec *evalContext) include(name string, m Map) (any, error) {
+	p, ok := ec.partials[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errPartialNotFound, name)
+	}
+	if err := ec.enterPartial(name, p); err != nil {
+		return nil, err
+	}
+	defer ec.includes.leave()
+
+	pec := p.partialContext(ec)
+	bb := p.getBuffer()
+	defer p.putBuffer(bb)
+	if _, err := p.executeContext(bb, m, pec, false); err != nil {
+		return nil, fmt.Errorf("partial %q: %w", name, err)
+	}
+	s := bb.String()
+	switch p.escapeMode {
+	case EscapeHTML:
+		return escapedHTML(s), nil
+	case EscapeXML:
+		return escapedXML(s), nil
+	}
+	return s, nil
+}
+
+// defaultMaxIncludeDepth is the include depth limit unless set with
+// WithMaxIncludeDepth.
+const defaultMaxIncludeDepth = 32
+
+// WithMaxIncludeDepth limits how deeply partials registered with
+// [WithPartial] may be nested by include and extends tags. Exceeding the
+// limit fails the execution with [ErrTemplateCycle] instead of recursing
+// without bound. The default limit is 32.
+func WithMaxIncludeDepth(n int) Option {
+	return func(t *Template) {
+		t.maxIncludeDepth = n
+	}
+}
+
+// includeChain tracks the templates being included by an execution.
+// Templates are identified by their address, as different partials may be
+// registered under the same name and a partial under several names; names
+// are only kept for error messages.
+type includeChain struct {
+	names     []string
+	templates []*Template
+	maxDepth  int
+}
+
+// enter adds the template t, included as name, to the chain. It returns an
+// error naming the chain if t is already being executed or the chain would
+// exceed the depth limit.
+func (c *includeChain) enter(name string, t *Template) error {
+	for _, included := range c.templates {
+		if included == t {
+			return fmt.Errorf("%w: %s -> %s", ErrTemplateCycle, strings.Join(c.names, " -> "), name)
+		}
+	}
+	maxDepth := c.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxIncludeDepth
+	}
+	if len(c.names) >= maxDepth {
+		return fmt.Errorf("%w: include depth %d exceeded: %s -> %s", ErrTemplateCycle, maxDepth, strings.Join(c.names, " -> "), name)
+	}
+	c.names = append(c.names, name)
+	c.templates = append(c.templates, t)
+	return nil
+}
+
+// leave removes the most recently entered template from the chain.
+func (c *includeChain) leave() {
+	c.names = c.names[:len(c.names)-1]
+	c.templates = c.templates[:len(c.templates)-1]
+}
+
+// enterPartial adds the partial t, included as name, to the include chain
+// of the execution.
+func (ec *evalContext) enterPartial(name string, t *Template) error {
+	if ec.includes == nil {
+		ec.includes = &includeChain{maxDepth: ec.maxIncludeDepth}
+	}
+	return ec.includes.enter(name, t)
+}
+
+// partialContext returns the evaluation context of the partial t executed
+// by the execution ec. Partials share the include chain of the execution,
+// are deterministic if ec is and fall back to the partials of ec.
+func (t *Template) partialContext(ec *evalContext) *evalContext {
+	pec := t.newEvalContext()
+	pec.includes = ec.includes
+	pec.deterministic = pec.deterministic || ec.deterministic
+	if pec.partials == nil {
+		pec.partials = ec.partials
+	}
+	pec.blocks = ec.blocks
+	pec.counters = ec.sharedCounters()
+	return pec
+}
@@ -0,0 +1,103 @@
+package fasttemplate
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestIncludeChain(t *testing.T) {
+	templates := make(map[string]*Template)
+	for _, name := range []string{"page", "layout", "footer", "copyright"} {
+		templates[name] = New(name, "{{", "}}")
+	}
+	c := includeChain{maxDepth: 3}
+	for _, name := range []string{"page", "layout"} {
+		if err := c.enter(name, templates[name]); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err := c.enter("page", templates["page"])
+	if !errors.Is(err, ErrTemplateCycle) || !strings.Contains(err.Error(), "page -> layout -> page") {
+		t.Fatalf("expected cycle error naming the chain, got %v", err)
+	}
+	// templates are identified by address rather than name
+	err = c.enter("home", templates["page"])
+	if !errors.Is(err, ErrTemplateCycle) || !strings.Contains(err.Error(), "page -> layout -> home") {
+		t.Fatalf("expected cycle error naming the chain, got %v", err)
+	}
+
+	if err := c.enter("footer", templates["footer"]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = c.enter("copyright", templates["copyright"])
+	if !errors.Is(err, ErrTemplateCycle) || !strings.Contains(err.Error(), "depth 3") {
+		t.Fatalf("expected depth error, got %v", err)
+	}
+
+	c.leave()
+	if err := c.enter("copyright", templates["copyright"]); err != nil {
+		t.Fatalf("unexpected error after leave: %s", err)
+	}
+}
+
+func TestPartials(t *testing.T) {
+	header := New("<h1>{{title}}</h1>", "{{", "}}")
+	nav := New("[{{join(links, ' | ')}}]", "{{", "}}")
+	footer := New(`{{include("nav")}} (c) {{year}}`, "{{", "}}")
+	page := New(`{{include("header")}}{{body}}{{include("footer")}}`, "{{", "}}",
+		WithPartial("header", header),
+		WithPartial("footer", footer),
+		WithPartial("nav", nav))
+
+	m := Map{"title": "Hi", "body": "text", "year": 2024, "links": []string{"a", "b"}}
+	if s := page.ExecuteString(m); s != "<h1>Hi</h1>text[a | b] (c) 2024" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// partials included by each other fail instead of recursing
+	a := New(`a{{include("b")}}`, "{{", "}}")
+	b := New(`b{{include("a")}}`, "{{", "}}")
+	cyclic := New(`{{include("a")}}`, "{{", "}}", WithPartial("a", a), WithPartial("b", b))
+	if _, err := cyclic.Execute(io.Discard, Map{}); !errors.Is(err, ErrTemplateCycle) || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	// different partials registered under the same name aren't a cycle,
+	// while a partial included under another name is
+	inner := New("inner", "{{", "}}")
+	outer := New(`outer {{include("card")}}`, "{{", "}}", WithPartial("card", inner))
+	nested := New(`{{include("card")}}`, "{{", "}}", WithPartial("card", outer))
+	var sb strings.Builder
+	if _, err := nested.Execute(&sb, Map{}); err != nil || sb.String() != "outer inner" {
+		t.Fatalf("unexpected output %q, error %v", sb.String(), err)
+	}
+	self := New(`{{include("copy")}}`, "{{", "}}")
+	aliased := New(`{{include("orig")}}`, "{{", "}}", WithPartial("orig", self), WithPartial("copy", self))
+	if _, err := aliased.Execute(io.Discard, Map{}); !errors.Is(err, ErrTemplateCycle) || !strings.HasSuffix(err.Error(), ": orig -> copy") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	if _, err := New(`{{include("missing")}}`, "{{", "}}").Execute(io.Discard, Map{}); !errors.Is(err, errPartialNotFound) {
+		t.Fatalf("expected missing partial error, got %v", err)
+	}
+	if _, err := Execute(`{{include("header")}}`, "{{", "}}", io.Discard, Map{}); err == nil {
+		t.Fatal("expected error outside of a template execution")
+	}
+
+	// partials escape their own output, which isn't escaped again
+	item := New("<li>{{name}}</li>", "{{", "}}", WithEscaping(EscapeHTML))
+	list := New(`<ul>{{include("item")}}</ul>{{name}}`, "{{", "}}", WithEscaping(EscapeHTML), WithPartial("item", item))
+	if s := list.ExecuteString(Map{"name": "<b>"}); s != "<ul><li>&lt;b&gt;</li></ul>&lt;b&gt;" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// deterministic mode applies to the functions called by partials
+	now := New("{{now()}}", "{{", "}}")
+	strict := New(`{{include("now")}}`, "{{", "}}", WithDeterministic(), WithPartial("now", now))
+	if _, err := strict.Execute(io.Discard, Map{"now": func() int { return 1 }}); !errors.Is(err, errNondeterministicFunc) {
+		t.Fatalf("expected deterministic mode error, got %v", err)
+	}
+}
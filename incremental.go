@@ -0,0 +1,238 @@
+package fasttemplate
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Incremental renders a template repeatedly, e.g. for dashboards refreshing
+// every second with mostly unchanged data. It caches the output of every
+// tag together with the inputs it was rendered from and only re-renders the
+// tags whose inputs changed since the previous Render.
+//
+// Inputs are compared by value if they are strings, booleans, numbers or
+// time.Time values. Tags depending on other values, e.g. slices, maps or
+// structs which may have been modified in place, are always re-rendered, as
+// are tags calling functions not annotated as idempotent with [Func] and
+// tags routed to tag handlers. Value transformers only see the values of
+// re-rendered tags. Templates with blocks or token budgets are always
+// rendered completely.
+//
+// Functions can't be compared by value, so replacing a function of the map
+// with one of different code re-renders the tags calling it, but closures
+// made by the same function, e.g. greeter("Hi ") and greeter("Hallo "), are
+// indistinguishable. Call Invalidate after replacing such a function, or
+// after changing a function of the registry.
+//
+// An Incremental is safe for concurrent use, but renders are serialized.
+type Incremental struct {
+	t *Template
+
+	mu       sync.Mutex
+	segments []segment
+	rendered int
+	reused   int
+}
+
+// segment is the cached output of a tag.
+type segment struct {
+	// deps and funcs name the variables and functions the tag depends on.
+	// Volatile tags are rendered every time.
+	deps     []string
+	funcs    []string
+	volatile bool
+
+	// out is the output of the tag rendered from the input values, valid
+	// if it may be reused.
+	valid  bool
+	values []any
+	out    []byte
+}
+
+// NewIncremental returns an Incremental rendering t. t must not be reset
+// while the Incremental is in use.
+func (t *Template) NewIncremental() *Incremental {
+	r := &Incremental{t: t, segments: make([]segment, len(t.tags))}
+	for i, tag := range t.tags {
+		seg := &r.segments[i]
+		a := t.analyzeTag(tag)
+		if a.err != nil || t.isHandlerTag(tag) {
+			seg.volatile = true
+			continue
+		}
+		if a.kind == tagVariable {
+			// variables are looked up untrimmed
+			seg.deps = []string{tag}
+			if !t.isLiteralTag(tag) && isHyphenatedName(tag) {
+				// without a matching key the tag is a subtraction
+				seg.deps = append(seg.deps, strings.Split(tag, "-")...)
+			}
+			continue
+		}
+		seg.deps = a.idents
+		seg.funcs = a.funcs
+	}
+	return r
+}
+
+// Render executes the template like Execute, reusing the cached output of
+// tags whose inputs didn't change. A failing render invalidates the cache.
+func (r *Incremental) Render(w io.Writer, m Map) (n int64, err error) {
+	t := r.t
+	if t.nodes != nil || t.tokenizer != nil || len(t.texts) == 0 {
+		return t.Execute(w, m)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rendered, r.reused = 0, 0
+
+	ec := t.newEvalContext()
+	ec.color = t.useColor(w)
+	if t.renderHooks != nil {
+		defer t.observeRender(time.Now(), &n, &err, ec)
+	}
+	if len(t.outputStages) > 0 {
+		fw, finish := t.filterOutput(w)
+		_, err = r.render(fw, m, ec)
+		var ferr error
+		n, ferr = finish()
+		if err == nil {
+			err = ferr
+		}
+	} else {
+		n, err = r.render(w, m, ec)
+	}
+	if err != nil {
+		r.invalidate()
+	}
+	return n, t.formatError(err)
+}
+
+// render writes the texts and tags of the template, rendering the tags
+// whose inputs changed.
+func (r *Incremental) render(w io.Writer, m Map, ec *evalContext) (int64, error) {
+	t := r.t
+	var nn int64
+	for i, text := range t.texts {
+		ni, err := writeFull(w, text)
+		nn += int64(ni)
+		if err != nil || i == len(t.tags) {
+			return nn, err
+		}
+
+		seg := &r.segments[i]
+		values, cacheable := r.inputs(seg, m, ec)
+		if !seg.valid || !cacheable || !sameInputs(seg.values, values) {
+			bb := t.getBuffer()
+			_, err = t.writeTag(bb, i, m, ec, false)
+			seg.out = append(seg.out[:0], bb.B...)
+			t.putBuffer(bb)
+			if err != nil {
+				return nn, err
+			}
+			seg.values = values
+			seg.valid = cacheable
+			r.rendered++
+		} else {
+			r.reused++
+		}
+
+		ni, err = writeFull(w, seg.out)
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+	}
+	return nn, nil
+}
+
+// inputs returns the current inputs of the segment. It returns false if the
+// segment must be rendered regardless of its inputs.
+func (r *Incremental) inputs(seg *segment, m Map, ec *evalContext) ([]any, bool) {
+	if seg.volatile {
+		return nil, false
+	}
+	values := make([]any, 0, len(seg.deps)+len(seg.funcs))
+	for _, name := range seg.deps {
+		v, ok := ec.lookup(m, name)
+		if !ok {
+			values = append(values, missingInput{})
+			continue
+		}
+		if !isScalarInput(v) {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	for _, name := range seg.funcs {
+		if _, scoped := scopedBuiltin(name); scoped {
+			return nil, false
+		}
+		f, ok := ec.lookupFunc(name, m)
+		if !ok || !f.Idempotent {
+			return nil, false
+		}
+		// the code of the function is an input, as m may hold another
+		// function. Closures sharing it need Invalidate.
+		values = append(values, reflect.ValueOf(f.Fn).Pointer())
+	}
+	return values, true
+}
+
+// Invalidate discards the cached output, so the next Render renders every
+// tag.
+func (r *Incremental) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalidate()
+}
+
+func (r *Incremental) invalidate() {
+	for i := range r.segments {
+		r.segments[i].valid = false
+	}
+}
+
+// Stats returns the number of tags rendered and reused by the last Render.
+func (r *Incremental) Stats() (rendered, reused int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rendered, r.reused
+}
+
+// missingInput stands for inputs missing from the map.
+type missingInput struct{}
+
+// isScalarInput reports whether v can be compared to detect changes.
+func isScalarInput(v any) bool {
+	if v == nil {
+		return true
+	}
+	if _, ok := v.(time.Time); ok {
+		return true
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// sameInputs reports whether the inputs a and b are equal.
+func sameInputs(a, b []any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	calls := 0
+	fmtLoad := Func{Fn: func(v float64) string {
+		calls++
+		return strings.Repeat("#", int(v*10))
+	}, Idempotent: true}
+	tpl := New("cpu {{cpu}} {{bar(cpu)}} mem {{mem * 100}}% hosts {{len(hosts)}} #{{counter('r')}}", "{{", "}}")
+	r := tpl.NewIncremental()
+	m := Map{
+		"cpu":   0.5,
+		"mem":   0.25,
+		"bar":   fmtLoad,
+		"len":   func(s []string) int { return len(s) },
+		"hosts": []string{"a", "b"},
+	}
+
+	render := func(expected string, rendered, reused int) {
+		t.Helper()
+		var bb bytes.Buffer
+		if _, err := r.Render(&bb, m); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bb.String() != expected {
+			t.Fatalf("unexpected output %q. Expected %q", bb.String(), expected)
+		}
+		if s := tpl.ExecuteString(m); s != expected {
+			t.Fatalf("incremental output %q differs from Execute output %q", expected, s)
+		}
+		if gotRendered, gotReused := r.Stats(); gotRendered != rendered || gotReused != reused {
+			t.Fatalf("rendered %d and reused %d tags. Expected %d and %d", gotRendered, gotReused, rendered, reused)
+		}
+	}
+
+	render("cpu 0.5 ##### mem 25% hosts 2 #1", 5, 0)
+	render("cpu 0.5 ##### mem 25% hosts 2 #1", 2, 3)
+	if calls != 3 {
+		// two renders plus the comparison with Execute
+		t.Fatalf("unexpected number of calls %d", calls)
+	}
+
+	m["mem"] = 0.5
+	m["hosts"] = []string{"a"}
+	render("cpu 0.5 ##### mem 50% hosts 1 #1", 3, 2)
+
+	m["cpu"] = 0.2
+	render("cpu 0.2 ## mem 50% hosts 1 #1", 4, 1)
+
+	// a failing render invalidates the cache
+	delete(m, "bar")
+	var bb bytes.Buffer
+	if _, err := r.Render(&bb, m); err == nil {
+		t.Fatalf("expected error")
+	}
+	m["bar"] = fmtLoad
+	render("cpu 0.2 ## mem 50% hosts 1 #1", 5, 0)
+}
+
+func TestIncrementalInvalidate(t *testing.T) {
+	greeter := func(greeting string) Func {
+		return Func{Fn: func(name string) string { return greeting + name }, Idempotent: true}
+	}
+	r := New("{{greet(name)}}", "{{", "}}").NewIncremental()
+	m := Map{"name": "Ann", "greet": greeter("Hi ")}
+	render := func(expected string) {
+		t.Helper()
+		var bb bytes.Buffer
+		if _, err := r.Render(&bb, m); err != nil || bb.String() != expected {
+			t.Fatalf("unexpected output %q, %v. Expected %q", bb.String(), err, expected)
+		}
+	}
+	render("Hi Ann")
+
+	// closures made by the same function need an explicit invalidation
+	m["greet"] = greeter("Hallo ")
+	r.Invalidate()
+	render("Hallo Ann")
+	if rendered, reused := r.Stats(); rendered != 1 || reused != 0 {
+		t.Fatalf("unexpected stats %d, %d", rendered, reused)
+	}
+
+	// functions of different code are detected
+	m["greet"] = Func{Fn: strings.ToUpper, Idempotent: true}
+	render("ANN")
+}
+
+func TestIncrementalBlocks(t *testing.T) {
+	tpl := New("{{capture x}}{{a}}{{end}}[{{x}}]", "{{", "}}")
+	r := tpl.NewIncremental()
+	var bb bytes.Buffer
+	if _, err := r.Render(&bb, Map{"a": "1"}); err != nil || bb.String() != "[1]" {
+		t.Fatalf("unexpected output %q, %v", bb.String(), err)
+	}
+}
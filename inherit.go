@@ -0,0 +1,94 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+)
+
+// Template inheritance keywords.
+const (
+	keywordBlock   = "block"
+	keywordExtends = "extends"
+)
+
+// blockOverride is a block of a template extending a layout, which replaces
+// the block with the same name in the layout.
+type blockOverride struct {
+	t     *Template
+	nodes []node
+}
+
+// extendLayout returns the nodes of a template extending a layout: the
+// extends node holding the blocks of the template. Content outside of the
+// blocks isn't rendered. Templates without an extends tag are returned
+// unchanged.
+func extendLayout(nodes []node) []node {
+	ext := -1
+	for i := range nodes {
+		if nodes[i].kind == nodeExtends {
+			ext = i
+			break
+		}
+	}
+	if ext < 0 {
+		return nodes
+	}
+	nd := nodes[ext]
+	for _, b := range nodes {
+		if b.kind == nodeBlock {
+			nd.nodes = append(nd.nodes, b)
+		}
+	}
+	return []node{nd}
+}
+
+// executeExtends renders the layout of an extends node, with the blocks of
+// the template overriding those of the layout. Blocks of templates further
+// down the inheritance chain take precedence.
+func (t *Template) executeExtends(w io.Writer, nd *node, s *scope, std bool) (int64, error) {
+	s.ec.setTag(nd.tag, t.tags[nd.tag])
+	layout, ok := s.ec.partials[nd.name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", errPartialNotFound, nd.name)
+	}
+	if err := s.ec.enterPartial(nd.name, layout); err != nil {
+		return 0, err
+	}
+	defer s.ec.includes.leave()
+
+	if s.ec.blocks == nil {
+		s.ec.blocks = make(map[string]blockOverride, len(nd.nodes))
+	}
+	for _, b := range nd.nodes {
+		if _, overridden := s.ec.blocks[b.name]; !overridden {
+			s.ec.blocks[b.name] = blockOverride{t: t, nodes: b.nodes}
+		}
+	}
+
+	lec := layout.partialContext(s.ec)
+	var n int64
+	var err error
+	if layout.nodes != nil {
+		ls := scope{data: s.data, routes: s.routes, ec: lec}
+		n, err = layout.executeNodes(w, layout.nodes, &ls, std)
+	} else {
+		n, err = layout.render(w, s.data, lec, std)
+	}
+	if err != nil {
+		return n, fmt.Errorf("layout %q: %w", nd.name, err)
+	}
+	return n, nil
+}
+
+// executeBlock renders a block, or the block overriding it.
+func (t *Template) executeBlock(w io.Writer, nd *node, s *scope, std bool) (int64, error) {
+	o, ok := s.ec.blocks[nd.name]
+	if !ok {
+		return t.executeNodes(w, nd.nodes, s, std)
+	}
+	// blocks nested in the override with the same name render their own
+	// content
+	delete(s.ec.blocks, nd.name)
+	defer func() { s.ec.blocks[nd.name] = o }()
+	return o.t.executeNodes(w, o.nodes, s, std)
+}
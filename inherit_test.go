@@ -0,0 +1,48 @@
+package fasttemplate
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestTemplateInheritance(t *testing.T) {
+	base := New(`<title>{{block "title"}}Site{{end}}</title><main>{{block "content"}}{{end}}</main>`, "{{", "}}")
+	if s := base.ExecuteString(Map{}); s != "<title>Site</title><main></main>" {
+		t.Fatalf("unexpected output of the layout %q", s)
+	}
+
+	page := New("{{extends \"base\"}}\nignored {{name}}\n{{block \"content\"}}Hi {{upper(name)}}{{end}}", "{{", "}}",
+		WithPartial("base", base))
+	if s := page.ExecuteString(Map{"name": "ann"}); s != "<title>Site</title><main>Hi ANN</main>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// the most derived template overrides the blocks of all its layouts
+	section := New(`{{extends 'base'}}{{block "title"}}Blog{{end}}{{block "content"}}<ul>{{block "posts"}}none{{end}}</ul>{{end}}`, "{{", "}}")
+	post := New(`{{extends "section"}}{{block "posts"}}{{range p in posts}}<li>{{p}}</li>{{end}}{{end}}`, "{{", "}}",
+		WithPartial("base", base), WithPartial("section", section))
+	if s := post.ExecuteString(Map{"posts": []string{"a", "b"}}); s != "<title>Blog</title><main><ul><li>a</li><li>b</li></ul></main>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New(`{{extends "section"}}`, "{{", "}}", WithPartial("base", base), WithPartial("section", section)).ExecuteString(Map{}); s != "<title>Blog</title><main><ul>none</ul></main>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if _, err := New(`{{extends "missing"}}`, "{{", "}}").Execute(io.Discard, Map{}); !errors.Is(err, errPartialNotFound) {
+		t.Fatalf("expected missing layout error, got %v", err)
+	}
+	a := New(`{{extends "b"}}`, "{{", "}}")
+	b := New(`{{extends "a"}}`, "{{", "}}")
+	cyclic := New(`{{extends "a"}}`, "{{", "}}", WithPartial("a", a), WithPartial("b", b))
+	if _, err := cyclic.Execute(io.Discard, Map{}); !errors.Is(err, ErrTemplateCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	// block and extends without a quoted name are regular tags, and extends
+	// only applies at the top level
+	m := Map{"block": "b", "extends": "e", "x": true}
+	if s := New("{{block}} {{extends}} {{if x}}{{extends \"base\"}}{{end}}", "{{", "}}").ExecuteString(m); s != "b e " {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
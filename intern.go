@@ -0,0 +1,116 @@
+package fasttemplate
+
+import "sync"
+
+// WithInterning interns the source and the tags of the template in
+// package-level tables shared by all templates using the option. Templates
+// compiled from equal sources then retain a single copy of the source, which
+// their static texts and tags point into, and templates with overlapping tag
+// names share the tag strings, which also improves the locality of map
+// lookups by tag. Interning never copies: the first template holding a
+// string keeps it alive for the templates sharing it.
+//
+// Interned strings are reference counted. Call [Template.Release] once the
+// template is no longer needed so the tables can drop strings no other
+// template uses; Reset releases the strings of the previous template
+// automatically.
+func WithInterning() Option {
+	return func(t *Template) {
+		t.interning = true
+	}
+}
+
+// internTable holds the interned strings with their reference counts.
+type internTable struct {
+	mu      sync.Mutex
+	strings map[string]*internEntry
+}
+
+type internEntry struct {
+	s    string
+	refs int
+}
+
+// interned is the package-level intern table of tags.
+var interned = internTable{
+	strings: make(map[string]*internEntry),
+}
+
+// internedSources is the package-level intern table of template sources.
+var internedSources = internTable{
+	strings: make(map[string]*internEntry),
+}
+
+// intern returns the interned string equal to s, adding a reference to it.
+// s itself is interned if the table holds no equal string.
+func (it *internTable) intern(s string) string {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	e, ok := it.strings[s]
+	if !ok {
+		e = &internEntry{s: s}
+		it.strings[s] = e
+	}
+	e.refs++
+	return e.s
+}
+
+// release drops a reference to the interned string s, removing it from the
+// table once it is no longer referenced.
+func (it *internTable) release(s string) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	e, ok := it.strings[s]
+	if !ok {
+		return
+	}
+	e.refs--
+	if e.refs <= 0 {
+		delete(it.strings, s)
+	}
+}
+
+// len returns the number of interned strings.
+func (it *internTable) len() int {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return len(it.strings)
+}
+
+// InternedCount returns the number of distinct tags in the intern table
+// used by templates created with [WithInterning].
+func InternedCount() int {
+	return interned.len()
+}
+
+// internSource returns the interned template source equal to template.
+func (t *Template) internSource(template string) string {
+	t.internedSource = true
+	return internedSources.intern(template)
+}
+
+// internTags interns the tags of the template.
+func (t *Template) internTags() {
+	for i, tag := range t.tags {
+		t.tags[i] = interned.intern(tag)
+	}
+	t.internedTags = len(t.tags)
+}
+
+// Release releases the interned source and tags of a template created with
+// [WithInterning]. The template keeps working after Release, but its strings
+// no longer count as references, so the intern tables may drop them. Release
+// is a no-op for other templates and when called more than once.
+//
+// Release may be called only if no other goroutines call t methods at the
+// moment.
+func (t *Template) Release() {
+	for _, tag := range t.tags[:t.internedTags] {
+		interned.release(tag)
+	}
+	t.internedTags = 0
+	if t.internedSource {
+		internedSources.release(t.template)
+		t.internedSource = false
+	}
+}
@@ -0,0 +1,112 @@
+package fasttemplate
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestWithInterning(t *testing.T) {
+	before := InternedCount()
+
+	a := New("Hello, {{name}}! {{upper(name)}}", "{{", "}}", WithInterning())
+	b := New("Bye, {{name}}.", "{{", "}}", WithInterning())
+	if n := InternedCount() - before; n != 2 {
+		t.Fatalf("expected 2 interned tags, got %d", n)
+	}
+	if unsafe.StringData(a.tags[0]) != unsafe.StringData(b.tags[0]) {
+		t.Fatal("expected templates to share the interned tag")
+	}
+	if s := a.ExecuteString(Map{"name": "ann", "upper": strings.ToUpper}); s != "Hello, ann! ANN" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	a.Release()
+	a.Release()
+	if n := InternedCount() - before; n != 1 {
+		t.Fatalf("expected 1 interned tag after Release, got %d", n)
+	}
+	if s := a.ExecuteString(Map{"name": "ann", "upper": strings.ToUpper}); s != "Hello, ann! ANN" {
+		t.Fatalf("unexpected output after Release %q", s)
+	}
+
+	if err := b.Reset("{{greeting}}, {{name}}", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := InternedCount() - before; n != 2 {
+		t.Fatalf("expected 2 interned tags after Reset, got %d", n)
+	}
+	b.Release()
+	if n := InternedCount() - before; n != 0 {
+		t.Fatalf("expected no interned tags, got %d", n)
+	}
+
+	New("{{name}}", "{{", "}}")
+	if n := InternedCount() - before; n != 0 {
+		t.Fatalf("expected no interning without the option, got %d", n)
+	}
+}
+
+func TestWithInterningSources(t *testing.T) {
+	source := strings.Repeat("Hello, {{name}}! ", 100)
+	a := New(strings.Clone(source), "{{", "}}", WithInterning())
+	b := New(strings.Clone(source), "{{", "}}", WithInterning())
+	if unsafe.StringData(a.template) != unsafe.StringData(b.template) {
+		t.Fatal("expected templates to share the interned source")
+	}
+	// the texts and tags point into the retained source instead of copies
+	start := uintptr(unsafe.Pointer(unsafe.StringData(a.template)))
+	end := start + uintptr(len(a.template))
+	for _, tag := range b.tags {
+		if p := uintptr(unsafe.Pointer(unsafe.StringData(tag))); p < start || p >= end {
+			t.Fatal("expected the tags to point into the source")
+		}
+	}
+	if s := b.ExecuteString(Map{"name": "ann"}); s != strings.Repeat("Hello, ann! ", 100) {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if err := b.Patch(TextEdit{Offset: 0, Length: 5, Text: "Hi"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := b.ExecuteString(Map{"name": "ann"}); !strings.HasPrefix(s, "Hi, ann! Hello, ann! ") {
+		t.Fatalf("unexpected output after Patch %q", s)
+	}
+	a.Release()
+	b.Release()
+	c := New(strings.Clone(source), "{{", "}}", WithInterning())
+	defer c.Release()
+	if unsafe.StringData(c.template) == unsafe.StringData(a.template) {
+		t.Fatal("expected the released source to be dropped")
+	}
+}
+
+func BenchmarkInterning(b *testing.B) {
+	source := strings.Repeat("Dear {{name}}, your order {{order}} ships on {{date}}.\n", 50)
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Plain", nil},
+		{"Interning", []Option{WithInterning()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			templates := make([]*Template, b.N)
+			for i := range templates {
+				// every template gets its own copy of the source, like
+				// templates loaded from storage
+				templates[i] = New(strings.Clone(source), "{{", "}}", bc.opts...)
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(b.N), "retained-B/op")
+			for _, t := range templates {
+				t.Release()
+			}
+		})
+	}
+}
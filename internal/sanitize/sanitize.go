@@ -0,0 +1,19 @@
+// Package sanitize holds the sanitizing of rendered header values shared by
+// the sub-packages of fasttemplate.
+package sanitize
+
+import (
+	"strings"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// StripLineBreaks replaces the line breaks of s with spaces, so values can't
+// inject header lines.
+func StripLineBreaks(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
+
+// StripValueLineBreaks is a value transformer stripping the line breaks of
+// the text values render as.
+var StripValueLineBreaks = fasttemplate.TextTransformer(StripLineBreaks)
@@ -0,0 +1,16 @@
+package sanitize
+
+import "testing"
+
+func TestStripLineBreaks(t *testing.T) {
+	for s, expected := range map[string]string{
+		"a":               "a",
+		"a\r\nBcc: x":     "a Bcc: x",
+		"\na\n\nb\r":      "a b",
+		"no break at all": "no break at all",
+	} {
+		if got := StripLineBreaks(s); got != expected {
+			t.Fatalf("unexpected result %q for %q; want %q", got, s, expected)
+		}
+	}
+}
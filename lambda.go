@@ -0,0 +1,216 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Lambda is an anonymous function written in a template, e.g. the second
+// argument of {{map(items, (x) => upper(x))}}.
+//
+// Functions receive lambdas through parameters of type Lambda or any.
+// Lambdas passed to parameters of other function types, such as
+// func(string) bool, are converted to that type: the arguments are bound to
+// the lambda parameters and the result is converted to the result type.
+//
+// The body of a lambda is a variable, literal, function call or expression.
+// It is evaluated against the substitution map of the execution, with the
+// lambda parameters taking precedence. Dotted names select fields of
+// parameters, e.g. (u) => u.name.
+type Lambda func(args ...any) (any, error)
+
+var (
+	lambdaType = reflect.TypeOf(Lambda(nil))
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// lambdaArg is a lambda literal parsed from a function argument.
+type lambdaArg struct {
+	params []string
+	body   any
+}
+
+// parseLambda parses s as a lambda literal, (x, y) => body or x => body. It
+// reports false if s isn't a lambda literal.
+func parseLambda(s string) (*lambdaArg, bool, error) {
+	left, body, ok := strings.Cut(s, "=>")
+	if !ok {
+		return nil, false, nil
+	}
+
+	left = strings.TrimSpace(left)
+	var params []string
+	if strings.HasPrefix(left, "(") && strings.HasSuffix(left, ")") {
+		if inner := strings.TrimSpace(left[1 : len(left)-1]); inner != "" {
+			for _, p := range strings.Split(inner, ",") {
+				params = append(params, strings.TrimSpace(p))
+			}
+		}
+	} else {
+		params = []string{left}
+	}
+	for _, p := range params {
+		if !isValidFunctionName(p) {
+			return nil, false, nil
+		}
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, true, fmt.Errorf("%w: missing body: %s", errInvalidLambda, s)
+	}
+	parsed, err := parseArg(body)
+	if err != nil {
+		return nil, true, err
+	}
+	if _, isSpread := parsed.(*spreadArg); isSpread {
+		return nil, true, fmt.Errorf("%w: cannot spread the body: %s", errInvalidLambda, s)
+	}
+	return &lambdaArg{params: params, body: parsed}, true, nil
+}
+
+// bind returns the lambda as a function evaluating its body in the context
+// of the call it is passed to.
+func (l *lambdaArg) bind(funcs, data Map, ec *evalContext) Lambda {
+	return func(args ...any) (any, error) {
+		var child evalContext
+		if ec != nil {
+			ec.sharedCounters()
+			child = *ec
+		}
+		// copy the parameters of enclosing lambdas, so nested lambdas can
+		// refer to them
+		child.params = make(map[string]any, len(child.params)+len(l.params))
+		if ec != nil {
+			for name, v := range ec.params {
+				child.params[name] = v
+			}
+		}
+		for i, name := range l.params {
+			if i < len(args) {
+				child.params[name] = args[i]
+			}
+		}
+		return evalArg(l.body, funcs, data, &child)
+	}
+}
+
+// evalArg evaluates a parsed argument strictly: unlike plain function
+// arguments, unknown variables are errors rather than literal strings.
+func evalArg(arg any, funcs, data Map, ec *evalContext) (any, error) {
+	switch a := arg.(type) {
+	case literalString:
+		return string(a), nil
+	case string:
+		v, ok := ec.lookup(data, a)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errVariableNotFound, a)
+		}
+		return v, nil
+	case *functionCall:
+		return a.execute(funcs, data, ec)
+	case *expressionPlaceholder:
+		return evalExpression(a.expression, data, ec)
+	case *lambdaArg:
+		return a.bind(funcs, data, ec), nil
+	default:
+		return a, nil
+	}
+}
+
+// param returns the value of a lambda parameter, or of a field of it if
+// name is a dotted path.
+func (ec *evalContext) param(name string) (any, bool) {
+	if v, ok := ec.params[name]; ok {
+		return v, true
+	}
+	root, rest, nested := strings.Cut(name, ".")
+	if !nested {
+		return nil, false
+	}
+	v, ok := ec.params[root]
+	if !ok {
+		return nil, false
+	}
+	return fieldValue(v, rest)
+}
+
+// paramType returns the type of the i-th argument of a call of a function
+// of type fnType, or nil if the function doesn't take that many arguments.
+func paramType(fnType reflect.Type, i int) reflect.Type {
+	n := fnType.NumIn()
+	switch {
+	case fnType.IsVariadic() && i >= n-1:
+		return fnType.In(n - 1).Elem()
+	case i < n:
+		return fnType.In(i)
+	}
+	return nil
+}
+
+// lambdaValue converts the lambda to a value of type t.
+func lambdaValue(l Lambda, t reflect.Type) reflect.Value {
+	if t == nil || t.Kind() != reflect.Func || t == lambdaType {
+		return reflect.ValueOf(l)
+	}
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]any, len(in))
+		for i, arg := range in {
+			args[i] = arg.Interface()
+		}
+
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		fail := func(err error) []reflect.Value {
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				out[len(out)-1] = reflect.ValueOf(&err).Elem()
+				return out
+			}
+			// functions without an error result can only fail by
+			// panicking; the panic is recovered by the calling template
+			panic(err)
+		}
+
+		v, err := l(args...)
+		if err != nil {
+			return fail(err)
+		}
+		if len(out) > 0 && t.Out(0) != errorType {
+			rv, err := convertValue(v, t.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+			out[0] = rv
+		}
+		return out
+	})
+}
+
+// convertValue converts v to a value of type t, applying the conversions of
+// expressions to strings, booleans and numbers.
+func convertValue(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(toString(v)).Convert(t), nil
+	case reflect.Bool:
+		return reflect.ValueOf(toBool(v)).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(toFloat64(v)).Convert(t), nil
+	}
+	if rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("%w: cannot use result of type %T as %s", errInvalidLambda, v, t)
+}
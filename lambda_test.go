@@ -0,0 +1,86 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLambdas(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	m := Map{
+		"upper": strings.ToUpper,
+		"items": []string{"a", "b", "c"},
+		"nums":  []int{3, 1, 2},
+		"users": []user{{"Cid", 17}, {"Ann", 42}, {"Bob", 30}},
+		"apply": func(f func(string) string, s string) string { return f(s) },
+		"count": func(xs []int, pred func(int) bool) int {
+			n := 0
+			for _, x := range xs {
+				if pred(x) {
+					n++
+				}
+			}
+			return n
+		},
+		"call":   func(l Lambda) (any, error) { return l("arg") },
+		"suffix": "!",
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{map(items, (x) => upper(x))}}", "[A B C]"},
+		{"{{map(nums, x => x * 2)}}", "[6 2 4]"},
+		{"{{map(nums, (x) => x + 1 > 2)}}", "[true false true]"},
+		{"{{map(users, (u) => u.name)}}", "[Cid Ann Bob]"},
+		{"{{mapField(filter(users, (u) => u.age >= 18), 'name')}}", "[Ann Bob]"},
+		{"{{mapField(sortBy(users, (u) => u.age), 'name')}}", "[Cid Bob Ann]"},
+		{"{{map(items, (x) => map(nums, (n) => x + n))}}", "[[a3 a1 a2] [b3 b1 b2] [c3 c1 c2]]"},
+		{"{{map(items, () => 'k')}}", "[k k k]"},
+		{"{{apply((s) => upper(s) + suffix, 'hi')}}", "HI!"},
+		{"{{count(nums, (n) => n >= 2)}}", "2"},
+		{"{{call((a, b) => a)}}", "arg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var bb bytes.Buffer
+			if _, err := New(tt.template, "{{", "}}").Execute(&bb, m); err != nil || bb.String() != tt.expected {
+				t.Fatalf("unexpected result %q, %v", bb.String(), err)
+			}
+		})
+	}
+
+	for _, template := range []string{
+		"{{map(items, (x) => missing)}}",
+		"{{map(items, (x) =>)}}",
+		"{{map(items, (x) => items...)}}",
+		"{{apply((s) => missing, 'hi')}}",
+		"{{map(items, 'x')}}",
+	} {
+		t.Run(template, func(t *testing.T) {
+			if _, err := New(template, "{{", "}}").Execute(io.Discard, m); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+
+	_, err := New("{{map(items, (x) => missing)}}", "{{", "}}").Execute(io.Discard, m)
+	if !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("expected variable not found error, got %v", err)
+	}
+}
+
+func TestLambdaDeterministic(t *testing.T) {
+	m := Map{"items": []string{"a"}, "rand": func() string { return "r" }}
+	_, err := New("{{map(items, (x) => rand())}}", "{{", "}}", WithDeterministic()).Execute(io.Discard, m)
+	if !errors.Is(err, errNondeterministicFunc) {
+		t.Fatalf("expected deterministic mode error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package fasttemplate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// WithLiteralTags makes tags matching re plain variables, even if they
+// contain operator characters, e.g. to resolve header names such as
+// {{content-type}} and {{x-request-id}}:
+//
+//	fasttemplate.WithLiteralTags(regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`))
+//
+// Without the option, hyphenated names are only resolved as variables if
+// the substitution map has a key matching the tag; otherwise they are
+// evaluated as subtractions.
+func WithLiteralTags(re *regexp.Regexp) Option {
+	return func(t *Template) {
+		t.literalTags = re
+	}
+}
+
+// WithTagNameCharset makes tags consisting of letters, digits, underscores
+// and the characters of extra plain variables, before they are classified as
+// function calls or expressions, e.g. to resolve keys such as
+// labels.app/name with the extra characters "./":
+//
+//	fasttemplate.WithTagNameCharset("./-")
+//
+// Tags routed to tag handlers by WithTagPrefix are still routed to them, so
+// avoid ':' in extra when using tag prefixes.
+func WithTagNameCharset(extra string) Option {
+	return func(t *Template) {
+		t.tagNameChars = extra
+	}
+}
+
+// isLiteralTag reports whether the tag is a plain variable by WithLiteralTags,
+// WithTagNameCharset or WithLegacyBehavior.
+func (t *Template) isLiteralTag(tag string) bool {
+	if t.legacy {
+		return true
+	}
+	if t.literalTags != nil && t.literalTags.MatchString(tag) {
+		return true
+	}
+	return t.tagNameChars != "" && isTagName(tag, t.tagNameChars)
+}
+
+// literalTagFunc returns isLiteralTag, or nil if no tag is literal.
+func (t *Template) literalTagFunc() func(string) bool {
+	if t.literalTags == nil && t.tagNameChars == "" && !t.legacy {
+		return nil
+	}
+	return t.isLiteralTag
+}
+
+// isTagName reports whether the tag consists of letters, digits,
+// underscores and the characters of extra.
+func isTagName(tag, extra string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && !strings.ContainsRune(extra, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// classifyTag works like the package-level classifyTag, but honors
+// WithLiteralTags and WithTagNameCharset.
+func (t *Template) classifyTag(tag string) tagKind {
+	return classifyTag(tag, t.literalTagFunc())
+}
+
+// analyzeTag works like the package-level analyzeTag, but honors
+// WithLiteralTags and WithTagNameCharset.
+func (t *Template) analyzeTag(tag string) *tagAnalysis {
+	return analyzeTag(tag, t.literalTagFunc())
+}
+
+// isVariableTag reports whether evalTag resolves the tag as a plain
+// variable before trying defaults, calls and expressions: if literal
+// reports it, or if it is a hyphenated name.
+func isVariableTag(tag string, literal func(string) bool) bool {
+	return (literal != nil && literal(tag)) || isHyphenatedName(tag)
+}
+
+// isHyphenatedName reports whether the tag is a name with inner hyphens,
+// e.g. content-type, which reads as a subtraction otherwise.
+func isHyphenatedName(tag string) bool {
+	if !strings.Contains(tag, "-") || tag[0] == '-' || tag[len(tag)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		if c := tag[i]; c != '-' && !isIdentByte(c, false) {
+			return false
+		}
+	}
+	return !strings.Contains(tag, "--")
+}
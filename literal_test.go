@@ -0,0 +1,96 @@
+package fasttemplate
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHyphenatedTags(t *testing.T) {
+	tpl := New("{{content-type}} {{a-b}} {{a - b}}", "{{", "}}")
+	m := Map{"content-type": "text/html", "a": 5, "b": 2}
+	if s := tpl.ExecuteString(m); s != "text/html 3 3" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// a matching key wins over the subtraction
+	m["a-b"] = "key"
+	if s := tpl.ExecuteString(m); s != "text/html key 3" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithLiteralTags(t *testing.T) {
+	re := regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+	tpl := New("[{{x-request-id}}] [{{a-b}}] {{a * b}}", "{{", "}}", WithLiteralTags(re))
+	m := Map{"x-request-id": "42", "a": 5, "b": 2}
+	if s := tpl.ExecuteString(m); s != "[42] [] 10" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if vars := tpl.Variables(); !reflect.DeepEqual(vars, []string{"x-request-id", "a-b", "a", "b"}) {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+	if err := tpl.Validate(m); err == nil || !strings.Contains(err.Error(), `unresolved tag "a-b"`) {
+		t.Fatalf("unexpected validation error %v", err)
+	}
+	findings := tpl.Analyze(m)
+	if len(findings) != 1 || findings[0].Kind != FindingAlwaysEmpty || findings[0].Tag != "a-b" {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	if s := tpl.Explain(); !strings.Contains(s, "variable {{x-request-id}}") {
+		t.Fatalf("unexpected explanation %q", s)
+	}
+}
+
+func TestIsHyphenatedName(t *testing.T) {
+	for tag, want := range map[string]bool{
+		"content-type": true,
+		"x-request-id": true,
+		"user_id-2":    true,
+		"name":         false,
+		"-a":           false,
+		"a-":           false,
+		"a--b":         false,
+		"a - b":        false,
+		"a-b*c":        false,
+		"":             false,
+	} {
+		if got := isHyphenatedName(tag); got != want {
+			t.Fatalf("isHyphenatedName(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestWithTagNameCharset(t *testing.T) {
+	tpl := New("{{labels.app/name}} {{aws:region}} {{a/b}} {{upper(env)}} [{{x.y/z}}]", "{{", "}}", WithTagNameCharset(".:/"))
+	m := Map{
+		"labels.app/name": "web",
+		"aws:region":      "eu-west-1",
+		"a/b":             "slash",
+		"a":               6,
+		"b":               3,
+		"env":             "prod",
+		"upper":           strings.ToUpper,
+	}
+	if s := tpl.ExecuteString(m); s != "web eu-west-1 slash PROD []" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if vars := tpl.Variables(); !reflect.DeepEqual(vars, []string{"labels.app/name", "aws:region", "a/b", "env", "x.y/z"}) {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+
+	// without the charset, a/b is a division
+	if s := New("{{a/b}}", "{{", "}}").ExecuteString(m); s != "2" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// tag handlers take precedence
+	tpl = New("{{aws:region}}", "{{", "}}", WithTagNameCharset(":"), WithTagPrefix("aws", func(arg string, m Map) (any, error) {
+		return "handled " + arg, nil
+	}))
+	if s := tpl.ExecuteString(m); s != "handled region" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -3,8 +3,10 @@ package fasttemplate
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -14,6 +16,9 @@ type Map map[string]any
 
 // Merge combines the contents of another Map into this Map.
 // Values from the other Map will overwrite values in this Map if keys conflict.
+//
+// Merge modifies the receiver. Use MergedWith to keep maps shared between
+// executions intact.
 func (m Map) Merge(other Map) Map {
 	for k, v := range other {
 		m[k] = v
@@ -21,6 +26,93 @@ func (m Map) Merge(other Map) Map {
 	return m
 }
 
+// Keys returns the keys of the Map in sorted order, so diagnostics and other
+// output derived from the Map are stable between runs. Range blocks iterate
+// Map values and completions list variables in this order.
+func (m Map) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Clone returns a shallow copy of the Map.
+func (m Map) Clone() Map {
+	if m == nil {
+		return nil
+	}
+	clone := make(Map, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// MergedWith returns a new Map with the contents of this Map and the other
+// one. Values from the other Map take precedence if keys conflict. Neither
+// Map is modified.
+func (m Map) MergedWith(other Map) Map {
+	merged := make(Map, len(m)+len(other))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ReadOnlyMap is a read-only view of a Map, e.g. a base map shared between
+// tenants. Writes fail instead of polluting the underlying Map; use Clone or
+// MergedWith to derive writable copies for execution.
+type ReadOnlyMap struct {
+	m Map
+}
+
+// ReadOnly returns a read-only view of m.
+func ReadOnly(m Map) ReadOnlyMap {
+	return ReadOnlyMap{m: m}
+}
+
+// Get returns the value stored under key.
+func (r ReadOnlyMap) Get(key string) (any, bool) {
+	v, ok := r.m[key]
+	return v, ok
+}
+
+// Len returns the number of keys.
+func (r ReadOnlyMap) Len() int {
+	return len(r.m)
+}
+
+// Keys returns the keys of the underlying Map in sorted order.
+func (r ReadOnlyMap) Keys() []string {
+	return r.m.Keys()
+}
+
+// Set always fails, as the map is read-only.
+func (r ReadOnlyMap) Set(key string, v any) error {
+	return fmt.Errorf("%w: cannot set %q", errReadOnlyMap, key)
+}
+
+// Merge always fails, as the map is read-only. Use MergedWith instead.
+func (r ReadOnlyMap) Merge(other Map) error {
+	return fmt.Errorf("%w: cannot merge", errReadOnlyMap)
+}
+
+// Clone returns a writable shallow copy of the underlying Map.
+func (r ReadOnlyMap) Clone() Map {
+	return r.m.Clone()
+}
+
+// MergedWith returns a new Map with the contents of the underlying Map and
+// the other one, like Map.MergedWith.
+func (r ReadOnlyMap) MergedWith(other Map) Map {
+	return r.m.MergedWith(other)
+}
+
 // FunctionCall represents a parsed function call in a template.
 type functionCall struct {
 	Name string
@@ -32,24 +124,63 @@ type expressionPlaceholder struct {
 	expression string
 }
 
+// spreadArg represents an argument followed by "...", whose elements are
+// passed as separate arguments, e.g. items in join(", ", items...).
+type spreadArg struct {
+	arg any
+}
+
+// hasSpread reports whether the call spreads a slice argument, so its
+// argument count is only known when it is executed.
+func (fc *functionCall) hasSpread() bool {
+	if n := len(fc.Args); n > 0 {
+		_, ok := fc.Args[n-1].(*spreadArg)
+		return ok
+	}
+	return false
+}
+
 // literalString represents a string that was quoted in the original template
 // and should be treated as a literal value, not a variable reference.
 type literalString string
 
 // executeFunctionCall executes the function represented by this call.
-func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
-	fn, ok := funcs[fc.Name]
+func (fc *functionCall) execute(funcs, data Map, ec *evalContext) (interface{}, error) {
+	// Calls with a timeout may be abandoned while still running, so the
+	// function and its lambdas are bound to a detached copy of the
+	// execution state, which is adopted once the call returns in time
+	p := ec.policy(fc.Name)
+	cec, cdata := ec, data
+	if p.detaches() {
+		cec, cdata = ec.detach(), data.Clone()
+	}
+
+	v, ok := funcs[fc.Name]
+	f := asFunc(v)
+	if !ok {
+		f, ok = cec.lookupFunc(fc.Name, cdata)
+	}
 	if !ok {
 		return nil, fmt.Errorf("%w: %s", errFunctionNotFound, fc.Name)
 	}
+	fn := f.Fn
 
 	// Prepare args
 	fnType := reflect.TypeOf(fn)
-	if fnType.Kind() != reflect.Func {
+	if fnType == nil || fnType.Kind() != reflect.Func {
 		return nil, fmt.Errorf("%s is not a function", fc.Name)
 	}
+	if err := checkResults(fc.Name, fnType); err != nil {
+		return nil, err
+	}
+	if err := ec.checkCall(fc.Name, f, funcs, data); err != nil {
+		return nil, err
+	}
 
-	reflectArgs := make([]reflect.Value, 0, len(fc.Args))
+	reflectArgs := make([]reflect.Value, 0, len(fc.Args)+1)
+	if takesCallInfo(fnType) {
+		reflectArgs = append(reflectArgs, reflect.ValueOf(ec.callInfo(fc.Name)))
+	}
 
 	for _, arg := range fc.Args {
 		// Fast path for simple types (most common case)
@@ -62,20 +193,20 @@ func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
 		case string:
 			// Handle variable lookup for strings
 			if data != nil {
-				if val, exists := data[typedArg]; exists {
+				if val, exists := ec.lookup(data, typedArg); exists {
 					reflectArgs = append(reflectArgs, reflect.ValueOf(val))
 					continue
 				}
 
-				// For unquoted variables like in upper(last_name)
-				// We need to check if this string is likely a variable name
-				// rather than a literal string value
-				if isLikelyVariable(typedArg) {
+				// Unquoted strings like hello world in upper(hello world)
+				// are only literals with WithLiteralFallback, as they are
+				// usually typos of variable names
+				if ec == nil || !ec.literalFallback || isLikelyVariable(typedArg) {
 					return nil, fmt.Errorf("%w: %s", errVariableNotFound, typedArg)
 				}
 			}
 
-			// If not a variable or if data is nil, treat as literal
+			// If data is nil or the fallback applies, treat as literal
 			reflectArgs = append(reflectArgs, reflect.ValueOf(typedArg))
 
 		case int, float64, bool:
@@ -83,7 +214,7 @@ func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
 			reflectArgs = append(reflectArgs, reflect.ValueOf(arg))
 		case *functionCall:
 			// Handle nested function calls
-			result, err := typedArg.execute(funcs, data)
+			result, err := typedArg.execute(funcs, data, ec)
 			if err != nil {
 				// Bubble up the error for proper handling in Std mode
 				return nil, err
@@ -91,17 +222,37 @@ func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
 			reflectArgs = append(reflectArgs, reflect.ValueOf(result))
 		case *expressionPlaceholder:
 			// Handle expressions
-			result, err := evalExpression(typedArg.expression, data)
+			result, err := evalExpression(typedArg.expression, data, ec)
 			if err != nil {
 				// Bubble up the error for proper handling in Std mode
 				return nil, err
 			}
 			reflectArgs = append(reflectArgs, reflect.ValueOf(result))
+		case *spreadArg:
+			// Pass the elements of a slice as separate arguments
+			values, err := fc.spread(typedArg.arg, funcs, data, ec)
+			if err != nil {
+				return nil, err
+			}
+			reflectArgs = append(reflectArgs, values...)
+		case *lambdaArg:
+			// Pass lambdas as functions of the parameter type
+			l := typedArg.bind(funcs, cdata, cec)
+			reflectArgs = append(reflectArgs, lambdaValue(l, paramType(fnType, len(reflectArgs))))
 		default:
 			reflectArgs = append(reflectArgs, reflect.ValueOf(arg))
 		}
 	}
 
+	bridgeBytes(fnType, reflectArgs)
+
+	memoKey, memoize := ec.memoKey(fc.Name, f, funcs, data, reflectArgs)
+	if memoize {
+		if result, ok := ec.memoized(memoKey); ok {
+			return result, nil
+		}
+	}
+
 	// Call the function with panic recovery
 	var panicErr error
 	var result []reflect.Value
@@ -111,7 +262,7 @@ func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
 
 	// For variadic funcs, we need to handle the arguments differently
 	var callResult []reflect.Value
-	func() {
+	call := func() {
 		defer func() {
 			if r := recover(); r != nil {
 				panicErr = fmt.Errorf("%s: %v", fc.Name, r)
@@ -142,35 +293,163 @@ func (fc *functionCall) execute(funcs, data Map) (interface{}, error) {
 			// For non-variadic funcs, just call normally
 			callResult = fnValue.Call(reflectArgs)
 		}
-	}()
+	}
+
+	// Apply the timeout and circuit breaker configured for the function
+	if err := p.allow(fc.Name); err != nil {
+		return nil, err
+	}
+	if err := p.call(fc.Name, call); err != nil {
+		p.done(false)
+		return nil, err
+	}
+	if cec != ec {
+		ec.adopt(cec)
+	}
 
 	if panicErr != nil {
+		p.done(false)
 		return nil, panicErr
 	}
 
 	result = callResult
 
-	// Fast path for functions with no return value
-	if len(result) == 0 {
+	// Handle error return value if present
+	if n := len(result); n > 0 && isErrorType(fnType.Out(n-1)) && !result[n-1].IsNil() {
+		p.done(false)
+		return nil, result[n-1].Interface().(error)
+	}
+	p.done(true)
+
+	// Functions with no return value, or returning only an error, render
+	// nothing
+	if len(result) == 0 || (len(result) == 1 && isErrorType(fnType.Out(0))) {
 		return nil, nil
 	}
 
-	// Fast path for single return value (most common case)
-	if len(result) == 1 {
-		return result[0].Interface(), nil
+	value := result[0].Interface()
+	if memoize {
+		ec.memoize(memoKey, value)
+	}
+	return value, nil
+}
+
+// bridgeBytes converts []byte arguments passed to string parameters, and
+// string arguments passed to []byte parameters, so functions can take []byte
+// values of the Map like strings and be declared with []byte parameters.
+func bridgeBytes(fnType reflect.Type, args []reflect.Value) {
+	for i, arg := range args {
+		pt := paramType(fnType, i)
+		if pt == nil || !arg.IsValid() {
+			continue
+		}
+		at := arg.Type()
+		if (isBytesType(at) && pt.Kind() == reflect.String) || (at.Kind() == reflect.String && isBytesType(pt)) {
+			args[i] = arg.Convert(pt)
+		}
 	}
+}
 
-	// Handle error return value if present
-	if len(result) >= 2 && !result[1].IsNil() && result[1].Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-		return nil, result[1].Interface().(error)
+// isBytesType reports whether t is []byte or a type based on it.
+func isBytesType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// checkResults returns an error unless the results of the named function
+// have one of the supported shapes: none, T, error or (T, error).
+func checkResults(name string, fnType reflect.Type) error {
+	switch n := fnType.NumOut(); {
+	case n <= 1:
+		return nil
+	case n == 2 && isErrorType(fnType.Out(1)):
+		return nil
+	}
+	results := make([]string, fnType.NumOut())
+	for i := range results {
+		results[i] = fnType.Out(i).String()
+	}
+	return fmt.Errorf("%w: %s returns (%s), expected T, error or (T, error)", errUnsupportedResults, name, strings.Join(results, ", "))
+}
+
+// isErrorType reports whether results of type t are errors: the error
+// interface, or an interface or pointer type implementing it.
+func isErrorType(t reflect.Type) bool {
+	if t == errorType {
+		return true
+	}
+	return (t.Kind() == reflect.Interface || t.Kind() == reflect.Pointer) && t.Implements(errorType)
+}
+
+// spread evaluates the spread argument arg and returns its elements.
+func (fc *functionCall) spread(arg any, funcs, data Map, ec *evalContext) ([]reflect.Value, error) {
+	v, err := evalArg(arg, funcs, data, ec)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: %s: cannot spread %T", errInvalidSpread, fc.Name, v)
+	}
+	values := make([]reflect.Value, rv.Len())
+	for i := range values {
+		values[i] = reflect.ValueOf(rv.Index(i).Interface())
+	}
+	return values, nil
+}
+
+// functionCallCache is a cache for storing parsed function calls
+type functionCallCache struct {
+	mu    sync.RWMutex
+	calls map[string]*functionCall
+}
+
+// callCache is the global function call cache
+var callCache = functionCallCache{
+	calls: make(map[string]*functionCall),
+}
+
+// maxParseCacheSize is the maximum number of entries of the global parse
+// caches. A full cache is emptied before adding another entry, so templates
+// built from user input can't grow it without bound.
+const maxParseCacheSize = 4096
+
+// parseFunctionCallCached works like parseFunctionCall, but caches the parsed
+// calls. Parsed calls are never modified, so they can be shared.
+func parseFunctionCallCached(s string) (*functionCall, error) {
+	callCache.mu.RLock()
+	fc, found := callCache.calls[s]
+	callCache.mu.RUnlock()
+	if found {
+		return fc, nil
 	}
 
-	return result[0].Interface(), nil
+	// s may be a substring of a template source, which the cache must not
+	// keep alive
+	s = strings.Clone(s)
+	fc, err := parseFunctionCall(s)
+	if err != nil {
+		return nil, err
+	}
+
+	callCache.mu.Lock()
+	if len(callCache.calls) >= maxParseCacheSize {
+		callCache.calls = make(map[string]*functionCall)
+	}
+	callCache.calls[s] = fc
+	callCache.mu.Unlock()
+	return fc, nil
 }
 
 // parseFunctionCall parses a string into a function call structure.
 func parseFunctionCall(s string) (*functionCall, error) {
 	s = strings.TrimSpace(s)
+	if isMethodChain(s) {
+		// method chain sugar, e.g. name.upper().trim()
+		if chained, ok := desugarChain(s); ok {
+			s = chained
+		}
+	}
 
 	// find function name
 	parenIdx := strings.IndexByte(s, '(')
@@ -282,6 +561,12 @@ func parseArgs(s string) ([]interface{}, error) {
 		args = append(args, arg)
 	}
 
+	for i, arg := range args {
+		if _, ok := arg.(*spreadArg); ok && i != len(args)-1 {
+			return nil, fmt.Errorf("%w: only the last argument can be spread: %s", errInvalidSpread, s)
+		}
+	}
+
 	return args, nil
 }
 
@@ -301,6 +586,27 @@ func parseArg(s string) (interface{}, error) {
 		}
 	}
 
+	// Check for a lambda, e.g. (x) => upper(x)
+	if lambda, ok, err := parseLambda(s); ok || err != nil {
+		return lambda, err
+	}
+
+	// Check for a spread argument, e.g. items...
+	if inner, ok := strings.CutSuffix(s, "..."); ok {
+		inner = strings.TrimSpace(inner)
+		if inner == "" {
+			return nil, fmt.Errorf("%w: missing argument before ...", errInvalidSpread)
+		}
+		arg, err := parseArg(inner)
+		if err != nil {
+			return nil, err
+		}
+		if _, isLiteral := arg.(literalString); isLiteral {
+			return nil, fmt.Errorf("%w: cannot spread string literal %s", errInvalidSpread, inner)
+		}
+		return &spreadArg{arg: arg}, nil
+	}
+
 	// Check if it's a nested func call
 	if strings.IndexByte(s, '(') > 0 && s[len(s)-1] == ')' {
 		funcCall, err := parseFunctionCall(s)
@@ -359,11 +665,12 @@ func isValidFunctionName(name string) bool {
 	return true
 }
 
-// isFunctionCall checks if a tag might be a function call.
+// isFunctionCall checks if a tag might be a function call. Negations such
+// as !f(x) are expressions.
 func isFunctionCall(tag string) bool {
 	tag = strings.TrimSpace(tag)
 	parenIdx := strings.IndexByte(tag, '(')
-	return parenIdx > 0 && strings.HasSuffix(tag, ")")
+	return parenIdx > 0 && tag[0] != '!' && strings.HasSuffix(tag, ")")
 }
 
 // isLikelyVariable determines if a string is likely a variable name rather than
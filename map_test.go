@@ -0,0 +1,72 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMapCloneAndMergedWith(t *testing.T) {
+	base := Map{"a": 1, "b": 2}
+
+	clone := base.Clone()
+	clone["a"] = 10
+	if base["a"] != 1 {
+		t.Fatalf("Clone must not share storage with the original")
+	}
+	if Map(nil).Clone() != nil {
+		t.Fatalf("Clone of nil Map must be nil")
+	}
+
+	merged := base.MergedWith(Map{"b": 20, "c": 30})
+	if len(base) != 2 || base["b"] != 2 {
+		t.Fatalf("MergedWith must not modify the receiver: %v", base)
+	}
+	if len(merged) != 3 || merged["a"] != 1 || merged["b"] != 20 || merged["c"] != 30 {
+		t.Fatalf("unexpected merged map %v", merged)
+	}
+}
+
+func TestReadOnlyMap(t *testing.T) {
+	base := Map{"greeting": "Hello"}
+	ro := ReadOnly(base)
+
+	if err := ro.Set("greeting", "Hi"); !errors.Is(err, errReadOnlyMap) {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	if err := ro.Merge(Map{"greeting": "Hi"}); !errors.Is(err, errReadOnlyMap) {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	if v, ok := ro.Get("greeting"); !ok || v != "Hello" || ro.Len() != 1 {
+		t.Fatalf("unexpected value %v", v)
+	}
+
+	tpl := New("{{greeting}}, {{name}}!", "{{", "}}")
+	for _, name := range []string{"Ann", "Bob"} {
+		m := ro.MergedWith(Map{"name": name})
+		if s := tpl.ExecuteString(m); s != "Hello, "+name+"!" {
+			t.Fatalf("unexpected output %q", s)
+		}
+	}
+	if len(base) != 1 {
+		t.Fatalf("base map was modified: %v", base)
+	}
+	if c := ro.Clone(); len(c) != 1 || c["greeting"] != "Hello" {
+		t.Fatalf("unexpected clone %v", c)
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	m := Map{"b": 1, "c": 2, "a": 3}
+	for i := 0; i < 10; i++ {
+		if keys := strings.Join(m.Keys(), ","); keys != "a,b,c" {
+			t.Fatalf("unexpected keys %q", keys)
+		}
+	}
+	if keys := ReadOnly(m).Keys(); len(keys) != 3 || keys[0] != "a" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if keys := Map(nil).Keys(); len(keys) != 0 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
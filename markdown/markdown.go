@@ -0,0 +1,260 @@
+// Package markdown provides an optional markdown builtin for fasttemplate.
+//
+// Importing the package registers the builtin:
+//
+//	import _ "github.com/dwisiswant0/fasttemplate/markdown"
+//
+// after which templates may call {{markdown(body)}} to convert Markdown
+// values to HTML. The converter supports the commonly used subset of
+// Markdown: ATX headings, paragraphs, block quotes, ordered and unordered
+// lists, fenced code blocks, horizontal rules, emphasis, strong emphasis,
+// code spans and links.
+//
+// The output is always safe to embed in HTML documents: raw HTML in the
+// input is escaped rather than passed through, and links with schemes other
+// than http, https and mailto are neutralized.
+package markdown
+
+import (
+	"html"
+	"strings"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func init() {
+	fasttemplate.RegisterBuiltin("markdown", fasttemplate.Func{
+		Fn:         Render,
+		Idempotent: true,
+		Cost:       fasttemplate.CostMedium,
+	})
+}
+
+// Render converts the Markdown source s to sanitized HTML.
+//
+// The result has the [fasttemplate.HTML] type, so it isn't escaped again by
+// templates using [fasttemplate.EscapeHTML].
+func Render(s string) fasttemplate.HTML {
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	renderBlocks(&sb, lines)
+	return fasttemplate.HTML(sb.String())
+}
+
+// renderBlocks renders block-level elements.
+func renderBlocks(sb *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			i++
+			sb.WriteString("<pre><code>")
+			for ; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				sb.WriteString(html.EscapeString(lines[i]))
+				sb.WriteByte('\n')
+			}
+			sb.WriteString("</code></pre>\n")
+			i++ // skip the closing fence
+
+		case isHorizontalRule(trimmed):
+			sb.WriteString("<hr>\n")
+			i++
+
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			text := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			tag := "h" + string(rune('0'+level))
+			sb.WriteString("<" + tag + ">")
+			renderInline(sb, text)
+			sb.WriteString("</" + tag + ">\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, quoted)
+			sb.WriteString("</blockquote>\n")
+
+		case listItem(trimmed) != "":
+			tag := listItem(trimmed)
+			sb.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && listItem(strings.TrimSpace(lines[i])) == tag; i++ {
+				sb.WriteString("<li>")
+				renderInline(sb, listItemText(strings.TrimSpace(lines[i])))
+				sb.WriteString("</li>\n")
+			}
+			sb.WriteString("</" + tag + ">\n")
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" || startsBlock(t) {
+					break
+				}
+				para = append(para, t)
+			}
+			sb.WriteString("<p>")
+			renderInline(sb, strings.Join(para, "\n"))
+			sb.WriteString("</p>\n")
+		}
+	}
+}
+
+// startsBlock reports whether the line starts a block other than a
+// paragraph.
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, ">") ||
+		headingLevel(line) > 0 || listItem(line) != "" || isHorizontalRule(line)
+}
+
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && n < 6 && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n >= len(line) || line[n] != ' ' {
+		return 0
+	}
+	return n
+}
+
+func isHorizontalRule(line string) bool {
+	if len(line) < 3 {
+		return false
+	}
+	c := line[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	count := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case c:
+			count++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// listItem returns the list tag ("ul" or "ol") if the line is a list item.
+func listItem(line string) string {
+	if len(line) >= 2 && (line[0] == '-' || line[0] == '*' || line[0] == '+') && line[1] == ' ' {
+		return "ul"
+	}
+	n := 0
+	for n < len(line) && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+	if n > 0 && n+1 < len(line) && line[n] == '.' && line[n+1] == ' ' {
+		return "ol"
+	}
+	return ""
+}
+
+func listItemText(line string) string {
+	if listItem(line) == "ul" {
+		return strings.TrimSpace(line[2:])
+	}
+	return strings.TrimSpace(line[strings.IndexByte(line, '.')+1:])
+}
+
+// renderInline renders inline elements of s, escaping everything else.
+func renderInline(sb *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()#+-.!>", s[i+1]) >= 0:
+			sb.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				sb.WriteString("<code>")
+				sb.WriteString(html.EscapeString(s[i+1 : i+1+j]))
+				sb.WriteString("</code>")
+				i += j + 2
+				continue
+			}
+
+		case (c == '*' || c == '_') && (c == '*' || i == 0 || !isWordChar(s[i-1])):
+			delim := s[i : i+1]
+			if i+1 < len(s) && s[i+1] == c {
+				delim = s[i : i+2]
+			}
+			if j := strings.Index(s[i+len(delim):], delim); j > 0 {
+				tag := "em"
+				if len(delim) == 2 {
+					tag = "strong"
+				}
+				sb.WriteString("<" + tag + ">")
+				renderInline(sb, s[i+len(delim):i+len(delim)+j])
+				sb.WriteString("</" + tag + ">")
+				i += j + 2*len(delim)
+				continue
+			}
+
+		case c == '[':
+			if text, url, n, ok := parseLink(s[i:]); ok {
+				sb.WriteString(`<a href="`)
+				sb.WriteString(html.EscapeString(sanitizeURL(url)))
+				sb.WriteString(`" rel="nofollow">`)
+				renderInline(sb, text)
+				sb.WriteString("</a>")
+				i += n
+				continue
+			}
+
+		case c == '\n':
+			sb.WriteString("\n")
+			i++
+			continue
+		}
+
+		sb.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+}
+
+// parseLink parses a [text](url) link at the start of s.
+func parseLink(s string) (text, url string, n int, ok bool) {
+	end := strings.Index(s, "](")
+	if end < 0 {
+		return "", "", 0, false
+	}
+	closing := strings.IndexByte(s[end+2:], ')')
+	if closing < 0 {
+		return "", "", 0, false
+	}
+	return s[1:end], strings.TrimSpace(s[end+2 : end+2+closing]), end + 3 + closing, true
+}
+
+// sanitizeURL neutralizes URLs with schemes other than http, https and
+// mailto.
+func sanitizeURL(url string) string {
+	if i := strings.IndexByte(url, ':'); i >= 0 && !strings.ContainsAny(url[:i], "/?#") {
+		switch strings.ToLower(url[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "#"
+		}
+	}
+	return url
+}
+
+func isWordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "Paragraphs",
+			source:   "Hello *world*!\nSecond line.\n\nNew **paragraph** with `a<b`.",
+			expected: "<p>Hello <em>world</em>!\nSecond line.</p>\n<p>New <strong>paragraph</strong> with <code>a&lt;b</code>.</p>\n",
+		},
+		{
+			name:     "Headings",
+			source:   "# Title\n### Sub _title_ ###",
+			expected: "<h1>Title</h1>\n<h3>Sub <em>title</em></h3>\n",
+		},
+		{
+			name:     "Lists",
+			source:   "- one\n- two\n\n1. first\n2. second",
+			expected: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		},
+		{
+			name:     "Blockquote",
+			source:   "> quoted\n> text",
+			expected: "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n",
+		},
+		{
+			name:     "CodeBlock",
+			source:   "```\n<script>x</script>\n```",
+			expected: "<pre><code>&lt;script&gt;x&lt;/script&gt;\n</code></pre>\n",
+		},
+		{
+			name:     "Rule",
+			source:   "a\n\n---\n\nb",
+			expected: "<p>a</p>\n<hr>\n<p>b</p>\n",
+		},
+		{
+			name:     "Links",
+			source:   "[site](https://example.com) [bad](javascript:void) [rel](/a?b=1&c=2)",
+			expected: "<p><a href=\"https://example.com\" rel=\"nofollow\">site</a> <a href=\"#\" rel=\"nofollow\">bad</a> <a href=\"/a?b=1&amp;c=2\" rel=\"nofollow\">rel</a></p>\n",
+		},
+		{
+			name:     "RawHTML",
+			source:   "<img src=x onerror=alert(1)> & \"quotes\"",
+			expected: "<p>&lt;img src=x onerror=alert(1)&gt; &amp; &#34;quotes&#34;</p>\n",
+		},
+		{
+			name:     "IntrawordUnderscore",
+			source:   "snake_case_name and \\*literal\\*",
+			expected: "<p>snake_case_name and *literal*</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := string(Render(tt.source)); s != tt.expected {
+				t.Fatalf("unexpected output\n got: %q\nwant: %q", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	tpl := fasttemplate.New("<div>{{markdown(body)}}</div>", "{{", "}}", fasttemplate.WithEscaping(fasttemplate.EscapeHTML))
+
+	s := tpl.ExecuteString(fasttemplate.Map{"body": "**hi** <there>"})
+	if expected := "<div><p><strong>hi</strong> &lt;there&gt;</p>\n</div>"; s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+}
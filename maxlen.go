@@ -0,0 +1,85 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxLengthMarker introduces the length limit of a tag following a "|" at
+// the end of the tag, e.g. {{title|max:50}}.
+const maxLengthMarker = "max:"
+
+// WithMaxLengths limits the length of the values of tags, in user-perceived
+// characters like the truncate function, keyed by tag, e.g. {"title": 50}. Tags can also be limited in place with
+// a "|max:N" suffix, e.g. {{title|max:50}}, which takes precedence.
+//
+// Values exceeding their limit fail the execution with a [LimitError]
+// unless WithTruncateOverlong is set. Limits apply to the values before
+// they are escaped, so they match what downstream systems such as SMS
+// gateways or database columns receive.
+func WithMaxLengths(limits map[string]int) Option {
+	return func(t *Template) {
+		t.maxLengths = limits
+	}
+}
+
+// WithTruncateOverlong truncates values exceeding the limits of their tags
+// instead of failing the execution. Values are cut between grapheme
+// clusters, so combining marks, emoji sequences and flags are never split.
+func WithTruncateOverlong() Option {
+	return func(t *Template) {
+		t.truncateOverlong = true
+	}
+}
+
+// markMaxLengths strips the length limit suffixes from the tags, recording
+// the limits of all tags in t.maxLens.
+func (t *Template) markMaxLengths() {
+	t.maxLens = nil
+	for i, tag := range t.tags {
+		limit := 0
+		if n := strings.LastIndexByte(tag, '|'); n > 0 && tag[n-1] != '|' {
+			if s, ok := strings.CutPrefix(strings.TrimSpace(tag[n+1:]), maxLengthMarker); ok {
+				if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && v > 0 {
+					tag = strings.TrimSpace(tag[:n])
+					t.stripTag(i, tag)
+					limit = v
+				}
+			}
+		}
+		if limit == 0 && t.maxLengths != nil {
+			limit = t.maxLengths[strings.TrimSpace(tag)]
+		}
+		if limit <= 0 {
+			continue
+		}
+		if t.maxLens == nil {
+			t.maxLens = make([]int, len(t.tags))
+		}
+		t.maxLens[i] = limit
+	}
+}
+
+// limitLength returns the formatted value of the i-th tag if it fits the
+// limit of the tag, truncating it if WithTruncateOverlong is set.
+func (t *Template) limitLength(i int, v any, kind tagKind) (any, error) {
+	limit := t.maxLens[i]
+	if limit == 0 {
+		return v, nil
+	}
+	var bb bytes.Buffer
+	if _, err := t.writeValue(&bb, i, v, kind); err != nil {
+		return nil, err
+	}
+	s := bb.String()
+	clusters := graphemes(s)
+	if len(clusters) <= limit {
+		return s, nil
+	}
+	if !t.truncateOverlong {
+		return nil, &LimitError{Limit: fmt.Sprintf("tag %q length", t.tags[i]), Max: limit, Value: len(clusters)}
+	}
+	return strings.Join(clusters[:limit], ""), nil
+}
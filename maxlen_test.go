@@ -0,0 +1,59 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMaxLength(t *testing.T) {
+	m := Map{"title": "Hello, wörld", "body": "short"}
+
+	tpl := New("[{{title|max:12}}] [{{ body | max: 5 }}]", "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "[Hello, wörld] [short]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	tpl = New("[{{title|max:5}}]", "{{", "}}")
+	var bb bytes.Buffer
+	_, err := tpl.Execute(&bb, m)
+	var le *LimitError
+	if !errors.As(err, &le) || le.Max != 5 || le.Value != 12 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err.Error() != `tag "title" length 12 exceeds the limit of 5` {
+		t.Fatalf("unexpected error message %q", err)
+	}
+	if s := tpl.ExecuteStringStd(m); s != "[{{title}}]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	tpl = New("[{{title|max:9}}] [{{body}}]", "{{", "}}", WithTruncateOverlong(), WithMaxLengths(map[string]int{"body": 3, "title": 1}))
+	if s := tpl.ExecuteString(m); s != "[Hello, wö] [sho]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// single tag templates are limited as well
+	if s := New("{{title|max:4}}", "{{", "}}", WithTruncateOverlong()).ExecuteString(m); s != "Hell" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// limits apply before escaping
+	tpl = New("<p>{{v|max:3}}</p>", "{{", "}}", WithEscaping(EscapeHTML))
+	if s := tpl.ExecuteString(Map{"v": "<&>"}); s != "<p>&lt;&amp;&gt;</p>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// lengths are counted in grapheme clusters, like truncate counts them
+	emoji := Map{"v": "e\u0301👍🏽🇩🇪x"}
+	if s := New("{{v|max:4}}", "{{", "}}").ExecuteString(emoji); s != "e\u0301👍🏽🇩🇪x" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	_, err = New("{{v|max:3}}", "{{", "}}").Execute(&bb, emoji)
+	if !errors.As(err, &le) || le.Value != 4 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s := New("{{v|max:3}}", "{{", "}}", WithTruncateOverlong()).ExecuteString(emoji); s != "e\u0301👍🏽🇩🇪" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -0,0 +1,178 @@
+// Package metrics records render statistics of fasttemplate templates.
+//
+// A Registry collects render counts, durations, error counts and the hit
+// ratio of memoized function calls per named template:
+//
+//	reg := metrics.NewRegistry()
+//	reg.Publish("templates")
+//
+//	t := fasttemplate.New(src, "{{", "}}", reg.Option("welcome"))
+//
+// Publish exports the statistics via expvar, so they are served by the
+// /debug/vars handler along with the other variables of the process.
+package metrics
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// Registry records statistics of named templates. It is safe for concurrent
+// use.
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]*counters
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]*counters)}
+}
+
+// counters holds the statistics of a single template.
+type counters struct {
+	renders    atomic.Int64
+	errors     atomic.Int64
+	duration   atomic.Int64
+	bytes      atomic.Int64
+	memoHits   atomic.Int64
+	memoMisses atomic.Int64
+}
+
+// Stats is a snapshot of the statistics of a template.
+type Stats struct {
+	Renders       int64         `json:"renders"`
+	Errors        int64         `json:"errors"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	Bytes         int64         `json:"bytes"`
+	MemoHits      int64         `json:"memo_hits"`
+	MemoMisses    int64         `json:"memo_misses"`
+}
+
+// AvgDuration returns the average render duration.
+func (s Stats) AvgDuration() time.Duration {
+	if s.Renders == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Renders)
+}
+
+// HitRatio returns the share of memoized function calls answered from the
+// cache, or 0 if there were none.
+func (s Stats) HitRatio() float64 {
+	total := s.MemoHits + s.MemoMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.MemoHits) / float64(total)
+}
+
+// Option returns the template option recording the renders of the template
+// under name. Templates sharing a name share their statistics.
+func (r *Registry) Option(name string) fasttemplate.Option {
+	c := r.counters(name)
+	return fasttemplate.WithRenderHook(c.record)
+}
+
+// Named returns the template option recording the renders of the template
+// under its name set with fasttemplate.NewNamed or fasttemplate.WithName,
+// so one option serves a fleet of named templates.
+func (r *Registry) Named() fasttemplate.Option {
+	return fasttemplate.WithRenderHook(func(s fasttemplate.RenderStats) {
+		r.counters(s.Template).record(s)
+	})
+}
+
+// record adds the statistics of a render.
+func (c *counters) record(s fasttemplate.RenderStats) {
+	c.renders.Add(1)
+	if s.Err != nil {
+		c.errors.Add(1)
+	}
+	c.duration.Add(int64(s.Duration))
+	c.bytes.Add(s.Bytes)
+	c.memoHits.Add(int64(s.MemoHits))
+	c.memoMisses.Add(int64(s.MemoMisses))
+}
+
+// counters returns the counters of the named template, creating them if
+// needed.
+func (r *Registry) counters(name string) *counters {
+	r.mu.RLock()
+	c, ok := r.templates[name]
+	r.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok = r.templates[name]; !ok {
+		c = &counters{}
+		r.templates[name] = c
+	}
+	return c
+}
+
+// Names returns the names of the recorded templates in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the statistics of the named template.
+func (r *Registry) Stats(name string) Stats {
+	r.mu.RLock()
+	c, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Stats{}
+	}
+	return Stats{
+		Renders:       c.renders.Load(),
+		Errors:        c.errors.Load(),
+		TotalDuration: time.Duration(c.duration.Load()),
+		Bytes:         c.bytes.Load(),
+		MemoHits:      c.memoHits.Load(),
+		MemoMisses:    c.memoMisses.Load(),
+	}
+}
+
+// Snapshot returns the statistics of all recorded templates by name.
+func (r *Registry) Snapshot() map[string]Stats {
+	names := r.Names()
+	stats := make(map[string]Stats, len(names))
+	for _, name := range names {
+		stats[name] = r.Stats(name)
+	}
+	return stats
+}
+
+// Publish exports the statistics of the registry as the expvar variable
+// varName. Like [expvar.Publish], it panics if the name is already in use.
+func (r *Registry) Publish(varName string) {
+	expvar.Publish(varName, expvar.Func(func() any {
+		vars := make(map[string]publishedStats)
+		for name, s := range r.Snapshot() {
+			vars[name] = publishedStats{Stats: s, HitRatio: s.HitRatio()}
+		}
+		return vars
+	}))
+}
+
+// publishedStats are the statistics of a template as exported via expvar.
+type publishedStats struct {
+	Stats
+	HitRatio float64 `json:"hit_ratio"`
+}
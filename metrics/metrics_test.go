@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	calls := 0
+	lookup := fasttemplate.Func{
+		Fn: func(s string) string {
+			calls++
+			return s + "!"
+		},
+		Idempotent: true,
+		Cost:       fasttemplate.CostHigh,
+	}
+	fail := func() (string, error) { return "", errors.New("boom") }
+
+	tpl := fasttemplate.New("{{lookup(name)}} {{lookup(name)}}", "{{", "}}", reg.Option("greeting"))
+	for i := 0; i < 2; i++ {
+		if s := tpl.ExecuteString(fasttemplate.Map{"name": "Ann", "lookup": lookup}); s != "Ann! Ann!" {
+			t.Fatalf("unexpected result %q", s)
+		}
+	}
+	broken := fasttemplate.New("{{fail()}}", "{{", "}}", reg.Option("broken"))
+	if _, err := broken.Execute(io.Discard, fasttemplate.Map{"fail": fail}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	s := reg.Stats("greeting")
+	if s.Renders != 2 || s.Errors != 0 || s.Bytes != 18 || s.MemoHits != 2 || s.MemoMisses != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.HitRatio() != 0.5 || s.AvgDuration() != s.TotalDuration/2 {
+		t.Fatalf("unexpected ratio %v or average %v", s.HitRatio(), s.AvgDuration())
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if s := reg.Stats("broken"); s.Renders != 1 || s.Errors != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s := reg.Stats("unknown"); s != (Stats{}) {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if names := reg.Names(); len(names) != 2 || names[0] != "broken" || names[1] != "greeting" {
+		t.Fatalf("unexpected names %v", names)
+	}
+}
+
+func TestRegistryNamed(t *testing.T) {
+	reg := NewRegistry()
+	welcome := fasttemplate.NewNamed("welcome", "Hi {{name}}", "{{", "}}", reg.Named())
+	bye := fasttemplate.NewNamed("bye", "Bye {{name}}", "{{", "}}", reg.Named())
+	m := fasttemplate.Map{"name": "Ann"}
+	welcome.ExecuteString(m)
+	welcome.ExecuteString(m)
+	bye.ExecuteString(m)
+
+	if s := reg.Stats("welcome"); s.Renders != 2 || s.Bytes != 12 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s := reg.Stats("bye"); s.Renders != 1 || s.Bytes != 7 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	reg := NewRegistry()
+	reg.Publish("fasttemplate_test")
+
+	tpl := fasttemplate.New("Hello, {{name}}!", "{{", "}}", reg.Option("hello"))
+	tpl.ExecuteString(fasttemplate.Map{"name": "Ann"})
+
+	var vars map[string]struct {
+		Renders  int64   `json:"renders"`
+		Bytes    int64   `json:"bytes"`
+		HitRatio float64 `json:"hit_ratio"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("fasttemplate_test").String()), &vars); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := vars["hello"]; v.Renders != 1 || v.Bytes != 11 {
+		t.Fatalf("unexpected vars %+v", vars)
+	}
+}
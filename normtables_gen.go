@@ -0,0 +1,220 @@
+//go:build ignore
+// +build ignore
+
+// This program generates normtables.go from the Unicode character database.
+// Run it with go generate; pass -ucd to read the database files from a local
+// directory instead of downloading them.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const unicodeVersion = "14.0.0"
+
+var (
+	ucd    = flag.String("ucd", "", "directory holding UnicodeData.txt and CompositionExclusions.txt; downloaded from unicode.org if empty")
+	output = flag.String("o", "normtables.go", "output file")
+)
+
+func main() {
+	flag.Parse()
+
+	decompositions, classes := parseUnicodeData()
+	exclusions := parseExclusions()
+
+	var bb bytes.Buffer
+	fmt.Fprintf(&bb, "// Code generated by normtables_gen.go from the Unicode %s character database. DO NOT EDIT.\n\n", unicodeVersion)
+	bb.WriteString("package fasttemplate\n\n")
+	writeDecompositions(&bb, decompositions)
+	writeCompositions(&bb, decompositions, classes, exclusions)
+	writeCombiningClasses(&bb, classes)
+
+	src, err := format.Source(bb.Bytes())
+	if err != nil {
+		log.Fatalf("cannot format the tables: %s", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// open returns the database file with the given name.
+func open(name string) io.ReadCloser {
+	if *ucd != "" {
+		f, err := os.Open(filepath.Join(*ucd, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return f
+	}
+	url := "https://www.unicode.org/Public/" + unicodeVersion + "/ucd/" + name
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("cannot download %s: %s", url, resp.Status)
+	}
+	return resp.Body
+}
+
+// lines calls f with the fields of the data lines of the database file,
+// with comments stripped.
+func lines(name string, f func(fields []string)) {
+	r := open(name)
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		f(fields)
+	}
+	if err := s.Err(); err != nil {
+		log.Fatalf("cannot read %s: %s", name, err)
+	}
+}
+
+func parseRune(s string) rune {
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		log.Fatalf("invalid code point %q", s)
+	}
+	return rune(n)
+}
+
+// parseUnicodeData returns the canonical decompositions and the non-zero
+// canonical combining classes.
+func parseUnicodeData() (map[rune][]rune, map[rune]uint8) {
+	decompositions := make(map[rune][]rune)
+	classes := make(map[rune]uint8)
+	lines("UnicodeData.txt", func(fields []string) {
+		r := parseRune(fields[0])
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			log.Fatalf("invalid combining class %q of %U", fields[3], r)
+		}
+		if class != 0 {
+			classes[r] = uint8(class)
+		}
+		// compatibility decompositions start with a <tag>
+		if d := fields[5]; d != "" && !strings.HasPrefix(d, "<") {
+			for _, s := range strings.Fields(d) {
+				decompositions[r] = append(decompositions[r], parseRune(s))
+			}
+		}
+	})
+	return decompositions, classes
+}
+
+// parseExclusions returns the runes excluded from composition by script or
+// as post composition version characters.
+func parseExclusions() map[rune]bool {
+	exclusions := make(map[rune]bool)
+	lines("CompositionExclusions.txt", func(fields []string) {
+		lo, hi, ok := strings.Cut(fields[0], "..")
+		if !ok {
+			hi = lo
+		}
+		for r := parseRune(lo); r <= parseRune(hi); r++ {
+			exclusions[r] = true
+		}
+	})
+	return exclusions
+}
+
+func sortedRunes[V any](m map[rune]V) []rune {
+	runes := make([]rune, 0, len(m))
+	for r := range m {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// writeEntries writes the entries four per line.
+func writeEntries(bb *bytes.Buffer, entries []string) {
+	for i, e := range entries {
+		if i%4 == 0 {
+			bb.WriteString("\t")
+		} else {
+			bb.WriteString(" ")
+		}
+		bb.WriteString(e + ",")
+		if i%4 == 3 || i == len(entries)-1 {
+			bb.WriteString("\n")
+		}
+	}
+	bb.WriteString("}\n")
+}
+
+func runeList(runes []rune) string {
+	s := make([]string, len(runes))
+	for i, r := range runes {
+		s[i] = fmt.Sprintf("0x%X", r)
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+func writeDecompositions(bb *bytes.Buffer, decompositions map[rune][]rune) {
+	bb.WriteString("// nfcDecompositions maps runes to their canonical decompositions.\n")
+	bb.WriteString("var nfcDecompositions = map[rune][]rune{\n")
+	var entries []string
+	for _, r := range sortedRunes(decompositions) {
+		entries = append(entries, fmt.Sprintf("0x%X: %s", r, runeList(decompositions[r])))
+	}
+	writeEntries(bb, entries)
+}
+
+// writeCompositions writes the primary composites: the canonical
+// decompositions into two runes, except for the excluded runes and the
+// decompositions of or starting with a non-starter.
+func writeCompositions(bb *bytes.Buffer, decompositions map[rune][]rune, classes map[rune]uint8, exclusions map[rune]bool) {
+	bb.WriteString("\n// nfcCompositions maps pairs of runes to their primary composite.\n")
+	bb.WriteString("var nfcCompositions = map[[2]rune]rune{\n")
+	var entries []string
+	for _, r := range sortedRunes(decompositions) {
+		d := decompositions[r]
+		if len(d) != 2 || exclusions[r] || classes[r] != 0 || classes[d[0]] != 0 {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s: 0x%X", runeList(d), r))
+	}
+	writeEntries(bb, entries)
+}
+
+func writeCombiningClasses(bb *bytes.Buffer, classes map[rune]uint8) {
+	bb.WriteString("\n// combiningClasses holds the sorted ranges of runes with a non-zero\n")
+	bb.WriteString("// canonical combining class.\n")
+	bb.WriteString("var combiningClasses = []combiningClassRange{\n")
+	var entries []string
+	runes := sortedRunes(classes)
+	for i := 0; i < len(runes); {
+		lo, class := runes[i], classes[runes[i]]
+		j := i + 1
+		for j < len(runes) && runes[j] == runes[j-1]+1 && classes[runes[j]] == class {
+			j++
+		}
+		entries = append(entries, fmt.Sprintf("{0x%X, 0x%X, %d}", lo, runes[j-1], class))
+		i = j
+	}
+	writeEntries(bb, entries)
+}
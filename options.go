@@ -0,0 +1,205 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Option configures optional behaviour of a [Template].
+//
+// Options are passed to [New] and [NewTemplate] and are kept across
+// [Template.Reset] calls.
+type Option func(*Template)
+
+// WithErrorFormatter registers a function used to rewrite the message of
+// every error returned by the template, e.g. to translate or augment
+// Go-flavored messages before they are shown to end users.
+//
+// The returned errors still wrap the original ones, so [errors.Is] and
+// [errors.As] keep working.
+func WithErrorFormatter(f func(err error) string) Option {
+	return func(t *Template) {
+		t.errorFormatter = f
+	}
+}
+
+// WithAllowedVariables restricts the variables tags, expressions and function
+// arguments may reference to the given names. Other keys of the substitution
+// map are treated as missing, so templates from untrusted sources can't probe
+// internal keys placed in a shared map.
+//
+// Functions are looked up by name as usual and aren't restricted.
+func WithAllowedVariables(names ...string) Option {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return WithVariablePolicy(func(name string) bool {
+		return allowed[name]
+	})
+}
+
+// WithVariablePolicy works like [WithAllowedVariables], but decides whether
+// a variable may be referenced using the allow predicate.
+func WithVariablePolicy(allow func(name string) bool) Option {
+	return func(t *Template) {
+		t.allowVariable = allow
+	}
+}
+
+// WithCaseInsensitiveKeys makes variable lookups case-insensitive, so a
+// {{username}} tag resolves the UserName key when the map has no exact match.
+// Exact matches take precedence. If several keys differ in case only, the
+// lexicographically smallest one is used.
+func WithCaseInsensitiveKeys() Option {
+	return func(t *Template) {
+		t.caseInsensitive = true
+	}
+}
+
+// WithStrictIdentifiers makes Execute fail on tags referencing unknown
+// identifiers: missing variables and unknown operands of expressions and
+// function arguments. By default such tags render empty, so typos like
+// {{statuss == "ok"}} go unnoticed. ExecuteStd keeps preserving the tags.
+func WithStrictIdentifiers() Option {
+	return func(t *Template) {
+		t.strictIdentifiers = true
+	}
+}
+
+// WithLiteralFallback restores the former handling of unquoted function
+// arguments that don't look like variable names, e.g. hello world in
+// {{upper(hello world)}}: unless found in the substitution map, they are
+// passed as literal strings instead of failing as unknown identifiers.
+//
+// This is a breaking change for the package-level functions such as
+// [Execute], [ExecuteStd] and [Eval], which formerly fell back as well:
+// they take no options and never fall back now. Templates relying on the
+// fallback must be parsed with [New] and this option, and expressions
+// evaluated with [ExecuteTyped] on such a template.
+func WithLiteralFallback() Option {
+	return func(t *Template) {
+		t.literalFallback = true
+	}
+}
+
+// WithStringOperators enables the Python-style string operators of
+// templates migrated from Python: a string literal multiplied by an integer
+// is repeated, as in {{"-" * 20}}, and % formats its right operand, or the
+// elements of a []any right operand, with a string literal holding fmt verbs
+// on its left, as in {{"%05d" % id}}. Formatting fails if the operands don't
+// match the verbs, and repeating fails if the result would exceed 1 MiB.
+// String values of variables, e.g. numbers read from JSON as strings, aren't
+// affected and still fail as non-numeric operands.
+func WithStringOperators() Option {
+	return func(t *Template) {
+		t.stringOperators = true
+	}
+}
+
+// WithAliases maps tag names to the data keys they resolve to, so templates
+// keep working after the data model is renamed:
+//
+//	fasttemplate.WithAliases(map[string]string{"customer_name": "user.name"})
+//
+// Aliases only apply to names missing from the substitution map. A target
+// may be a key or a dotted path selecting a field of a nested map or struct.
+func WithAliases(aliases map[string]string) Option {
+	return func(t *Template) {
+		if t.aliases == nil {
+			t.aliases = make(map[string]string, len(aliases))
+		}
+		for name, target := range aliases {
+			t.aliases[name] = target
+		}
+	}
+}
+
+// WithMaxTemplateSize limits the size of templates to n bytes. Parsing a
+// larger template fails with a [*LimitError] before anything is allocated.
+func WithMaxTemplateSize(n int) Option {
+	return func(t *Template) {
+		t.maxTemplateSize = n
+	}
+}
+
+// WithMaxTags limits the number of tags of templates to n. Parsing a template
+// with more start tags fails with a [*LimitError] before anything is
+// allocated.
+func WithMaxTags(n int) Option {
+	return func(t *Template) {
+		t.maxTags = n
+	}
+}
+
+// WithValueTransformer registers a function applied to the value of every
+// tag after it is resolved and before it is written, e.g. to trim whitespace
+// or redact sensitive patterns globally. Transformers registered by several
+// options are applied in order.
+//
+// Values of tags that fail to resolve aren't transformed.
+func WithValueTransformer(f func(tag string, v any) any) Option {
+	return func(t *Template) {
+		t.transformers = append(t.transformers, f)
+	}
+}
+
+// TextTransformer returns a value transformer for [WithValueTransformer]
+// applying f to the text a value renders as, e.g. to escape values for a
+// markup language or strip line breaks from header values. Strings, byte
+// slices, fmt.Stringer and streamed values are transformed as well as the
+// %v formatting of other values, so values of named string types can't
+// bypass f. nil values and times, which are formatted with the time layout
+// of the template, are left unchanged.
+func TextTransformer(f func(s string) string) func(tag string, v any) any {
+	return func(_ string, v any) any {
+		switch v := v.(type) {
+		case nil, time.Time:
+			return v
+		case string:
+			return f(v)
+		}
+		var bb bytes.Buffer
+		if _, err := writeResult(&bb, v); err != nil {
+			// fail the execution once the value is written
+			return Stream{errorReader{err}}
+		}
+		return f(bb.String())
+	}
+}
+
+// errorReader is a reader failing with err.
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// formattedError is an error whose message was rewritten by the template's
+// error formatter.
+type formattedError struct {
+	err error
+	msg string
+}
+
+func (e *formattedError) Error() string { return e.msg }
+
+func (e *formattedError) Unwrap() error { return e.err }
+
+// formatError applies the template's error formatter to err, if any, and
+// prefixes the message with the name of the template.
+func (t *Template) formatError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if t.errorFormatter != nil {
+		err = &formattedError{err: err, msg: t.errorFormatter(err)}
+	}
+	if t.name != "" {
+		err = fmt.Errorf("template %q: %w", t.name, err)
+	}
+	return err
+}
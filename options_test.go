@@ -0,0 +1,382 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestWithErrorFormatter(t *testing.T) {
+	formatter := func(err error) string {
+		switch {
+		case errors.Is(err, errFunctionNotFound):
+			return "Unbekannte Funktion"
+		case strings.Contains(err.Error(), "reflect"):
+			return "Ungültiger Argumenttyp"
+		}
+		return "Fehler: " + err.Error()
+	}
+
+	t.Run("Execute", func(t *testing.T) {
+		tpl := New("{{double(name)}}", "{{", "}}", WithErrorFormatter(formatter))
+
+		var bb bytes.Buffer
+		_, err := tpl.Execute(&bb, Map{
+			"name":   "john",
+			"double": func(n int) int { return n * 2 },
+		})
+		if err == nil || err.Error() != "Ungültiger Argumenttyp" {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		_, err = tpl.Execute(&bb, Map{})
+		if err == nil || err.Error() != "Unbekannte Funktion" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !errors.Is(err, errFunctionNotFound) {
+			t.Fatalf("formatted error must wrap the original one: %v", err)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		tpl := New("{{name}}", "{{", "}}", WithErrorFormatter(formatter))
+		err := tpl.Validate(Map{})
+		if err == nil || err.Error() != `Fehler: unresolved tag "name"` {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		_, err := NewTemplate("{{name", "{{", "}}", WithErrorFormatter(formatter))
+		if err == nil || !strings.HasPrefix(err.Error(), "Fehler: cannot find end tag") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("NoError", func(t *testing.T) {
+		tpl := New("{{name}}", "{{", "}}", WithErrorFormatter(formatter))
+		var bb bytes.Buffer
+		if _, err := tpl.Execute(&bb, Map{"name": "john"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestWithAllowedVariables(t *testing.T) {
+	m := Map{
+		"name":   "Ann",
+		"secret": "s3cr3t",
+		"upper":  strings.ToUpper,
+	}
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{name}}:{{secret}}", "Ann:{{secret}}"},
+		{"{{upper(name)}}", "ANN"},
+		{"{{name + secret}}", "{{name + secret}}"},
+		{"{{upper(secret)}}", "{{upper(secret)}}"},
+		{"{{capture s}}<{{name}}>{{end}}{{s}}", "<Ann>"},
+	}
+	for _, tt := range tests {
+		tpl := New(tt.template, "{{", "}}", WithAllowedVariables("name"))
+		if s := tpl.ExecuteStringStd(m); s != tt.expected {
+			t.Fatalf("unexpected output for %q: %q. Expected %q", tt.template, s, tt.expected)
+		}
+	}
+
+	tpl := New("{{secret}}", "{{", "}}", WithVariablePolicy(func(name string) bool {
+		return !strings.HasPrefix(name, "sec")
+	}))
+	if err := tpl.Validate(m); err == nil {
+		t.Fatalf("expected validation error for disallowed variable")
+	}
+}
+
+func TestWithCaseInsensitiveKeys(t *testing.T) {
+	m := Map{
+		"UserName": "ann",
+		"username": "exact",
+		"Email":    "ann@example.com",
+		"Count":    3,
+		"upper":    strings.ToUpper,
+	}
+	tpl := New("{{username}} {{USERNAME}} {{email}} {{upper(EMAIL)}} {{count * 2}} {{capture Greeting}}hi{{end}}{{greeting}}", "{{", "}}", WithCaseInsensitiveKeys())
+	if s := tpl.ExecuteString(m); s != "exact ann ann@example.com ANN@EXAMPLE.COM 6 hi" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	if s := New("{{email}}", "{{", "}}").ExecuteString(m); s != "" {
+		t.Fatalf("lookups must be case-sensitive by default, got %q", s)
+	}
+}
+
+func TestFoldIndex(t *testing.T) {
+	// keys differing in case only used to rebuild the index on every miss
+	m := Map{"Name": "ann", "NAME": "ANN"}
+	ec := &evalContext{caseInsensitive: true}
+	if key, ok := ec.foldKey(m, "name"); !ok || key != "NAME" {
+		t.Fatalf("unexpected key %q", key)
+	}
+	fold := ec.fold
+	for i := 0; i < 3; i++ {
+		if _, ok := ec.foldKey(m, "missing"); ok {
+			t.Fatal("unexpected match")
+		}
+	}
+	if ec.fold != fold || len(fold.misses) != 1 {
+		t.Fatal("expected the index to be built once and the miss to be cached")
+	}
+
+	m["Missing"] = 1
+	if key, ok := ec.foldKey(m, "missing"); !ok || key != "Missing" {
+		t.Fatalf("expected the index to be rebuilt for the added key, got %q", key)
+	}
+	if _, ok := ec.foldKey(Map{"Other": 1}, "name"); ok {
+		t.Fatal("expected the index to be rebuilt for another map")
+	}
+}
+
+func TestWithStrictIdentifiers(t *testing.T) {
+	m := Map{"status": "ok", "upper": strings.ToUpper}
+	for _, tag := range []string{`statuss == "ok"`, "statuss", `upper(statuss) + "!"`} {
+		tpl := New("[{{"+tag+"}}]", "{{", "}}")
+		if s := tpl.ExecuteString(m); s != "[]" {
+			t.Fatalf("%s: unexpected output %q", tag, s)
+		}
+
+		tpl = New("[{{"+tag+"}}]", "{{", "}}", WithStrictIdentifiers())
+		var bb bytes.Buffer
+		_, err := tpl.Execute(&bb, m)
+		if !errors.Is(err, errVariableNotFound) || !strings.Contains(err.Error(), "statuss") {
+			t.Fatalf("%s: expected an unknown identifier error, got %v", tag, err)
+		}
+		if s := tpl.ExecuteStringStd(m); s != "[{{"+tag+"}}]" {
+			t.Fatalf("%s: ExecuteStd must keep the tag, got %q", tag, s)
+		}
+	}
+	if s := New(`{{status == "ok"}}`, "{{", "}}", WithStrictIdentifiers()).ExecuteString(m); s != "true" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithLiteralFallback(t *testing.T) {
+	m := Map{"upper": strings.ToUpper, "hello world": "hi"}
+	var bb bytes.Buffer
+	_, err := New("{{upper(foo bar)}}", "{{", "}}").Execute(&bb, m)
+	if !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unquoted arguments must not fall back to literals by default, got %v", err)
+	}
+
+	tpl := New("{{upper(foo bar)}} {{upper(hello world)}}", "{{", "}}", WithLiteralFallback())
+	if s := tpl.ExecuteString(m); s != "FOO BAR HI" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// names are never literals
+	_, err = New("{{upper(foo)}}", "{{", "}}", WithLiteralFallback()).Execute(&bb, m)
+	if !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// nor are the identifiers of expressions, except for the booleans
+	for _, opts := range [][]Option{nil, {WithLiteralFallback()}} {
+		if s := New("{{status == pending}}", "{{", "}}", opts...).ExecuteStringStd(Map{"status": "pending"}); s != "{{status == pending}}" {
+			t.Fatalf("unexpected output %q", s)
+		}
+		if s := New("{{false || true}}", "{{", "}}", opts...).ExecuteString(nil); s != "true" {
+			t.Fatalf("unexpected output %q", s)
+		}
+	}
+
+	// the package-level functions take no options and never fall back
+	if _, err := Execute("{{upper(foo bar)}}", "{{", "}}", &bb, m); !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s := ExecuteStringStd("{{upper(foo bar)}}", "{{", "}}", m); s != "{{upper(foo bar)}}" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if _, err := Eval[string]("upper(foo bar)", m); !errors.Is(err, errVariableNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tpl = New("{{upper(foo bar)}}", "{{", "}}", WithLiteralFallback())
+	if s, err := ExecuteTyped[string](tpl, m); err != nil || s != "FOO BAR" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+}
+
+func TestWithAliases(t *testing.T) {
+	type user struct {
+		Name  string
+		Email string `json:"email"`
+	}
+	m := Map{
+		"user":    user{Name: "Ann", Email: "ann@example.com"},
+		"account": map[string]any{"plan": "pro"},
+		"total":   10,
+		"upper":   strings.ToUpper,
+	}
+	tpl := New("{{customer_name}} <{{customer_email}}> {{upper(plan)}} {{amount * 2}} [{{missing}}]", "{{", "}}", WithAliases(map[string]string{
+		"customer_name":  "user.Name",
+		"customer_email": "user.email",
+		"plan":           "account.plan",
+		"amount":         "total",
+		"missing":        "user.Phone",
+	}))
+	if s := tpl.ExecuteString(m); s != "Ann <ann@example.com> PRO 20 []" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if err := tpl.Validate(m); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected validation error for alias with missing target, got %v", err)
+	}
+
+	// keys present in the map take precedence over aliases
+	if s := tpl.ExecuteString(Map{"customer_name": "Bob", "user": user{Name: "Ann"}}); !strings.HasPrefix(s, "Bob ") {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithAliasesCycle(t *testing.T) {
+	tpl := New("[{{a}}]", "{{", "}}", WithAliases(map[string]string{"a": "b", "b": "a"}))
+	if s := tpl.ExecuteString(Map{}); s != "[]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithValueTransformer(t *testing.T) {
+	trim := func(tag string, v any) any {
+		if s, ok := v.(string); ok {
+			return strings.TrimSpace(s)
+		}
+		return v
+	}
+	redact := func(tag string, v any) any {
+		if tag == "card" {
+			return "****"
+		}
+		return v
+	}
+	m := Map{
+		"name": "  Ann ",
+		"card": "4111 1111",
+		"pad":  func(s string) string { return " " + s + " " },
+	}
+	tpl := New("[{{name}}] [{{card}}] [{{pad('x')}}] [{{unknown}}]", "{{", "}}", WithValueTransformer(trim), WithValueTransformer(redact))
+	if s := tpl.ExecuteString(m); s != "[Ann] [****] [x] []" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := tpl.ExecuteStringStd(m); s != "[Ann] [****] [x] [{{unknown}}]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestTextTransformer(t *testing.T) {
+	type label string
+	upper := WithValueTransformer(TextTransformer(strings.ToUpper))
+	m := Map{
+		"s":      "a",
+		"b":      []byte("b"),
+		"label":  label("c"),
+		"err":    errors.New("d"),
+		"n":      42,
+		"stream": Stream{strings.NewReader("e")},
+		"nil":    nil,
+		"time":   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	tpl := New("{{s}} {{b}} {{label}} {{err}} {{n}} {{stream}} [{{nil}}] {{time}}", "{{", "}}", upper, WithTimeLayout("Jan 2"))
+	if s := tpl.ExecuteString(m); s != "A B C D 42 E [] Jan 2" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	failed := Map{"stream": Stream{iotest.ErrReader(errors.New("read failed"))}}
+	if _, err := New("{{stream}}", "{{", "}}", upper).Execute(io.Discard, failed); err == nil || !strings.Contains(err.Error(), "read failed") {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+}
+
+func TestWithMaxTemplateSize(t *testing.T) {
+	if _, err := NewTemplate("0123456789", "{{", "}}", WithMaxTemplateSize(10)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err := NewTemplate("0123456789a", "{{", "}}", WithMaxTemplateSize(10))
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "template size" || le.Max != 10 || le.Value != 11 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err.Error() != "template size 11 exceeds the limit of 10" {
+		t.Fatalf("unexpected error message %q", err)
+	}
+}
+
+func TestWithMaxTags(t *testing.T) {
+	tpl, err := NewTemplate("{{a}}{{b}}", "{{", "}}", WithMaxTags(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = tpl.Reset("{{a}}{{b}}{{c}}", "{{", "}}")
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "tag count" || le.Max != 2 || le.Value != 3 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the previous template is kept
+	if s := tpl.ExecuteString(Map{"a": "1", "b": "2"}); s != "12" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestNewNamed(t *testing.T) {
+	var stats []RenderStats
+	tpl := NewNamed("welcome_email", "Hi {{upper(name)}}", "{{", "}}", WithRenderHook(func(s RenderStats) {
+		stats = append(stats, s)
+	}))
+	if tpl.Name() != "welcome_email" {
+		t.Fatalf("unexpected name %q", tpl.Name())
+	}
+
+	var bb bytes.Buffer
+	_, err := tpl.Execute(&bb, Map{"name": 42})
+	if err == nil || !strings.HasPrefix(err.Error(), `template "welcome_email": `) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = tpl.Execute(&bb, Map{})
+	if !errors.Is(err, errVariableNotFound) && !errors.Is(err, errFunctionNotFound) {
+		if err != nil {
+			t.Fatalf("errors must stay matchable, got %v", err)
+		}
+	}
+	if len(stats) != 2 || stats[0].Template != "welcome_email" {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	trace, _ := tpl.ExecuteTraced(&bb, Map{"name": "ann"})
+	if len(trace) != 1 || trace[0].Template != "welcome_email" {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+
+	findings := NewNamed("welcome_email", "{{missing}}", "{{", "}}").Analyze(Map{})
+	if len(findings) != 1 || !strings.HasPrefix(findings[0].String(), `template "welcome_email": offset 0`) {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	// the name wraps formatted errors
+	tpl = NewNamed("greeting", "{{fail()}}", "{{", "}}", WithErrorFormatter(func(error) string { return "oops" }))
+	_, err = tpl.Execute(&bb, Map{})
+	if err == nil || err.Error() != `template "greeting": oops` || !errors.Is(err, errFunctionNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = NewTemplate("{{a}}", "", "}}", WithName("broken"))
+	var de *DelimiterError
+	if !errors.As(err, &de) || !strings.HasPrefix(err.Error(), `template "broken": `) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
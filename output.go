@@ -0,0 +1,88 @@
+package fasttemplate
+
+import (
+	"io"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// WithOutputFilter registers a function post-processing the complete
+// rendered output, e.g. to collapse whitespace or append a signature. The
+// output is buffered until rendering finishes, then f is applied and its
+// result is written to the destination writer.
+//
+// Filters and writers registered by several options are chained in order.
+// They apply to Execute, ExecuteStd, ExecuteAsync and the methods built on
+// them, but not to ExecuteMulti.
+func WithOutputFilter(f func(p []byte) []byte) Option {
+	return func(t *Template) {
+		t.outputStages = append(t.outputStages, func(w io.Writer) io.WriteCloser {
+			return &filterWriter{w: w, f: f, t: t}
+		})
+	}
+}
+
+// WithOutputWriter registers a streaming output filter: wrap returns a writer
+// the rendered output is written to, which writes the processed output to
+// w. It is closed once rendering finishes. Use it for filters that don't need
+// the complete output, such as compression:
+//
+//	fasttemplate.WithOutputWriter(func(w io.Writer) io.WriteCloser {
+//		return gzip.NewWriter(w)
+//	})
+func WithOutputWriter(wrap func(w io.Writer) io.WriteCloser) Option {
+	return func(t *Template) {
+		t.outputStages = append(t.outputStages, wrap)
+	}
+}
+
+// filterOutput wraps w with the output stages of the template. The returned
+// function closes the stages in order and returns the number of bytes
+// written to w.
+func (t *Template) filterOutput(w io.Writer) (io.Writer, func() (int64, error)) {
+	var n int64
+	cw := &countingWriter{w: w, n: &n}
+	stages := make([]io.WriteCloser, len(t.outputStages))
+	var next io.Writer = cw
+	for i := len(stages) - 1; i >= 0; i-- {
+		stages[i] = t.outputStages[i](next)
+		next = stages[i]
+	}
+	return next, func() (int64, error) {
+		var err error
+		for _, s := range stages {
+			if cerr := s.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return n, err
+	}
+}
+
+// filterWriter buffers the output and writes it through f when closed.
+type filterWriter struct {
+	w  io.Writer
+	f  func(p []byte) []byte
+	t  *Template
+	bb *bytebufferpool.ByteBuffer
+}
+
+func (fw *filterWriter) Write(p []byte) (int, error) {
+	if fw.bb == nil {
+		fw.bb = fw.t.getBuffer()
+	}
+	return fw.bb.Write(p)
+}
+
+func (fw *filterWriter) Close() error {
+	var p []byte
+	if fw.bb != nil {
+		p = fw.bb.B
+	}
+	_, err := writeFull(fw.w, fw.f(p))
+	if fw.bb != nil {
+		fw.t.putBuffer(fw.bb)
+		fw.bb = nil
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"regexp"
+	"testing"
+)
+
+func TestWithOutputFilter(t *testing.T) {
+	spaces := regexp.MustCompile(`\s+`)
+	collapse := func(p []byte) []byte { return spaces.ReplaceAll(p, []byte(" ")) }
+	sign := func(p []byte) []byte { return append(p, "\n-- sig"...) }
+
+	tpl := New("Hello,\n\n  {{name}}!\t{{unknown}}", "{{", "}}", WithOutputFilter(collapse), WithOutputFilter(sign))
+
+	var bb bytes.Buffer
+	n, err := tpl.Execute(&bb, Map{"name": "Ann"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "Hello, Ann! \n-- sig"; bb.String() != expected || n != int64(len(expected)) {
+		t.Fatalf("unexpected output %q (%d bytes)", bb.String(), n)
+	}
+	if s := tpl.ExecuteStringStd(Map{"name": "Ann"}); s != "Hello, Ann! {{unknown}}\n-- sig" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	bb.Reset()
+	if _, err := tpl.ExecuteAsync(&bb, Map{"name": "Ann"}); err != nil || bb.String() != "Hello, Ann! \n-- sig" {
+		t.Fatalf("unexpected output %q, %v", bb.String(), err)
+	}
+}
+
+func TestWithOutputWriter(t *testing.T) {
+	tpl := New("Hello, {{name}}!", "{{", "}}", WithOutputWriter(func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}))
+
+	var bb bytes.Buffer
+	n, err := tpl.Execute(&bb, Map{"name": "Ann"})
+	if err != nil || n != int64(bb.Len()) {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+	zr, err := gzip.NewReader(&bb)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s, err := io.ReadAll(zr)
+	if err != nil || string(s) != "Hello, Ann!" {
+		t.Fatalf("unexpected output %q, %v", s, err)
+	}
+}
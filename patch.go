@@ -0,0 +1,96 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TextEdit replaces Length bytes of a template source starting at Offset
+// with Text.
+type TextEdit struct {
+	Offset int
+	Length int
+	Text   string
+}
+
+// Patch applies edit to the template source, e.g. after a keystroke in an
+// editor with live preview.
+//
+// Edits confined to the static text between two tags that can't form a new
+// start tag update the parsed template in place: the text and tags are
+// re-sliced from the new source without scanning it for tags again. Other
+// edits, and all edits of templates created with [WithInterning], re-parse
+// the template with Reset.
+//
+// Like Reset, Patch may be called only if no other goroutines call t methods
+// at the moment.
+func (t *Template) Patch(edit TextEdit) error {
+	if edit.Offset < 0 || edit.Length < 0 || edit.Offset+edit.Length > len(t.template) {
+		return t.formatError(fmt.Errorf("%w: offset %d and length %d out of range [0, %d]", errInvalidEdit, edit.Offset, edit.Length, len(t.template)))
+	}
+	source := t.template[:edit.Offset] + edit.Text + t.template[edit.Offset+edit.Length:]
+
+	i, start := t.textAt(edit.Offset, edit.Offset+edit.Length)
+	if i < 0 || t.interning || t.stripped != nil || t.dropped != nil || (t.maxTemplateSize > 0 && len(source) > t.maxTemplateSize) {
+		return t.Reset(source, t.startTag, t.endTag)
+	}
+	end := start + len(t.texts[i]) + len(edit.Text) - edit.Length
+	if !t.keepsTags(source[start:end], i) {
+		return t.Reset(source, t.startTag, t.endTag)
+	}
+
+	// re-slice the texts and tags from the new source, so the old one can
+	// be collected
+	t.template = source
+	s := unsafeString2Bytes(source)
+	pos := 0
+	for j := range t.texts {
+		n := len(t.texts[j])
+		if j == i {
+			n = end - start
+		}
+		t.texts[j] = s[pos : pos+n]
+		pos += n
+		if j == len(t.tags) {
+			break
+		}
+		pos += len(t.startTag)
+		t.tags[j] = unsafeBytes2String(s[pos : pos+len(t.tags[j])])
+		pos += len(t.tags[j]) + len(t.endTag)
+	}
+
+	if err := t.parseBlocks(); err != nil {
+		return t.formatError(err)
+	}
+	return t.formatError(t.computeContexts())
+}
+
+// textAt returns the index and source offset of the static text containing
+// the source range [from, to], or -1 if the range touches a tag or the
+// template has no tags.
+func (t *Template) textAt(from, to int) (int, int) {
+	offsets := t.tagOffsets()
+	pos := 0
+	for i, text := range t.texts {
+		if from >= pos && to <= pos+len(text) {
+			return i, pos
+		}
+		if i < len(t.tags) {
+			pos = offsets[i] + len(t.startTag) + len(t.tags[i]) + len(t.endTag)
+			if t.stripped != nil {
+				pos += t.stripped[i]
+			}
+		}
+	}
+	return -1, 0
+}
+
+// keepsTags reports whether the i-th static text may be replaced with text
+// without changing where the tags of the template start.
+func (t *Template) keepsTags(text string, i int) bool {
+	if i == len(t.tags) {
+		return !strings.Contains(text, t.startTag)
+	}
+	// the start tag following the text must remain the first one
+	return strings.Index(text+t.startTag, t.startTag) == len(text)
+}
@@ -0,0 +1,118 @@
+package fasttemplate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	m := Map{"name": "Ann", "n": 3}
+	tests := []struct {
+		name     string
+		template string
+		edit     TextEdit
+		expected string
+		inPlace  bool
+	}{
+		{
+			name:     "InsertText",
+			template: "Hi {{name}}, you have {{n}} messages",
+			edit:     TextEdit{Offset: 2, Text: " there"},
+			expected: "Hi there Ann, you have 3 messages",
+			inPlace:  true,
+		},
+		{
+			name:     "ReplaceLastText",
+			template: "Hi {{name}}, you have {{n}} messages",
+			edit:     TextEdit{Offset: 28, Length: 8, Text: "new mails"},
+			expected: "Hi Ann, you have 3 new mails",
+			inPlace:  true,
+		},
+		{
+			name:     "DeleteText",
+			template: "Hi {{name}}, you have {{n}} messages",
+			edit:     TextEdit{Offset: 11, Length: 11},
+			expected: "Hi Ann3 messages",
+			inPlace:  true,
+		},
+		{
+			name:     "EditTag",
+			template: "Hi {{name}}, you have {{n}} messages",
+			edit:     TextEdit{Offset: 5, Length: 4, Text: "n"},
+			expected: "Hi 3, you have 3 messages",
+		},
+		{
+			name:     "AddTag",
+			template: "Hi {{name}}!",
+			edit:     TextEdit{Offset: 11, Text: " {{n}}"},
+			expected: "Hi Ann 3!",
+		},
+		{
+			name:     "StartTagBeforeTag",
+			template: "Hi {{name}}!",
+			edit:     TextEdit{Offset: 3, Text: "{"},
+			expected: "Hi !",
+		},
+		{
+			name:     "AfterStrippedSuffix",
+			template: "Hi {{name|max:5}}, you have {{n}} messages",
+			edit:     TextEdit{Offset: 19, Length: 9},
+			expected: "Hi Ann, 3 messages",
+		},
+		{
+			name:     "NoTags",
+			template: "Hello",
+			edit:     TextEdit{Offset: 5, Text: " {{name}}"},
+			expected: "Hello Ann",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}")
+			tags := len(tpl.tags)
+			if err := tpl.Patch(tt.edit); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if s := tpl.ExecuteString(m); s != tt.expected {
+				t.Fatalf("unexpected output %q. Expected %q", s, tt.expected)
+			}
+
+			fresh := New(tpl.template, "{{", "}}")
+			if s := fresh.ExecuteString(m); s != tt.expected {
+				t.Fatalf("unexpected patched source %q", tpl.template)
+			}
+			if tt.inPlace && (len(tpl.tags) != tags || textAtEdit(tpl, tt.edit) < 0) {
+				t.Fatalf("expected the edit to be applied in place")
+			}
+		})
+	}
+}
+
+// textAtEdit returns the static text containing the edited range.
+func textAtEdit(tpl *Template, edit TextEdit) int {
+	i, _ := tpl.textAt(edit.Offset, edit.Offset+len(edit.Text))
+	return i
+}
+
+func TestPatchBlocksAndEscaping(t *testing.T) {
+	tpl := New("{{capture x}}<b>{{name}}</b>{{end}}<p>{{x}}</p>", "{{", "}}", WithEscaping(EscapeHTML))
+	if err := tpl.Patch(TextEdit{Offset: 35, Length: 3, Text: `<a href="`}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tpl.Patch(TextEdit{Offset: 49, Length: 4, Text: `">`}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != `<a href="%3Cb%3EAnn%3C/b%3E">` {
+		t.Fatalf("unexpected output %q for %q", s, tpl.template)
+	}
+}
+
+func TestPatchInvalidEdit(t *testing.T) {
+	tpl := New("Hi {{name}}", "{{", "}}")
+	for _, edit := range []TextEdit{{Offset: -1}, {Offset: 3, Length: 9}, {Offset: 12}} {
+		if err := tpl.Patch(edit); !errors.Is(err, errInvalidEdit) {
+			t.Fatalf("expected errInvalidEdit for %+v; got %v", edit, err)
+		}
+	}
+}
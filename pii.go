@@ -0,0 +1,152 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// PIIPattern detects a kind of personally identifiable information in
+// values.
+type PIIPattern struct {
+	// Name identifies the pattern in findings, e.g. "email".
+	Name string
+
+	// Regexp matches candidate values.
+	Regexp *regexp.Regexp
+
+	// Valid, if set, filters the matches of Regexp, e.g. by checking a
+	// checksum to rule out false positives.
+	Valid func(match string) bool
+}
+
+var (
+	// PIIEmail detects email addresses.
+	PIIEmail = PIIPattern{
+		Name:   "email",
+		Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+
+	// PIICardNumber detects payment card numbers of 13 to 19 digits,
+	// optionally grouped by spaces or dashes, passing the Luhn check.
+	PIICardNumber = PIIPattern{
+		Name:   "card number",
+		Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Valid:  luhnValid,
+	}
+)
+
+// PIIFinding reports PII masked in the value of a tag. It never holds the
+// PII itself.
+type PIIFinding struct {
+	// Tag is the tag whose value contained the PII.
+	Tag string
+
+	// Pattern is the name of the pattern that matched.
+	Pattern string
+
+	// Count is the number of matches masked.
+	Count int
+}
+
+// WithPIIRedaction masks PII detected by patterns in the values of tags
+// with "****", leaving the static text of the template alone. It uses
+// PIIEmail and PIICardNumber if no patterns are given. onFinding, if not
+// nil, is called for every tag and pattern with matches, e.g. to collect a
+// compliance report; it may be called concurrently by concurrent
+// executions.
+//
+// Values are scanned as they are written, after value transformers and
+// before length limits and escaping, so slices, maps, structs, TagFunc
+// output and streamed results are covered as well. Values with matches are
+// written as strings, and so are values which can only be written once,
+// such as TagFunc values and streams. [ExecuteTyped] masks string results.
+func WithPIIRedaction(onFinding func(PIIFinding), patterns ...PIIPattern) Option {
+	if len(patterns) == 0 {
+		patterns = []PIIPattern{PIIEmail, PIICardNumber}
+	}
+	return func(t *Template) {
+		t.pii = &piiRedactor{patterns: patterns, onFinding: onFinding}
+	}
+}
+
+// piiRedactor masks PII in the values of tags.
+type piiRedactor struct {
+	patterns  []PIIPattern
+	onFinding func(PIIFinding)
+}
+
+// redact returns s with the PII masked and whether anything was masked.
+func (r *piiRedactor) redact(tag, s string) (string, bool) {
+	masked := false
+	for _, p := range r.patterns {
+		count := 0
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if p.Valid != nil && !p.Valid(match) {
+				return match
+			}
+			count++
+			return redactMask
+		})
+		if count > 0 {
+			masked = true
+			if r.onFinding != nil {
+				r.onFinding(PIIFinding{Tag: tag, Pattern: p.Name, Count: count})
+			}
+		}
+	}
+	return s, masked
+}
+
+// redactPII returns the value of the i-th tag with the PII masked. The
+// value is formatted to be scanned; it is kept unless PII was masked or it
+// can't be written again.
+func (t *Template) redactPII(i int, v any, kind tagKind) (any, error) {
+	var bb bytes.Buffer
+	if _, err := t.writeValue(&bb, i, v, kind); err != nil {
+		return nil, err
+	}
+	s, masked := t.pii.redact(t.tags[i], bb.String())
+	if masked || writtenOnce(v, kind) {
+		return s, nil
+	}
+	return v, nil
+}
+
+// writtenOnce reports whether writing v consumes it or has side effects, so
+// it must not be written again.
+func writtenOnce(v any, kind tagKind) bool {
+	switch v.(type) {
+	case TagFunc, func(io.Writer, string) (int, error), *os.File, Stream:
+		return true
+	case fmt.Stringer:
+		return false
+	case io.Reader, io.WriterTo:
+		return kind != tagVariable
+	}
+	return false
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check, ignoring
+// other characters.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,90 @@
+package fasttemplate
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithPIIRedaction(t *testing.T) {
+	var mu sync.Mutex
+	var findings []PIIFinding
+	report := func(f PIIFinding) {
+		mu.Lock()
+		findings = append(findings, f)
+		mu.Unlock()
+	}
+
+	tpl := New("Contact support@example.com. Note: {{note}} Card: {{card}} Order: {{order}}", "{{", "}}", WithPIIRedaction(report))
+	s := tpl.ExecuteString(Map{
+		"note":  "mail ann@example.org or bob.smith@mail.example.co.uk",
+		"card":  "4111 1111 1111 1111",
+		"order": 1234567890123,
+	})
+	expected := "Contact support@example.com. Note: mail **** or **** Card: **** Order: 1234567890123"
+	if s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+	if len(findings) != 2 || findings[0] != (PIIFinding{Tag: "note", Pattern: "email", Count: 2}) || findings[1] != (PIIFinding{Tag: "card", Pattern: "card number", Count: 1}) {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+
+	// integers passing the Luhn check are masked
+	if s := New("{{n}}", "{{", "}}", WithPIIRedaction(nil)).ExecuteString(Map{"n": 4242424242424242}); s != "****" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	ssn := PIIPattern{Name: "ssn", Regexp: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)}
+	tpl = New("{{v}}", "{{", "}}", WithPIIRedaction(nil, ssn))
+	if s := tpl.ExecuteString(Map{"v": "ssn 123-45-6789, mail ann@example.org"}); s != "ssn ****, mail ann@example.org" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWithPIIRedactionFormattedValues(t *testing.T) {
+	type contact struct {
+		Name  string
+		Email string
+	}
+	var findings []PIIFinding
+	tpl := New("{{list}}|{{struct}}|{{tagFunc}}|{{stream()}}|{{n}}", "{{", "}}",
+		WithPIIRedaction(func(f PIIFinding) { findings = append(findings, f) }))
+	s := tpl.ExecuteString(Map{
+		"list":   []string{"x@y.com", "z@w.org"},
+		"struct": contact{Name: "Ann", Email: "ann@example.org"},
+		"tagFunc": TagFunc(func(w io.Writer, tag string) (int, error) {
+			return w.Write([]byte("mail x@y.com"))
+		}),
+		"stream": func() io.Reader { return strings.NewReader("to z@w.org") },
+		"n":      42,
+	})
+	expected := "****, ****|{Ann ****}|mail ****|to ****|42"
+	if s != expected {
+		t.Fatalf("unexpected output %q. Expected %q", s, expected)
+	}
+	if len(findings) != 4 || findings[0] != (PIIFinding{Tag: "list", Pattern: "email", Count: 2}) {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+
+	// values without PII keep their type, e.g. for escaping
+	tpl = New("<script>var n = {{n}};</script>", "{{", "}}", WithPIIRedaction(nil), WithEscaping(EscapeHTML))
+	if s := tpl.ExecuteString(Map{"n": 42}); s != "<script>var n = 42;</script>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := map[string]bool{
+		"4111 1111 1111 1111": true,
+		"4111-1111-1111-1112": false,
+		"5500005555555559":    true,
+		"":                    false,
+	}
+	for s, expected := range tests {
+		if got := luhnValid(s); got != expected {
+			t.Errorf("luhnValid(%q) = %t, expected %t", s, got, expected)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package fasttemplate
+
+import "strings"
+
+// TagHandler resolves the argument of a tag routed to it by
+// [WithTagPrefix]. Its result is written like the result of a function.
+type TagHandler func(arg string, m Map) (any, error)
+
+// WithTagPrefix routes tags of the form prefix:arg to handler instead of
+// evaluating them as variables, function calls or expressions, e.g.
+// {{secret:db_password}} with the prefix "secret" calls handler with
+// "db_password". It enables domain-specific tag dialects. Whitespace around
+// the prefix and the argument is ignored.
+//
+// Errors returned by handler fail the execution like function errors; with
+// ExecuteStd the tag is kept instead.
+func WithTagPrefix(prefix string, handler TagHandler) Option {
+	return func(t *Template) {
+		if t.tagHandlers == nil {
+			t.tagHandlers = make(map[string]TagHandler)
+		}
+		t.tagHandlers[prefix] = handler
+	}
+}
+
+// lookupTagHandler returns the handler the tag is routed to by handlers and
+// its argument.
+func lookupTagHandler(handlers map[string]TagHandler, tag string) (TagHandler, string, bool) {
+	if handlers == nil {
+		return nil, "", false
+	}
+	prefix, arg, ok := strings.Cut(tag, ":")
+	if !ok {
+		return nil, "", false
+	}
+	h, ok := handlers[strings.TrimSpace(prefix)]
+	return h, strings.TrimSpace(arg), ok
+}
+
+// isHandlerTag reports whether the tag is routed to a tag handler.
+func (t *Template) isHandlerTag(tag string) bool {
+	_, _, ok := lookupTagHandler(t.tagHandlers, tag)
+	return ok
+}
+
+// TagArgs returns the arguments of the tags of the form prefix:arg in order
+// of first appearance, whether or not a handler is registered for prefix.
+// It lets tag handlers prefetch what a template needs before executing it.
+func (t *Template) TagArgs(prefix string) []string {
+	handlers := map[string]TagHandler{prefix: nil}
+	var args []string
+	seen := make(map[string]bool)
+	for i, tag := range t.tags {
+		if t.isBlockTag(i) {
+			continue
+		}
+		if _, arg, ok := lookupTagHandler(handlers, tag); ok && !seen[arg] {
+			seen[arg] = true
+			args = append(args, arg)
+		}
+	}
+	return args
+}
@@ -0,0 +1,62 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithTagPrefix(t *testing.T) {
+	secrets := func(arg string, m Map) (any, error) {
+		if arg == "db_password" {
+			return "hunter2", nil
+		}
+		return nil, errors.New("unknown secret " + arg)
+	}
+	i18n := func(arg string, m Map) (any, error) {
+		return strings.ToUpper(arg) + "@" + m["lang"].(string), nil
+	}
+	tpl := New("{{secret:db_password}} {{ i18n : welcome.title }} {{name}}", "{{", "}}",
+		WithTagPrefix("secret", secrets), WithTagPrefix("i18n", i18n))
+
+	m := Map{"name": "Ann", "lang": "de"}
+	if s := tpl.ExecuteString(m); s != "hunter2 WELCOME.TITLE@de Ann" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	if vars := tpl.Variables(); len(vars) != 1 || vars[0] != "name" {
+		t.Fatalf("unexpected variables %q", vars)
+	}
+
+	// the map is not consulted for routed tags, even with a single tag
+	single := New("{{secret:db_password}}", "{{", "}}", WithTagPrefix("secret", secrets))
+	if s := single.ExecuteString(Map{"secret:db_password": "leak"}); s != "hunter2" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	bad := New("[{{secret:api_key}}]", "{{", "}}", WithTagPrefix("secret", secrets))
+	var bb strings.Builder
+	if _, err := bad.Execute(&bb, m); err == nil || !strings.Contains(err.Error(), "unknown secret api_key") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s := bad.ExecuteStringStd(m); s != "[{{secret:api_key}}]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// unregistered prefixes go through the regular pipeline
+	if s := New("[{{other:x}}]", "{{", "}}", WithTagPrefix("secret", secrets)).ExecuteString(Map{"other:x": "v"}); s != "[v]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestTagArgs(t *testing.T) {
+	tpl := New("{{secret:a}} {{ secret : b }} {{secret:a}} {{i18n:c}} {{name}}", "{{", "}}")
+	if args := tpl.TagArgs("secret"); len(args) != 2 || args[0] != "a" || args[1] != "b" {
+		t.Fatalf("unexpected args %q", args)
+	}
+	if args := tpl.TagArgs("other"); args != nil {
+		t.Fatalf("unexpected args %q", args)
+	}
+}
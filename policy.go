@@ -0,0 +1,198 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithFuncTimeout limits the duration of calls of the named function.
+//
+// Tags whose calls exceed the timeout are handled like missing variables:
+// Execute renders them empty and ExecuteStd keeps them unchanged. The
+// function itself can't be interrupted and keeps running in the background
+// until it returns, so it should still give up eventually. Lambdas passed to
+// the function evaluate on a copy of the execution state, so an abandoned
+// call doesn't interfere with the rest of the execution.
+func WithFuncTimeout(name string, d time.Duration) Option {
+	return func(t *Template) {
+		t.callPolicy(name).timeout = d
+	}
+}
+
+// WithCircuitBreaker stops calling the named function for the cooldown
+// period once it has failed maxFailures times in a row, so a failing
+// dependency degrades renders quickly instead of stalling every one of them.
+//
+// Failures are errors returned by the function, panics and timeouts set
+// with [WithFuncTimeout]. While the circuit is open, calls are skipped and
+// their tags handled like missing variables. After the cooldown a single call is let through; the
+// circuit is closed again once a call succeeds.
+//
+// The breaker state is shared by all executions of the template.
+func WithCircuitBreaker(name string, maxFailures int, cooldown time.Duration) Option {
+	return func(t *Template) {
+		p := t.callPolicy(name)
+		p.maxFailures = maxFailures
+		p.cooldown = cooldown
+	}
+}
+
+// callPolicy returns the call policy of the named function, creating it if
+// needed.
+func (t *Template) callPolicy(name string) *callPolicy {
+	if t.callPolicies == nil {
+		t.callPolicies = make(map[string]*callPolicy)
+	}
+	p, ok := t.callPolicies[name]
+	if !ok {
+		p = &callPolicy{}
+		t.callPolicies[name] = p
+	}
+	return p
+}
+
+// callPolicy holds the timeout and circuit breaker of a function. A nil
+// policy allows every call without a timeout.
+type callPolicy struct {
+	timeout     time.Duration
+	maxFailures int
+	cooldown    time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// allow returns an error if the circuit of the function is open.
+func (p *callPolicy) allow(name string) error {
+	if p == nil || p.maxFailures <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures < p.maxFailures {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(p.openUntil) {
+		return fmt.Errorf("%w: %s", errCircuitOpen, name)
+	}
+	// half-open: let this call through and keep the others out until it
+	// reports back
+	p.openUntil = now.Add(p.cooldown)
+	return nil
+}
+
+// detaches reports whether calls may be abandoned on timeout, so they must
+// run on a detached copy of the execution state.
+func (p *callPolicy) detaches() bool {
+	return p != nil && p.timeout > 0
+}
+
+// call runs fn, giving up once the timeout elapses.
+func (p *callPolicy) call(name string, fn func()) error {
+	if p == nil || p.timeout <= 0 {
+		fn()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w: %s after %s", errFuncTimeout, name, p.timeout)
+	}
+}
+
+// done records the outcome of a call.
+func (p *callPolicy) done(ok bool) {
+	if p == nil || p.maxFailures <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		p.failures = 0
+		return
+	}
+	p.failures++
+	if p.failures >= p.maxFailures {
+		p.openUntil = time.Now().Add(p.cooldown)
+	}
+}
+
+// isUnavailable reports whether err is due to a function call timing out or
+// its circuit being open. Such tags degrade like missing variables instead
+// of failing the execution.
+func isUnavailable(err error) bool {
+	return errors.Is(err, errFuncTimeout) || errors.Is(err, errCircuitOpen)
+}
+
+// detach returns a copy of ec for a call that may keep running after the
+// execution has moved on. The copy shares no mutable state with ec.
+func (ec *evalContext) detach() *evalContext {
+	if ec == nil {
+		return nil
+	}
+	d := *ec
+	d.memo = cloneState(ec.memo)
+	d.counters = cloneState(ec.counters)
+	d.fold = nil
+	d.trace = nil
+	d.batch = nil
+	// clip the with stack, so pushing onto it doesn't overwrite the frames
+	// of ec
+	d.with = ec.with[:len(ec.with):len(ec.with)]
+	if ec.includes != nil {
+		c := *ec.includes
+		c.names = c.names[:len(c.names):len(c.names)]
+		c.templates = c.templates[:len(c.templates):len(c.templates)]
+		d.includes = &c
+	}
+	return &d
+}
+
+// adopt takes over the memoized results and counters of d, a detached copy
+// of ec whose call returned in time. They are merged into the maps of ec,
+// which may be shared with partials or the other executions of a batch.
+func (ec *evalContext) adopt(d *evalContext) {
+	if ec == nil {
+		return
+	}
+	ec.memo = mergeState(ec.memo, d.memo)
+	ec.counters = mergeState(ec.counters, d.counters)
+	ec.memoHits, ec.memoMisses = d.memoHits, d.memoMisses
+}
+
+// cloneState returns a copy of m, or nil if m is empty.
+func cloneState[V any](m map[string]V) map[string]V {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// mergeState copies the entries of src into dst, allocating dst if needed.
+func mergeState[V any](dst, src map[string]V) map[string]V {
+	if dst == nil {
+		return src
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
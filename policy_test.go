@@ -0,0 +1,107 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFuncTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m := Map{
+		"slow": func() string {
+			<-release
+			return "slow"
+		},
+		"fast": func() string { return "fast" },
+	}
+	tpl := New("{{fast()}} {{slow()}}", "{{", "}}", WithFuncTimeout("slow", 10*time.Millisecond), WithFuncTimeout("fast", time.Second))
+
+	var sb strings.Builder
+	if _, err := tpl.Execute(&sb, m); err != nil || sb.String() != "fast " {
+		t.Fatalf("unexpected output %q, error %v", sb.String(), err)
+	}
+	if s := tpl.ExecuteStringStd(m); s != "fast {{slow()}}" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	tpl = New(`{{slow() ?? "n/a"}}{{if slow()}}yes{{else}}no{{end}}`, "{{", "}}", WithFuncTimeout("slow", 10*time.Millisecond))
+	sb.Reset()
+	if _, err := tpl.Execute(&sb, m); err != nil || sb.String() != "n/ano" {
+		t.Fatalf("unexpected output %q, error %v", sb.String(), err)
+	}
+}
+
+// TestFuncTimeoutDetached checks that lambdas of abandoned calls don't touch
+// the state of the execution; run it with -race.
+func TestFuncTimeoutDetached(t *testing.T) {
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	m := Map{
+		"slow": func(f func(string) any) string {
+			defer wg.Done()
+			<-release
+			for i := 0; i < 100; i++ {
+				f("late")
+			}
+			return "slow"
+		},
+		"unblock": func() string {
+			close(release)
+			return ""
+		},
+		"id":   Func{Fn: func(s string) string { return s }, Idempotent: true},
+		"rows": make([]int, 100),
+	}
+	tpl := New(`{{slow((s) => counter(id(s)))}}{{unblock()}}{{range rows}}{{counter("late")}},{{id("x")}}{{end}}`, "{{", "}}", WithFuncTimeout("slow", 10*time.Millisecond))
+
+	var sb strings.Builder
+	_, err := tpl.Execute(&sb, m)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s := sb.String(); !strings.HasPrefix(s, "1,x2,x") {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	calls := 0
+	failing := true
+	m := Map{
+		"lookup": func() (string, error) {
+			calls++
+			if failing {
+				return "", errors.New("unavailable")
+			}
+			return "ok", nil
+		},
+	}
+	tpl := New("[{{lookup()}}]", "{{", "}}", WithCircuitBreaker("lookup", 2, 20*time.Millisecond))
+
+	for i := 0; i < 4; i++ {
+		tpl.ExecuteStringStd(m)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the circuit to open after 2 calls, got %d calls", calls)
+	}
+	var sb strings.Builder
+	if _, err := tpl.Execute(&sb, m); err != nil || sb.String() != "[]" || calls != 2 {
+		t.Fatalf("unexpected output %q, error %v after %d calls", sb.String(), err, calls)
+	}
+
+	// after the cooldown a trial call closes the circuit again
+	time.Sleep(30 * time.Millisecond)
+	failing = false
+	if s := tpl.ExecuteStringStd(m); s != "[ok]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := tpl.ExecuteStringStd(m); s != "[ok]" || calls != 4 {
+		t.Fatalf("unexpected output %q after %d calls", s, calls)
+	}
+}
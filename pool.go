@@ -0,0 +1,108 @@
+package fasttemplate
+
+import (
+	"io"
+	"sync"
+)
+
+// TemplatePool executes constantly changing templates by reusing [Template]
+// objects via Reset, so they benefit from the pooled buffers and the parsed
+// template representation just like frozen templates. Templates are pooled
+// per pair of delimiters.
+//
+// Unlike the top-level Execute functions, the pool parses every template
+// completely before executing it, so block syntax, escaping and the given
+// options are supported and malformed templates result in an error.
+//
+// A TemplatePool is safe for concurrent use.
+type TemplatePool struct {
+	opts  []Option
+	pools sync.Map // delimiters -> *sync.Pool
+}
+
+// delimiters is the key of the pools of a TemplatePool.
+type delimiters struct {
+	startTag, endTag string
+}
+
+// NewTemplatePool returns a pool of templates created with the given
+// options.
+func NewTemplatePool(opts ...Option) *TemplatePool {
+	return &TemplatePool{opts: opts}
+}
+
+// get returns a template parsed from template.
+func (p *TemplatePool) get(template, startTag, endTag string) (*Template, *sync.Pool, error) {
+	key := delimiters{startTag, endTag}
+	v, ok := p.pools.Load(key)
+	if !ok {
+		v, _ = p.pools.LoadOrStore(key, &sync.Pool{})
+	}
+	pool := v.(*sync.Pool)
+
+	t, ok := pool.Get().(*Template)
+	if !ok {
+		t = &Template{}
+		for _, opt := range p.opts {
+			opt(t)
+		}
+	}
+	if err := t.Reset(template, startTag, endTag); err != nil {
+		p.put(t, pool)
+		return nil, nil, err
+	}
+	return t, pool, nil
+}
+
+// put returns t to the pool, dropping the references to the template
+// source.
+func (p *TemplatePool) put(t *Template, pool *sync.Pool) {
+	t.clearParsed()
+	pool.Put(t)
+}
+
+// Execute works like [Template.Execute] for the template parsed from
+// template, startTag and endTag.
+func (p *TemplatePool) Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, error) {
+	t, pool, err := p.get(template, startTag, endTag)
+	if err != nil {
+		return 0, err
+	}
+	defer p.put(t, pool)
+	return t.Execute(w, m)
+}
+
+// ExecuteStd works like [Template.ExecuteStd] for the template parsed from
+// template, startTag and endTag.
+func (p *TemplatePool) ExecuteStd(template, startTag, endTag string, w io.Writer, m Map) (int64, error) {
+	t, pool, err := p.get(template, startTag, endTag)
+	if err != nil {
+		return 0, err
+	}
+	defer p.put(t, pool)
+	return t.ExecuteStd(w, m)
+}
+
+// ExecuteString works like [Template.ExecuteString] for the template parsed
+// from template, startTag and endTag. Templates which can't be parsed result
+// in an empty string.
+func (p *TemplatePool) ExecuteString(template, startTag, endTag string, m Map) string {
+	t, pool, err := p.get(template, startTag, endTag)
+	if err != nil {
+		return ""
+	}
+	defer p.put(t, pool)
+	return t.ExecuteString(m)
+}
+
+// ExecuteStringStd works like [Template.ExecuteStringStd] for the template
+// parsed from template, startTag and endTag. Templates which can't be parsed
+// result in an empty string.
+func (p *TemplatePool) ExecuteStringStd(template, startTag, endTag string, m Map) string {
+	t, pool, err := p.get(template, startTag, endTag)
+	if err != nil {
+		return ""
+	}
+	defer p.put(t, pool)
+	return t.ExecuteStringStd(m)
+}
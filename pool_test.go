@@ -0,0 +1,82 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTemplatePool(t *testing.T) {
+	p := NewTemplatePool(WithAliases(map[string]string{"who": "name"}))
+	m := Map{"name": "Ann", "upper": strings.ToUpper}
+
+	var bb bytes.Buffer
+	n, err := p.Execute("Hello, {{who}}!", "{{", "}}", &bb, m)
+	if err != nil || bb.String() != "Hello, Ann!" || n != 11 {
+		t.Fatalf("unexpected result %q (%d bytes), %v", bb.String(), n, err)
+	}
+	if s := p.ExecuteString("[upper(name)] [x]", "[", "]", m); s != "ANN " {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := p.ExecuteStringStd("[upper(name)] [x]", "[", "]", m); s != "ANN [x]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	bb.Reset()
+	if _, err := p.ExecuteStd("{{capture x}}{{name}}{{end}}<{{x}}>", "{{", "}}", &bb, m); err != nil || bb.String() != "<Ann>" {
+		t.Fatalf("unexpected result %q, %v", bb.String(), err)
+	}
+
+	if _, err := p.Execute("Hello, {{name", "{{", "}}", &bb, m); err == nil {
+		t.Fatal("expected error for unclosed tag")
+	}
+	if s := p.ExecuteString("Hello, {{name", "{{", "}}", m); s != "" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := p.ExecuteString("{{name}}", "{{", "}}", m); s != "Ann" {
+		t.Fatalf("unexpected output %q after error", s)
+	}
+}
+
+func TestTemplatePoolPut(t *testing.T) {
+	p := NewTemplatePool(WithInterning())
+	tpl, pool, err := p.get("a {{b}} c {{d}} e", "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.put(tpl, pool)
+	if tpl.template != "" || tpl.internedTags != 0 || tpl.internedSource {
+		t.Fatal("expected the template source to be released")
+	}
+	// the backing arrays are kept for reuse without pointing into the source
+	for _, text := range tpl.texts[:cap(tpl.texts)] {
+		if text != nil {
+			t.Fatalf("unexpected text %q left in the pooled template", text)
+		}
+	}
+	for _, tag := range tpl.tags[:cap(tpl.tags)] {
+		if tag != "" {
+			t.Fatalf("unexpected tag %q left in the pooled template", tag)
+		}
+	}
+}
+
+func TestTemplatePoolConcurrent(t *testing.T) {
+	p := NewTemplatePool()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				template := fmt.Sprintf("%d-{{a}}-%d", i, j)
+				if s := p.ExecuteString(template, "{{", "}}", Map{"a": i * j}); s != fmt.Sprintf("%d-%d-%d", i, i*j, j) {
+					t.Errorf("unexpected output %q", s)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
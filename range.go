@@ -0,0 +1,280 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keywordRange opens a range block.
+const keywordRange = "range"
+
+// parseRange splits the argument of a range block into the names bound to
+// the key and the element, both optional, and the ranged expression:
+//
+//	items
+//	item in items
+//	i, item in items
+func parseRange(arg string) (key, elem, expr string, ok bool) {
+	names, expr, found := strings.Cut(arg, " in ")
+	if !found {
+		// a trailing "in" is missing the ranged expression
+		return "", "", arg, arg != "" && !strings.HasSuffix(arg, " in")
+	}
+	expr = strings.TrimSpace(expr)
+	key, elem, found = strings.Cut(names, ",")
+	if !found {
+		key, elem = "", key
+	}
+	key, elem = strings.TrimSpace(key), strings.TrimSpace(elem)
+	if (found && !isValidFunctionName(key)) || !isValidFunctionName(elem) || expr == "" {
+		return "", "", "", false
+	}
+	return key, elem, expr, true
+}
+
+// checkRanges calls report for the range blocks ranging over a number
+// literal that isn't an integer, which would otherwise render nothing.
+func (t *Template) checkRanges(report func(i int, err error)) {
+	for i, tag := range t.tags {
+		if !t.isBlockTag(i) {
+			continue
+		}
+		kw, arg, _ := parseBlockTag(tag)
+		if kw != keywordRange {
+			continue
+		}
+		_, _, expr, _ := parseRange(arg)
+		if _, err := strconv.ParseFloat(expr, 64); err != nil {
+			continue
+		}
+		if _, err := strconv.Atoi(expr); err != nil {
+			report(i, fmt.Errorf("cannot range over %s: not an integer", expr))
+		}
+	}
+}
+
+// inRange reports whether a frame of stack is the body of a range block,
+// where break and continue are allowed.
+func inRange(stack []blockFrame) bool {
+	for _, f := range stack {
+		if f.node.kind == nodeRange && !f.inElse {
+			return true
+		}
+	}
+	return false
+}
+
+// usesLoop reports whether any tag or block argument of nodes may reference
+// the loop variable.
+func (t *Template) usesLoop(nodes []node) bool {
+	for i := range nodes {
+		nd := &nodes[i]
+		switch nd.kind {
+		case nodeText:
+			continue
+		case nodeTag:
+			if strings.Contains(t.tags[nd.tag], "loop") {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(nd.name, "loop") || t.usesLoop(nd.nodes) || t.usesLoop(nd.els) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRanged resolves the value of a range block like resolveTag, but
+// awaits futures even in asynchronous executions. Integer literals are
+// counts, e.g. {{range 3}}.
+func resolveRanged(tag string, m Map, ec *evalContext) (any, tagKind, error) {
+	if n, err := strconv.Atoi(tag); err == nil {
+		return n, tagExpression, nil
+	}
+	v, kind, err := evalTag(tag, m, ec)
+	if f, ok := v.(Future); ok && err == nil {
+		v, err = awaitFuture(f)
+	}
+	return v, kind, err
+}
+
+// rangeScope returns the value pushed on the with stack for an iteration:
+// the element itself, or a map holding the names bound by the block.
+func rangeScope(nd *node, key, elem any) any {
+	if nd.elem == "" {
+		return elem
+	}
+	if nd.key == "" {
+		return Map{nd.elem: elem}
+	}
+	return Map{nd.key: key, nd.elem: elem}
+}
+
+// executeRange renders the body of a range block for every element of v,
+// streaming each iteration to w, and returns the number of iterations.
+//
+// If the body references the loop variable, every element is rendered once
+// the next one is known, so that loop.last is available.
+func (t *Template) executeRange(w io.Writer, nd *node, v any, s *scope, std bool) (int64, int, error) {
+	var nn int64
+	var count int
+	var err error
+	render := func(key, elem any, last bool) bool {
+		frames := len(s.ec.with)
+		if nd.loop {
+			loop := Map{"index": count + 1, "index0": count, "first": count == 0, "last": last}
+			s.ec.with = append(s.ec.with, Map{"loop": loop})
+		}
+		s.ec.with = append(s.ec.with, rangeScope(nd, key, elem))
+		count++
+		var ni int64
+		ni, err = t.executeNodes(w, nd.nodes, s, std)
+		s.ec.with = s.ec.with[:frames]
+		nn += ni
+		switch err {
+		case errContinue:
+			err = nil
+		case errBreak:
+			err = nil
+			return false
+		}
+		return err == nil
+	}
+
+	var pendingKey, pendingElem any
+	pending, stopped := false, false
+	rerr := each(v, func(key, elem any) bool {
+		if !nd.loop {
+			return render(key, elem, false)
+		}
+		if pending && !render(pendingKey, pendingElem, false) {
+			stopped = true
+			return false
+		}
+		pendingKey, pendingElem, pending = key, elem, true
+		return true
+	})
+	if pending && !stopped && rerr == nil {
+		render(pendingKey, pendingElem, true)
+	}
+	if err == nil && rerr != nil {
+		err = fmt.Errorf("cannot range over %q: %w", nd.name, rerr)
+	}
+	return nn, count, err
+}
+
+// each calls yield with the keys and elements of v until it returns false.
+// Slices and arrays yield their indexes, maps their keys in sorted order,
+// channels the index of the received value and functions shaped like
+// iter.Seq or iter.Seq2 the values they produce. Like in Go, integers yield
+// the integers from 0 up to them, as do floats holding integers, which
+// expressions evaluate to. Nil values yield nothing.
+func each(v any, yield func(key, elem any) bool) error {
+	if m, ok := v.(Map); ok {
+		for _, k := range m.Keys() {
+			if !yield(k, m[k]) {
+				return nil
+			}
+		}
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return countTo(rv.Int(), yield)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return fmt.Errorf("%d is too large to range over", rv.Uint())
+		}
+		return countTo(int64(rv.Uint()), yield)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+			return fmt.Errorf("%v isn't an integer", v)
+		}
+		return countTo(int64(f), yield)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if !yield(i, rv.Index(i).Interface()) {
+				return nil
+			}
+		}
+		return nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return compareValues(keys[i].Interface(), keys[j].Interface()) < 0
+		})
+		for _, k := range keys {
+			if !yield(k.Interface(), rv.MapIndex(k).Interface()) {
+				return nil
+			}
+		}
+		return nil
+	case reflect.Chan:
+		if rv.Type().ChanDir()&reflect.RecvDir == 0 {
+			return fmt.Errorf("cannot receive from %T", v)
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		for i := 0; ; i++ {
+			x, ok := rv.Recv()
+			if !ok || !yield(i, x.Interface()) {
+				return nil
+			}
+		}
+	case reflect.Func:
+		if !isSeq(rv.Type()) {
+			break
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		yieldType := rv.Type().In(0)
+		i, done := 0, false
+		fn := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			if !done {
+				if len(args) == 1 {
+					done = !yield(i, args[0].Interface())
+				} else {
+					done = !yield(args[0].Interface(), args[1].Interface())
+				}
+				i++
+			}
+			return []reflect.Value{reflect.ValueOf(!done).Convert(yieldType.Out(0))}
+		})
+		rv.Call([]reflect.Value{fn})
+		return nil
+	}
+	return fmt.Errorf("%T isn't iterable", v)
+}
+
+// countTo yields the integers from 0 up to n as both key and element.
+func countTo(n int64, yield func(key, elem any) bool) error {
+	for i := int64(0); i < n; i++ {
+		if !yield(int(i), int(i)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// isSeq reports whether fnType is shaped like iter.Seq or iter.Seq2, i.e.
+// func(yield func(V) bool) or func(yield func(K, V) bool).
+func isSeq(fnType reflect.Type) bool {
+	if fnType.NumIn() != 1 || fnType.NumOut() != 0 {
+		return false
+	}
+	y := fnType.In(0)
+	return y.Kind() == reflect.Func && !y.IsVariadic() && (y.NumIn() == 1 || y.NumIn() == 2) &&
+		y.NumOut() == 1 && y.Out(0).Kind() == reflect.Bool
+}
@@ -0,0 +1,197 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeBlock(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int
+	}
+	m := Map{
+		"users": []user{{"Ann", 30}, {"Bob", 25}},
+		"tags":  []string{"a", "b"},
+		"ages":  map[string]int{"bob": 25, "ann": 30},
+		"env":   Map{"b": 2, "c": 3, "a": 1},
+		"site":  "example.com",
+		"upper": strings.ToUpper,
+	}
+	tests := map[string]string{
+		"{{range users}}{{name}} ({{Age}}) {{end}}":                       "Ann (30) Bob (25) ",
+		"{{range u in users}}{{upper(u.name)}}@{{site}} {{end}}":          "ANN@example.com BOB@example.com ",
+		"{{range i, tag in tags}}{{i}}={{tag}};{{end}}":                   "0=a;1=b;",
+		"{{range tags}}<{{.}}>{{end}}":                                    "<a><b>",
+		"{{range name, age in ages}}{{name}}:{{age}} {{end}}":             "ann:30 bob:25 ",
+		"{{range k, v in env}}{{k}}={{v}};{{end}}":                        "a=1;b=2;c=3;",
+		"{{range missing}}x{{else}}none{{end}}":                           "none",
+		"{{range u in users}}{{range tags}}{{u.name}}{{.}}{{end}}{{end}}": "AnnaAnnbBobaBobb",
+	}
+	for src, want := range tests {
+		if s := New(src, "{{", "}}").ExecuteString(m); s != want {
+			t.Fatalf("unexpected output of %q: %q, want %q", src, s, want)
+		}
+	}
+
+	tpl := New("{{range u in users}}{{u.name}}{{end}} {{range tags}}{{.}}{{end}}", "{{", "}}")
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "users,tags" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	// invalid range tags are regular tags
+	if s := New("{{range x in}}", "{{", "}}").ExecuteString(Map{}); s != "" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestRangeBlockIterators(t *testing.T) {
+	seq := func(yield func(string) bool) {
+		for _, s := range []string{"x", "y", "z"} {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+	seq2 := func(yield func(string, int) bool) {
+		_ = yield("a", 1) && yield("b", 2)
+	}
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	close(ch)
+	m := Map{"seq": seq, "seq2": seq2, "ch": ch, "empty": (<-chan int)(nil)}
+	tpl := New("{{range seq}}{{.}}{{end}} {{range k, v in seq2}}{{k}}{{v}}{{end}} {{range i, n in ch}}{{i}}:{{n}} {{end}}{{range empty}}x{{else}}-{{end}}", "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "xyz a1b2 0:1 1:2 -" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// an error stops the iteration
+	calls := 0
+	m["seq"] = func(yield func(int) bool) {
+		for i := 0; i < 5; i++ {
+			calls++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	m["fail"] = func(n int) (string, error) {
+		if n == 1 {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	}
+	if _, err := New("{{range seq}}{{fail(.)}}{{end}}", "{{", "}}").Execute(&bytes.Buffer{}, m); err == nil || calls != 2 {
+		t.Fatalf("unexpected error %v after %d calls", err, calls)
+	}
+
+	_, err := New("{{range n}}x{{end}}", "{{", "}}").Execute(&bytes.Buffer{}, Map{"n": true})
+	if err == nil || !strings.Contains(err.Error(), `cannot range over "n": bool isn't iterable`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRangeBlockIntegers(t *testing.T) {
+	m := Map{"n": 3, "u": uint8(2), "items": []int{10, 20}}
+	tests := map[string]string{
+		"{{range 3}}{{.}}{{end}}":                        "012",
+		"{{range i, x in n}}{{i}}{{x}} {{end}}":          "00 11 22 ",
+		"{{range u}}x{{end}}":                            "xx",
+		"{{range n + 1}}{{. * 2}},{{end}}":               "0,2,4,6,",
+		"{{range 0}}x{{else}}none{{end}}":                "none",
+		"{{range -2}}x{{else}}none{{end}}":               "none",
+		"{{range items}}{{. + 1}} {{!(. > 10)}} {{end}}": "11 true 21 false ",
+	}
+	for src, want := range tests {
+		var sb strings.Builder
+		if _, err := New(src, "{{", "}}").Execute(&sb, m); err != nil || sb.String() != want {
+			t.Fatalf("unexpected output of %q: %q, %v, want %q", src, sb.String(), err, want)
+		}
+	}
+
+	if _, err := New("{{range n / 2}}x{{end}}", "{{", "}}").Execute(&bytes.Buffer{}, m); err == nil || !strings.Contains(err.Error(), "1.5 isn't an integer") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := NewTemplate("{{range 2.5}}x{{end}}", "{{", "}}"); err == nil || !strings.Contains(err.Error(), "cannot range over 2.5: not an integer") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, errs := ParseTolerant("{{range 2.5}}x{{end}}", "{{", "}}"); len(errs) != 1 || errs[0].Offset != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+// signalWriter signals every write.
+type signalWriter struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	w.written <- struct{}{}
+	return n, err
+}
+
+func TestRangeBlockStreams(t *testing.T) {
+	ch := make(chan string)
+	w := &signalWriter{written: make(chan struct{}, 1)}
+	done := make(chan error, 1)
+	go func() {
+		_, err := New("{{range ch}}{{.}}{{end}}", "{{", "}}").Execute(w, Map{"ch": ch})
+		done <- err
+	}()
+	for _, s := range []string{"a", "b"} {
+		ch <- s
+		select {
+		case <-w.written:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q wasn't written before the next element", s)
+		}
+	}
+	close(ch)
+	if err := <-done; err != nil || w.String() != "ab" {
+		t.Fatalf("unexpected result %q, %v", w.String(), err)
+	}
+}
+
+func TestRangeBlockLoop(t *testing.T) {
+	ch := make(chan int, 5)
+	for i := 1; i <= 5; i++ {
+		ch <- i
+	}
+	close(ch)
+	m := Map{
+		"tags": []string{"a", "b", "c", "d"},
+		"ch":   ch,
+		"end":  "e",
+	}
+	tests := map[string]string{
+		"{{range tags}}{{.}}{{if loop.last}}.{{else}}, {{end}}{{end}}":                            "a, b, c, d.",
+		"{{range tags}}{{loop.index}}{{loop.index0}}{{if loop.first}}*{{end}} {{end}}":            "10* 21 32 43 ",
+		"{{range tags}}{{if loop.index > 2}}{{break}}{{end}}{{.}}{{end}}":                         "ab",
+		"{{range t in tags}}{{if t == 'b'}}{{continue}}{{end}}{{t}}{{end}}":                       "acd",
+		"{{range x in tags}}{{range y in tags}}{{if y == x}}{{break}}{{end}}{{y}}{{end}}|{{end}}": "|a|ab|abc|",
+		"{{range tags}}{{range tags}}{{end}}{{loop.index}}{{end}}":                                "1234",
+		"{{range n in ch}}{{n}}{{if loop.last}}!{{else}}-{{end}}{{end}}":                          "1-2-3-4-5!",
+		"{{break}}{{range tags}}{{end}}{{continue}}":                                              "",
+		"{{range tags}}{{break}}{{else}}empty{{end}}":                                             "",
+	}
+	for src, want := range tests {
+		if s := New(src, "{{", "}}").ExecuteString(m); s != want {
+			t.Fatalf("unexpected output of %q: %q, want %q", src, s, want)
+		}
+	}
+
+	// break and continue outside of range blocks are regular tags
+	tpl := New("{{break}} {{continue}}", "{{", "}}")
+	if s := tpl.ExecuteString(Map{"break": 1, "continue": 2}); s != "1 2" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
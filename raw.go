@@ -0,0 +1,43 @@
+package fasttemplate
+
+import "strings"
+
+// Raw block keywords.
+const (
+	keywordRaw    = "raw"
+	keywordEndRaw = "endraw"
+)
+
+// isRawTag reports whether tag opens a raw block, whose content up to the
+// next endraw tag is copied literally, delimiters included.
+func isRawTag(tag string) bool {
+	tag, _, _ = cutTrimMarkers(tag)
+	return strings.TrimSpace(tag) == keywordRaw
+}
+
+// isEndRawTag reports whether tag closes a raw block.
+func isEndRawTag(tag string) bool {
+	tag, _, _ = cutTrimMarkers(tag)
+	return strings.TrimSpace(tag) == keywordEndRaw
+}
+
+// rawEnd returns the length of the content of a raw block starting at s,
+// or -1 if it has no endraw tag.
+func rawEnd(s, startTag, endTag string) int {
+	pos := 0
+	for {
+		n := strings.Index(s[pos:], startTag)
+		if n < 0 {
+			return -1
+		}
+		start := pos + n
+		end := strings.Index(s[start+len(startTag):], endTag)
+		if end < 0 {
+			return -1
+		}
+		if isEndRawTag(s[start+len(startTag) : start+len(startTag)+end]) {
+			return start
+		}
+		pos = start + len(startTag) + end + len(endTag)
+	}
+}
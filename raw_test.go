@@ -0,0 +1,40 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawBlocks(t *testing.T) {
+	src := "{{name}}: {{raw}}Hi {{user.name}}, {{# kept #}}{{if x}}{{end}}{{ endraw }}!{{# note #}} {{raw}}{{endraw}}{{n}}"
+	tpl := New(src, "{{", "}}")
+	m := Map{"name": "greeting", "n": 1}
+	if s := tpl.ExecuteString(m); s != "greeting: Hi {{user.name}}, {{# kept #}}{{if x}}{{end}}! 1" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if vars := tpl.Variables(); strings.Join(vars, ",") != "name,n" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+
+	// offsets account for the raw blocks
+	findings := tpl.Analyze(Map{})
+	if len(findings) != 2 || findings[1].Offset != strings.LastIndex(src, "{{n}}") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	// raw without endraw is a regular tag
+	if s := New("{{raw}} {{x}}", "{{", "}}").ExecuteString(Map{"raw": "r", "x": "x"}); s != "r x" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New("[[raw]]{{a}}[[endraw]]", "[[", "]]").ExecuteString(nil); s != "{{a}}" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestScanRawBlocks(t *testing.T) {
+	got := formatTokens(Scan("{{raw}}{{a}}{{endraw}}", "{{", "}}"))
+	want := "tagOpen:{{ identifier:raw tagClose:}} text:{{a}} tagOpen:{{ identifier:endraw tagClose:}}"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
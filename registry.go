@@ -0,0 +1,97 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry holds functions callable from the templates bound to it with
+// [WithRegistry], so helpers can be provided without adding them to every
+// substitution map or registering them as builtins visible to all
+// templates.
+//
+// Registries form a tree of namespaces: a namespace sees its own functions
+// and those of its ancestors, but never those of its siblings. Multi-tenant
+// platforms can register shared helpers on the root and give every tenant a
+// namespace of its own:
+//
+//	reg := fasttemplate.NewRegistry()
+//	reg.Register("brand", brand)
+//	reg.Namespace("tenantA").Register("invoiceURL", invoiceURLForA)
+//
+//	t := fasttemplate.New(src, "{{", "}}", fasttemplate.WithRegistry(reg.Namespace("tenantA")))
+//
+// Functions in the substitution [Map] take precedence over registered ones,
+// which take precedence over builtins. A Registry is safe for concurrent
+// use.
+type Registry struct {
+	parent *Registry
+
+	mu         sync.RWMutex
+	funcs      map[string]any
+	namespaces map[string]*Registry
+}
+
+// NewRegistry returns an empty root registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register makes fn callable under the given name from templates bound to
+// the registry or one of its namespaces. fn may be a [Func] annotating the
+// function with metadata. It panics if fn isn't a function.
+func (r *Registry) Register(name string, fn any) {
+	if !isFunc(fn) {
+		panic(fmt.Sprintf("function %q must be a function, got %T", name, fn))
+	}
+
+	r.mu.Lock()
+	if r.funcs == nil {
+		r.funcs = make(map[string]any)
+	}
+	r.funcs[name] = fn
+	r.mu.Unlock()
+}
+
+// Namespace returns the namespace of r with the given name, creating it on
+// first use.
+func (r *Registry) Namespace(name string) *Registry {
+	r.mu.RLock()
+	ns, ok := r.namespaces[name]
+	r.mu.RUnlock()
+	if ok {
+		return ns
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ns, ok = r.namespaces[name]; !ok {
+		if r.namespaces == nil {
+			r.namespaces = make(map[string]*Registry)
+		}
+		ns = &Registry{parent: r}
+		r.namespaces[name] = ns
+	}
+	return ns
+}
+
+// lookup returns the function called name from r or its ancestors.
+func (r *Registry) lookup(name string) (any, bool) {
+	for ; r != nil; r = r.parent {
+		r.mu.RLock()
+		fn, ok := r.funcs[name]
+		r.mu.RUnlock()
+		if ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// WithRegistry binds the template to the registry or namespace r, making
+// its functions callable from the template.
+func WithRegistry(r *Registry) Option {
+	return func(t *Template) {
+		t.registry = r
+	}
+}
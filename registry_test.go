@@ -0,0 +1,61 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("brand", func() string { return "ACME" })
+	reg.Namespace("a").Register("greet", func(s string) string { return "Hi " + s })
+	reg.Namespace("b").Register("greet", func(s string) string { return "Hello " + s })
+	reg.Namespace("b").Register("shout", strings.ToUpper)
+
+	m := Map{"name": "Ann"}
+	src := "{{brand()}}: {{greet(name)}}"
+
+	if s := New(src, "{{", "}}", WithRegistry(reg.Namespace("a"))).ExecuteString(m); s != "ACME: Hi Ann" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New(src, "{{", "}}", WithRegistry(reg.Namespace("b"))).ExecuteString(m); s != "ACME: Hello Ann" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// functions of other namespaces aren't visible
+	tpl := New("{{shout(name)}}", "{{", "}}", WithRegistry(reg.Namespace("a")))
+	if _, err := tpl.Execute(&strings.Builder{}, m); !errors.Is(err, errFunctionNotFound) {
+		t.Fatalf("expected function not found error, got %v", err)
+	}
+	if err := tpl.Validate(m); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if _, err := New("{{greet(name)}}", "{{", "}}").Execute(&strings.Builder{}, m); !errors.Is(err, errFunctionNotFound) {
+		t.Fatalf("expected function not found error without registry, got %v", err)
+	}
+
+	// nested calls, expressions and builtins
+	tpl = New("{{shout(greet(name))}} {{shout(name) + '!'}} {{redact('secret-value', 2)}}", "{{", "}}", WithRegistry(reg.Namespace("b")))
+	if err := tpl.Validate(m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := tpl.ExecuteString(m); s != "HELLO ANN ANN! ****ue" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// the map takes precedence
+	m["greet"] = func(s string) string { return "Yo " + s }
+	if s := New(src, "{{", "}}", WithRegistry(reg.Namespace("a"))).ExecuteString(m); s != "ACME: Yo Ann" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestRegistryRegisterPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	NewRegistry().Register("x", "not a function")
+}
@@ -0,0 +1,89 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// graphQL is the body of a GraphQL request.
+type graphQL struct {
+	query string
+	vars  []string
+}
+
+// NewGraphQL parses a GraphQL request posting query to url. Instead of
+// substituting tags into the query, the variables referenced in the query as
+// $name are extracted and their values taken from the Map of each render, so
+// values can't alter the query. Variables missing from the Map are left out.
+//
+// The url and header values are templates as in New.
+func NewGraphQL(url, query string, headers map[string]string, startTag, endTag string, opts ...fasttemplate.Option) (*Template, error) {
+	t, err := New(Spec{Method: http.MethodPost, URL: url, Headers: headers}, startTag, endTag, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.graphql = &graphQL{query: query, vars: graphQLVariables(query)}
+	return t, nil
+}
+
+func (g *graphQL) body(data fasttemplate.Map) ([]byte, error) {
+	vars := make(map[string]any, len(g.vars))
+	for _, name := range g.vars {
+		if v, ok := data[name]; ok {
+			vars[name] = v
+		}
+	}
+	return json.Marshal(struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables,omitempty"`
+	}{g.query, vars})
+}
+
+// graphQLVariables returns the names of the variables referenced in query,
+// in order of first appearance. Strings and comments are skipped.
+func graphQLVariables(query string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '"' && len(query)-i >= 3 && query[i:i+3] == `"""`:
+			i += 3
+			for i < len(query) && !(len(query)-i >= 3 && query[i:i+3] == `"""`) {
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i += 2
+		case c == '"':
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case c == '$':
+			j := i + 1
+			for j < len(query) && isNameChar(query[j], j == i+1) {
+				j++
+			}
+			if name := query[i+1 : j]; name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			i = j - 1
+		}
+	}
+	return names
+}
+
+// isNameChar reports whether c may appear in a GraphQL name, at its start if
+// first is set.
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
@@ -0,0 +1,69 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestGraphQL(t *testing.T) {
+	query := `query User($id: ID!, $first: Int) {
+  # $ignored in comments
+  user(id: $id, note: "costs $5") { posts(first: $first) { title } }
+}`
+	tpl, err := NewGraphQL("{{endpoint}}", query, map[string]string{"Authorization": "Bearer {{token}}"}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, expected := tpl.Variables(), []string{"endpoint", "token", "id", "first"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected variables %q. Expected %q", got, expected)
+	}
+
+	req, err := tpl.Render(context.Background(), fasttemplate.Map{
+		"endpoint": "https://example.com/graphql",
+		"token":    "t",
+		"id":       `1") { admin } #`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if req.Method != "POST" || req.URL.String() != "https://example.com/graphql" {
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	var got struct {
+		Query     string
+		Variables map[string]any
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid body %s: %s", body, err)
+	}
+	if got.Query != query {
+		t.Fatalf("unexpected query %q", got.Query)
+	}
+	if expected := map[string]any{"id": `1") { admin } #`}; !reflect.DeepEqual(got.Variables, expected) {
+		t.Fatalf("unexpected variables %v", got.Variables)
+	}
+}
+
+func TestGraphQLVariables(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`{ a }`, nil},
+		{`query($a: Int, $b_2: [String]) { x(a: $a, b: $b_2, c: $a) }`, []string{"a", "b_2"}},
+		{`{ x(s: """block $not \""" still $not""", v: $yes) }`, []string{"yes"}},
+		{`{ x(s: "esc \" $not", v: $yes) }`, []string{"yes"}},
+	}
+	for _, tt := range tests {
+		if got := graphQLVariables(tt.query); !reflect.DeepEqual(got, tt.expected) {
+			t.Fatalf("unexpected variables %q for %q. Expected %q", got, tt.query, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package request renders HTTP requests from fasttemplate templates.
+//
+// A Spec describes the method, URL, headers and body of a request. Tags are
+// escaped according to their position: URL tags are percent-encoded as path
+// segments or query parameters, header tags can't inject line breaks and
+// body tags are escaped as JSON strings or values:
+//
+//	t, err := request.New(request.Spec{
+//		Method: http.MethodPost,
+//		URL:    "{{base}}/users/{{id}}/notes?tag={{tag}}",
+//		Headers: map[string]string{
+//			"Authorization": "Bearer {{token}}",
+//		},
+//		Body: `{"text": "{{text}}", "pinned": {{pinned}}}`,
+//	}, "{{", "}}")
+//	...
+//	req, err := t.Render(ctx, fasttemplate.Map{...})
+//
+// GraphQL requests are created with NewGraphQL, which passes values as
+// GraphQL variables instead of substituting them into the query.
+package request
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/dwisiswant0/fasttemplate"
+	"github.com/dwisiswant0/fasttemplate/internal/sanitize"
+)
+
+// Spec describes a templated HTTP request.
+type Spec struct {
+	// Method is the HTTP method; GET if empty.
+	Method string
+
+	// URL is the template of the request URL.
+	URL string
+
+	// Headers maps header names to templates of their values.
+	Headers map[string]string
+
+	// Body is the template of a JSON request body. Requests with a body
+	// default to the Content-Type application/json.
+	Body string
+}
+
+// Template renders HTTP requests.
+type Template struct {
+	method  string
+	url     *fasttemplate.Template
+	headers []header
+	body    *fasttemplate.Template
+	graphql *graphQL
+}
+
+type header struct {
+	name  string
+	value *fasttemplate.Template
+}
+
+// New parses the templates of the spec. The given options apply to all
+// templates.
+func New(spec Spec, startTag, endTag string, opts ...fasttemplate.Option) (*Template, error) {
+	t := Template{method: spec.Method}
+	if t.method == "" {
+		t.method = http.MethodGet
+	}
+
+	var err error
+	urlOpts := append([]fasttemplate.Option{fasttemplate.WithEscaping(fasttemplate.EscapeURL)}, opts...)
+	if t.url, err = fasttemplate.NewTemplate(spec.URL, startTag, endTag, urlOpts...); err != nil {
+		return nil, fmt.Errorf("request: URL: %w", err)
+	}
+
+	names := make([]string, 0, len(spec.Headers))
+	for name := range spec.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headerOpts := append([]fasttemplate.Option{fasttemplate.WithValueTransformer(sanitize.StripValueLineBreaks)}, opts...)
+	for _, name := range names {
+		v, err := fasttemplate.NewTemplate(spec.Headers[name], startTag, endTag, headerOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("request: header %s: %w", name, err)
+		}
+		t.headers = append(t.headers, header{name: name, value: v})
+	}
+
+	if spec.Body != "" {
+		bodyOpts := append([]fasttemplate.Option{fasttemplate.WithEscaping(fasttemplate.EscapeJSON)}, opts...)
+		if t.body, err = fasttemplate.NewTemplate(spec.Body, startTag, endTag, bodyOpts...); err != nil {
+			return nil, fmt.Errorf("request: body: %w", err)
+		}
+	}
+	return &t, nil
+}
+
+// Render renders the request for data.
+func (t *Template) Render(ctx context.Context, data fasttemplate.Map) (*http.Request, error) {
+	var bb bytes.Buffer
+	if _, err := t.url.Execute(&bb, data); err != nil {
+		return nil, fmt.Errorf("request: URL: %w", err)
+	}
+	url := bb.String()
+
+	var body []byte
+	switch {
+	case t.graphql != nil:
+		var err error
+		if body, err = t.graphql.body(data); err != nil {
+			return nil, fmt.Errorf("request: body: %w", err)
+		}
+	case t.body != nil:
+		var bodyBuf bytes.Buffer
+		if _, err := t.body.Execute(&bodyBuf, data); err != nil {
+			return nil, fmt.Errorf("request: body: %w", err)
+		}
+		body = bodyBuf.Bytes()
+	}
+
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequestWithContext(ctx, t.method, url, bytes.NewReader(body))
+	} else {
+		req, err = http.NewRequestWithContext(ctx, t.method, url, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+
+	for _, h := range t.headers {
+		bb.Reset()
+		if _, err := h.value.Execute(&bb, data); err != nil {
+			return nil, fmt.Errorf("request: header %s: %w", h.name, err)
+		}
+		req.Header.Set(h.name, bb.String())
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// Variables returns the names of the variables referenced by the request,
+// in order of first appearance.
+func (t *Template) Variables() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(vars []string) {
+		for _, name := range vars {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	add(t.url.Variables())
+	for _, h := range t.headers {
+		add(h.value.Variables())
+	}
+	if t.body != nil {
+		add(t.body.Variables())
+	}
+	if t.graphql != nil {
+		add(t.graphql.vars)
+	}
+	return names
+}
@@ -0,0 +1,138 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestRender(t *testing.T) {
+	tpl, err := New(Spec{
+		Method: http.MethodPost,
+		URL:    "{{base}}/users/{{id}}/notes?tag={{tag}}",
+		Headers: map[string]string{
+			"Authorization": "Bearer {{token}}",
+		},
+		Body: `{"text": "{{text}}", "pinned": {{pinned}}, "tags": {{tags}}}`,
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	req, err := tpl.Render(context.Background(), fasttemplate.Map{
+		"base":   "https://api.example.com",
+		"id":     "42/../admin",
+		"tag":    "a&b",
+		"token":  "secret\r\nX-Admin: 1",
+		"text":   `say "hi"`,
+		"pinned": true,
+		"tags":   []string{"x", "y"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if req.Method != http.MethodPost {
+		t.Fatalf("unexpected method %q", req.Method)
+	}
+	if got := req.URL.String(); got != "https://api.example.com/users/42%2F..%2Fadmin/notes?tag=a%26b" {
+		t.Fatalf("unexpected URL %q", got)
+	}
+	if got := req.URL.Query().Get("tag"); got != "a&b" {
+		t.Fatalf("unexpected query value %q", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret X-Admin: 1" {
+		t.Fatalf("unexpected Authorization %q", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("unexpected Content-Type %q", got)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid body %s: %s", body, err)
+	}
+	expected := map[string]any{"text": `say "hi"`, "pinned": true, "tags": []any{"x", "y"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected body %v", got)
+	}
+}
+
+func TestRenderHeaderInjection(t *testing.T) {
+	type token string
+	tpl, err := New(Spec{
+		URL:     "https://api.example.com",
+		Headers: map[string]string{"Authorization": "Bearer {{token}}", "X-Reason": "{{reason}}"},
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// line breaks are stripped from the formatted values, whatever their type
+	req, err := tpl.Render(context.Background(), fasttemplate.Map{
+		"token":  token("secret\r\nX-Admin: 1"),
+		"reason": errors.New("denied\nX-Admin: 1"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret X-Admin: 1" {
+		t.Fatalf("unexpected Authorization %q", got)
+	}
+	if got := req.Header.Get("X-Reason"); got != "denied X-Admin: 1" {
+		t.Fatalf("unexpected X-Reason %q", got)
+	}
+}
+
+func TestRenderWithoutBody(t *testing.T) {
+	tpl, err := New(Spec{URL: "https://example.com/{{path}}"}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req, err := tpl.Render(context.Background(), fasttemplate.Map{"path": "a b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if req.Method != http.MethodGet || req.Body != nil || req.Header.Get("Content-Type") != "" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	if got := req.URL.String(); got != "https://example.com/a%20b" {
+		t.Fatalf("unexpected URL %q", got)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tpl, err := New(Spec{
+		URL:     "{{base}}/users/{{id}}",
+		Headers: map[string]string{"Authorization": "Bearer {{token}}"},
+		Body:    `{"id": {{id}}, "name": "{{upper(name)}}"}`,
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{"base", "id", "token", "name"}
+	if got := tpl.Variables(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected variables %q. Expected %q", got, expected)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := New(Spec{URL: "{{"}, "{{", "}}"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+
+	tpl, err := New(Spec{URL: "https://example.com", Body: "{{fail()}}"}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fail := func() (string, error) { return "", io.ErrUnexpectedEOF }
+	if _, err := tpl.Render(context.Background(), fasttemplate.Map{"fail": fail}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
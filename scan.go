@@ -0,0 +1,201 @@
+package fasttemplate
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind is the lexical kind of a token reported by [Scan].
+type TokenKind int
+
+const (
+	// TokenText is static text outside of tags.
+	TokenText TokenKind = iota
+	// TokenTagOpen is a start tag delimiter.
+	TokenTagOpen
+	// TokenIdentifier is a variable, function or keyword name, including
+	// dotted paths such as user.name.
+	TokenIdentifier
+	// TokenOperator is an operator or punctuation, e.g. "+", "&&", "(" or
+	// ",".
+	TokenOperator
+	// TokenString is a quoted string literal, possibly unterminated.
+	TokenString
+	// TokenNumber is a number literal.
+	TokenNumber
+	// TokenTagClose is an end tag delimiter.
+	TokenTagClose
+	// TokenInvalid is a character that can't start any token.
+	TokenInvalid
+	// TokenComment is the content of a comment tag such as {{# note #}}.
+	TokenComment
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenText:
+		return "text"
+	case TokenTagOpen:
+		return "tagOpen"
+	case TokenIdentifier:
+		return "identifier"
+	case TokenOperator:
+		return "operator"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenTagClose:
+		return "tagClose"
+	case TokenComment:
+		return "comment"
+	}
+	return "invalid"
+}
+
+// TokenInfo is a token of a template and its byte range [Start, End) in the
+// template source.
+type TokenInfo struct {
+	Kind  TokenKind
+	Start int
+	End   int
+	Text  string
+}
+
+// scanOperators lists the operators of more than one character, longest
+// first.
+var scanOperators = []string{"...", "=>", "**", "&&", "||", "==", "!=", "<=", ">="}
+
+// Scan splits template into tokens with their byte ranges, e.g. for syntax
+// highlighting or language servers. Tag contents are split into identifiers,
+// operators, string and number literals; whitespace isn't reported.
+//
+// Unlike parsing, scanning never fails: an unclosed tag extends to the end of
+// the template and unknown characters are reported as TokenInvalid, so Scan
+// may be used on templates being edited.
+func Scan(template, startTag, endTag string) []TokenInfo {
+	var tokens []TokenInfo
+	add := func(kind TokenKind, start, end int) {
+		tokens = append(tokens, TokenInfo{Kind: kind, Start: start, End: end, Text: template[start:end]})
+	}
+	if startTag == "" || endTag == "" {
+		if template != "" {
+			add(TokenText, 0, len(template))
+		}
+		return tokens
+	}
+
+	pos := 0
+	for pos < len(template) {
+		n := strings.Index(template[pos:], startTag)
+		if n < 0 {
+			add(TokenText, pos, len(template))
+			break
+		}
+		if n > 0 {
+			add(TokenText, pos, pos+n)
+		}
+		pos += n
+		add(TokenTagOpen, pos, pos+len(startTag))
+		pos += len(startTag)
+
+		end := strings.Index(template[pos:], endTag)
+		if end >= 0 {
+			if n, err := commentEnd(template[pos:], endTag); err != nil {
+				// the rest of the template is the unterminated comment
+				add(TokenComment, pos, len(template))
+				break
+			} else if n >= 0 {
+				end = n
+			}
+		}
+		if end < 0 {
+			tokens = scanTag(tokens, template, pos, len(template))
+			break
+		}
+		if isCommentTag(template[pos : pos+end]) {
+			add(TokenComment, pos, pos+end)
+		} else {
+			tokens = scanTag(tokens, template, pos, pos+end)
+		}
+		pos += end
+		add(TokenTagClose, pos, pos+len(endTag))
+		pos += len(endTag)
+		if isRawTag(template[pos-len(endTag)-end : pos-len(endTag)]) {
+			if n := rawEnd(template[pos:], startTag, endTag); n > 0 {
+				add(TokenText, pos, pos+n)
+				pos += n
+			}
+		}
+	}
+	return tokens
+}
+
+// scanTag appends the tokens of the tag content template[start:end].
+func scanTag(tokens []TokenInfo, template string, start, end int) []TokenInfo {
+	s := template[:end]
+	for i := start; i < end; {
+		c := s[i]
+		from := i
+		kind := TokenOperator
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c >= '0' && c <= '9':
+			kind = TokenNumber
+			for i < end && ((s[i] >= '0' && s[i] <= '9') || s[i] == '.') {
+				// leave "..." of a spread to the operator
+				if strings.HasPrefix(s[i:], "...") {
+					break
+				}
+				i++
+			}
+		case isIdentStart(c):
+			kind = TokenIdentifier
+			for i++; i < end; i++ {
+				ch := s[i]
+				if ch == '.' && i+1 < end && isIdentStart(s[i+1]) {
+					continue
+				}
+				if !isIdentStart(ch) && (ch < '0' || ch > '9') {
+					break
+				}
+			}
+		case c == '"' || c == '\'':
+			kind = TokenString
+			for i++; i < end && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i < end {
+				i++
+			} else {
+				i = end
+			}
+		default:
+			i += scanOperator(s[i:])
+			if i == from {
+				_, size := utf8.DecodeRuneInString(s[i:])
+				i += size
+				kind = TokenInvalid
+			}
+		}
+		tokens = append(tokens, TokenInfo{Kind: kind, Start: from, End: i, Text: s[from:i]})
+	}
+	return tokens
+}
+
+// scanOperator returns the length of the operator s starts with, or 0.
+func scanOperator(s string) int {
+	for _, op := range scanOperators {
+		if strings.HasPrefix(s, op) {
+			return len(op)
+		}
+	}
+	if strings.IndexByte("+-*/%<>!=&|(),.?:[]", s[0]) >= 0 {
+		return 1
+	}
+	return 0
+}
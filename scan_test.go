@@ -0,0 +1,94 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatTokens formats tokens as "kind:text" for comparison.
+func formatTokens(tokens []TokenInfo) string {
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		parts[i] = fmt.Sprintf("%s:%s", tok.Kind, tok.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Text",
+			template: "plain",
+			expected: "text:plain",
+		},
+		{
+			name:     "Variable",
+			template: "Hi {{ user.name }}!",
+			expected: "text:Hi  tagOpen:{{ identifier:user.name tagClose:}} text:!",
+		},
+		{
+			name:     "Expression",
+			template: "{{price * 1.5 >= 10 && active}}",
+			expected: "tagOpen:{{ identifier:price operator:* number:1.5 operator:>= number:10 operator:&& identifier:active tagClose:}}",
+		},
+		{
+			name:     "Call",
+			template: `{{join(", ", items...)}}`,
+			expected: `tagOpen:{{ identifier:join operator:( string:", " operator:, identifier:items operator:... operator:) tagClose:}}`,
+		},
+		{
+			name:     "Lambda",
+			template: `{{map(xs, (x) => x + 'a\'b')}}`,
+			expected: `tagOpen:{{ identifier:map operator:( identifier:xs operator:, operator:( identifier:x operator:) operator:=> identifier:x operator:+ string:'a\'b' operator:) tagClose:}}`,
+		},
+		{
+			name:     "Unclosed",
+			template: "a {{name + 'x",
+			expected: "text:a  tagOpen:{{ identifier:name operator:+ string:'x",
+		},
+		{
+			name:     "Invalid",
+			template: "{{a # ü}}",
+			expected: "tagOpen:{{ identifier:a invalid:# invalid:ü tagClose:}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := Scan(tt.template, "{{", "}}")
+			if s := formatTokens(tokens); s != tt.expected {
+				t.Fatalf("unexpected tokens\n got: %s\nwant: %s", s, tt.expected)
+			}
+			for _, tok := range tokens {
+				if tt.template[tok.Start:tok.End] != tok.Text {
+					t.Fatalf("token %+v doesn't match its range", tok)
+				}
+			}
+		})
+	}
+}
+
+func TestScanEmptyDelimiters(t *testing.T) {
+	if s := formatTokens(Scan("{{a}}", "", "}}")); s != "text:{{a}}" {
+		t.Fatalf("unexpected tokens %s", s)
+	}
+	if tokens := Scan("", "{{", "}}"); tokens != nil {
+		t.Fatalf("unexpected tokens %+v", tokens)
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	got := formatTokens(Scan("a{{# x + y #}}", "{{", "}}"))
+	if want := "text:a tagOpen:{{ comment:# x + y # tagClose:}}"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	got = formatTokens(Scan("{{# a }} b #}}c{{# d }}", "{{", "}}"))
+	if want := "tagOpen:{{ comment:# a }} b # tagClose:}} text:c tagOpen:{{ comment:# d }}"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+// Package secrets resolves {{secret:name}} tags of fasttemplate templates
+// from secret backends such as Vault or a KMS.
+//
+// A Resolver fetches the secrets referenced by a template concurrently
+// before rendering it, caches them for a configurable TTL and masks secret
+// values in every error it returns:
+//
+//	r := secrets.NewResolver(backend, 5*time.Minute)
+//	t := fasttemplate.New("postgres://app:{{secret:db_password}}@db/app", "{{", "}}", r.Option(context.Background()))
+//	...
+//	_, err := r.Execute(ctx, t, w, m)
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// Prefix is the tag prefix routed to a Resolver, as in {{secret:name}}.
+const Prefix = "secret"
+
+// mask replaces secret values in error messages.
+const mask = "****"
+
+// minMaskLength is the length of the shortest secret values masked. Shorter
+// values, such as "1", occur in most messages by chance, so masking them
+// would garble every error instead of protecting them.
+const minMaskLength = 4
+
+// ErrNotFound is returned by backends for unknown secrets.
+var ErrNotFound = errors.New("secret not found")
+
+// Backend fetches secrets by name. Implementations must be safe for
+// concurrent use.
+type Backend interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// Memory is an in-memory Backend, mapping secret names to their values. It
+// is meant for tests and as a reference implementation.
+type Memory map[string]string
+
+// Secret implements Backend.
+func (m Memory) Secret(ctx context.Context, name string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	v, ok := m[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return v, nil
+}
+
+// entry is a cached secret.
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+// Resolver resolves secret tags from a Backend.
+type Resolver struct {
+	backend Backend
+	ttl     time.Duration
+	now     func() time.Time
+
+	// cache holds the last value fetched for every name. Expired entries
+	// are kept until they are fetched again, so their values stay masked
+	// until they are rotated.
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+// NewResolver returns a Resolver fetching secrets from backend and caching
+// them for ttl. Secrets are cached until the process exits if ttl is zero
+// or negative.
+func NewResolver(backend Backend, ttl time.Duration) *Resolver {
+	return &Resolver{
+		backend: backend,
+		ttl:     ttl,
+		now:     time.Now,
+		cache:   make(map[string]entry),
+	}
+}
+
+// Option returns the option routing {{secret:name}} tags to r. Secrets
+// missing from the cache are fetched with ctx when the tag is rendered, so
+// ctx must outlive the template; use [Resolver.Execute] or
+// [Resolver.Prefetch] to fetch them up front with the context of a request.
+//
+// The option also masks secret values in the errors returned by the
+// template using [fasttemplate.WithErrorFormatter], replacing the formatter
+// of an earlier option. Unlike the errors of [Resolver.Execute], these
+// errors still unwrap to the unmasked errors, so errors.Is and errors.As
+// keep working.
+func (r *Resolver) Option(ctx context.Context) fasttemplate.Option {
+	prefix := fasttemplate.WithTagPrefix(Prefix, func(name string, _ fasttemplate.Map) (any, error) {
+		return r.Resolve(ctx, name)
+	})
+	mask := fasttemplate.WithErrorFormatter(func(err error) string {
+		return r.Mask(err).Error()
+	})
+	return func(t *fasttemplate.Template) {
+		prefix(t)
+		mask(t)
+	}
+}
+
+// Resolve returns the named secret, fetching it if it isn't cached.
+func (r *Resolver) Resolve(ctx context.Context, name string) (string, error) {
+	if v, ok := r.cached(name); ok {
+		return v, nil
+	}
+	v, err := r.backend.Secret(ctx, name)
+	if err != nil {
+		return "", r.Mask(fmt.Errorf("secret %q: %w", name, err))
+	}
+	r.store(name, v)
+	return v, nil
+}
+
+// Prefetch fetches the named secrets that aren't cached concurrently. It
+// returns the errors of all failed fetches.
+func (r *Resolver) Prefetch(ctx context.Context, names ...string) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	for i, name := range names {
+		if _, ok := r.cached(name); ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, errs[i] = r.Resolve(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Execute prefetches the secrets referenced by t and executes it. Errors,
+// including those of functions called by the template, have all secret
+// values masked.
+func (r *Resolver) Execute(ctx context.Context, t *fasttemplate.Template, w io.Writer, m fasttemplate.Map) (int64, error) {
+	if err := r.Prefetch(ctx, t.TagArgs(Prefix)...); err != nil {
+		return 0, err
+	}
+	n, err := t.Execute(w, m)
+	return n, r.Mask(err)
+}
+
+// Mask returns err with the secret values cached by r replaced by "****".
+// The returned error matches the targets err matches with errors.Is, but
+// doesn't unwrap to err, so the unmasked message can't be recovered.
+//
+// Values stay masked after they expire, until a fetch returns a rotated
+// value, so memory doesn't grow with every rotation. Values shorter than 4
+// bytes aren't masked, as they would garble the messages.
+func (r *Resolver) Mask(err error) error {
+	if err == nil {
+		return nil
+	}
+	if me, ok := err.(*maskedError); ok {
+		// mask values fetched since err was masked as well
+		err = me.err
+	}
+
+	r.mu.Lock()
+	values := make([]string, 0, len(r.cache))
+	for _, e := range r.cache {
+		if len(e.value) >= minMaskLength {
+			values = append(values, e.value)
+		}
+	}
+	r.mu.Unlock()
+	// longer values first, so values containing others are fully masked
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	msg := err.Error()
+	for _, v := range values {
+		msg = strings.ReplaceAll(msg, v, mask)
+	}
+	return &maskedError{msg: msg, err: err}
+}
+
+// cached returns the named secret if it is cached and not expired.
+func (r *Resolver) cached(name string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.cache[name]
+	if !ok || (!e.expires.IsZero() && !r.now().Before(e.expires)) {
+		return "", false
+	}
+	return e.value, true
+}
+
+// store caches the named secret.
+func (r *Resolver) store(name, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := entry{value: value}
+	if r.ttl > 0 {
+		e.expires = r.now().Add(r.ttl)
+	}
+	r.cache[name] = e
+}
+
+// maskedError is an error with secret values masked.
+type maskedError struct {
+	msg string
+	err error
+}
+
+func (e *maskedError) Error() string {
+	return e.msg
+}
+
+func (e *maskedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// countingBackend counts the fetches of a Memory backend.
+type countingBackend struct {
+	Memory
+	fetches atomic.Int32
+}
+
+func (b *countingBackend) Secret(ctx context.Context, name string) (string, error) {
+	b.fetches.Add(1)
+	return b.Memory.Secret(ctx, name)
+}
+
+func TestResolverExecute(t *testing.T) {
+	b := &countingBackend{Memory: Memory{"db_user": "app", "db_password": "hunter2"}}
+	r := NewResolver(b, time.Minute)
+	tpl := fasttemplate.New("postgres://{{secret:db_user}}:{{secret:db_password}}@{{host}}/{{secret:db_user}}", "{{", "}}", r.Option(context.Background()))
+
+	var bb bytes.Buffer
+	if _, err := r.Execute(context.Background(), tpl, &bb, fasttemplate.Map{"host": "db"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := bb.String(); s != "postgres://app:hunter2@db/app" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if n := b.fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+
+	// cached secrets aren't fetched again, even without prefetching
+	if s := tpl.ExecuteString(fasttemplate.Map{"host": "db"}); s != "postgres://app:hunter2@db/app" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if n := b.fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	b := &countingBackend{Memory: Memory{"key": "v1"}}
+	r := NewResolver(b, time.Minute)
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for _, v := range []string{"v1", "v1"} {
+		if s, err := r.Resolve(ctx, "key"); err != nil || s != v {
+			t.Fatalf("unexpected secret %q, %v", s, err)
+		}
+	}
+	if n := b.fetches.Load(); n != 1 {
+		t.Fatalf("expected 1 fetch, got %d", n)
+	}
+
+	b.Memory["key"] = "v2"
+	now = now.Add(time.Minute)
+	if s, err := r.Resolve(ctx, "key"); err != nil || s != "v2" {
+		t.Fatalf("unexpected secret %q, %v", s, err)
+	}
+	if n := b.fetches.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+}
+
+func TestResolverPrefetchErrors(t *testing.T) {
+	r := NewResolver(Memory{"a": "1"}, 0)
+	err := r.Prefetch(context.Background(), "a", "b", "c")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `"b"`) || !strings.Contains(msg, `"c"`) {
+		t.Fatalf("expected both missing secrets to be reported, got %q", msg)
+	}
+
+	tpl := fasttemplate.New("{{secret:b}}", "{{", "}}", r.Option(context.Background()))
+	if _, err := r.Execute(context.Background(), tpl, &bytes.Buffer{}, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestResolverMask(t *testing.T) {
+	r := NewResolver(Memory{"token": "s3cr3t-token", "short": "s3cr3t"}, 0)
+	fail := func(s string) (string, error) {
+		return "", errors.New("cannot use " + s)
+	}
+	tpl := fasttemplate.New("{{fail(secret)}}", "{{", "}}", r.Option(context.Background()))
+	if err := r.Prefetch(context.Background(), "token", "short"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err := r.Execute(context.Background(), tpl, &bytes.Buffer{}, fasttemplate.Map{
+		"secret": "s3cr3t-token",
+		"fail":   fail,
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if msg := err.Error(); strings.Contains(msg, "s3cr3t") || !strings.Contains(msg, "cannot use ****") {
+		t.Fatalf("secret not masked in %q", msg)
+	}
+	if errors.Unwrap(err) != nil {
+		t.Fatalf("masked error must not unwrap to the original error")
+	}
+
+	// errors returned by the template itself are masked as well
+	_, err = tpl.Execute(&bytes.Buffer{}, fasttemplate.Map{"secret": "s3cr3t-token", "fail": fail})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if msg := err.Error(); strings.Contains(msg, "s3cr3t") || !strings.Contains(msg, "cannot use ****") {
+		t.Fatalf("secret not masked in %q", msg)
+	}
+
+	if r.Mask(nil) != nil {
+		t.Fatalf("expected nil")
+	}
+}
+
+func TestResolverMaskRotation(t *testing.T) {
+	now := time.Now()
+	b := Memory{"key": "first-value", "pin": "1"}
+	r := NewResolver(b, time.Minute)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+	if err := r.Prefetch(ctx, "key", "pin"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// expired values stay masked, short ones are never masked
+	now = now.Add(2 * time.Minute)
+	if msg := r.Mask(errors.New("first-value 1")).Error(); msg != "**** 1" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	// rotated values are dropped
+	b["key"] = "second-value"
+	if _, err := r.Resolve(ctx, "key"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if msg := r.Mask(errors.New("first-value second-value")).Error(); msg != "first-value ****" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestResolverOptionContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := NewResolver(Memory{"key": "value"}, 0)
+	tpl := fasttemplate.New("{{secret:key}}", "{{", "}}", r.Option(ctx))
+	if _, err := tpl.Execute(&bytes.Buffer{}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the fetch to use the option context, got %v", err)
+	}
+}
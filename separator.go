@@ -0,0 +1,106 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// defaultSeparator separates the elements of written slices unless
+// WithSliceSeparator is set.
+const defaultSeparator = ", "
+
+func init() {
+	registerScoped("join", Func{Fn: joinFunc(defaultSeparator), Idempotent: true}, func(ec *evalContext, _ Map) Func {
+		return Func{Fn: joinFunc(ec.separator), Idempotent: true}
+	})
+}
+
+// WithSliceSeparator sets the separator written between the elements of
+// []string and []any values, which defaults to ", ". It is also the default
+// separator of the join builtin.
+func WithSliceSeparator(sep string) Option {
+	return func(t *Template) {
+		t.separator = &sep
+	}
+}
+
+// sliceSeparator returns the separator of written slices.
+func (t *Template) sliceSeparator() string {
+	if t.separator == nil {
+		return defaultSeparator
+	}
+	return *t.separator
+}
+
+// writeValue writes the value of the i-th tag like writeValue, joining the
+// elements of []string and []any variables with the slice separator instead
+// of the default one. Slices returned by functions and expressions are
+// written as before.
+func (t *Template) writeValue(w io.Writer, i int, v any, kind tagKind) (int, error) {
+	if kind == tagVariable {
+		switch s := v.(type) {
+		case []string:
+			return writeFull(w, unsafeString2Bytes(strings.Join(s, t.sliceSeparator())))
+		case []any:
+			return writeFull(w, unsafeString2Bytes(joinValues(s, t.sliceSeparator(), t.timeLayout)))
+		}
+	}
+	return writeValue(w, t.tags[i], v, kind)
+}
+
+// joinValues joins the elements of values formatted like written values.
+func joinValues(values []any, sep, timeLayout string) string {
+	if timeLayout == "" {
+		timeLayout = defaultTimeLayout
+	}
+	var sb strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		switch e := v.(type) {
+		case nil:
+		case string:
+			sb.WriteString(e)
+		case []byte:
+			sb.Write(e)
+		case time.Time:
+			sb.WriteString(e.Format(timeLayout))
+		default:
+			fmt.Fprintf(&sb, "%v", e)
+		}
+	}
+	return sb.String()
+}
+
+// joinFunc returns the join builtin, joining the elements of a slice with
+// the given separator, or with def if the separator is omitted.
+func joinFunc(def string) func(items any, sep ...string) (string, error) {
+	return func(items any, sep ...string) (string, error) {
+		if len(sep) > 1 {
+			if _, ok := items.(string); ok {
+				// join(", ", items...) passes the separator first
+				return "", fmt.Errorf("join: expected a slice, got a string; the separator comes last, e.g. join(items, %q)", items)
+			}
+			return "", fmt.Errorf("join: expected at most one separator, got %d", len(sep))
+		}
+		separator := def
+		if len(sep) == 1 {
+			separator = sep[0]
+		}
+		if s, ok := items.([]string); ok {
+			return strings.Join(s, separator), nil
+		}
+		rv, err := sliceValue("join", items)
+		if err != nil {
+			return "", err
+		}
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return joinValues(values, separator, ""), nil
+	}
+}
@@ -0,0 +1,83 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSliceValues(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := Map{
+		"tags":  []string{"a", "b", "c"},
+		"mixed": []any{1, "x", nil, []byte("y"), at},
+		"count": func(tags []string) int { return len(tags) },
+		"split": func(s string) []string { return strings.Split(s, ",") },
+	}
+	tpl := New("{{tags}}; {{mixed}}; {{count(tags)}}; {{split('p,q')}}", "{{", "}}")
+	if s := tpl.ExecuteString(m); s != "a, b, c; 1, x, , y, 2024-05-01T00:00:00Z; 3; [p q]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	tpl = New("{{tags}} {{mixed}}", "{{", "}}", WithSliceSeparator("|"), WithTimeLayout("2006"))
+	if s := tpl.ExecuteString(m); s != "a|b|c 1|x||y|2024" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// package-level functions join with the default separator
+	if s := ExecuteString("{{tags}}; {{mixed}}", "{{", "}}", m); s != "a, b, c; 1, x, , y, 2024-05-01T00:00:00Z" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	// slices are joined before escaping
+	tpl = New("<b>{{tags}}</b>", "{{", "}}", WithEscaping(EscapeHTML))
+	if s := tpl.ExecuteString(Map{"tags": []string{"<a>", "b"}}); s != "<b>&lt;a&gt;, b</b>" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestJoinBuiltin(t *testing.T) {
+	m := Map{"tags": []string{"a", "b"}, "nums": []int{1, 2, 3}}
+	tpl := New("{{join(tags)}} {{join(nums, '-')}} {{join(tags, '')}}", "{{", "}}", WithSliceSeparator(" / "))
+	if s := tpl.ExecuteString(m); s != "a / b 1-2-3 ab" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := New("{{join(nums)}}", "{{", "}}").ExecuteString(m); s != "1, 2, 3" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	var sb strings.Builder
+	if _, err := New("{{join('a')}}", "{{", "}}").Execute(&sb, m); err == nil {
+		t.Fatal("expected an error joining a string")
+	}
+	if _, err := New("{{join(', ', tags...)}}", "{{", "}}").Execute(&sb, m); err == nil || !strings.Contains(err.Error(), "the separator comes last") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// functions of the map take precedence over the builtin
+	m["join"] = func(sep string, items ...string) string { return strings.Join(items, sep) }
+	if s := New("{{join('+', 'x', 'y')}}", "{{", "}}").ExecuteString(m); s != "x+y" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestJoinBuiltinReplaced(t *testing.T) {
+	builtins.mu.RLock()
+	stock := builtins.funcs["join"]
+	builtins.mu.RUnlock()
+	defer func() {
+		builtins.mu.Lock()
+		builtins.funcs["join"] = stock
+		delete(builtins.replaced, "join")
+		builtins.mu.Unlock()
+	}()
+
+	// a registered join replaces the builtin bound to the template
+	RegisterBuiltin("join", func(sep string, items ...string) string { return strings.Join(items, sep) })
+	m := Map{"tags": []string{"a", "b"}}
+	if s := New("{{join('+', tags...)}}", "{{", "}}", WithSliceSeparator(" / ")).ExecuteString(m); s != "a+b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if s := ExecuteString("{{join('+', tags...)}}", "{{", "}}", m); s != "a+b" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -0,0 +1,187 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TemplateSet holds named templates sharing delimiters and options, e.g. a
+// [Registry] set with [WithRegistry]. Templates of a set reference each
+// other by name: they render the other templates with {{include("name")}}
+// and extend them with {{extends "name"}}.
+//
+//	set := fasttemplate.NewTemplateSet("{{", "}}", fasttemplate.WithRegistry(funcs))
+//	set.Add("footer", "-- {{team}}")
+//	set.Add("welcome", `Hi {{name}}! {{include("footer")}}`)
+//	_, err := set.Execute("welcome", w, m)
+//
+// The templates of a set can be executed by concurrently running goroutines,
+// but Add and the Parse methods may be called only if no other goroutines
+// use the set.
+type TemplateSet struct {
+	startTag  string
+	endTag    string
+	opts      []Option
+	templates map[string]*Template
+}
+
+// NewTemplateSet returns an empty set of templates using the given startTag
+// and endTag as tag start and tag end, and the given options.
+func NewTemplateSet(startTag, endTag string, opts ...Option) *TemplateSet {
+	return &TemplateSet{
+		startTag:  startTag,
+		endTag:    endTag,
+		opts:      opts,
+		templates: make(map[string]*Template),
+	}
+}
+
+// Add parses the template and adds it to the set under name, replacing the
+// template with the same name, if any. The template is named like with
+// [WithName].
+func (s *TemplateSet) Add(name, template string) error {
+	opts := append([]Option{WithName(name)}, s.opts...)
+	opts = append(opts, func(t *Template) {
+		// the templates of the set are the partials of every template,
+		// including the ones added later
+		t.partials = s.templates
+	})
+	t, err := NewTemplate(template, s.startTag, s.endTag, opts...)
+	if err != nil {
+		return err
+	}
+	s.templates[name] = t
+	return nil
+}
+
+// Lookup returns the template added under name.
+func (s *TemplateSet) Lookup(name string) (*Template, bool) {
+	t, ok := s.templates[name]
+	return t, ok
+}
+
+// Names returns the names of the templates of the set in sorted order.
+func (s *TemplateSet) Names() []string {
+	names := make([]string, 0, len(s.templates))
+	for name := range s.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// template returns the template added under name, or an error if there is
+// none.
+func (s *TemplateSet) template(name string) (*Template, error) {
+	t, ok := s.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+// Execute executes the template added under name like [Template.Execute].
+func (s *TemplateSet) Execute(name string, w io.Writer, m Map) (int64, error) {
+	t, err := s.template(name)
+	if err != nil {
+		return 0, err
+	}
+	return t.Execute(w, m)
+}
+
+// ExecuteStd executes the template added under name like
+// [Template.ExecuteStd].
+func (s *TemplateSet) ExecuteStd(name string, w io.Writer, m Map) (int64, error) {
+	t, err := s.template(name)
+	if err != nil {
+		return 0, err
+	}
+	return t.ExecuteStd(w, m)
+}
+
+// ExecuteString executes the template added under name like
+// [Template.ExecuteString], returning an error if there is no such template.
+func (s *TemplateSet) ExecuteString(name string, m Map) (string, error) {
+	t, err := s.template(name)
+	if err != nil {
+		return "", err
+	}
+	return t.ExecuteString(m), nil
+}
+
+// ParseFS parses the files of fsys matching the patterns, as accepted by
+// fs.Glob, and adds them to the set, e.g. templates embedded with go:embed:
+//
+//	//go:embed templates/*.tmpl
+//	var templates embed.FS
+//
+//	err := set.ParseFS(templates, "templates/*.tmpl")
+//
+// The templates are named after the files: the base name without the
+// extension, so templates/header.tmpl is added as "header". Files with the
+// same name in different directories replace each other. A pattern matching
+// no files is an error.
+func (s *TemplateSet) ParseFS(fsys fs.FS, patterns ...string) error {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("%w: pattern %q matches no files", errNoTemplateFiles, pattern)
+		}
+		files = append(files, matches...)
+	}
+	return s.parseFiles(files, func(file string) ([]byte, error) {
+		return fs.ReadFile(fsys, file)
+	})
+}
+
+// ParseFiles parses the named files and adds them to the set, named like
+// with ParseFS.
+func (s *TemplateSet) ParseFiles(filenames ...string) error {
+	if len(filenames) == 0 {
+		return fmt.Errorf("%w: no files named", errNoTemplateFiles)
+	}
+	return s.parseFiles(filenames, os.ReadFile)
+}
+
+// ParseGlob parses the files matching the pattern, as accepted by
+// filepath.Glob, and adds them to the set, named like with ParseFS.
+func (s *TemplateSet) ParseGlob(pattern string) error {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%w: pattern %q matches no files", errNoTemplateFiles, pattern)
+	}
+	return s.parseFiles(files, os.ReadFile)
+}
+
+// parseFiles adds the files read with readFile to the set.
+func (s *TemplateSet) parseFiles(files []string, readFile func(file string) ([]byte, error)) error {
+	for _, file := range files {
+		b, err := readFile(file)
+		if err != nil {
+			return err
+		}
+		if err := s.Add(templateName(file), string(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateName returns the name of the template parsed from file: its base
+// name without the extension.
+func templateName(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,117 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplateSet(t *testing.T) {
+	r := NewRegistry()
+	r.Register("shout", strings.ToUpper)
+	set := NewTemplateSet("[[", "]]", WithRegistry(r))
+
+	// templates may reference templates added later
+	if err := set.Add("welcome", `[[include("greeting")]] [[include("footer")]]`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, src := range map[string]string{
+		"greeting": "Hi [[shout(name)]]!",
+		"footer":   "-- [[team]]",
+		"layout":   `<[[block "body"]][[end]]>`,
+		"page":     `[[extends "layout"]][[block "body"]][[include("greeting")]][[end]]`,
+	} {
+		if err := set.Add(name, src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	m := Map{"name": "ann", "team": "ops"}
+	var bb bytes.Buffer
+	if _, err := set.Execute("welcome", &bb, m); err != nil || bb.String() != "Hi ANN! -- ops" {
+		t.Fatalf("unexpected result %q, %v", bb.String(), err)
+	}
+	if s, err := set.ExecuteString("page", m); err != nil || s != "<Hi ANN!>" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+	bb.Reset()
+	if _, err := set.ExecuteStd("footer", &bb, Map{}); err != nil || bb.String() != "-- [[team]]" {
+		t.Fatalf("unexpected result %q, %v", bb.String(), err)
+	}
+
+	if names := set.Names(); strings.Join(names, ",") != "footer,greeting,layout,page,welcome" {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if tpl, ok := set.Lookup("footer"); !ok || tpl.Name() != "footer" {
+		t.Fatalf("unexpected lookup result %v, %v", tpl, ok)
+	}
+
+	if _, err := set.ExecuteString("missing", m); !errors.Is(err, errTemplateNotFound) {
+		t.Fatalf("expected missing template error, got %v", err)
+	}
+	if err := set.Add("broken", "[[name"); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if _, ok := set.Lookup("broken"); ok {
+		t.Fatal("unexpected template added despite the parse error")
+	}
+}
+
+func TestTemplateSetParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mail/welcome.tmpl": {Data: []byte(`Hi {{name}}! {{include("footer")}}`)},
+		"mail/footer.tmpl":  {Data: []byte("-- {{team}}")},
+		"mail/notes.txt":    {Data: []byte("ignored")},
+	}
+	set := NewTemplateSet("{{", "}}")
+	if err := set.ParseFS(fsys, "mail/*.tmpl"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := set.Names(); strings.Join(names, ",") != "footer,welcome" {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if s, err := set.ExecuteString("welcome", Map{"name": "Ann", "team": "ops"}); err != nil || s != "Hi Ann! -- ops" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+	if err := set.ParseFS(fsys, "mail/*.md"); !errors.Is(err, errNoTemplateFiles) {
+		t.Fatalf("expected no files error, got %v", err)
+	}
+
+	dir := t.TempDir()
+	for name, src := range map[string]string{"a.html": "<{{x}}>", "b.html": `{{include("a")}}!`, "broken.txt": "{{x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set = NewTemplateSet("{{", "}}")
+	if err := set.ParseGlob(filepath.Join(dir, "*.html")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s, err := set.ExecuteString("b", Map{"x": 1}); err != nil || s != "<1>!" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+	if err := set.ParseGlob(filepath.Join(dir, "*.md")); !errors.Is(err, errNoTemplateFiles) {
+		t.Fatalf("expected no files error, got %v", err)
+	}
+
+	set = NewTemplateSet("{{", "}}")
+	if err := set.ParseFiles(filepath.Join(dir, "a.html")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := set.Lookup("a"); !ok {
+		t.Fatal("expected template a")
+	}
+	if err := set.ParseFiles(filepath.Join(dir, "broken.txt")); err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Fatalf("expected parse error naming the template, got %v", err)
+	}
+	if err := set.ParseFiles(filepath.Join(dir, "missing.html")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing file error, got %v", err)
+	}
+	if err := set.ParseFiles(); !errors.Is(err, errNoTemplateFiles) {
+		t.Fatalf("expected no files error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package fasttemplate
+
+import "fmt"
+
+// TaintViolation is a value substituted without passing through an escaper
+// appropriate for its context, reported by the taint checking enabled with
+// [WithTaintCheck].
+type TaintViolation struct {
+	// Template is the name of the template, if any.
+	Template string
+
+	// Tag is the content of the tag.
+	Tag string
+
+	// Offset is the byte offset of the start tag in the template source.
+	Offset int
+
+	Message string
+}
+
+func (v TaintViolation) String() string {
+	s := fmt.Sprintf("offset %d: tag %q: %s", v.Offset, v.Tag, v.Message)
+	if v.Template != "" {
+		s = fmt.Sprintf("template %q: %s", v.Template, s)
+	}
+	return s
+}
+
+// WithTaintCheck enables taint checking, a debug mode verifying that every
+// substituted value passes through an escaper appropriate for the escaping
+// mode set with [WithEscaping] and the context of the tag. report is called
+// during the execution for every violation:
+//
+//   - values written while escaping is disabled, except numbers and booleans
+//   - trusted [HTML] and [XML] values written verbatim
+//
+// The output isn't changed. Partials escaping their own output like the
+// including template aren't reported. report may be called by concurrent
+// executions.
+func WithTaintCheck(report func(TaintViolation)) Option {
+	return func(t *Template) {
+		t.taintReport = report
+	}
+}
+
+// escapedHTML and escapedXML are the outputs of partials escaping HTML and
+// XML. They are written verbatim like HTML and XML values, but aren't
+// reported by taint checking.
+type (
+	escapedHTML string
+	escapedXML  string
+)
+
+// checkTaint reports the value v of the i-th tag if it isn't escaped
+// appropriately.
+func (t *Template) checkTaint(i int, v any) {
+	var msg string
+	switch v.(type) {
+	case nil, bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64:
+		// safe in every context
+		return
+	case HTML:
+		if ctx, ok := t.verbatimContext(i); ok && !ctx.xml {
+			msg = "trusted HTML value written without escaping"
+		}
+	case XML:
+		if ctx, ok := t.verbatimContext(i); ok && ctx.xml {
+			msg = "trusted XML value written without escaping"
+		}
+	}
+	if t.escapeMode == EscapeNone {
+		msg = "value written without escaping, as escaping is disabled"
+	}
+	if msg == "" {
+		return
+	}
+	t.taintReport(TaintViolation{Template: t.name, Tag: t.tags[i], Offset: t.tagOffsets()[i], Message: msg})
+}
+
+// verbatimContext returns the escaping context of the i-th tag if trusted
+// values are written verbatim in it.
+func (t *Template) verbatimContext(i int) (escapeContext, bool) {
+	if t.contexts == nil {
+		return escapeContext{}, false
+	}
+	ctx := t.contexts[i]
+	return ctx, ctx.kind == ctxText && ctx.attr == attrNone
+}
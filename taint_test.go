@@ -0,0 +1,37 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaintCheck(t *testing.T) {
+	var violations []TaintViolation
+	report := func(v TaintViolation) {
+		violations = append(violations, v)
+	}
+
+	src := `<p>{{name}}</p>{{bio}}<a title="{{bio}}">{{count}}</a>{{include("card")}}`
+	card := New("<div>{{name}}</div>", "{{", "}}", WithEscaping(EscapeHTML))
+	tpl := New(src, "{{", "}}", WithEscaping(EscapeHTML), WithTaintCheck(report), WithPartial("card", card), WithName("profile"))
+	m := Map{"name": "<b>", "bio": HTML("<i>hi</i>"), "count": 3}
+	want := New(src, "{{", "}}", WithEscaping(EscapeHTML), WithPartial("card", card)).ExecuteString(m)
+	if s := tpl.ExecuteString(m); s != want {
+		t.Fatalf("taint checking changed the output %q, want %q", s, want)
+	}
+	// only the trusted value written verbatim is reported, not the escaped
+	// one in the attribute nor the output of the partial
+	if len(violations) != 1 || violations[0].Tag != "bio" || violations[0].Offset != strings.Index(src, "{{bio}}") {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+	if s := violations[0].String(); s != `template "profile": offset 15: tag "bio": trusted HTML value written without escaping` {
+		t.Fatalf("unexpected string %q", s)
+	}
+
+	// without escaping, every value but numbers and booleans is reported
+	violations = nil
+	New("{{a}} {{n}} {{ok}}", "{{", "}}", WithTaintCheck(report)).ExecuteString(Map{"a": "x", "n": 1, "ok": true})
+	if len(violations) != 1 || violations[0].Tag != "a" || !strings.Contains(violations[0].Message, "escaping is disabled") {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"os"
 	"reflect"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/valyala/bytebufferpool"
 )
@@ -27,10 +32,16 @@ import (
 //
 // Returns the number of bytes written to w.
 //
+// A nil m is treated as an empty Map: variables are missing, and only
+// builtins may be called, so calls of other functions fail with an error.
+//
 // This function is optimized for constantly changing templates.
 // Use Template.Execute for frozen templates. For validating templates, use
 // the [Validate] function.
 func Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, error) {
+	if err := checkDelimiters(startTag, endTag); err != nil {
+		return 0, err
+	}
 	s := unsafeString2Bytes(template)
 	a := unsafeString2Bytes(startTag)
 	b := unsafeString2Bytes(endTag)
@@ -43,7 +54,7 @@ func Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, erro
 		if n < 0 {
 			break
 		}
-		ni, err = w.Write(s[:n])
+		ni, err = writeFull(w, s[:n])
 		nn += int64(ni)
 		if err != nil {
 			return nn, err
@@ -53,12 +64,20 @@ func Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, erro
 		n = bytes.Index(s, b)
 		if n < 0 {
 			// cannot find end tag - just write it to the output.
-			ni, _ = w.Write(a)
+			ni, err = writeFull(w, a)
 			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
 			break
 		}
 
 		tag := unsafeBytes2String(s[:n])
+		if len(m) == 0 && isPlainTag(tag) {
+			// plain variables cannot resolve without data
+			s = s[n+len(b):]
+			continue
+		}
 		ni, err = processTag(w, tag, m)
 		nn += int64(ni)
 		if err != nil {
@@ -71,7 +90,7 @@ func Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, erro
 		}
 		s = s[n+len(b):]
 	}
-	ni, err = w.Write(s)
+	ni, err = writeFull(w, s)
 	nn += int64(ni)
 
 	return nn, err
@@ -91,6 +110,9 @@ func Execute(template, startTag, endTag string, w io.Writer, m Map) (int64, erro
 // Use Template.ExecuteStd for frozen templates. For validating templates, use
 // the [Validate] function.
 func ExecuteStd(template, startTag, endTag string, w io.Writer, m Map) (int64, error) {
+	if err := checkDelimiters(startTag, endTag); err != nil {
+		return 0, err
+	}
 	s := unsafeString2Bytes(template)
 	a := unsafeString2Bytes(startTag)
 	b := unsafeString2Bytes(endTag)
@@ -103,7 +125,7 @@ func ExecuteStd(template, startTag, endTag string, w io.Writer, m Map) (int64, e
 		if n < 0 {
 			break
 		}
-		ni, err = w.Write(s[:n])
+		ni, err = writeFull(w, s[:n])
 		nn += int64(ni)
 		if err != nil {
 			return nn, err
@@ -113,20 +135,27 @@ func ExecuteStd(template, startTag, endTag string, w io.Writer, m Map) (int64, e
 		n = bytes.Index(s, b)
 		if n < 0 {
 			// cannot find end tag - just write it to the output.
-			ni, _ = w.Write(a)
+			ni, err = writeFull(w, a)
 			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
 			break
 		}
 
 		tag := unsafeBytes2String(s[:n])
-		ni, err = processTagStd(w, tag, startTag, endTag, m)
+		if len(m) == 0 && isPlainTag(tag) {
+			ni, err = preserveTag(w, tag, startTag, endTag)
+		} else {
+			ni, err = processTagStd(w, tag, startTag, endTag, m)
+		}
 		nn += int64(ni)
 		if err != nil {
 			return nn, err
 		}
 		s = s[n+len(b):]
 	}
-	ni, err = w.Write(s)
+	ni, err = writeFull(w, s)
 	nn += int64(ni)
 
 	return nn, err
@@ -194,6 +223,162 @@ type Template struct {
 	texts          [][]byte
 	tags           []string
 	byteBufferPool bytebufferpool.Pool
+	// bufferPool replaces byteBufferPool if set with WithBufferPool.
+	bufferPool    BufferPool
+	maxBufferSize int
+
+	// nodes is the block tree; nil for templates without block tags.
+	nodes []node
+	// blockTags marks the tags consumed by block syntax.
+	blockTags []bool
+	// scopedTags marks the tags inside with blocks; nil if there are none.
+	scopedTags []bool
+	// contexts holds the escaping context of every tag; nil when escaping
+	// is disabled.
+	contexts []escapeContext
+	// interning enables interning of the source and tags; internedTags is
+	// the number of tags holding a reference in the intern table and
+	// internedSource reports whether the source holds one.
+	interning      bool
+	internedTags   int
+	internedSource bool
+
+	// tokenBudget limits the output to the given number of tokens counted
+	// by tokenizer, set with WithTokenBudget. truncated marks the tags that
+	// may be truncated to fit the budget.
+	tokenBudget int
+	tokenizer   Tokenizer
+	truncated   []bool
+
+	// singleTag enables the fast path for templates consisting of a single
+	// plain variable tag and the surrounding text.
+	singleTag bool
+
+	// plainTags enables the fast path for empty maps: all tags are plain
+	// variables, which cannot resolve without data.
+	plainTags bool
+
+	escapeMode EscapeMode
+
+	deterministic bool
+
+	caseInsensitive bool
+
+	// strictIdentifiers propagates unknown identifiers as errors from
+	// Execute, set with WithStrictIdentifiers.
+	strictIdentifiers bool
+
+	// literalFallback passes unquoted arguments that don't look like
+	// variable names as literal strings, set with WithLiteralFallback.
+	literalFallback bool
+
+	// stringOperators enables string repetition and formatting with the *
+	// and % operators, set with WithStringOperators.
+	stringOperators bool
+
+	// literalTags matches tags resolved as plain variables, set with
+	// WithLiteralTags.
+	literalTags *regexp.Regexp
+
+	// tagNameChars holds the characters allowed in plain variable tags in
+	// addition to letters, digits and underscores, set with
+	// WithTagNameCharset.
+	tagNameChars string
+
+	// aliases maps tag names to data keys, set with WithAliases.
+	aliases map[string]string
+
+	// transformers are applied to every resolved value.
+	transformers []func(tag string, v any) any
+
+	// outputStages post-process the rendered output.
+	outputStages []func(w io.Writer) io.WriteCloser
+
+	// renderHooks are called after every execution.
+	renderHooks []func(RenderStats)
+
+	// timeLayout is the layout of time.Time values set with WithTimeLayout.
+	timeLayout string
+
+	// separator separates the elements of written slices, if set with
+	// WithSliceSeparator.
+	separator *string
+
+	// allowVariable restricts the variables tags may reference.
+	allowVariable func(name string) bool
+
+	// maxTemplateSize and maxTags limit the templates accepted by Reset.
+	maxTemplateSize int
+	maxTags         int
+
+	// maxIncludeDepth limits the depth of included templates.
+	maxIncludeDepth int
+
+	// partials holds the templates rendered by include, set with
+	// WithPartial.
+	partials map[string]*Template
+
+	// flushEvery is the number of bytes written between automatic flushes,
+	// set with WithFlushEvery.
+	flushEvery int
+
+	// taintReport reports values that aren't escaped appropriately, set
+	// with WithTaintCheck.
+	taintReport func(TaintViolation)
+
+	// pii masks PII in values, set with WithPIIRedaction.
+	pii *piiRedactor
+
+	// legacy resolves every tag as a plain variable like
+	// valyala/fasttemplate, set with WithLegacyBehavior.
+	legacy bool
+
+	// registry provides functions in addition to the builtins.
+	registry *Registry
+
+	// tagHandlers maps tag prefixes to handlers, set with WithTagPrefix.
+	tagHandlers map[string]TagHandler
+
+	// envInfo exposes the _env object, set with WithEnvironmentInfo.
+	envInfo bool
+
+	// checksumTag enables the _checksum tag, set with WithChecksumTag.
+	checksumTag bool
+
+	// color selects whether the color builtins emit ANSI escapes, set with
+	// WithColor.
+	color colorMode
+
+	// maxLengths holds the length limits of tags set with WithMaxLengths.
+	// maxLens holds the limits of the parsed tags, nil if none is limited.
+	// Overlong values are truncated if truncateOverlong is set.
+	maxLengths       map[string]int
+	maxLens          []int
+	truncateOverlong bool
+
+	// stripped holds the number of bytes of marker suffixes stripped from
+	// the tags, nil if none was stripped.
+	stripped []int
+
+	// dropped holds the number of bytes of comment tags and raw block tags
+	// dropped before every tag, nil if none was dropped.
+	dropped []int
+
+	// exprEngine evaluates expressions, set with WithExprEngine.
+	exprEngine *exprEngine
+
+	// cel parses tags as CEL expressions, set with WithCELCompatibility.
+	cel bool
+
+	// name is the name of the template, set with WithName.
+	name string
+
+	// callPolicies holds the timeouts and circuit breakers of functions.
+	callPolicies map[string]*callPolicy
+
+	// errorFormatter formats the messages of returned errors, set with
+	// WithErrorFormatter.
+	errorFormatter func(err error) string
 }
 
 // New parses the given template using the given startTag and endTag
@@ -204,21 +389,41 @@ type Template struct {
 //
 // New panics if the given template cannot be parsed. Use NewTemplate instead
 // if template may contain errors.
-func New(template, startTag, endTag string) *Template {
-	t, err := NewTemplate(template, startTag, endTag)
+func New(template, startTag, endTag string, opts ...Option) *Template {
+	t, err := NewTemplate(template, startTag, endTag, opts...)
 	if err != nil {
 		panic(err)
 	}
 	return t
 }
 
+// NewNamed works like New, but names the template. Errors returned by the
+// template are prefixed with its name, which is also reported by
+// RenderStats, traces, findings and CallInfo, so errors and metrics of
+// many templates can be told apart:
+//
+//	t := fasttemplate.NewNamed("welcome_email", src, "{{", "}}")
+func NewNamed(name, template, startTag, endTag string, opts ...Option) *Template {
+	return New(template, startTag, endTag, append([]Option{WithName(name)}, opts...)...)
+}
+
+// Name returns the name of the template set with NewNamed or WithName.
+func (t *Template) Name() string {
+	return t.name
+}
+
 // NewTemplate parses the given template using the given startTag and endTag
 // as tag start and tag end.
 //
 // The returned template can be executed by concurrently running goroutines
 // using Execute* methods.
-func NewTemplate(template, startTag, endTag string) (*Template, error) {
+//
+// The given options are applied before the template is parsed.
+func NewTemplate(template, startTag, endTag string, opts ...Option) (*Template, error) {
 	var t Template
+	for _, opt := range opts {
+		opt(&t)
+	}
 	err := t.Reset(template, startTag, endTag)
 	if err != nil {
 		return nil, err
@@ -229,30 +434,47 @@ func NewTemplate(template, startTag, endTag string) (*Template, error) {
 // Reset resets the template t to new one defined by
 // template, startTag and endTag.
 //
-// Reset allows Template object re-use.
+// Reset allows Template object re-use. It returns a [*DelimiterError] and
+// leaves t unchanged if startTag or endTag is empty, and a [*LimitError] if
+// the template exceeds the limits set with [WithMaxTemplateSize] or
+// [WithMaxTags].
 //
 // Reset may be called only if no other goroutines call t methods at the moment.
 func (t *Template) Reset(template, startTag, endTag string) error {
+	return t.reset(template, startTag, endTag, nil)
+}
+
+// reset implements Reset. If errs is non-nil, structural errors of the
+// template are appended to errs instead of being returned and the template
+// is parsed as far as possible: unclosed tags are kept as static text and
+// blocks missing their end tag are closed at the end of the template.
+func (t *Template) reset(template, startTag, endTag string, errs *[]*ParseError) error {
+	if err := checkDelimiters(startTag, endTag); err != nil {
+		return t.formatError(err)
+	}
+	if t.maxTemplateSize > 0 && len(template) > t.maxTemplateSize {
+		return t.formatError(&LimitError{Limit: "template size", Max: t.maxTemplateSize, Value: len(template)})
+	}
+	tagsCount := strings.Count(template, startTag)
+	if t.maxTags > 0 && tagsCount > t.maxTags {
+		return t.formatError(&LimitError{Limit: "tag count", Max: t.maxTags, Value: tagsCount})
+	}
+
+	t.clearParsed()
+	if t.interning {
+		template = t.internSource(template)
+	}
+
 	// Keep these vars in t, so GC won't collect them and won't break
 	// vars derived via unsafe*
 	t.template = template
 	t.startTag = startTag
 	t.endTag = endTag
-	t.texts = t.texts[:0]
-	t.tags = t.tags[:0]
-
-	if len(startTag) == 0 {
-		panic("startTag cannot be empty")
-	}
-	if len(endTag) == 0 {
-		panic("endTag cannot be empty")
-	}
 
 	s := unsafeString2Bytes(template)
 	a := unsafeString2Bytes(startTag)
 	b := unsafeString2Bytes(endTag)
 
-	tagsCount := bytes.Count(s, a)
 	if tagsCount == 0 {
 		return nil
 	}
@@ -264,6 +486,11 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 		t.tags = make([]string, 0, tagsCount)
 	}
 
+	// offsets holds the source offsets of the tags when collecting errors
+	var offsets []int
+	// inRaw is set between raw and endraw tags, whose content isn't parsed
+	inRaw := false
+
 	for {
 		n := bytes.Index(s, a)
 		if n < 0 {
@@ -272,19 +499,214 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 		}
 		t.texts = append(t.texts, s[:n])
 
+		rest := s
 		s = s[n+len(a):]
+		if errs != nil {
+			offsets = append(offsets, len(template)-len(s)-len(a))
+		}
 		n = bytes.Index(s, b)
-		if n < 0 {
-			return fmt.Errorf("cannot find end tag=%q in the template=%q starting from %q", endTag, template, s)
+		var err error
+		if n >= 0 && !t.legacy && !inRaw {
+			var end int
+			if end, err = commentEnd(unsafeBytes2String(s), endTag); end >= 0 {
+				n = end
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("cannot find end of the comment in the template=%q starting from %q: %w", template, s, err)
+		} else if n < 0 {
+			err = fmt.Errorf("cannot find end tag=%q in the template=%q starting from %q", endTag, template, s)
+		}
+		if err != nil {
+			if errs == nil {
+				return t.formatError(err)
+			}
+			*errs = append(*errs, &ParseError{Offset: offsets[len(offsets)-1], Err: err})
+			// keep the unclosed tag as static text
+			t.texts[len(t.texts)-1] = rest
+			break
 		}
 
-		t.tags = append(t.tags, unsafeBytes2String(s[:n]))
+		tag := unsafeBytes2String(s[:n])
+		t.tags = append(t.tags, tag)
 		s = s[n+len(b):]
+		if isRawTag(tag) {
+			inRaw = true
+		} else if isEndRawTag(tag) {
+			inRaw = false
+		}
 	}
 
+	if !t.legacy {
+		offsets = t.dropTags(offsets)
+		t.trimMarkers()
+	}
+	if t.tokenizer != nil {
+		t.markTruncated()
+	}
+	t.markMaxLengths()
+	if t.interning {
+		t.internTags()
+	}
+	if errs == nil {
+		if err := t.parseBlocks(); err != nil {
+			return t.formatError(err)
+		}
+	} else {
+		t.collectErrors(errs, offsets)
+	}
+	if t.tokenizer != nil && t.nodes != nil {
+		return t.formatError(errTokenBudgetBlocks)
+	}
+	if err := t.computeContexts(); err != nil {
+		return t.formatError(err)
+	}
+	t.singleTag = t.canUseSingleTag()
+	t.plainTags = t.hasOnlyPlainTags()
 	return nil
 }
 
+// stripTag replaces the i-th tag with tag, a prefix of it without a marker
+// suffix, recording the stripped bytes for tagOffsets.
+func (t *Template) stripTag(i int, tag string) {
+	if t.stripped == nil {
+		t.stripped = make([]int, len(t.tags))
+	}
+	t.stripped[i] += len(t.tags[i]) - len(tag)
+	t.tags[i] = tag
+}
+
+// clearParsed drops the parsed template and all references to its source,
+// keeping the capacity of the texts and tags for the next template.
+func (t *Template) clearParsed() {
+	t.Release()
+	t.template = ""
+	// zero the elements, as they point into the source
+	for i := range t.texts {
+		t.texts[i] = nil
+	}
+	t.texts = t.texts[:0]
+	for i := range t.tags {
+		t.tags[i] = ""
+	}
+	t.tags = t.tags[:0]
+	t.nodes = nil
+	t.blockTags = nil
+	t.scopedTags = nil
+	t.contexts = nil
+	t.truncated = nil
+	t.maxLens = nil
+	t.stripped = nil
+	t.dropped = nil
+	t.singleTag = false
+	t.plainTags = false
+}
+
+// tagOffsets returns the source offsets of the start tags of the tags.
+func (t *Template) tagOffsets() []int {
+	offsets := make([]int, len(t.tags))
+	pos := 0
+	for i, tag := range t.tags {
+		pos += len(t.texts[i])
+		if t.dropped != nil {
+			pos += t.dropped[i]
+		}
+		offsets[i] = pos
+		pos += len(t.startTag) + len(tag) + len(t.endTag)
+		if t.stripped != nil {
+			pos += t.stripped[i]
+		}
+	}
+	return offsets
+}
+
+// canUseSingleTag reports whether the template can be executed by the single
+// tag fast path: it has no blocks, a single variable tag and no options
+// altering how values are resolved or written.
+func (t *Template) canUseSingleTag() bool {
+	if len(t.tags) != 1 || t.nodes != nil || t.contexts != nil {
+		return false
+	}
+	if t.allowVariable != nil || t.transformers != nil || t.outputStages != nil || t.renderHooks != nil || t.tokenizer != nil || t.tagHandlers != nil || t.maxLens != nil || t.taintReport != nil || t.pii != nil {
+		return false
+	}
+	tag := t.tags[0]
+	if t.cel && !isPlainTag(tag) {
+		return false
+	}
+	return !isFunctionCall(tag) && !isExpression(tag)
+}
+
+// hasOnlyPlainTags reports whether the template can be executed by the empty
+// map fast path: it has no blocks, only plain variable tags and no options
+// resolving variables without data or observing the execution.
+func (t *Template) hasOnlyPlainTags() bool {
+	if len(t.tags) == 0 || t.nodes != nil || t.tokenizer != nil {
+		return false
+	}
+	if t.outputStages != nil || t.renderHooks != nil || t.tagHandlers != nil || t.envInfo || t.checksumTag || t.strictIdentifiers {
+		return false
+	}
+	for _, tag := range t.tags {
+		if !isPlainTag(tag) || isFlushTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeStatic writes the template for an empty map when all tags are plain
+// variables: the texts with the tags omitted, or preserved if std is set.
+func (t *Template) writeStatic(w io.Writer, std bool) (int64, error) {
+	var nn int64
+	for i, text := range t.texts {
+		ni, err := writeFull(w, text)
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+		if std && i < len(t.tags) {
+			ni, err = preserveTag(w, t.tags[i], t.startTag, t.endTag)
+			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
+		}
+	}
+	return nn, nil
+}
+
+// singleValue returns the value of the tag of a single tag template if it can
+// be written directly, i.e. it is a []byte or string present in m.
+func (t *Template) singleValue(m Map) ([]byte, bool) {
+	if !t.singleTag {
+		return nil, false
+	}
+	switch v := m[t.tags[0]].(type) {
+	case []byte:
+		return v, true
+	case string:
+		return unsafeString2Bytes(v), true
+	}
+	return nil, false
+}
+
+// writeSingle writes a single tag template with the tag value v.
+func (t *Template) writeSingle(w io.Writer, v []byte) (int64, error) {
+	nn, err := writeFull(w, t.texts[0])
+	if err != nil {
+		return int64(nn), err
+	}
+	ni, err := writeFull(w, v)
+	nn += ni
+	if err != nil {
+		return int64(nn), err
+	}
+	ni, err = writeFull(w, t.texts[1])
+	nn += ni
+	return int64(nn), err
+}
+
 // Execute substitutes template tags (placeholders) with the corresponding
 // values from the map m and writes the result to the given writer w.
 //
@@ -295,42 +717,175 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 //
 // Returns the number of bytes written to w.
 //
+// A nil m is treated as an empty Map. Templates with plain variable tags only
+// are rendered without allocations for empty maps.
+//
 // Note: It is advised to call [Validate] before Execute to ensure all tags can
 // be resolved or use ExecuteStd if you want to keep the unknown placeholders.
 func (t *Template) Execute(w io.Writer, m Map) (int64, error) {
+	nn, err := t.execute(w, m, false)
+	return nn, t.formatError(err)
+}
+
+// execute renders the template applying the error policy of ExecuteStd if
+// std is set or of Execute otherwise.
+func (t *Template) execute(w io.Writer, m Map, std bool) (n int64, err error) {
+	w = t.flushing(w)
+	if v, ok := t.singleValue(m); ok {
+		return t.writeSingle(w, v)
+	}
+	if len(m) == 0 && t.plainTags {
+		return t.writeStatic(w, std)
+	}
+	return t.executeContext(w, m, t.newEvalContext(), std)
+}
+
+// executeContext works like execute in the evaluation context ec, without
+// the single tag fast path.
+func (t *Template) executeContext(w io.Writer, m Map, ec *evalContext, std bool) (n int64, err error) {
+	ec.color = t.useColor(w)
+	if t.renderHooks != nil {
+		defer t.observeRender(time.Now(), &n, &err, ec)
+	}
+	if len(t.outputStages) > 0 {
+		fw, finish := t.filterOutput(w)
+		_, err = t.render(fw, m, ec, std)
+		var ferr error
+		n, ferr = finish()
+		if err == nil {
+			err = ferr
+		}
+		return n, err
+	}
+	return t.render(w, m, ec, std)
+}
+
+// render renders the template to w without output filters.
+func (t *Template) render(w io.Writer, m Map, ec *evalContext, std bool) (int64, error) {
+	if t.nodes != nil {
+		s := scope{data: m, ec: ec}
+		return t.executeNodes(w, t.nodes, &s, std)
+	}
+	if t.tokenizer != nil {
+		return t.renderBudgeted(w, m, ec, std)
+	}
+
 	var nn int64
 
 	n := len(t.texts) - 1
 	if n == -1 {
-		ni, err := w.Write(unsafeString2Bytes(t.template))
+		ni, err := writeFull(w, unsafeString2Bytes(t.template))
 		return int64(ni), err
 	}
 
 	for i := 0; i < n; i++ {
-		ni, err := w.Write(t.texts[i])
+		ni, err := writeFull(w, t.texts[i])
 		nn += int64(ni)
 		if err != nil {
 			return nn, err
 		}
 
-		ni, err = processTag(w, t.tags[i], m)
+		ni, err = t.writeTag(w, i, m, ec, std)
 		nn += int64(ni)
-		// Special handling for errors:
-		// - For function calls, propagate all errors
-		// - For variables, only propagate non-"variable not found" errors
-		//   (backward compatibility)
 		if err != nil {
-			if isFunctionCall(t.tags[i]) || !errors.Is(err, errVariableNotFound) {
-				return nn, err
-			}
-			// for simple variable not found, ignore for backward compatibility
+			return nn, err
 		}
 	}
-	ni, err := w.Write(t.texts[n])
+	ni, err := writeFull(w, t.texts[n])
 	nn += int64(ni)
 	return nn, err
 }
 
+// ExecuteMulti executes the template like Execute, writing the content of
+// every {{section "name"}}...{{end}} block to routes[name] and the content
+// outside of sections to routes[""].
+//
+// Sections without a route are evaluated but their output is discarded. Use
+// io.MultiWriter as a route to send a section to several destinations.
+//
+// Returns the total number of bytes written to all routes.
+func (t *Template) ExecuteMulti(m Map, routes map[string]io.Writer) (int64, error) {
+	var n int64
+	counted := make(map[string]io.Writer, len(routes))
+	for name, w := range routes {
+		if w != nil {
+			counted[name] = &countingWriter{w: w, n: &n}
+		}
+	}
+	w, ok := counted[""]
+	if !ok {
+		w = io.Discard
+	}
+
+	var err error
+	if t.nodes != nil {
+		s := scope{data: m, routes: counted, ec: t.newEvalContext()}
+		if t.renderHooks != nil {
+			defer t.observeRender(time.Now(), &n, &err, s.ec)
+		}
+		_, err = t.executeNodes(w, t.nodes, &s, false)
+	} else {
+		_, err = t.execute(w, m, false)
+	}
+	return n, t.formatError(err)
+}
+
+// countingWriter adds the number of bytes written to w to n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += int64(n)
+	return n, err
+}
+
+// Flush flushes w, so flush tags reach the routed writers.
+func (cw *countingWriter) Flush() error {
+	return flushWriter(cw.w)
+}
+
+// ExecuteHash executes the template like Execute, feeding the output to h
+// instead of a writer. It returns the length of the output and its checksum,
+// e.g. for Content-Length, Content-MD5 or ETag headers.
+//
+// h is reset before use. To compute the checksum while writing the output
+// in a single pass, execute the template to io.MultiWriter(w, h) instead.
+func (t *Template) ExecuteHash(h hash.Hash, m Map) (n int64, sum []byte, err error) {
+	h.Reset()
+	n, err = t.Execute(h, m)
+	if err != nil {
+		return n, nil, err
+	}
+	return n, h.Sum(nil), nil
+}
+
+// ExecuteIf evaluates the guard condition cond against m and executes the
+// template like Execute only when it holds.
+//
+// The condition may be a variable name, a function call or an expression, the
+// same forms accepted by [Eval]. It is evaluated like the tags of the
+// template, honoring options such as the variable policy, registry and
+// aliases. When it evaluates to false nothing is written to w and
+// [ErrSkipped] is returned, so callers can tell a suppressed render apart
+// from an empty one.
+func (t *Template) ExecuteIf(cond string, w io.Writer, m Map) (int64, error) {
+	v, _, err := resolveTag(cond, m, t.newEvalContext())
+	var ok bool
+	if err == nil {
+		ok, err = convertToType[bool](v)
+	}
+	if err != nil {
+		return 0, t.formatError(fmt.Errorf("cannot evaluate condition %q: %w", cond, err))
+	}
+	if !ok {
+		return 0, ErrSkipped
+	}
+	return t.Execute(w, m)
+}
+
 // ExecuteStd works the same way as Execute, but keeps the unknown placeholders.
 // This can be used as a drop-in replacement for strings.Replacer
 //
@@ -347,30 +902,8 @@ func (t *Template) Execute(w io.Writer, m Map) (int64, error) {
 // Note: It is advised to call [Validate] before ExecuteStd if you want to
 // ensure all tags can be resolved.
 func (t *Template) ExecuteStd(w io.Writer, m Map) (int64, error) {
-	var nn int64
-
-	n := len(t.texts) - 1
-	if n == -1 {
-		ni, err := w.Write(unsafeString2Bytes(t.template))
-		return int64(ni), err
-	}
-
-	for i := 0; i < n; i++ {
-		ni, err := w.Write(t.texts[i])
-		nn += int64(ni)
-		if err != nil {
-			return nn, err
-		}
-
-		ni, err = processTagStd(w, t.tags[i], t.startTag, t.endTag, m)
-		nn += int64(ni)
-		if err != nil {
-			return nn, err
-		}
-	}
-	ni, err := w.Write(t.texts[n])
-	nn += int64(ni)
-	return nn, err
+	nn, err := t.execute(w, m, true)
+	return nn, t.formatError(err)
 }
 
 // ExecuteString substitutes template tags (placeholders) with the corresponding
@@ -388,11 +921,14 @@ func (t *Template) ExecuteStd(w io.Writer, m Map) (int64, error) {
 // tags can be resolved or use ExecuteStringStd if you want to keep the unknown
 // placeholders.
 func (t *Template) ExecuteString(m Map) string {
-	bb := t.byteBufferPool.Get()
+	if v, ok := t.singleValue(m); ok {
+		return string(t.texts[0]) + string(v) + string(t.texts[1])
+	}
+
+	bb := t.getBuffer()
 	t.Execute(bb, m)
 	s := bb.String()
-	bb.Reset()
-	t.byteBufferPool.Put(bb)
+	t.putBuffer(bb)
 	return s
 }
 
@@ -410,11 +946,14 @@ func (t *Template) ExecuteString(m Map) string {
 // Note: It is advised to call [Validate] before ExecuteStringStd if you want to
 // ensure all tags can be resolved.
 func (t *Template) ExecuteStringStd(m Map) string {
-	bb := t.byteBufferPool.Get()
+	if v, ok := t.singleValue(m); ok {
+		return string(t.texts[0]) + string(v) + string(t.texts[1])
+	}
+
+	bb := t.getBuffer()
 	t.ExecuteStd(bb, m)
 	s := bb.String()
-	bb.Reset()
-	t.byteBufferPool.Put(bb)
+	t.putBuffer(bb)
 	return s
 }
 
@@ -424,32 +963,58 @@ func (t *Template) ExecuteStringStd(m Map) string {
 // It returns nil if all tags are resolvable, otherwise it returns an error with
 // details about the first unresolved tag found.
 func (t *Template) Validate(m Map) error {
-	if m == nil {
-		// If no map is provided, return error for any tags
-		if len(t.tags) > 0 {
-			return fmt.Errorf("unresolved tag %q: nil map provided", t.tags[0])
+	return t.formatError(t.validate(m))
+}
+
+func (t *Template) validate(m Map) error {
+	defined := t.blockVars()
+	if t.caseInsensitive {
+		for name := range defined {
+			defined[strings.ToLower(name)] = true
 		}
-		return nil
 	}
+	ec := t.newEvalContext()
+	for i, tag := range t.tags {
+		// block tags, variables assigned by blocks, tags inside with blocks,
+		// tags routed to tag handlers, tags with defaults and flush hints are
+		// resolved during execution
+		if t.isBlockTag(i) || t.isScopedTag(i) || defined[tag] || (t.caseInsensitive && defined[strings.ToLower(tag)]) || t.isHandlerTag(tag) || t.hasDefault(tag) || t.isFlushHint(tag, m) {
+			continue
+		}
+
+		if m == nil {
+			// If no map is provided, return error for any tags
+			return fmt.Errorf("unresolved tag %q: nil map provided", tag)
+		}
+
+		if t.isLiteralTag(tag) {
+			if _, ok := ec.lookup(m, tag); !ok {
+				return fmt.Errorf("unresolved tag %q", tag)
+			}
+			continue
+		}
+
+		if t.cel && !isPlainTag(tag) {
+			// variables of CEL expressions are resolved during execution
+			if _, err := compileCEL(tag); err != nil {
+				return fmt.Errorf("invalid expression %q: %w", tag, err)
+			}
+			continue
+		}
 
-	for _, tag := range t.tags {
 		if isFunctionCall(tag) {
 			funcCall, err := parseFunctionCall(tag)
 			if err != nil {
 				return fmt.Errorf("invalid function call %q: %w", tag, err)
 			}
 
-			funcExists := false
-			for k, v := range m {
-				if v != nil && reflect.TypeOf(v).Kind() == reflect.Func && k == funcCall.Name {
-					funcExists = true
-					break
-				}
-			}
-
-			if !funcExists {
+			f, ok := ec.lookupFunc(funcCall.Name, m)
+			if !ok {
 				return fmt.Errorf("unresolved function %q in tag %q", funcCall.Name, tag)
 			}
+			if t.deterministic && !f.Idempotent {
+				return fmt.Errorf("%w: %s in tag %q", errNondeterministicFunc, funcCall.Name, tag)
+			}
 
 			// We don't validate function args here as they could be vars
 			// that will be resolved during execution
@@ -464,7 +1029,7 @@ func (t *Template) Validate(m Map) error {
 		}
 
 		// check if regular tag exists in map
-		if _, ok := m[tag]; !ok {
+		if _, ok := ec.lookup(m, tag); !ok {
 			return fmt.Errorf("unresolved tag %q", tag)
 		}
 	}
@@ -472,210 +1037,282 @@ func (t *Template) Validate(m Map) error {
 	return nil
 }
 
-// Helper functions to process tags
+// writeTag processes the i-th tag applying the error policy of ExecuteStd if
+// std is set or of Execute otherwise.
+func (t *Template) writeTag(w io.Writer, i int, m Map, ec *evalContext, std bool) (int, error) {
+	if t.isFlushHint(t.tags[i], m) {
+		return 0, flushWriter(w)
+	}
+	ec.setTag(i, t.tags[i])
+	v, kind, err := resolveTag(t.tags[i], m, ec)
+	if ec != nil && ec.batch != nil && err == nil && kind == tagVariable && t.formatsPlainly() {
+		v = ec.batch.format(v)
+	}
+	if ec != nil && ec.trace != nil && ec.trace.w == w {
+		start := *ec.trace.n
+		n, err := t.writeResolved(w, i, v, kind, err, std)
+		ec.trace.add(t, i, start, err)
+		return n, err
+	}
+	return t.writeResolved(w, i, v, kind, err, std)
+}
 
-func processTag(w io.Writer, tag string, m Map) (int, error) {
-	if isFunctionCall(tag) {
-		funcCall, err := parseFunctionCall(tag)
-		if err != nil {
-			return 0, fmt.Errorf("error parsing function call %q: %w", tag, err)
+// writeResolved writes the resolved value of the i-th tag, handling the
+// resolution error like writeTag.
+func (t *Template) writeResolved(w io.Writer, i int, v any, kind tagKind, err error, std bool) (int, error) {
+	tag := t.tags[i]
+	if err == nil && t.isChecksumMarker(tag, v) {
+		// the marker is replaced by the digest, so it bypasses transformers
+		// and escaping
+		return writeFull(w, unsafeString2Bytes(checksumMarker))
+	}
+	if err == nil {
+		v, err = t.finishValue(i, v, kind)
+	}
+	if err != nil {
+		if std {
+			return preserveTag(w, tag, t.startTag, t.endTag)
 		}
-
-		if m != nil {
-			tempFuncs := Map{}
-
-			// scan for all funcs in the `m` map
-			for k, v := range m {
-				if v != nil && reflect.TypeOf(v).Kind() == reflect.Func {
-					tempFuncs[k] = v
-				}
-			}
-
-			// check if we have the func being called
-			if fn, ok := tempFuncs[funcCall.Name]; ok {
-				fnType := reflect.TypeOf(fn)
-				if !isValidArgCount(fnType, len(funcCall.Args)) {
-					return 0, fmt.Errorf("invalid argument count for function %q", funcCall.Name)
-				}
-
-				// exec the func with access to all funcs for nested calls
-				result, err := funcCall.execute(tempFuncs, m)
-				if err != nil {
-					return 0, err // Propagate the error from the function call
-				}
-				if result != nil {
-					switch v := result.(type) {
-					case []byte:
-						return w.Write(v)
-					case string:
-						return w.Write(unsafeString2Bytes(v))
-					default:
-						return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
-					}
-				}
-				return 0, nil
-			}
-			// Function not found, return a specific error
-			return 0, fmt.Errorf("%w: %s", errFunctionNotFound, funcCall.Name)
+		// Special handling for errors:
+		// - For function calls, propagate all errors
+		// - For variables, only propagate non-"variable not found" errors
+		//   (backward compatibility)
+		// - Timed out calls and open circuits render empty like missing
+		//   variables
+		if isUnavailable(err) {
+			return 0, nil
 		}
-		// Missing map, return an error
-		return 0, fmt.Errorf("no functions map provided for function call: %s", tag)
-	}
-
-	// Check if this is an expr with operators
-	if isExpression(tag) {
-		result, err := evalExpression(tag, m)
-		if err != nil {
+		if kind == tagFunction || !errors.Is(err, errVariableNotFound) || t.strictIdentifiers {
 			return 0, err
 		}
-		if result != nil {
-			switch v := result.(type) {
-			case []byte:
-				return w.Write(v)
-			case string:
-				return w.Write(unsafeString2Bytes(v))
-			default:
-				return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
-			}
-		}
+		// for simple variable not found, ignore for backward compatibility
 		return 0, nil
 	}
+	if t.taintReport != nil {
+		t.checkTaint(i, v)
+	}
+	if t.contexts != nil {
+		return t.writeEscaped(w, i, v, kind)
+	}
+	return t.writeValue(w, i, v, kind)
+}
 
-	v, ok := m[tag]
-	if !ok {
-		return 0, fmt.Errorf("%w: %s", errVariableNotFound, tag)
+// finishValue applies the value transformers, time layout, PII redaction and
+// length limits of the template to the resolved value of the i-th tag.
+func (t *Template) finishValue(i int, v any, kind tagKind) (any, error) {
+	for _, transform := range t.transformers {
+		v = transform(t.tags[i], v)
 	}
-	if v == nil {
-		return 0, nil
+	if t.timeLayout != "" {
+		if tm, ok := v.(time.Time); ok {
+			v = tm.Format(t.timeLayout)
+		}
 	}
-	switch value := v.(type) {
-	case []byte:
-		return w.Write(value)
-	case string:
-		return w.Write(unsafeString2Bytes(value))
-	case func(io.Writer, string) (int, error):
-		// Maintain compatibility with existing code that uses TagFunc
-		return value(w, tag)
-	default:
-		// Convert numeric types and other values to string
-		return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
+	if t.pii != nil {
+		var err error
+		if v, err = t.redactPII(i, v, kind); err != nil {
+			return nil, err
+		}
+	}
+	if t.maxLens != nil {
+		return t.limitLength(i, v, kind)
+	}
+	return v, nil
+}
+
+// Helper functions to process tags
+
+func processTag(w io.Writer, tag string, m Map) (int, error) {
+	v, kind, err := resolveTag(tag, m, nil)
+	if err != nil {
+		return 0, err
 	}
+	return writeValue(w, tag, v, kind)
 }
 
 func processTagStd(w io.Writer, tag, startTag, endTag string, m Map) (int, error) {
-	// First check if this is a function call
+	v, kind, err := resolveTag(tag, m, nil)
+	if err != nil {
+		// Preserve the original tag for unknown variables and functions,
+		// parsing errors and function or expression errors
+		return preserveTag(w, tag, startTag, endTag)
+	}
+	return writeValue(w, tag, v, kind)
+}
+
+// resolveTag evaluates the tag against m and returns its value together with
+// the tag kind. ec is the evaluation context of the execution, if any.
+//
+// Futures are awaited unless the execution resolves them asynchronously.
+func resolveTag(tag string, m Map, ec *evalContext) (any, tagKind, error) {
+	v, kind, err := evalTag(tag, m, ec)
+	if f, ok := v.(Future); ok && err == nil && (ec == nil || !ec.async) {
+		v, err = awaitFuture(f)
+	}
+	return v, kind, err
+}
+
+// evalTag evaluates the tag against m.
+func evalTag(tag string, m Map, ec *evalContext) (any, tagKind, error) {
+	if ec != nil && ec.tagHandlers != nil {
+		if h, arg, ok := lookupTagHandler(ec.tagHandlers, tag); ok {
+			v, err := h(arg, m)
+			return v, tagFunction, err
+		}
+	}
+	if ec != nil && ec.literalTag != nil && ec.literalTag(tag) {
+		return lookupVariable(tag, m, ec)
+	}
+	if isHyphenatedName(tag) {
+		// a matching key wins over the subtraction, e.g. for content-type
+		if v, ok := ec.lookup(m, tag); ok {
+			return v, tagVariable, nil
+		}
+	}
+	if value, def, ok := splitDefault(tag); ok {
+		return evalDefault(value, def, m, ec)
+	}
+	if ec != nil && ec.cel && !isPlainTag(tag) {
+		v, err := evalCEL(tag, m, ec)
+		return v, tagExpression, err
+	}
 	if isFunctionCall(tag) {
-		funcCall, err := parseFunctionCall(tag)
+		funcCall, err := parseFunctionCallCached(tag)
 		if err != nil {
-			// Preserve the original tag if there's a parsing error
-			if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-				return 0, err
-			}
-			return len(startTag) + len(tag) + len(endTag), nil
+			return nil, tagFunction, fmt.Errorf("error parsing function call %q: %w", tag, err)
 		}
 
-		if m != nil {
-			tempFuncs := Map{}
-
-			// scan for all funcs in the `m` map
-			for k, v := range m {
-				if v != nil && reflect.TypeOf(v).Kind() == reflect.Func {
-					tempFuncs[k] = v
-				}
-			}
-
-			// check if we have the func being called
-			if fn, ok := tempFuncs[funcCall.Name]; ok {
-				fnType := reflect.TypeOf(fn)
-				if !isValidArgCount(fnType, len(funcCall.Args)) {
-					// Preserve the original tag if the argument count is incorrect
-					if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-						return 0, err
-					}
-					return len(startTag) + len(tag) + len(endTag), nil
-				}
-
-				// exec the func with access to all funcs for nested calls
-				result, err := funcCall.execute(tempFuncs, m)
-				if err != nil {
-					// for func errors, preserve the original tag
-					if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-						return 0, err
-					}
-					return len(startTag) + len(tag) + len(endTag), nil
-				}
-
-				if result != nil {
-					switch v := result.(type) {
-					case []byte:
-						return w.Write(v)
-					case string:
-						return w.Write(unsafeString2Bytes(v))
-					default:
-						return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
-					}
-				}
-				return 0, nil
-			}
+		// check if we have the func being called
+		fn, ok := ec.lookupFunc(funcCall.Name, m)
+		if !ok {
+			// Function not found, return a specific error
+			return nil, tagFunction, fmt.Errorf("%w: %s", errFunctionNotFound, funcCall.Name)
 		}
-
-		// If we get here, this is an unknown func call - keep the tag
-		if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-			return 0, err
+		if !funcCall.hasSpread() && !isValidArgCount(reflect.TypeOf(fn.Fn), len(funcCall.Args)) {
+			return nil, tagFunction, fmt.Errorf("invalid argument count for function %q", funcCall.Name)
 		}
-		return len(startTag) + len(tag) + len(endTag), nil
+
+		// exec the func with access to all funcs for nested calls
+		result, err := funcCall.execute(m, m, ec)
+		return result, tagFunction, err
 	}
 
-	// Check if this is an expression with operators
+	// Check if this is an expr with operators
 	if isExpression(tag) {
-		result, err := evalExpression(tag, m)
-		if err != nil {
-			// for expression errors, preserve the original tag
-			if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-				return 0, err
-			}
-			return len(startTag) + len(tag) + len(endTag), nil
-		}
-		if result != nil {
-			switch v := result.(type) {
-			case []byte:
-				return w.Write(v)
-			case string:
-				return w.Write(unsafeString2Bytes(v))
-			default:
-				return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
-			}
-		}
-		return 0, nil
+		result, err := evalExpression(tag, m, ec)
+		return result, tagExpression, err
 	}
 
-	// Handle normal tags (not func calls or expressions)
-	v, ok := m[tag]
+	return lookupVariable(tag, m, ec)
+}
+
+// lookupVariable resolves the tag as a plain variable.
+func lookupVariable(tag string, m Map, ec *evalContext) (any, tagKind, error) {
+	v, ok := ec.lookup(m, tag)
 	if !ok {
-		if _, err := preserveTag(w, tag, startTag, endTag); err != nil {
-			return 0, err
-		}
-		return len(startTag) + len(tag) + len(endTag), nil
+		return nil, tagVariable, fmt.Errorf("%w: %s", errVariableNotFound, tag)
 	}
-	if v == nil {
-		return 0, nil
+	return v, tagVariable, nil
+}
+
+// writeValue writes the resolved value of the tag to w.
+func writeValue(w io.Writer, tag string, v any, kind tagKind) (int, error) {
+	if kind != tagVariable {
+		return writeResult(w, v)
 	}
 	switch value := v.(type) {
+	case nil:
+		return 0, nil
 	case []byte:
-		return w.Write(value)
+		return writeFull(w, value)
 	case string:
-		return w.Write(unsafeString2Bytes(value))
+		return writeFull(w, unsafeString2Bytes(value))
+	case []string:
+		return writeFull(w, unsafeString2Bytes(strings.Join(value, defaultSeparator)))
+	case []any:
+		return writeFull(w, unsafeString2Bytes(joinValues(value, defaultSeparator, "")))
 	case func(io.Writer, string) (int, error):
 		// Maintain compatibility with existing code that uses TagFunc
-		return value(w, tag)
+		return value(fullWriter{w}, tag)
+	case TagFunc:
+		return value(fullWriter{w}, tag)
+	case time.Time:
+		return writeFull(w, unsafeString2Bytes(value.Format(defaultTimeLayout)))
+	case *os.File:
+		// Stream files, letting io.Copy use the zero-copy paths of the
+		// destination. They belong to the caller, so unlike function results
+		// they aren't closed.
+		n, err := io.Copy(w, value)
+		return int(n), err
+	case Stream:
+		n, err := io.Copy(w, value.Reader)
+		return int(n), err
 	default:
 		// Convert numeric types and other values to string
-		return w.Write(unsafeString2Bytes(fmt.Sprintf("%v", v)))
+		return writeFull(w, unsafeString2Bytes(fmt.Sprintf("%v", v)))
+	}
+}
+
+// Stream wraps a reader to be copied into the output when substituted,
+// instead of being formatted, e.g. Map{"body": fasttemplate.Stream{r}}. Like
+// *os.File values, streams are copied with io.Copy, so zero-copy paths such
+// as sendfile are used when the destination supports them. A stream is
+// consumed by the first tag rendering it and isn't closed.
+type Stream struct {
+	io.Reader
+}
+
+// writeResult writes the result of a function call or an expression to w.
+//
+// Results implementing io.WriterTo or io.Reader are streamed into w instead
+// of being formatted, and closed afterwards if they implement io.Closer.
+// Results implementing fmt.Stringer, such as *bytes.Buffer, are formatted
+// instead, so they aren't consumed.
+func writeResult(w io.Writer, result any) (int, error) {
+	switch v := result.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return writeFull(w, v)
+	case string:
+		return writeFull(w, unsafeString2Bytes(v))
+	case Stream:
+		n, err := io.Copy(w, v.Reader)
+		return int(n), closeResult(v.Reader, err)
+	case time.Time:
+		return writeFull(w, unsafeString2Bytes(v.Format(defaultTimeLayout)))
+	case fmt.Stringer:
+		return writeFull(w, unsafeString2Bytes(v.String()))
+	case io.Reader:
+		// io.Copy uses the io.WriterTo of the reader or the io.ReaderFrom
+		// of w, keeping zero-copy paths such as sendfile available
+		n, err := io.Copy(w, v)
+		return int(n), closeResult(v, err)
+	case io.WriterTo:
+		n, err := v.WriteTo(fullWriter{w})
+		return int(n), closeResult(v, err)
+	default:
+		return writeFull(w, unsafeString2Bytes(fmt.Sprintf("%v", v)))
 	}
 }
 
+// closeResult closes a streamed result if it implements io.Closer.
+func closeResult(v any, err error) error {
+	c, ok := v.(io.Closer)
+	if !ok {
+		return err
+	}
+	if cerr := c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 // Helper function to check if the argument count is valid for a func
 func isValidArgCount(fnType reflect.Type, argCount int) bool {
+	if takesCallInfo(fnType) {
+		argCount++
+	}
 	if fnType.IsVariadic() {
 		// For variadic funcs, the number of non-variadic arguments must match
 		return argCount >= fnType.NumIn()-1
@@ -684,12 +1321,36 @@ func isValidArgCount(fnType reflect.Type, argCount int) bool {
 	return fnType.NumIn() == argCount
 }
 
+// writeFull writes p to w, reporting a short write without an error as
+// io.ErrShortWrite, so partial output never goes unnoticed.
+func writeFull(w io.Writer, p []byte) (int, error) {
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// fullWriter reports short writes of the underlying writer as errors, for
+// writers handed to user code.
+type fullWriter struct {
+	w io.Writer
+}
+
+func (fw fullWriter) Write(p []byte) (int, error) {
+	return writeFull(fw.w, p)
+}
+
 func preserveTag(w io.Writer, tag, startTag, endTag string) (int, error) {
-	if _, err := w.Write(unsafeString2Bytes(startTag)); err != nil {
-		return 0, err
+	nn, err := writeFull(w, unsafeString2Bytes(startTag))
+	if err != nil {
+		return nn, err
 	}
-	if _, err := w.Write(unsafeString2Bytes(tag)); err != nil {
-		return 0, err
+	ni, err := writeFull(w, unsafeString2Bytes(tag))
+	nn += ni
+	if err != nil {
+		return nn, err
 	}
-	return w.Write(unsafeString2Bytes(endTag))
+	ni, err = writeFull(w, unsafeString2Bytes(endTag))
+	return nn + ni, err
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 )
@@ -944,3 +945,229 @@ func TestTopLevelExecuteWithMixedVarsAndFunctions(t *testing.T) {
 		})
 	}
 }
+
+type trackingReadCloser struct {
+	io.Reader
+	closed bool
+}
+
+func (r *trackingReadCloser) Close() error {
+	r.closed = true
+	return nil
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestFunctionStreamResults(t *testing.T) {
+	rc := &trackingReadCloser{Reader: strings.NewReader("file contents")}
+	data := Map{
+		"reader": func() io.Reader { return strings.NewReader("from reader") },
+		"writerTo": func(s string) io.WriterTo {
+			return bytes.NewBufferString("<" + s + ">")
+		},
+		"file":   func() io.ReadCloser { return rc },
+		"broken": func() io.Reader { return failingReader{} },
+	}
+
+	tpl := New("[{{reader()}}] [{{writerTo(\"blob\")}}] [{{file()}}]", "{{", "}}")
+
+	var bb bytes.Buffer
+	n, err := tpl.Execute(&bb, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "[from reader] [<blob>] [file contents]"
+	if bb.String() != expected {
+		t.Fatalf("unexpected output %q. Expected %q", bb.String(), expected)
+	}
+	if n != int64(len(expected)) {
+		t.Fatalf("unexpected byte count %d. Expected %d", n, len(expected))
+	}
+	if !rc.closed {
+		t.Fatalf("streamed result must be closed")
+	}
+
+	bb.Reset()
+	_, err = New("a{{broken()}}b", "{{", "}}").Execute(&bb, data)
+	if err == nil || err.Error() != "read failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	concat := func(sep string, items ...string) string { return strings.Join(items, sep) }
+	sum := func(xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}
+	m := Map{
+		"concat": concat,
+		"sum":    sum,
+		"items":  []string{"a", "b", "c"},
+		"anys":   []any{"x", "y"},
+		"nums":   [3]int{1, 2, 3},
+		"empty":  []string{},
+		"name":   "Ann",
+		"split":  func(s string) []string { return strings.Split(s, "-") },
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{concat(", ", items...)}}`, "a, b, c"},
+		{`{{concat("+", anys...)}}`, "x+y"},
+		{`{{concat("+", empty...)}}`, ""},
+		{`{{sum(nums...)}}`, "6"},
+		{`{{concat(" ", split("p-q-r")...)}}`, "p q r"},
+		{`{{concat(sep, items ...)}}`, "a|b|c"},
+		{`{{concat("-", items...) + "!"}}`, "a-b-c!"},
+	}
+	m["sep"] = "|"
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var bb bytes.Buffer
+			if _, err := New(tt.template, "{{", "}}").Execute(&bb, m); err != nil || bb.String() != tt.expected {
+				t.Fatalf("unexpected result %q, %v", bb.String(), err)
+			}
+		})
+	}
+
+	for _, template := range []string{
+		`{{concat(", ", name...)}}`,
+		`{{concat(", ", missing...)}}`,
+		`{{concat(items..., ", ")}}`,
+		`{{concat(", ", "a"...)}}`,
+		`{{concat(...)}}`,
+	} {
+		t.Run(template, func(t *testing.T) {
+			if _, err := New(template, "{{", "}}").Execute(io.Discard, m); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestByteSliceArguments(t *testing.T) {
+	type raw []byte
+	m := Map{
+		"body":    []byte("hello"),
+		"name":    "Ann",
+		"upper":   strings.ToUpper,
+		"join":    func(sep string, items ...string) string { return strings.Join(items, sep) },
+		"size":    func(b []byte) int { return len(b) },
+		"rev":     func(b raw) string { return string(bytes.ToUpper(b)) },
+		"chunks":  [][]byte{[]byte("a"), []byte("b")},
+		"toBytes": func(s string) []byte { return []byte(s) },
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{upper(body)}}`, "HELLO"},
+		{`{{join("-", body, name)}}`, "hello-Ann"},
+		{`{{join("+", chunks...)}}`, "a+b"},
+		{`{{size(name)}} {{size("abc")}} {{size(body)}}`, "3 3 5"},
+		{`{{rev("abc")}}`, "ABC"},
+		{`{{upper(toBytes(name))}}`, "ANN"},
+		{`{{size(upper(body))}}`, "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var bb bytes.Buffer
+			if _, err := New(tt.template, "{{", "}}").Execute(&bb, m); err != nil || bb.String() != tt.expected {
+				t.Fatalf("unexpected result %q, %v", bb.String(), err)
+			}
+		})
+	}
+}
+
+// customError is an error type returned by pointer.
+type customError struct{ msg string }
+
+func (e *customError) Error() string { return e.msg }
+
+func TestFunctionResultShapes(t *testing.T) {
+	errBoom := errors.New("boom")
+	calls := 0
+	m := Map{
+		"none":       func() { calls++ },
+		"value":      func() string { return "v" },
+		"okErr":      func() error { calls++; return nil },
+		"failErr":    func() error { return errBoom },
+		"valueErr":   func() (string, error) { return "ve", nil },
+		"failTuple":  func() (string, error) { return "ignored", errBoom },
+		"customOK":   func() (int, *customError) { return 7, nil },
+		"customFail": func() *customError { return &customError{"custom"} },
+		"pair":       func() (string, int) { return "a", 1 },
+		"triple":     func() (int, int, error) { return 1, 2, nil },
+	}
+	tests := []struct {
+		tag  string
+		want string
+		err  error
+	}{
+		{tag: "none()", want: ""},
+		{tag: "value()", want: "v"},
+		{tag: "okErr()", want: ""},
+		{tag: "failErr()", err: errBoom},
+		{tag: "valueErr()", want: "ve"},
+		{tag: "failTuple()", err: errBoom},
+		{tag: "customOK()", want: "7"},
+		{tag: "customFail()", err: &customError{}},
+		{tag: "pair()", err: errUnsupportedResults},
+		{tag: "triple()", err: errUnsupportedResults},
+	}
+	for _, tt := range tests {
+		tpl := New("[{{"+tt.tag+"}}]", "{{", "}}")
+		var bb bytes.Buffer
+		_, err := tpl.Execute(&bb, m)
+		switch want := tt.err.(type) {
+		case nil:
+			if err != nil || bb.String() != "["+tt.want+"]" {
+				t.Fatalf("%s: unexpected result %q, %v", tt.tag, bb.String(), err)
+			}
+		case *customError:
+			var ce *customError
+			if !errors.As(err, &ce) {
+				t.Fatalf("%s: unexpected error %v", tt.tag, err)
+			}
+		default:
+			if !errors.Is(err, want) {
+				t.Fatalf("%s: unexpected error %v", tt.tag, err)
+			}
+		}
+
+		// ExecuteStd keeps failing tags
+		if tt.err != nil {
+			if s := tpl.ExecuteStringStd(m); s != "[{{"+tt.tag+"}}]" {
+				t.Fatalf("%s: unexpected output %q", tt.tag, s)
+			}
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("unexpected calls %d", calls)
+	}
+
+	// functions returning only an error may be called for side effects
+	calls = 0
+	if s := New("{{okErr()}}{{upper('x')}}", "{{", "}}").ExecuteString(m); s != "X" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	if calls != 1 {
+		t.Fatalf("unexpected calls %d", calls)
+	}
+
+	findings := New("{{pair()}}", "{{", "}}").Analyze(m)
+	if len(findings) != 1 || findings[0].Kind != FindingAlwaysError || !strings.Contains(findings[0].Message, "pair returns (string, int)") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+}
@@ -2,7 +2,11 @@ package fasttemplate
 
 import (
 	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
 	"io"
+	"os"
 	"strings"
 	"testing"
 )
@@ -207,11 +211,37 @@ func TestEmptyTemplate(t *testing.T) {
 }
 
 func TestEmptyTagStart(t *testing.T) {
-	expectPanic(t, func() { NewTemplate("foobar", "", "]") })
+	expectDelimiterError(t, "", "]")
 }
 
 func TestEmptyTagEnd(t *testing.T) {
-	expectPanic(t, func() { NewTemplate("foobar", "[", "") })
+	expectDelimiterError(t, "[", "")
+}
+
+func expectDelimiterError(t *testing.T, startTag, endTag string) {
+	t.Helper()
+
+	_, err := NewTemplate("foobar", startTag, endTag)
+	var de *DelimiterError
+	if !errors.As(err, &de) || de.StartTag != startTag || de.EndTag != endTag {
+		t.Fatalf("expected DelimiterError, got %v", err)
+	}
+
+	tpl := New("foo[bar]", "[", "]")
+	if err := tpl.Reset("foobar", startTag, endTag); !errors.As(err, &de) {
+		t.Fatalf("expected DelimiterError from Reset, got %v", err)
+	}
+	if s := tpl.ExecuteString(Map{"bar": "1"}); s != "foo1" {
+		t.Fatalf("expected failed Reset to keep the template, got %q", s)
+	}
+
+	if _, err := Execute("foobar", startTag, endTag, io.Discard, nil); !errors.As(err, &de) {
+		t.Fatalf("expected DelimiterError from Execute, got %v", err)
+	}
+	if _, err := ExecuteStd("foobar", startTag, endTag, io.Discard, nil); !errors.As(err, &de) {
+		t.Fatalf("expected DelimiterError from ExecuteStd, got %v", err)
+	}
+	expectPanic(t, func() { New("foobar", startTag, endTag) })
 }
 
 func TestNoTags(t *testing.T) {
@@ -585,3 +615,398 @@ func TestEvalAndExecute(t *testing.T) {
 		}
 	})
 }
+
+func TestExecuteIf(t *testing.T) {
+	tpl := New("Hello, {{name}}!", "{{", "}}")
+
+	t.Run("ConditionTrue", func(t *testing.T) {
+		var bb bytes.Buffer
+		n, err := tpl.ExecuteIf("enabled && count > 0", &bb, Map{"name": "John", "enabled": true, "count": 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bb.String() != "Hello, John!" || n != int64(bb.Len()) {
+			t.Fatalf("unexpected result %q (n=%d)", bb.String(), n)
+		}
+	})
+
+	t.Run("ConditionFalse", func(t *testing.T) {
+		var bb bytes.Buffer
+		n, err := tpl.ExecuteIf("enabled", &bb, Map{"name": "John", "enabled": false})
+		if !errors.Is(err, ErrSkipped) {
+			t.Fatalf("expecting ErrSkipped, got %v", err)
+		}
+		if n != 0 || bb.Len() != 0 {
+			t.Fatalf("unexpected output %q (n=%d)", bb.String(), n)
+		}
+	})
+
+	t.Run("FunctionCondition", func(t *testing.T) {
+		var bb bytes.Buffer
+		_, err := tpl.ExecuteIf("isVIP(name)", &bb, Map{
+			"name":  "Jane",
+			"isVIP": func(s string) bool { return s == "Jane" },
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bb.String() != "Hello, Jane!" {
+			t.Fatalf("unexpected result %q", bb.String())
+		}
+	})
+
+	t.Run("ConditionError", func(t *testing.T) {
+		var bb bytes.Buffer
+		_, err := tpl.ExecuteIf("missing", &bb, Map{"name": "John"})
+		if err == nil || errors.Is(err, ErrSkipped) {
+			t.Fatalf("expecting evaluation error, got %v", err)
+		}
+		if bb.Len() != 0 {
+			t.Fatalf("unexpected output %q", bb.String())
+		}
+	})
+
+	t.Run("TemplateOptions", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("isVIP", func(s string) bool { return s == "Jane" })
+		tpl := New("Hello, {{name}}!", "{{", "}}", WithRegistry(r), WithAllowedVariables("name"))
+
+		var bb bytes.Buffer
+		if _, err := tpl.ExecuteIf("isVIP(name)", &bb, Map{"name": "Jane"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bb.String() != "Hello, Jane!" {
+			t.Fatalf("unexpected result %q", bb.String())
+		}
+
+		bb.Reset()
+		_, err := tpl.ExecuteIf("admin", &bb, Map{"name": "Jane", "admin": true})
+		if err == nil || errors.Is(err, ErrSkipped) {
+			t.Fatalf("expecting the variable policy to reject the condition, got %v", err)
+		}
+		if bb.Len() != 0 {
+			t.Fatalf("unexpected output %q", bb.String())
+		}
+	})
+}
+
+func TestExecuteHash(t *testing.T) {
+	tpl := New("Hello, {{name}}!", "{{", "}}")
+	h := md5.New()
+
+	for i := 0; i < 2; i++ {
+		n, sum, err := tpl.ExecuteHash(h, Map{"name": "John"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := md5.Sum([]byte("Hello, John!"))
+		if n != 12 || !bytes.Equal(sum, expected[:]) {
+			t.Fatalf("unexpected result %d, %x", n, sum)
+		}
+	}
+
+	fail := func(w io.Writer, tag string) (int, error) { return 0, errors.New("failed") }
+	if _, sum, err := tpl.ExecuteHash(h, Map{"name": fail}); err == nil || sum != nil {
+		t.Fatalf("expected error without checksum, got %x, %v", sum, err)
+	}
+}
+
+func TestSingleTag(t *testing.T) {
+	tpl := New("https://sho.rt/{{id}}?ref=x", "{{", "}}")
+	if !tpl.singleTag {
+		t.Fatal("expected single tag fast path")
+	}
+
+	tests := []struct {
+		name     string
+		m        Map
+		expected string
+		std      string
+	}{
+		{"String", Map{"id": "abc"}, "https://sho.rt/abc?ref=x", "https://sho.rt/abc?ref=x"},
+		{"Bytes", Map{"id": []byte("abc")}, "https://sho.rt/abc?ref=x", "https://sho.rt/abc?ref=x"},
+		{"Number", Map{"id": 42}, "https://sho.rt/42?ref=x", "https://sho.rt/42?ref=x"},
+		{"Missing", Map{}, "https://sho.rt/?ref=x", "https://sho.rt/{{id}}?ref=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			n, err := tpl.Execute(&bb, tt.m)
+			if err != nil || bb.String() != tt.expected || n != int64(len(tt.expected)) {
+				t.Fatalf("unexpected result %q (%d bytes), %v", bb.String(), n, err)
+			}
+			if s := tpl.ExecuteString(tt.m); s != tt.expected {
+				t.Fatalf("unexpected result %q", s)
+			}
+			if s := tpl.ExecuteStringStd(tt.m); s != tt.std {
+				t.Fatalf("unexpected result %q", s)
+			}
+		})
+	}
+
+	for _, src := range []string{"{{a}}{{b}}", "x{{upper(a)}}", "x{{a + b}}", "no tags"} {
+		if New(src, "{{", "}}").singleTag {
+			t.Fatalf("unexpected fast path for %q", src)
+		}
+	}
+	if New("<a href={{url}}>", "{{", "}}", WithEscaping(EscapeHTML)).singleTag {
+		t.Fatal("unexpected fast path with escaping")
+	}
+	if New("x{{a}}", "{{", "}}", WithAllowedVariables("b")).ExecuteString(Map{"a": "1"}) != "x" {
+		t.Fatal("expected variable policy to apply")
+	}
+	if err := tpl.Reset("{{a}}{{b}}", "{{", "}}"); err != nil || tpl.singleTag {
+		t.Fatalf("expected Reset to disable the fast path, got %v", err)
+	}
+}
+
+func TestNilMap(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+		std      string
+	}{
+		{"a {{x}} b {{y}}", "a  b ", "a {{x}} b {{y}}"},
+		{"a {{upper('x')}} {{x}}", "a X ", "a X {{x}}"},
+		{"a {{1 + 2}}", "a 3", "a 3"},
+	}
+	for _, tt := range tests {
+		tpl := New(tt.template, "{{", "}}")
+		for _, m := range []Map{nil, {}} {
+			var bb bytes.Buffer
+			if _, err := tpl.Execute(&bb, m); err != nil || bb.String() != tt.expected {
+				t.Fatalf("unexpected result of %q for %v: %q, %v", tt.template, m, bb.String(), err)
+			}
+			if s := tpl.ExecuteStringStd(m); s != tt.std {
+				t.Fatalf("unexpected result of %q for %v: %q", tt.template, m, s)
+			}
+			if s := ExecuteString(tt.template, "{{", "}}", m); s != tt.expected {
+				t.Fatalf("unexpected result of %q for %v: %q", tt.template, m, s)
+			}
+			if s := ExecuteStringStd(tt.template, "{{", "}}", m); s != tt.std {
+				t.Fatalf("unexpected result of %q for %v: %q", tt.template, m, s)
+			}
+		}
+	}
+
+	// unknown functions fail the same way for nil and empty maps
+	for _, m := range []Map{nil, {}} {
+		if _, err := New("a {{f(x)}}", "{{", "}}").Execute(io.Discard, m); !errors.Is(err, errFunctionNotFound) {
+			t.Fatalf("unexpected error for %v: %v", m, err)
+		}
+		if _, err := Execute("a {{f(x)}}", "{{", "}}", io.Discard, m); !errors.Is(err, errFunctionNotFound) {
+			t.Fatalf("unexpected error for %v: %v", m, err)
+		}
+	}
+
+	tpl := New("a {{x}} b {{y}} c", "{{", "}}")
+	if !tpl.plainTags {
+		t.Fatal("expected empty map fast path")
+	}
+	empty := Map{}
+	allocs := testing.AllocsPerRun(100, func() {
+		tpl.Execute(io.Discard, nil)
+		tpl.ExecuteStd(io.Discard, empty)
+		Execute("a {{x}} b", "{{", "}}", io.Discard, nil)
+		ExecuteStd("a {{x}} b", "{{", "}}", io.Discard, nil)
+	})
+	if allocs != 0 {
+		t.Fatalf("unexpected allocations: %v", allocs)
+	}
+	for _, src := range []string{"{{upper(x)}}", "{{x + 1}}", "{{if x}}y{{end}}", "no tags"} {
+		if New(src, "{{", "}}").plainTags {
+			t.Fatalf("unexpected fast path for %q", src)
+		}
+	}
+	if New("{{x}}", "{{", "}}", WithStrictIdentifiers()).plainTags {
+		t.Fatal("unexpected fast path with strict identifiers")
+	}
+}
+
+// limitWriter accepts up to limit bytes and fails writes beyond that with a
+// short write. If silent is set, the short write isn't reported as an error,
+// like broken writers do.
+type limitWriter struct {
+	buf    bytes.Buffer
+	limit  int
+	silent bool
+}
+
+var errLimitReached = errors.New("write limit reached")
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); len(p) > room {
+		w.buf.Write(p[:room])
+		if w.silent {
+			return room, nil
+		}
+		return room, errLimitReached
+	}
+	return w.buf.Write(p)
+}
+
+func (w *limitWriter) Len() int { return w.buf.Len() }
+
+func (w *limitWriter) String() string { return w.buf.String() }
+
+func TestExecuteStdByteCount(t *testing.T) {
+	const (
+		template = "Hi {{name}}, {{unknown}} and {{fail()}}!"
+		expected = "Hi Ann, {{unknown}} and {{fail()}}!"
+	)
+	m := Map{"name": "Ann", "fail": func() (string, error) { return "", errors.New("failed") }}
+	tpl := New(template, "{{", "}}")
+
+	executors := map[string]func(w io.Writer) (int64, error){
+		"Template": func(w io.Writer) (int64, error) { return tpl.ExecuteStd(w, m) },
+		"Func":     func(w io.Writer) (int64, error) { return ExecuteStd(template, "{{", "}}", w, m) },
+	}
+	for name, execute := range executors {
+		t.Run(name, func(t *testing.T) {
+			for limit := 0; limit <= len(expected); limit++ {
+				w := &limitWriter{limit: limit}
+				n, err := execute(w)
+				if n != int64(w.Len()) {
+					t.Fatalf("limit %d: reported %d bytes, wrote %d", limit, n, w.Len())
+				}
+				if limit < len(expected) && !errors.Is(err, errLimitReached) {
+					t.Fatalf("limit %d: expected write error, got %v", limit, err)
+				}
+				if limit == len(expected) && (err != nil || w.String() != expected) {
+					t.Fatalf("unexpected result %q, %v", w.String(), err)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteShortWrites(t *testing.T) {
+	upper := strings.ToUpper
+	m := Map{
+		"name":  "Ann",
+		"upper": upper,
+		"stream": func() io.Reader {
+			return strings.NewReader("streamed")
+		},
+		"tagFunc": func(w io.Writer, tag string) (int, error) {
+			return w.Write([]byte("[" + tag + "]"))
+		},
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		execute  func(w io.Writer) (int64, error)
+	}{
+		{"Execute", "Hi Ann, ANN streamed [tagFunc]!", func(w io.Writer) (int64, error) {
+			return New("Hi {{name}}, {{upper(name)}} {{stream()}} {{tagFunc}}!", "{{", "}}").Execute(w, m)
+		}},
+		{"ExecuteStd", "Hi Ann {{unknown}}!", func(w io.Writer) (int64, error) {
+			return New("Hi {{name}} {{unknown}}!", "{{", "}}").ExecuteStd(w, m)
+		}},
+		{"ExecuteAsync", "Hi Ann!", func(w io.Writer) (int64, error) {
+			return New("Hi {{name}}!", "{{", "}}").ExecuteAsync(w, m)
+		}},
+		{"SingleTag", "Hi Ann!", func(w io.Writer) (int64, error) {
+			return New("Hi {{name}}!", "{{", "}}").Execute(w, m)
+		}},
+		{"NoTags", "Hi there!", func(w io.Writer) (int64, error) {
+			return New("Hi there!", "{{", "}}").Execute(w, m)
+		}},
+		{"Blocks", "<Hi Ann>!", func(w io.Writer) (int64, error) {
+			return New("{{capture x}}Hi {{name}}{{end}}<{{x}}>{{section \"s\"}}!{{end}}", "{{", "}}").Execute(w, m)
+		}},
+		{"Escaping", "<b title=\"&lt;Ann&gt;\">", func(w io.Writer) (int64, error) {
+			return New(`<b title="{{name}}">`, "{{", "}}", WithEscaping(EscapeHTML)).Execute(w, Map{"name": "<Ann>"})
+		}},
+		{"OutputFilter", "Hi Ann!", func(w io.Writer) (int64, error) {
+			return New("Hi {{name}}", "{{", "}}", WithOutputFilter(func(p []byte) []byte { return append(p, '!') })).Execute(w, m)
+		}},
+		{"Func", "Hi Ann, ANN {{x", func(w io.Writer) (int64, error) {
+			return Execute("Hi {{name}}, {{upper(name)}} {{x", "{{", "}}", w, m)
+		}},
+		{"FuncStd", "Hi Ann {{unknown}} {{x", func(w io.Writer) (int64, error) {
+			return ExecuteStd("Hi {{name}} {{unknown}} {{x", "{{", "}}", w, m)
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, silent := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/silent=%t", tt.name, silent), func(t *testing.T) {
+				for limit := 0; limit <= len(tt.expected); limit++ {
+					w := &limitWriter{limit: limit, silent: silent}
+					n, err := tt.execute(w)
+					if n != int64(w.Len()) {
+						t.Fatalf("limit %d: reported %d bytes, wrote %d", limit, n, w.Len())
+					}
+					if limit == len(tt.expected) {
+						if err != nil || w.String() != tt.expected {
+							t.Fatalf("unexpected result %q, %v", w.String(), err)
+						}
+						continue
+					}
+					if err == nil {
+						t.Fatalf("limit %d: expected write error, wrote %q", limit, w.String())
+					}
+					if !errors.Is(err, errLimitReached) && !errors.Is(err, io.ErrShortWrite) {
+						t.Fatalf("limit %d: unexpected error %v", limit, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// readerFromBuffer records calls of ReadFrom.
+type readerFromBuffer struct {
+	buf       bytes.Buffer
+	readFroms int
+}
+
+func (w *readerFromBuffer) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *readerFromBuffer) ReadFrom(r io.Reader) (int64, error) {
+	w.readFroms++
+	return w.buf.ReadFrom(r)
+}
+
+func TestReaderValues(t *testing.T) {
+	attachment := strings.Repeat("attachment data\n", 1000)
+	f, err := os.CreateTemp(t.TempDir(), "attachment")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(attachment); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tpl := New("--\n{{file}}--\n{{bytes}}--", "{{", "}}")
+	w := &readerFromBuffer{}
+	n, err := tpl.Execute(w, Map{"file": f, "bytes": Stream{bytes.NewReader([]byte("inline"))}})
+	expected := "--\n" + attachment + "--\ninline--"
+	if err != nil || w.buf.String() != expected || n != int64(len(expected)) {
+		t.Fatalf("unexpected result (%d bytes), %v", n, err)
+	}
+	if w.readFroms == 0 {
+		t.Fatal("expected the file to be copied with ReadFrom")
+	}
+
+	// values belong to the caller and aren't closed
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("expected the file to stay open, got %s", err)
+	}
+
+	// other readers are formatted, so templates can be executed again
+	buf := bytes.NewBufferString("hello")
+	tpl = New("[{{buf}}][{{buf}}][{{get()}}]", "{{", "}}")
+	m := Map{"buf": buf, "get": func() *bytes.Buffer { return buf }}
+	for i := 0; i < 2; i++ {
+		if s := tpl.ExecuteString(m); s != "[hello][hello][hello]" {
+			t.Fatalf("unexpected output of execution %d: %q", i+1, s)
+		}
+	}
+}
@@ -269,6 +269,33 @@ func BenchmarkFastTemplateExecuteStringStd(b *testing.B) {
 	})
 }
 
+func BenchmarkFastTemplateExecuteSingleTag(b *testing.B) {
+	const expected = "https://sho.rt/aaasdf?utm_source=share"
+	data := Map{"uid": "aaasdf"}
+
+	run := func(b *testing.B, t *Template) {
+		b.RunParallel(func(pb *testing.PB) {
+			var w bytes.Buffer
+			for pb.Next() {
+				_, _ = t.Execute(&w, data)
+				if w.String() != expected {
+					b.Fatalf("unexpected result\n%q\nExpected\n%q\n", w.String(), expected)
+				}
+				w.Reset()
+			}
+		})
+	}
+
+	b.Run("FastPath", func(b *testing.B) {
+		run(b, New("https://sho.rt/{{uid}}?utm_source=share", "{{", "}}"))
+	})
+	b.Run("Generic", func(b *testing.B) {
+		t := New("https://sho.rt/{{uid}}?utm_source=share", "{{", "}}")
+		t.singleTag = false
+		run(b, t)
+	})
+}
+
 func BenchmarkNewTemplate(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -286,17 +313,24 @@ func BenchmarkTemplateReset(b *testing.B) {
 	})
 }
 
-// func BenchmarkTemplateResetExecuteFunc(b *testing.B) {
-// 	b.RunParallel(func(pb *testing.PB) {
-// 		t := New(source, "{{", "}}")
-// 		var w bytes.Buffer
-// 		for pb.Next() {
-// 			t.Reset(source, "{{", "}}")
-// 			t.ExecuteFunc(&w, testTagFunc)
-// 			w.Reset()
-// 		}
-// 	})
-// }
+func BenchmarkTemplateResetExecuteFunc(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		t := New(source, "{{", "}}")
+		var w bytes.Buffer
+		for pb.Next() {
+			t.Reset(source, "{{", "}}")
+			t.ExecuteFunc(&w, testTagFunc)
+			w.Reset()
+		}
+	})
+}
+
+func testTagFunc(w io.Writer, tag string) (int, error) {
+	if t, ok := m[tag]; ok {
+		return w.Write(t.([]byte))
+	}
+	return 0, nil
+}
 
 func BenchmarkExecuteFunc(b *testing.B) {
 	// Redefine testTagFunc as a map
@@ -629,3 +663,20 @@ func BenchmarkFastTemplateVersus(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkTemplatePoolExecute(b *testing.B) {
+	p := NewTemplatePool()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var w bytes.Buffer
+		for pb.Next() {
+			_, _ = p.Execute(source, "{{", "}}", &w, m)
+			x := w.Bytes()
+			if !bytes.Equal(x, resultBytes) {
+				b.Fatalf("unexpected result\n%q\nExpected\n%q\n", x, resultBytes)
+			}
+			w.Reset()
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultTimeLayout is the layout of time.Time values unless set with
+// WithTimeLayout.
+const defaultTimeLayout = time.RFC3339
+
+func init() {
+	RegisterBuiltin("format", Func{Fn: formatTime, Idempotent: true})
+}
+
+// WithTimeLayout sets the layout used to write time.Time values, which
+// defaults to time.RFC3339. The layout is in the format accepted by
+// time.Time.Format.
+func WithTimeLayout(layout string) Option {
+	return func(t *Template) {
+		t.timeLayout = layout
+	}
+}
+
+// timeLayouts maps the names of the layouts defined by the time package to
+// their values, so templates can write format(t, 'RFC1123').
+var timeLayouts = map[string]string{
+	"Layout":      time.Layout,
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// formatTime formats the time value v using layout, which is either a
+// layout accepted by time.Time.Format or the name of a layout constant of
+// the time package.
+func formatTime(v any, layout string) (string, error) {
+	var t time.Time
+	switch tv := v.(type) {
+	case time.Time:
+		t = tv
+	case *time.Time:
+		if tv == nil {
+			return "", nil
+		}
+		t = *tv
+	default:
+		return "", fmt.Errorf("format: expected time.Time, got %T", v)
+	}
+	if named, ok := timeLayouts[layout]; ok {
+		layout = named
+	}
+	return t.Format(layout), nil
+}
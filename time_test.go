@@ -0,0 +1,45 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeValues(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+	m := Map{
+		"ts":  ts,
+		"now": func() time.Time { return ts },
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		opts     []Option
+		expected string
+	}{
+		{"DefaultLayout", "{{ts}} {{now()}}", nil, "2024-03-09T14:05:00Z 2024-03-09T14:05:00Z"},
+		{"TemplateLayout", "{{ts}} {{now()}}", []Option{WithTimeLayout(time.DateOnly)}, "2024-03-09 2024-03-09"},
+		{"FormatLayout", "{{format(ts, '02 Jan 15:04')}}", nil, "09 Mar 14:05"},
+		{"FormatNamedLayout", "{{format(now(), 'Kitchen')}}", nil, "2:05PM"},
+		{"Escaped", "<p>{{ts}}</p>", []Option{WithEscaping(EscapeHTML), WithTimeLayout("<2006>")}, "<p>&lt;2024&gt;</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := New(tt.template, "{{", "}}", tt.opts...)
+			if s := tpl.ExecuteString(m); s != tt.expected {
+				t.Fatalf("unexpected output %q. Expected %q", s, tt.expected)
+			}
+		})
+	}
+
+	// values with a monotonic clock reading are written without it
+	if s := New("{{ts}}", "{{", "}}").ExecuteString(Map{"ts": time.Now()}); strings.Contains(s, "m=") {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if _, err := Eval[string]("format(ts, 'RFC3339')", Map{"ts": "not a time"}); err == nil {
+		t.Fatalf("expected error for non-time argument")
+	}
+}
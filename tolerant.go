@@ -0,0 +1,76 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ParseError is a structural error of a template reported by
+// [ParseTolerant].
+type ParseError struct {
+	// Offset is the byte offset of the start tag of the erroneous tag in
+	// the template source.
+	Offset int
+
+	// Tag is the content of the erroneous tag; empty for unclosed tags.
+	Tag string
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ParseTolerant parses the template like [NewTemplate], but instead of
+// stopping at the first error it parses as much as possible and reports all
+// structural errors ordered by their position, e.g. to keep rendering
+// previews in editors while a template is being written.
+//
+// The returned template is usable even if errors are reported:
+//   - an unclosed tag and the text following it are kept as static text,
+//   - blocks missing their end tag are closed at the end of the template,
+//   - tags with invalid function calls or expressions are kept and fail
+//     when executed, as usual.
+//
+// The template is nil only if the delimiters are empty or a limit set with
+// [WithMaxTemplateSize] or [WithMaxTags] is exceeded, in which case the
+// single reported error wraps the [*DelimiterError] or [*LimitError].
+func ParseTolerant(template, startTag, endTag string, opts ...Option) (*Template, []*ParseError) {
+	var t Template
+	for _, opt := range opts {
+		opt(&t)
+	}
+	var errs []*ParseError
+	if err := t.reset(template, startTag, endTag, &errs); err != nil {
+		return nil, []*ParseError{{Err: err}}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Offset < errs[j].Offset })
+	return &t, errs
+}
+
+// collectErrors closes the blocks missing their end tag and appends their
+// errors and the syntax errors of the tags to errs. offsets holds the source
+// offsets of the tags.
+func (t *Template) collectErrors(errs *[]*ParseError, offsets []int) {
+	for _, i := range t.buildBlocks() {
+		*errs = append(*errs, &ParseError{
+			Offset: offsets[i],
+			Tag:    t.tags[i],
+			Err:    fmt.Errorf("missing %s%s%s for block tag", t.startTag, keywordEnd, t.endTag),
+		})
+	}
+	t.checkRanges(func(i int, err error) {
+		*errs = append(*errs, &ParseError{Offset: offsets[i], Tag: t.tags[i], Err: err})
+	})
+	for i, tag := range t.tags {
+		if t.isBlockTag(i) {
+			continue
+		}
+		if err := t.analyzeTag(tag).err; err != nil {
+			*errs = append(*errs, &ParseError{Offset: offsets[i], Tag: tag, Err: err})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package fasttemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTolerant(t *testing.T) {
+	template := "{{capture x}}Hi {{upper(name}} {{a +}}{{name}}! {{x}} {{unclosed"
+	tpl, errs := ParseTolerant(template, "{{", "}}")
+	if tpl == nil {
+		t.Fatalf("expected a template")
+	}
+
+	expected := []struct {
+		offset int
+		tag    string
+		msg    string
+	}{
+		{0, "capture x", "missing {{end}}"},
+		{31, "a +", ""},
+		{54, "", "cannot find end tag"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for i, want := range expected {
+		err := errs[i]
+		if err.Offset != want.offset || err.Tag != want.tag || !strings.Contains(err.Error(), want.msg) {
+			t.Fatalf("unexpected error %d: %+v (%s)", i, err, err)
+		}
+		if !strings.HasPrefix(template[err.Offset:], "{{") {
+			t.Fatalf("offset %d of error %d doesn't point to a tag", err.Offset, i)
+		}
+	}
+
+	// the unclosed block ends with the template, so nothing is rendered
+	if s := tpl.ExecuteStringStd(Map{"name": "Ann"}); s != "" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	tpl, errs = ParseTolerant("Hi {{name}}! {{unclosed", "{{", "}}")
+	if len(errs) != 1 || errs[0].Offset != 13 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if s := tpl.ExecuteString(Map{"name": "Ann"}); s != "Hi Ann! {{unclosed" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestParseTolerantValid(t *testing.T) {
+	tpl, errs := ParseTolerant("{{capture x}}{{a}}{{end}}[{{x}}]", "{{", "}}")
+	if errs != nil {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if s := tpl.ExecuteString(Map{"a": "1"}); s != "[1]" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestParseTolerantDelimiters(t *testing.T) {
+	tpl, errs := ParseTolerant("{{a}}", "", "}}")
+	var de *DelimiterError
+	if tpl != nil || len(errs) != 1 || !errors.As(errs[0], &de) {
+		t.Fatalf("unexpected result %v %v", tpl, errs)
+	}
+}
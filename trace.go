@@ -0,0 +1,88 @@
+package fasttemplate
+
+import (
+	"io"
+	"time"
+)
+
+// Span is the output produced by a tag occurrence.
+type Span struct {
+	// Template is the name of the template, if any.
+	Template string
+
+	// Tag is the content of the tag.
+	Tag string
+
+	// Index is the index of the tag occurrence in the template, counting
+	// block tags.
+	Index int
+
+	// Offset is the source offset of the start tag.
+	Offset int
+
+	// Start and End delimit the bytes the tag produced in the output.
+	Start, End int64
+
+	// Err is the error the tag failed with, if any.
+	Err error
+}
+
+// Trace lists the spans of the tags of an execution in output order.
+type Trace []Span
+
+// tracer records the spans of an execution writing to w.
+type tracer struct {
+	w       io.Writer
+	n       *int64
+	offsets []int
+	spans   Trace
+}
+
+// add records the span of the i-th tag.
+func (tr *tracer) add(t *Template, i int, start int64, err error) {
+	tr.spans = append(tr.spans, Span{
+		Template: t.name,
+		Tag:      t.tags[i],
+		Index:    i,
+		Offset:   tr.offsets[i],
+		Start:    start,
+		End:      *tr.n,
+		Err:      err,
+	})
+}
+
+// ExecuteTraced executes the template like Execute and returns the byte
+// range of the output each tag occurrence produced, e.g. to highlight
+// substitutions in previews or attribute errors to tags in editors.
+//
+// Tags are traced where they are written to w, so tags of sections
+// appear in the trace while those captured by capture blocks don't. A tag
+// occurring in a section rendered several times appears once per
+// rendering. If a tag fails, the trace ends with its span, holding the
+// error. Ranges refer to the output before output filters are applied.
+func (t *Template) ExecuteTraced(w io.Writer, m Map) (trace Trace, err error) {
+	ec := t.newEvalContext()
+	ec.color = t.useColor(w)
+	var pos int64
+	tr := &tracer{n: &pos, offsets: t.tagOffsets()}
+	ec.trace = tr
+
+	var n int64
+	if t.renderHooks != nil {
+		defer t.observeRender(time.Now(), &n, &err, ec)
+	}
+	if len(t.outputStages) > 0 {
+		fw, finish := t.filterOutput(w)
+		tr.w = &countingWriter{w: fw, n: &pos}
+		_, err = t.render(tr.w, m, ec, false)
+		var ferr error
+		n, ferr = finish()
+		if err == nil {
+			err = ferr
+		}
+	} else {
+		tr.w = &countingWriter{w: w, n: &pos}
+		n, err = t.render(tr.w, m, ec, false)
+	}
+	return tr.spans, t.formatError(err)
+}
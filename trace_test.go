@@ -0,0 +1,76 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecuteTraced(t *testing.T) {
+	tpl := New("Hi {{name}}, {{count * 2}} new{{missing}}!", "{{", "}}")
+	var bb bytes.Buffer
+	trace, err := tpl.ExecuteTraced(&bb, Map{"name": "Ann", "count": 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out := bb.String()
+	if out != "Hi Ann, 6 new!" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	expected := Trace{
+		{Tag: "name", Index: 0, Offset: 3, Start: 3, End: 6},
+		{Tag: "count * 2", Index: 1, Offset: 13, Start: 8, End: 9},
+		{Tag: "missing", Index: 2, Offset: 30, Start: 13, End: 13},
+	}
+	if len(trace) != len(expected) {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	for i, span := range trace {
+		if span != expected[i] {
+			t.Fatalf("unexpected span %+v. Expected %+v", span, expected[i])
+		}
+	}
+	if s := out[trace[0].Start:trace[0].End]; s != "Ann" {
+		t.Fatalf("unexpected span content %q", s)
+	}
+}
+
+func TestExecuteTracedError(t *testing.T) {
+	tpl := New("{{a}} {{fail()}} {{b}}", "{{", "}}")
+	var bb bytes.Buffer
+	trace, err := tpl.ExecuteTraced(&bb, Map{"a": "x", "b": "y"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(trace) != 2 || trace[1].Tag != "fail()" || trace[1].Offset != 6 || trace[1].Err == nil {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+}
+
+func TestExecuteTracedBlocks(t *testing.T) {
+	tpl := New("{{capture x}}[{{a}}]{{end}}{{section 's'}}{{x}}{{end}}{{b|max:3}}", "{{", "}}")
+	var bb bytes.Buffer
+	trace, err := tpl.ExecuteTraced(&bb, Map{"a": "1", "b": "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bb.String() != "[1]2" {
+		t.Fatalf("unexpected output %q", bb.String())
+	}
+	// the captured tag isn't traced, the stripped suffix is accounted for
+	if len(trace) != 2 || trace[0].Tag != "x" || trace[0].End != 3 || trace[1].Tag != "b" || trace[1].Offset != 54 || trace[1].Start != 3 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+}
+
+func TestExecuteTracedBudget(t *testing.T) {
+	tpl := New("a {{long|truncate}} b {{short}}", "{{", "}}", WithTokenBudget(100, nil))
+	var bb bytes.Buffer
+	trace, err := tpl.ExecuteTraced(&bb, Map{"long": strings.Repeat("x", 10), "short": "y"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(trace) != 2 || trace[0].Start != 2 || trace[0].End != 12 || trace[1].Offset != 22 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+}
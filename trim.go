@@ -0,0 +1,69 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"strings"
+)
+
+// trimCutset is the whitespace removed by trim markers.
+const trimCutset = " \t\r\n"
+
+// hasTrimPrefix reports whether tag starts with a trim marker such as in
+// {{- name}}: a dash followed by whitespace.
+func hasTrimPrefix(tag string) bool {
+	return len(tag) >= 2 && tag[0] == '-' && strings.IndexByte(trimCutset, tag[1]) >= 0
+}
+
+// hasTrimSuffix reports whether tag ends with a trim marker such as in
+// {{name -}}: whitespace followed by a dash.
+func hasTrimSuffix(tag string) bool {
+	return len(tag) >= 2 && tag[len(tag)-1] == '-' && strings.IndexByte(trimCutset, tag[len(tag)-2]) >= 0
+}
+
+// cutTrimMarkers returns tag without its trim markers and reports which
+// markers it has. The content of tags with markers is trimmed.
+func cutTrimMarkers(tag string) (inner string, left, right bool) {
+	left, right = hasTrimPrefix(tag), hasTrimSuffix(tag)
+	if left {
+		tag = tag[1:]
+	}
+	if right && len(tag) >= 2 {
+		tag = tag[:len(tag)-1]
+	}
+	if left || right {
+		tag = strings.TrimSpace(tag)
+	}
+	return tag, left, right
+}
+
+// trimMarkers strips the trim markers of the tags, removing the whitespace
+// of the static texts before tags starting with "- " and after tags ending
+// with " -". The removed bytes are recorded for tagOffsets.
+func (t *Template) trimMarkers() {
+	for i, tag := range t.tags {
+		inner, left, right := cutTrimMarkers(tag)
+		if left {
+			t.trimText(i, bytes.TrimRight(t.texts[i], trimCutset))
+		}
+		if right {
+			t.trimText(i+1, bytes.TrimLeft(t.texts[i+1], trimCutset))
+		}
+		if left || right {
+			t.stripTag(i, inner)
+		}
+	}
+}
+
+// trimText replaces the i-th static text with text, recording the removed
+// bytes as dropped before the i-th tag.
+func (t *Template) trimText(i int, text []byte) {
+	n := len(t.texts[i]) - len(text)
+	t.texts[i] = text
+	if n == 0 || i >= len(t.tags) {
+		return
+	}
+	if t.dropped == nil {
+		t.dropped = make([]int, len(t.tags))
+	}
+	t.dropped[i] += n
+}
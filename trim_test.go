@@ -0,0 +1,41 @@
+package fasttemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrimMarkers(t *testing.T) {
+	src := "server {\n    {{- range h in hosts }}\n    listen {{ h -}}\n    ;\n    {{- end }}\n}\n"
+	tpl := New(src, "{{", "}}")
+	if s := tpl.ExecuteString(Map{"hosts": []string{"a", "b"}}); s != "server {\n    listen a;\n    listen b;\n}\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	m := Map{"a": 5, "b": 2, "name": "Ann"}
+	tests := map[string]string{
+		"x  {{- name -}}  y":                       "xAnny",
+		"x {{- name}} y":                           "xAnn y",
+		"x {{name -}} y":                           "x Anny",
+		"x {{0 - a}} {{a - b}} {{a -b}}":           "x -5 3 3",
+		"a\n\n{{- if a > 1 -}}\n\n big\n{{- end}}": "abig",
+		"a\n{{- # note # -}}\nb":                   "ab",
+		"a {{- raw -}} {{x}} {{- endraw -}} b":     "a{{x}}b",
+		"a {{raw}} {{x}} {{endraw}} b":             "a  {{x}}  b",
+	}
+	for src, want := range tests {
+		if s := New(src, "{{", "}}").ExecuteString(m); s != want {
+			t.Fatalf("unexpected output of %q: %q, want %q", src, s, want)
+		}
+	}
+
+	// offsets account for the trimmed whitespace
+	src = "a  {{- x -}}  b {{- y }}"
+	findings := New(src, "{{", "}}").Analyze(Map{})
+	if len(findings) != 2 || findings[0].Offset != strings.Index(src, "{{- x") || findings[1].Offset != strings.Index(src, "{{- y") {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	if vars := New(src, "{{", "}}").Variables(); strings.Join(vars, ",") != "x,y" {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+}
@@ -0,0 +1,53 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Warm parses every function call and expression of the template into the
+// parse caches shared by all templates, so the first execution doesn't pay
+// for parsing them. Call it at deploy or startup time to avoid latency
+// spikes on the first requests. The caches hold copies of the tags, not
+// references to the template source, and are emptied once they hold a few
+// thousand entries, so warming many templates may evict earlier ones.
+//
+// Warm returns the parse errors of all tags joined with [errors.Join], or
+// nil if every tag parsed. Tags failing to parse don't stop the others from
+// being cached.
+func (t *Template) Warm() error {
+	var errs []error
+	for i, tag := range t.tags {
+		if t.isBlockTag(i) {
+			continue
+		}
+		if err := warmTag(tag); err != nil {
+			errs = append(errs, fmt.Errorf("tag %q: %w", tag, err))
+		}
+	}
+	return t.formatError(errors.Join(errs...))
+}
+
+// warmTag parses the tag into the caches.
+func warmTag(tag string) error {
+	if isFunctionCall(tag) {
+		_, err := parseFunctionCallCached(tag)
+		return err
+	}
+	if !isExpression(tag) {
+		return nil
+	}
+
+	tokens, err := compileExpression(tag)
+	if err != nil {
+		return err
+	}
+	for _, tok := range tokens {
+		if tok.typ == tokenFunctionCall {
+			if _, err := parseFunctionCallCached(tok.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
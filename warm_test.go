@@ -0,0 +1,75 @@
+package fasttemplate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWarm(t *testing.T) {
+	tpl := New("{{capture x}}{{warmA(name)}}{{end}}{{name}} {{warmB(1) * 2 > warmC}} {{x}}", "{{", "}}")
+	if err := tpl.Warm(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	callCache.mu.RLock()
+	_, callCached := callCache.calls["warmA(name)"]
+	_, nestedCached := callCache.calls["warmB(1)"]
+	callCache.mu.RUnlock()
+	exprCache.mu.RLock()
+	_, exprCached := exprCache.postfix["warmB(1) * 2 > warmC"]
+	exprCache.mu.RUnlock()
+	if !callCached || !nestedCached || !exprCached {
+		t.Fatalf("expected tags to be cached: call %t, nested call %t, expression %t", callCached, nestedCached, exprCached)
+	}
+
+	m := Map{
+		"name":  "Ann",
+		"warmA": strings.ToUpper,
+		"warmB": func(n int) int { return n },
+		"warmC": 1,
+	}
+	if s := tpl.ExecuteString(m); s != "Ann true ANN" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
+
+func TestWarmErrors(t *testing.T) {
+	tpl := New("{{bad name(x)}} {{ok(x)}} {{a + (b}} {{c}}", "{{", "}}",
+		WithErrorFormatter(func(err error) string { return "warm: " + err.Error() }))
+	err := tpl.Warm()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "warm: ") || !strings.Contains(msg, `tag "bad name(x)"`) || !strings.Contains(msg, `tag "a + (b"`) {
+		t.Fatalf("unexpected error %q", msg)
+	}
+	if strings.Contains(msg, "ok(x)") {
+		t.Fatalf("unexpected error for a valid tag: %q", msg)
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Fatalf("expected 2 joined errors, got %v", err)
+	}
+}
+
+func TestParseCacheBounded(t *testing.T) {
+	for i := 0; i < maxParseCacheSize+10; i++ {
+		src := []byte(fmt.Sprintf("{{bounded%d(x)}} {{x + %d}}", i, i))
+		if err := New(string(src), "{{", "}}").Warm(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	callCache.mu.RLock()
+	calls := len(callCache.calls)
+	callCache.mu.RUnlock()
+	exprCache.mu.RLock()
+	exprs := len(exprCache.postfix)
+	exprCache.mu.RUnlock()
+	if calls > maxParseCacheSize || exprs > maxParseCacheSize {
+		t.Fatalf("parse caches exceed %d entries: %d calls, %d expressions", maxParseCacheSize, calls, exprs)
+	}
+}
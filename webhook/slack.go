@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// Size limits of Slack messages.
+const (
+	slackMaxBlocks      = 50
+	slackMaxText        = 40000
+	slackMaxHeaderText  = 150
+	slackMaxSectionText = 3000
+	slackMaxContextText = 2000
+)
+
+// Slack renders Slack Block Kit messages.
+type Slack struct {
+	text   *fasttemplate.Template
+	blocks []block
+}
+
+// NewSlack parses a Slack message with the given fallback text, shown in
+// notifications, and blocks. Values substituted into mrkdwn texts have "&",
+// "<" and ">" escaped, so they can't inject mentions or links. Header texts
+// are plain text and aren't escaped.
+func NewSlack(text string, blocks []Block, startTag, endTag string, opts ...fasttemplate.Option) (*Slack, error) {
+	if len(blocks) > slackMaxBlocks {
+		return nil, fmt.Errorf("webhook: %d blocks exceed the Slack limit of %d", len(blocks), slackMaxBlocks)
+	}
+
+	mrkdwnOpts := append([]fasttemplate.Option{fasttemplate.WithValueTransformer(escapeSlackValue)}, opts...)
+	var s Slack
+	var err error
+	if s.text, err = fasttemplate.NewTemplate(text, startTag, endTag, mrkdwnOpts...); err != nil {
+		return nil, fmt.Errorf("webhook: text: %w", err)
+	}
+	s.blocks, err = parseBlocks(blocks, startTag, endTag, func(kind BlockKind) []fasttemplate.Option {
+		if kind == Header {
+			return opts
+		}
+		return mrkdwnOpts
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Render renders the message for data and returns its JSON payload.
+func (s *Slack) Render(data fasttemplate.Map) ([]byte, error) {
+	text, err := execute(s.text, data, slackMaxText, true)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: text: %w", err)
+	}
+
+	type textObject struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type slackBlock struct {
+		Type     string        `json:"type"`
+		Text     *textObject   `json:"text,omitempty"`
+		Elements []*textObject `json:"elements,omitempty"`
+	}
+	payload := struct {
+		Text   string       `json:"text"`
+		Blocks []slackBlock `json:"blocks,omitempty"`
+	}{Text: text}
+
+	for i, b := range s.blocks {
+		var sb slackBlock
+		switch b.kind {
+		case Header:
+			text, err = execute(b.text, data, slackMaxHeaderText, false)
+			sb = slackBlock{Type: "header", Text: &textObject{"plain_text", text}}
+		case Context:
+			text, err = execute(b.text, data, slackMaxContextText, true)
+			sb = slackBlock{Type: "context", Elements: []*textObject{{"mrkdwn", text}}}
+		case Divider:
+			sb = slackBlock{Type: "divider"}
+		default:
+			text, err = execute(b.text, data, slackMaxSectionText, true)
+			sb = slackBlock{Type: "section", Text: &textObject{"mrkdwn", text}}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("webhook: block %d: %w", i, err)
+		}
+		payload.Blocks = append(payload.Blocks, sb)
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestSlack(t *testing.T) {
+	msg, err := NewSlack("Deploy of {{app}} finished", []Block{
+		{Kind: Header, Text: "{{app}} <deployed>"},
+		{Kind: Section, Text: "*Version:* {{version}} by <@{{user}}>"},
+		{Kind: Divider},
+		{Kind: Context, Text: "{{note}}"},
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload, err := msg.Render(fasttemplate.Map{
+		"app":     `api "v2"`,
+		"version": "1.2",
+		"user":    "U123",
+		"note":    "<!channel> & friends",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %s", payload, err)
+	}
+	expected := map[string]any{
+		"text": `Deploy of api "v2" finished`,
+		"blocks": []any{
+			map[string]any{"type": "header", "text": map[string]any{"type": "plain_text", "text": `api "v2" <deployed>`}},
+			map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "*Version:* 1.2 by <@U123>"}},
+			map[string]any{"type": "divider"},
+			map[string]any{"type": "context", "elements": []any{
+				map[string]any{"type": "mrkdwn", "text": "&lt;!channel&gt; &amp; friends"},
+			}},
+		},
+	}
+	gotJSON, _ := json.Marshal(got)
+	expectedJSON, _ := json.Marshal(expected)
+	if string(gotJSON) != string(expectedJSON) {
+		t.Fatalf("unexpected payload\n%s\nwant\n%s", gotJSON, expectedJSON)
+	}
+}
+
+func TestSlackLimits(t *testing.T) {
+	if _, err := NewSlack("", make([]Block, slackMaxBlocks+1), "{{", "}}"); err == nil {
+		t.Fatalf("expected error for too many blocks")
+	}
+
+	msg, err := NewSlack("x", []Block{{Kind: Header, Text: "{{title}}"}}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload, err := msg.Render(fasttemplate.Map{"title": strings.Repeat("é", 200)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got struct {
+		Blocks []struct {
+			Text struct{ Text string }
+		}
+	}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+	title := []rune(got.Blocks[0].Text.Text)
+	if len(title) != slackMaxHeaderText || title[len(title)-1] != '…' {
+		t.Fatalf("unexpected header of %d runes: %q", len(title), string(title))
+	}
+
+	// Slack has no payload limit: every text is truncated to its field limit
+	blocks := make([]Block, slackMaxBlocks)
+	for i := range blocks {
+		blocks[i] = Block{Text: "{{body}}"}
+	}
+	msg, err = NewSlack("{{body}}", blocks, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload, err = msg.Render(fasttemplate.Map{"body": strings.Repeat("x", slackMaxText+1)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var full struct {
+		Text   string
+		Blocks []struct {
+			Text struct{ Text string }
+		}
+	}
+	if err := json.Unmarshal(payload, &full); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+	if n := len([]rune(full.Text)); n != slackMaxText {
+		t.Fatalf("unexpected text of %d runes", n)
+	}
+	for _, b := range full.Blocks {
+		if n := len([]rune(b.Text.Text)); n != slackMaxSectionText {
+			t.Fatalf("unexpected section of %d runes", n)
+		}
+	}
+}
+
+func TestSlackTruncateEntities(t *testing.T) {
+	msg, err := NewSlack("{{body}}", nil, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the cut falls inside the "&amp;" of the last "&"
+	body := strings.Repeat("x", slackMaxText-3) + "&&"
+	payload, err := msg.Render(fasttemplate.Map{"body": body})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got struct{ Text string }
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+	if expected := strings.Repeat("x", slackMaxText-3) + "…"; got.Text != expected {
+		t.Fatalf("unexpected text ending %q", got.Text[len(got.Text)-10:])
+	}
+}
+
+func TestSlackError(t *testing.T) {
+	if _, err := NewSlack("{{", nil, "{{", "}}"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	msg, err := NewSlack("x", []Block{{Text: "{{f()}}"}}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := msg.Render(fasttemplate.Map{"f": func() (string, error) { return "", ErrTooLarge }}); err == nil || !strings.Contains(err.Error(), "block 0") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// teamsMaxPayload is the size limit of Teams webhook payloads.
+const teamsMaxPayload = 28 << 10
+
+// Teams renders Microsoft Teams messages holding an Adaptive Card.
+type Teams struct {
+	blocks []block
+}
+
+// NewTeams parses a Teams message with the given blocks. Headers become
+// large, bold text blocks, context blocks small, subtle ones and dividers
+// add a separator line above the following block. Values substituted into
+// the texts have markdown emphasis, links and list markers escaped, so they
+// can't change the formatting of the message.
+func NewTeams(blocks []Block, startTag, endTag string, opts ...fasttemplate.Option) (*Teams, error) {
+	markdownOpts := append([]fasttemplate.Option{fasttemplate.WithValueTransformer(escapeTeamsValue)}, opts...)
+	parsed, err := parseBlocks(blocks, startTag, endTag, func(BlockKind) []fasttemplate.Option {
+		return markdownOpts
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Teams{blocks: parsed}, nil
+}
+
+// Render renders the message for data and returns its JSON payload. It
+// fails with [ErrTooLarge] if the payload exceeds the Teams size limit of
+// 28 KB.
+func (t *Teams) Render(data fasttemplate.Map) ([]byte, error) {
+	type textBlock struct {
+		Type      string `json:"type"`
+		Text      string `json:"text"`
+		Wrap      bool   `json:"wrap"`
+		Size      string `json:"size,omitempty"`
+		Weight    string `json:"weight,omitempty"`
+		IsSubtle  bool   `json:"isSubtle,omitempty"`
+		Separator bool   `json:"separator,omitempty"`
+	}
+	type card struct {
+		Schema  string      `json:"$schema"`
+		Type    string      `json:"type"`
+		Version string      `json:"version"`
+		Body    []textBlock `json:"body"`
+	}
+	type attachment struct {
+		ContentType string `json:"contentType"`
+		Content     card   `json:"content"`
+	}
+
+	c := card{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    []textBlock{},
+	}
+	separator := false
+	for i, b := range t.blocks {
+		if b.kind == Divider {
+			separator = true
+			continue
+		}
+		text, err := execute(b.text, data, teamsMaxPayload, false)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: block %d: %w", i, err)
+		}
+		tb := textBlock{Type: "TextBlock", Text: text, Wrap: true, Separator: separator}
+		switch b.kind {
+		case Header:
+			tb.Size, tb.Weight = "Large", "Bolder"
+		case Context:
+			tb.Size, tb.IsSubtle = "Small", true
+		}
+		c.Body = append(c.Body, tb)
+		separator = false
+	}
+
+	payload, err := json.Marshal(struct {
+		Type        string       `json:"type"`
+		Attachments []attachment `json:"attachments"`
+	}{
+		Type:        "message",
+		Attachments: []attachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: c}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > teamsMaxPayload {
+		return nil, fmt.Errorf("webhook: %w: %d bytes exceed the Teams limit of %d", ErrTooLarge, len(payload), teamsMaxPayload)
+	}
+	return payload, nil
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+func TestTeams(t *testing.T) {
+	msg, err := NewTeams([]Block{
+		{Kind: Header, Text: "{{app}} deployed"},
+		{Kind: Divider},
+		{Kind: Section, Text: "Version {{version}}"},
+		{Kind: Context, Text: "{{note}}"},
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload, err := msg.Render(fasttemplate.Map{"app": `api "v2"`, "version": "1.2", "note": "line\nbreak"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var got struct {
+		Type        string
+		Attachments []struct {
+			ContentType string
+			Content     struct {
+				Type string
+				Body []map[string]any
+			}
+		}
+	}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %s", payload, err)
+	}
+	if got.Type != "message" || len(got.Attachments) != 1 || got.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected payload %s", payload)
+	}
+	body := got.Attachments[0].Content.Body
+	if len(body) != 3 {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if body[0]["text"] != `api "v2" deployed` || body[0]["weight"] != "Bolder" || body[0]["separator"] != nil {
+		t.Fatalf("unexpected header %v", body[0])
+	}
+	if body[1]["text"] != "Version 1.2" || body[1]["separator"] != true {
+		t.Fatalf("unexpected section %v", body[1])
+	}
+	if body[2]["text"] != "line\nbreak" || body[2]["isSubtle"] != true {
+		t.Fatalf("unexpected context %v", body[2])
+	}
+}
+
+func TestTeamsEscaping(t *testing.T) {
+	msg, err := NewTeams([]Block{
+		{Kind: Header, Text: "**{{app}}**"},
+		{Text: "{{note}}"},
+	}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	payload, err := msg.Render(fasttemplate.Map{
+		"app":  fasttemplate.HTML("*b*"),
+		"note": "[click](http://evil.example) _x_ a\\b\n- item\n 12. step\n1.5 - 2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got struct {
+		Attachments []struct {
+			Content struct {
+				Body []struct{ Text string }
+			}
+		}
+	}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %s", payload, err)
+	}
+	body := got.Attachments[0].Content.Body
+	if body[0].Text != `**\*b\***` {
+		t.Fatalf("unexpected header %q", body[0].Text)
+	}
+	if expected := "\\[click\\]\\(http://evil.example\\) \\_x\\_ a\\\\b\n\\- item\n 12\\. step\n1.5 - 2"; body[1].Text != expected {
+		t.Fatalf("unexpected section %q; want %q", body[1].Text, expected)
+	}
+}
+
+func TestTeamsTooLarge(t *testing.T) {
+	msg, err := NewTeams([]Block{{Text: "{{a}}"}, {Text: "{{a}}"}}, "{{", "}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = msg.Render(fasttemplate.Map{"a": strings.Repeat("x", teamsMaxPayload/2)})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge; got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package webhook renders chat webhook payloads from fasttemplate templates.
+//
+// Messages are described as a list of blocks whose texts are templates.
+// Rendering a message produces the JSON payload expected by Slack incoming
+// webhooks (Block Kit) or Microsoft Teams (Adaptive Cards):
+//
+//	msg, err := webhook.NewSlack("Deploy of {{app}} finished", []webhook.Block{
+//		{Kind: webhook.Header, Text: "{{app}} deployed"},
+//		{Kind: webhook.Section, Text: "*Version:* {{version}}"},
+//	}, "{{", "}}")
+//	...
+//	payload, err := msg.Render(fasttemplate.Map{"app": "api", "version": "1.2"})
+//
+// Payloads are built with encoding/json, so substituted values can't break
+// out of their JSON strings, and substituted values are escaped for the
+// markup of the service. Slack texts exceeding their size limits are
+// truncated; Teams payloads exceeding the overall size limit are rejected.
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dwisiswant0/fasttemplate"
+)
+
+// BlockKind is the kind of a message block.
+type BlockKind int
+
+const (
+	// Section is a block of formatted text.
+	Section BlockKind = iota
+
+	// Header is a block of large, bold plain text.
+	Header
+
+	// Context is a block of small, secondary text.
+	Context
+
+	// Divider separates blocks. Its text is ignored.
+	Divider
+)
+
+// Block is a block of a message.
+type Block struct {
+	Kind BlockKind
+
+	// Text is the template of the block text.
+	Text string
+}
+
+// ErrTooLarge is returned when a rendered Teams payload exceeds its size
+// limit.
+var ErrTooLarge = errors.New("payload too large")
+
+// block is a parsed block.
+type block struct {
+	kind BlockKind
+	text *fasttemplate.Template
+}
+
+// parseBlocks parses the templates of blocks with the options returned by
+// opts for their kind.
+func parseBlocks(blocks []Block, startTag, endTag string, opts func(BlockKind) []fasttemplate.Option) ([]block, error) {
+	parsed := make([]block, len(blocks))
+	for i, b := range blocks {
+		parsed[i].kind = b.Kind
+		if b.Kind == Divider {
+			continue
+		}
+		t, err := fasttemplate.NewTemplate(b.Text, startTag, endTag, opts(b.Kind)...)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: block %d: %w", i, err)
+		}
+		parsed[i].text = t
+	}
+	return parsed, nil
+}
+
+// execute renders t and truncates the result to at most limit runes. If
+// mrkdwn is set, the result is Slack mrkdwn, whose entities aren't split.
+func execute(t *fasttemplate.Template, data fasttemplate.Map, limit int, mrkdwn bool) (string, error) {
+	var bb bytes.Buffer
+	if _, err := t.Execute(&bb, data); err != nil {
+		return "", err
+	}
+	return truncate(bb.String(), limit, mrkdwn), nil
+}
+
+// truncate shortens s to at most limit runes, ending it with an ellipsis.
+// With entities set, the cut backs up to the start of an entity such as
+// "&amp;" it would split.
+func truncate(s string, limit int, entities bool) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	n := 0
+	for i := range s {
+		if n == limit-1 {
+			if j := strings.LastIndexByte(s[:i], '&'); entities && j >= 0 && !strings.Contains(s[j:i], ";") && isEntityPrefix(s[j:]) {
+				i = j
+			}
+			return s[:i] + "…"
+		}
+		n++
+	}
+	return s
+}
+
+// isEntityPrefix reports whether s starts with one of the entities written
+// by slackEscaper.
+func isEntityPrefix(s string) bool {
+	return strings.HasPrefix(s, "&amp;") || strings.HasPrefix(s, "&lt;") || strings.HasPrefix(s, "&gt;")
+}
+
+// slackEscaper escapes the control characters of Slack mrkdwn.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlackValue escapes values for Slack mrkdwn.
+var escapeSlackValue = fasttemplate.TextTransformer(slackEscaper.Replace)
+
+// teamsEscaper escapes the inline markdown of Teams text blocks: emphasis
+// and links.
+var teamsEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`)
+
+// escapeTeamsValue escapes values for the markdown of Teams text blocks.
+var escapeTeamsValue = fasttemplate.TextTransformer(escapeTeamsMarkdown)
+
+// escapeTeamsMarkdown escapes the inline markdown of s and the list markers
+// starting its lines.
+func escapeTeamsMarkdown(s string) string {
+	lines := strings.Split(teamsEscaper.Replace(s), "\n")
+	for i, line := range lines {
+		text := strings.TrimLeft(line, " ")
+		indent := line[:len(line)-len(text)]
+		digits := len(text) - len(strings.TrimLeft(text, "0123456789"))
+		switch {
+		case strings.HasPrefix(text, "- "), strings.HasPrefix(text, "+ "):
+			lines[i] = indent + `\` + text
+		case digits > 0 && strings.HasPrefix(text[digits:], ". "):
+			lines[i] = indent + text[:digits] + `\` + text[digits:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}